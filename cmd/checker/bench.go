@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
+)
+
+// benchSlowestSets is the number of slowest backup sets listed by --bench
+const benchSlowestSets = 5
+
+// printBenchmark prints how long each validator took across the backup sets,
+// the slowest sets and the throughput of the run, along with the settings
+// that affect them
+func printBenchmark(tr *winbackupchecker.Localizer, run winbackupchecker.RunReport, scanOpts winbackupchecker.ScanOptions, throttle *winbackupchecker.Throttle, elapsed time.Duration) {
+	tr.Printf("\n===== Benchmark =====\n")
+	scanOpts = scanOpts.ForStorage(benchStorage(run))
+	sampleRate := "all files"
+	if scanOpts.SampleRate > 0 {
+		sampleRate = fmt.Sprintf("%g", scanOpts.SampleRate)
+	}
+	tr.Printf("Settings: parallel %d, file_workers %d, sample_rate %s, deep_validation %t, throttle %s\n",
+		scanOpts.MaxWorkers, max(scanOpts.FileWorkers, 1), tr.T(sampleRate), !scanOpts.SkipContent, tr.T(throttle.String()))
+
+	var sets []winbackupchecker.BackupReport
+	validatedFiles := 0
+	for _, scanReport := range run.Results {
+		for _, br := range scanReport.Reports {
+			if len(br.ValidationStats.PhaseTimings) == 0 {
+				continue
+			}
+			sets = append(sets, br)
+			validatedFiles += br.ValidationStats.ValidatedFiles
+		}
+	}
+	tr.Printf("Wall time: %s for %d backup set(s)\n", elapsed.Round(time.Millisecond), len(sets))
+	if len(sets) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%-14s %6s %12s %12s %12s  %s\n", tr.T("Validator"), tr.T("Sets"), tr.T("Total ms"),
+		tr.T("Average ms"), tr.T("Max ms"), tr.T("Slowest set"))
+	var validationMs float64
+	for _, bench := range winbackupchecker.BenchmarkPhases(run.Results) {
+		fmt.Printf("%-14s %6d %12.1f %12.1f %12.1f  %s\n", bench.Phase, bench.Sets,
+			bench.TotalMs, bench.AverageMs, bench.MaxMs, setName(bench.SlowestSet))
+		validationMs += bench.TotalMs
+	}
+
+	// Validation time summed over sets exceeds the wall time by the
+	// parallelism that was actually achieved
+	if wallMs := float64(elapsed) / float64(time.Millisecond); wallMs > 0 {
+		tr.Printf("\nEffective parallelism: %.1f of %d workers\n", validationMs/wallMs, scanOpts.MaxWorkers)
+	}
+	if content := run.PhaseTimings[winbackupchecker.PhaseContent]; content > 0 {
+		tr.Printf("Content throughput: %.1f files/s per worker\n", float64(validatedFiles)/(content/1000))
+	}
+
+	// Reads waiting on storage most of a phase mean more workers won't help
+	if total := winbackupchecker.TotalIO(run.Results); total.BytesRead > 0 {
+		tr.Printf("\nData read per phase:\n")
+		phases := make([]string, 0, len(total.Phases))
+		for phase := range total.Phases {
+			phases = append(phases, phase)
+		}
+		sort.Strings(phases)
+		for _, phase := range phases {
+			p := total.Phases[phase]
+			tr.Printf("  %-14s %10.1f MB %8.1f MB/s  %3.0f%% waiting on storage\n", phase,
+				float64(p.BytesRead)/(1<<20), p.MBPerSecond, p.StorageShare())
+		}
+	}
+
+	sort.SliceStable(sets, func(i, j int) bool {
+		return sets[i].ValidationStats.PhaseTimings.Total() > sets[j].ValidationStats.PhaseTimings.Total()
+	})
+	tr.Printf("\nSlowest backup sets:\n")
+	for _, br := range sets[:min(len(sets), benchSlowestSets)] {
+		tr.Printf("  %10.1f ms  %s (%d files)\n", br.ValidationStats.PhaseTimings.Total(), br.BackupDir, br.ValidationStats.ValidatedFiles)
+	}
+}
+
+// setName names a backup set by its machine and folder
+func setName(backupDir string) string {
+	return filepath.Join(winbackupchecker.MachineName(backupDir), filepath.Base(backupDir))
+}
+
+// benchStorage returns the storage type shared by the backup paths of run,
+// which sets the workers not given on the command line or in the config
+func benchStorage(run winbackupchecker.RunReport) winbackupchecker.StorageType {
+	storage := winbackupchecker.StorageUnknown
+	for i, scanReport := range run.Results {
+		if i == 0 {
+			storage = scanReport.Storage
+		} else if scanReport.Storage != storage {
+			return winbackupchecker.StorageUnknown
+		}
+	}
+	return storage
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// completionWords is what a shell offers after a command: its subcommands
+// and flags
+type completionWords struct {
+	command string
+	words   []string
+}
+
+// subcommandCompletions lists the subcommands with their flags. Keep in step
+// with the flag sets of the run functions.
+var subcommandCompletions = []completionWords{
+	{"find", []string{"--json", "--refresh", "--config"}},
+	{"report", []string{"aggregate"}},
+	{"report aggregate", []string{"--since", "--log", "--top", "--json"}},
+	{"config", []string{"validate"}},
+	{"config validate", []string{"--config", "--email-config", "--profile", "--timeout", "--json"}},
+	{"credentials", []string{"set", "delete"}},
+	{"quarantine", []string{"add", "remove", "list"}},
+	{"quarantine add", []string{"--reason", "--config", "--file"}},
+	{"quarantine remove", []string{"--config", "--file"}},
+	{"quarantine list", []string{"--config", "--file", "--json"}},
+	{"init", []string{"--config", "--email-config", "--force"}},
+	{"schedule", []string{"install", "uninstall"}},
+	{"schedule install", []string{"--daily", "--name", "--config", "--email-config", "--profile", "--system"}},
+	{"schedule uninstall", []string{"--name"}},
+	{"service", []string{"install", "uninstall", "run"}},
+	{"service install", serviceCompletionFlags},
+	{"service run", serviceCompletionFlags},
+	{"completion", []string{"bash", "zsh", "powershell"}},
+}
+
+var serviceCompletionFlags = []string{
+	"--interval", "--timeout", "--config", "--email-config", "--profile",
+	"--json-out", "--log-file", "--parallel", "--fail-on", "--lock-file",
+	"--lock-wait",
+}
+
+// runCompletion prints a completion script for the shell named in args
+func runCompletion(args []string) int {
+	if len(args) != 1 {
+		fmt.Println("Usage: checker completion bash|zsh|powershell")
+		return 2
+	}
+
+	name := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	table := completionTable()
+
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion(name, table))
+	case "zsh":
+		// zsh runs the bash script through its bash compatibility layer
+		fmt.Print("autoload -U +X bashcompinit && bashcompinit\n" + bashCompletion(name, table))
+	case "powershell":
+		fmt.Print(powershellCompletion(name, table))
+	default:
+		log.Printf("Unknown shell: %s", args[0])
+		return 2
+	}
+	return 0
+}
+
+// completionTable returns the words for each command. The empty command is
+// the top level, offering the subcommands and the scan flags, and "-" is a
+// scan flag, offering the other scan flags.
+func completionTable() []completionWords {
+	var flags []string
+	flag.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, "--"+f.Name)
+	})
+	sort.Strings(flags)
+
+	var top []string
+	for _, sub := range subcommandCompletions {
+		if !strings.Contains(sub.command, " ") {
+			top = append(top, sub.command)
+		}
+	}
+
+	table := []completionWords{
+		{"", append(top, flags...)},
+		{"-", flags},
+	}
+	return append(table, subcommandCompletions...)
+}
+
+func bashCompletion(name string, table []completionWords) string {
+	fn := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(name) + "_complete"
+
+	var nested []string
+	for _, entry := range table {
+		if strings.Contains(entry.command, " ") {
+			nested = append(nested, fmt.Sprintf("%q", entry.command))
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# bash completion for %s\n", name)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" key=\"\" words=\"\"\n")
+	b.WriteString("    if [ \"$COMP_CWORD\" -gt 1 ]; then\n")
+	b.WriteString("        key=\"${COMP_WORDS[1]}\"\n")
+	b.WriteString("        case \"$key\" in -*) key=\"-\" ;; esac\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    if [ \"$COMP_CWORD\" -gt 2 ]; then\n")
+	b.WriteString("        case \"${COMP_WORDS[1]} ${COMP_WORDS[2]}\" in\n")
+	fmt.Fprintf(&b, "            %s) key=\"${COMP_WORDS[1]} ${COMP_WORDS[2]}\" ;;\n", strings.Join(nested, "|"))
+	b.WriteString("        esac\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    case \"$key\" in\n")
+	for _, entry := range table {
+		fmt.Fprintf(&b, "        %q) words=%q ;;\n", entry.command, strings.Join(entry.words, " "))
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("    COMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -o default -F %s %s\n", fn, name)
+	return b.String()
+}
+
+func powershellCompletion(name string, table []completionWords) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# PowerShell completion for %s\n", name)
+	fmt.Fprintf(&b, "Register-ArgumentCompleter -Native -CommandName '%s', '%s.exe' -ScriptBlock {\n", name, name)
+	b.WriteString("    param($wordToComplete, $commandAst, $cursorPosition)\n")
+	b.WriteString("    $table = @{\n")
+	for _, entry := range table {
+		fmt.Fprintf(&b, "        '%s' = '%s'\n", entry.command, strings.Join(entry.words, " "))
+	}
+	b.WriteString("    }\n")
+	b.WriteString("    $prev = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })\n")
+	b.WriteString("    if ($wordToComplete -and $prev.Count -gt 0) { $prev = @($prev | Select-Object -SkipLast 1) }\n")
+	b.WriteString("    $key = ''\n")
+	b.WriteString("    if ($prev.Count -ge 1) { $key = $prev[0] }\n")
+	b.WriteString("    if ($key.StartsWith('-')) { $key = '-' }\n")
+	b.WriteString("    if ($prev.Count -ge 2 -and $table.ContainsKey(\"$($prev[0]) $($prev[1])\")) { $key = \"$($prev[0]) $($prev[1])\" }\n")
+	b.WriteString("    if (-not $table.ContainsKey($key)) { return }\n")
+	b.WriteString("    $table[$key] -split ' ' | Where-Object { $_ -like \"$wordToComplete*\" } | ForEach-Object {\n")
+	b.WriteString("        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)\n")
+	b.WriteString("    }\n")
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
+)
+
+// runConfig dispatches the config subcommands
+func runConfig(args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: checker config validate [flags]")
+		return 2
+	}
+
+	switch args[0] {
+	case "validate":
+		return runConfigValidate(args[1:])
+	default:
+		log.Printf("Unknown config command: %s", args[0])
+		return 2
+	}
+}
+
+// configValidation is the JSON output of config validate
+type configValidation struct {
+	Config        string           `json:"config"`
+	EmailConfig   string           `json:"email_config,omitempty"`
+	Profile       string           `json:"profile,omitempty"`
+	Profiles      []string         `json:"profiles,omitempty"`
+	Errors        []string         `json:"errors,omitempty"`
+	Email         string           `json:"email,omitempty"`
+	Notifications []string         `json:"notifications,omitempty"`
+	Schedules     []string         `json:"schedules,omitempty"`
+	Paths         []pathValidation `json:"paths,omitempty"`
+}
+
+// pathValidation is a probed backup path with its effective settings
+type pathValidation struct {
+	winbackupchecker.PathCheck
+	MinBackupAge     string   `json:"min_backup_age"`
+	MaxBackupAge     string   `json:"max_backup_age"`
+	DeepValidation   bool     `json:"deep_validation"`
+	SampleRate       float64  `json:"sample_rate,omitempty"`
+	ExpectedMachines []string `json:"expected_machines,omitempty"`
+	PathTimeout      string   `json:"path_timeout,omitempty"`
+	SetTimeout       string   `json:"set_timeout,omitempty"`
+	Parallel         int      `json:"parallel"`
+	FileWorkers      int      `json:"file_workers"`
+}
+
+// runConfigValidate loads both config files, probes every backup path and
+// prints the effective settings, without scanning. Exits with 2 when a config
+// file is invalid and 1 when a backup path cannot be scanned.
+func runConfigValidate(args []string) int {
+	fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+	configPath := fs.String("config", winbackupchecker.DefaultConfigPath("config.json"), "Config file")
+	emailConfigPath := fs.String("email-config", winbackupchecker.DefaultConfigPath("email.config.json"), "Email config file (optional)")
+	profile := fs.String("profile", "", "Named profile to show the effective settings of")
+	timeout := fs.Duration("timeout", 15*time.Second, "Time to wait for each backup path to respond")
+	jsonOnly := fs.Bool("json", false, "Output the results as JSON")
+	fs.Parse(args)
+
+	result := configValidation{Config: *configPath, EmailConfig: *emailConfigPath, Profile: *profile}
+
+	cfg, err := winbackupchecker.LoadConfigProfile(*configPath, *profile)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("config: %v", err))
+	}
+
+	// Every profile must load, not just the one shown
+	if base, err := winbackupchecker.LoadConfig(*configPath); err == nil {
+		result.Profiles = base.ProfileNames()
+		for _, name := range result.Profiles {
+			if name == *profile {
+				continue
+			}
+			if _, err := winbackupchecker.LoadConfigProfile(*configPath, name); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("config: %v", err))
+			}
+		}
+	}
+
+	emailCfg, err := winbackupchecker.LoadEmailConfig(*emailConfigPath)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("email config: %v", err))
+	}
+
+	switch {
+	case emailCfg == nil:
+		result.Email = "not configured"
+	case !emailCfg.Enabled:
+		result.Email = "disabled"
+	default:
+		result.Email = fmt.Sprintf("%s:%d to %s", emailCfg.SMTPHost, emailCfg.SMTPPort, strings.Join(emailCfg.To, ", "))
+	}
+
+	if cfg != nil {
+		if cfg.BaselineFile != "" {
+			if _, err := winbackupchecker.LoadBaseline(cfg.BaselineFile); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("baseline: %v", err))
+			}
+		}
+		if cfg.QuarantineFile != "" {
+			if _, err := winbackupchecker.LoadQuarantine(cfg.QuarantineFile); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("quarantine: %v", err))
+			}
+		}
+
+		if cfg.Notifications != nil {
+			for _, notifier := range winbackupchecker.BuildNotifiers(cfg.Notifications) {
+				result.Notifications = append(result.Notifications, notifier.Name())
+			}
+		}
+
+		for _, schedule := range cfg.Schedules {
+			cron, _ := winbackupchecker.ParseCron(schedule.Cron)
+			next := cron.Next(time.Now()).Format("Mon 2006-01-02 15:04")
+			result.Schedules = append(result.Schedules, fmt.Sprintf("%s, next %s", schedule, next))
+		}
+
+		base := cfg.ScanOptions()
+		for _, backupPath := range cfg.BackupPaths {
+			opts := backupPath.ScanOptions(base)
+			minAge, maxAge := opts.AgeLimits()
+			check := checkBackupPath(backupPath, *timeout)
+			if opts.Storage != winbackupchecker.StorageUnknown {
+				check.Storage = opts.Storage
+			}
+			opts = opts.ForStorage(check.Storage)
+			result.Paths = append(result.Paths, pathValidation{
+				PathCheck:        check,
+				MinBackupAge:     winbackupchecker.FormatDuration(minAge),
+				MaxBackupAge:     winbackupchecker.FormatDuration(maxAge),
+				DeepValidation:   !opts.SkipContent,
+				SampleRate:       opts.SampleRate,
+				ExpectedMachines: opts.ExpectedMachines,
+				PathTimeout:      formatTimeout(opts.PathTimeout),
+				SetTimeout:       formatTimeout(opts.SetTimeout),
+				Parallel:         opts.MaxWorkers,
+				FileWorkers:      opts.FileWorkers,
+			})
+		}
+	}
+
+	exitCode := 0
+	for _, path := range result.Paths {
+		if !path.OK() {
+			exitCode = 1
+		}
+	}
+	if len(result.Errors) > 0 {
+		exitCode = 2
+	}
+
+	if *jsonOnly {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			log.Printf("Failed to marshal validation result: %v", err)
+			return 2
+		}
+		fmt.Println(string(data))
+		return exitCode
+	}
+
+	printConfigValidation(result)
+	return exitCode
+}
+
+func printConfigValidation(result configValidation) {
+	fmt.Printf("Config:       %s\n", result.Config)
+	fmt.Printf("Email config: %s\n", result.EmailConfig)
+	if len(result.Profiles) > 0 {
+		fmt.Printf("Profiles:     %s\n", strings.Join(result.Profiles, ", "))
+	}
+	if result.Profile != "" {
+		fmt.Printf("Showing:      %s\n", result.Profile)
+	}
+	if len(result.Errors) > 0 {
+		fmt.Println("\nErrors:")
+		for _, err := range result.Errors {
+			fmt.Printf("  - %s\n", err)
+		}
+		if len(result.Paths) == 0 {
+			return
+		}
+	}
+
+	fmt.Printf("\nEmail: %s\n", result.Email)
+	if len(result.Notifications) > 0 {
+		fmt.Printf("Notifications: %s\n", strings.Join(result.Notifications, ", "))
+	} else {
+		fmt.Println("Notifications: none")
+	}
+	for _, schedule := range result.Schedules {
+		fmt.Printf("Schedule: %s\n", schedule)
+	}
+
+	fmt.Println("\nBackup Paths:")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  PATH\tSTATUS\tROOTS\tSTORAGE\tWORKERS\tMIN AGE\tMAX AGE\tDEEP\tSAMPLE\tPATH TIMEOUT\tSET TIMEOUT\tEXPECTED MACHINES")
+	for _, path := range result.Paths {
+		status := "ok"
+		if !path.OK() {
+			status = "FAILED"
+		}
+		sample := "all"
+		if path.SampleRate > 0 {
+			sample = fmt.Sprintf("%.0f%%", path.SampleRate*100)
+		}
+		machines := "-"
+		if len(path.ExpectedMachines) > 0 {
+			machines = strings.Join(path.ExpectedMachines, ", ")
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%d\t%s\t%d/%d\t%s\t%s\t%t\t%s\t%s\t%s\t%s\n",
+			path.Resolved, status, len(path.BackupRoots), path.Storage, path.Parallel, path.FileWorkers, path.MinBackupAge, path.MaxBackupAge,
+			path.DeepValidation, sample, orDash(path.PathTimeout), orDash(path.SetTimeout), machines)
+	}
+	tw.Flush()
+
+	for _, path := range result.Paths {
+		if path.Error != "" {
+			fmt.Printf("\n%s: %s (after %s)\n", path.Path, path.Error, path.Elapsed)
+		}
+	}
+}
+
+// checkBackupPath probes backupPath, connected to its share for the probe
+// when it has credentials
+func checkBackupPath(backupPath winbackupchecker.BackupPath, timeout time.Duration) winbackupchecker.PathCheck {
+	start := time.Now()
+	disconnect, err := backupPath.Connect()
+	if err != nil {
+		return winbackupchecker.PathCheck{
+			Path:     backupPath.Path,
+			Resolved: backupPath.Path,
+			Error:    err.Error(),
+			Elapsed:  time.Since(start).Round(time.Millisecond).String(),
+		}
+	}
+	defer disconnect()
+	return winbackupchecker.CheckBackupPath(backupPath.Path, timeout)
+}
+
+// formatTimeout formats an optional timeout, empty when unset
+func formatTimeout(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return winbackupchecker.FormatDuration(d)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
+)
+
+// runCredentials dispatches the credentials subcommands
+func runCredentials(args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: checker credentials set|delete <name>")
+		return 2
+	}
+
+	switch args[0] {
+	case "set":
+		return runCredentialsSet(args[1:])
+	case "delete":
+		return runCredentialsDelete(args[1:])
+	default:
+		log.Printf("Unknown credentials command: %s", args[0])
+		return 2
+	}
+}
+
+// runCredentialsSet stores a secret in the OS credential store. The secret is
+// prompted for without echo, or read from standard input when piped.
+func runCredentialsSet(args []string) int {
+	fs := flag.NewFlagSet("credentials set", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: checker credentials set <name>\n")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	name := fs.Arg(0)
+
+	fmt.Fprintf(os.Stderr, "Secret for %s: ", name)
+	secret, err := readSecret()
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		log.Printf("Failed to read secret: %v", err)
+		return 2
+	}
+	if secret == "" {
+		log.Printf("Secret cannot be empty")
+		return 2
+	}
+
+	if err := winbackupchecker.SetCredential(name, secret); err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	fmt.Printf("Stored credential %s; set \"password_credential\": %q in email.config.json to use it\n", name, name)
+	return 0
+}
+
+// runCredentialsDelete removes a stored secret
+func runCredentialsDelete(args []string) int {
+	fs := flag.NewFlagSet("credentials delete", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: checker credentials delete <name>\n")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	if err := winbackupchecker.DeleteCredential(fs.Arg(0)); err != nil {
+		if errors.Is(err, winbackupchecker.ErrCredentialNotFound) {
+			fmt.Printf("No credential named %s\n", fs.Arg(0))
+			return 1
+		}
+		log.Printf("%v", err)
+		return 2
+	}
+	fmt.Printf("Deleted credential %s\n", fs.Arg(0))
+	return 0
+}
+
+// stdin is shared by all prompts, so buffered piped input is not lost
+// between them
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine reads one line from standard input without its line ending
+func readLine() (string, error) {
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
+)
+
+// runDaemon checks the backups every interval, or on the config's schedules,
+// until interrupted. Alerts are only sent when a backup set changes between
+// valid and invalid, so a failure is reported once rather than on every check.
+func runDaemon(reloader *winbackupchecker.ConfigReloader, opts loopOptions) int {
+	if opts.interval <= 0 {
+		log.Printf("Invalid --interval: must be positive")
+		return 2
+	}
+	if opts.check.format == "text" {
+		// Printing every report would flood the terminal or journal
+		opts.check.format = "summary"
+	}
+	opts.onlyChanges = true
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Daemon started")
+	runCheckLoop(ctx, reloader, opts)
+	log.Printf("Daemon stopping")
+	return 0
+}
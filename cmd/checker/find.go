@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
+)
+
+// defaultIndexFile is used by find when the config does not set index_file
+const defaultIndexFile = "catalog-index.json"
+
+// runFind searches the catalog index for files by name across all backup sets
+func runFind(args []string) int {
+	fs := flag.NewFlagSet("find", flag.ExitOnError)
+	jsonOnly := fs.Bool("json", false, "Output matches as JSON")
+	refresh := fs.Bool("refresh", false, "Re-index changed catalogs before searching")
+	configPath := fs.String("config", winbackupchecker.DefaultConfigPath("config.json"), "Config file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: checker find [--json] [--refresh] [--config file] <name-or-pattern>\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := winbackupchecker.LoadConfig(*configPath)
+	if err != nil {
+		log.Printf("Error loading config: %v", err)
+		return 2
+	}
+
+	indexPath := cfg.IndexFile
+	if indexPath == "" {
+		indexPath = defaultIndexFile
+	}
+
+	index, err := winbackupchecker.LoadCatalogIndex(indexPath)
+	if err != nil {
+		log.Printf("Error loading catalog index: %v", err)
+		return 2
+	}
+
+	// An empty index has never been built, so build it now
+	if *refresh || index.CatalogCount() == 0 {
+		var cache *winbackupchecker.CatalogCache
+		if cfg.CatalogCache != nil && cfg.CatalogCache.Enabled {
+			cache, err = winbackupchecker.NewCatalogCache(*cfg.CatalogCache)
+			if err != nil {
+				log.Printf("Error creating catalog cache: %v", err)
+				return 2
+			}
+		}
+
+		for _, backupPath := range cfg.BackupPaths {
+			path := backupPath.Path
+			updated, err := indexBackupPath(index, backupPath, cfg.Links, cache)
+			if err != nil {
+				log.Printf("Failed to index %s: %v", path, err)
+				continue
+			}
+			if !*jsonOnly {
+				fmt.Printf("Indexed %d changed catalogs in %s\n", updated, path)
+			}
+		}
+
+		if err := index.Save(); err != nil {
+			log.Printf("Failed to save catalog index: %v", err)
+			return 2
+		}
+	}
+
+	hits, err := index.Search(fs.Arg(0))
+	if err != nil {
+		log.Printf("Search failed: %v", err)
+		return 2
+	}
+
+	if *jsonOnly {
+		data, err := json.MarshalIndent(hits, "", "  ")
+		if err != nil {
+			log.Printf("Failed to marshal results: %v", err)
+			return 2
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, hit := range hits {
+			fmt.Printf("%s\t%s\n", hit.BackupSet, hit.Path)
+		}
+		fmt.Printf("%d matches\n", len(hits))
+	}
+
+	if len(hits) == 0 {
+		return 1
+	}
+	return 0
+}
+
+// indexBackupPath indexes the changed catalogs of backupPath, connected to
+// its share for the update when it has credentials
+func indexBackupPath(index *winbackupchecker.CatalogIndex, backupPath winbackupchecker.BackupPath, links *winbackupchecker.LinkConfig, cache *winbackupchecker.CatalogCache) (int, error) {
+	disconnect, err := backupPath.Connect()
+	if err != nil {
+		return 0, err
+	}
+	defer disconnect()
+
+	sets, err := winbackupchecker.DiscoverBackupSets(backupPath.Path, links)
+	if err != nil {
+		return 0, fmt.Errorf("failed to discover backup sets: %w", err)
+	}
+	return index.Update(context.Background(), sets, cache)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
+)
+
+// smtpCredentialName is the credential init stores the SMTP password under
+const smtpCredentialName = "smtp"
+
+// runInit walks through creating the config files: backup paths (detected on
+// local drives), email settings and a daily schedule
+func runInit(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	configPath := fs.String("config", winbackupchecker.DefaultConfigPath("config.json"), "Config file to write")
+	emailConfigPath := fs.String("email-config", winbackupchecker.DefaultConfigPath("email.config.json"), "Email config file to write")
+	force := fs.Bool("force", false, "Overwrite existing config files without asking")
+	fs.Parse(args)
+
+	fmt.Println("Windows Backup Checker setup")
+	fmt.Println("Press Enter to accept the [default].")
+
+	cfg := winbackupchecker.DefaultConfig()
+	cfg.BackupPaths = askBackupPaths()
+	if len(cfg.BackupPaths) == 0 {
+		log.Printf("No backup paths entered")
+		return 2
+	}
+	for {
+		maxAge := ask("Warn when the newest backup is older than", cfg.MaxBackupAge)
+		if _, err := winbackupchecker.ParseDuration(maxAge); err != nil {
+			fmt.Println("  Enter a duration such as 7d, 36h or 90d")
+			continue
+		}
+		cfg.MaxBackupAge = maxAge
+		break
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Printf("Invalid config: %v", err)
+		return 2
+	}
+
+	emailCfg := askEmail()
+
+	if err := writeConfigFile(*configPath, cfg, *force); err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	if emailCfg != nil {
+		if err := writeConfigFile(*emailConfigPath, emailCfg, *force); err != nil {
+			log.Printf("%v", err)
+			return 2
+		}
+	}
+
+	askSchedule(*configPath, *emailConfigPath)
+
+	fmt.Println("\nCheck the setup without scanning with:")
+	fmt.Println("  checker config validate")
+	return 0
+}
+
+// askBackupPaths offers the backups detected on local drives and returns the
+// chosen paths, by number, or typed in
+func askBackupPaths() []winbackupchecker.BackupPath {
+	fmt.Println("\nLooking for backups on local drives...")
+	detected := winbackupchecker.DetectBackupLocations()
+
+	var defaults []string
+	for i, backup := range detected {
+		note := "file backup"
+		if backup.Kind == winbackupchecker.BackupKindSystemImage {
+			note = "system image (not validated by the checker)"
+		} else {
+			defaults = append(defaults, strconv.Itoa(i+1))
+		}
+		fmt.Printf("  %d) %s  %s\n", i+1, backup.Path, note)
+	}
+	if len(detected) == 0 {
+		fmt.Println("  No backups found; enter the folders Windows Backup writes to.")
+	}
+
+	for {
+		answer := ask("Backup paths to check (numbers or paths, comma separated)", strings.Join(defaults, ","))
+		var paths []winbackupchecker.BackupPath
+		valid := true
+		for _, item := range strings.Split(answer, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			if n, err := strconv.Atoi(item); err == nil {
+				if n < 1 || n > len(detected) {
+					fmt.Printf("  There is no backup number %d\n", n)
+					valid = false
+					break
+				}
+				item = detected[n-1].Path
+			}
+			paths = append(paths, winbackupchecker.BackupPath{Path: item})
+		}
+		if valid && len(paths) > 0 {
+			return paths
+		}
+		if inputClosed {
+			return nil
+		}
+		if valid {
+			fmt.Println("  At least one backup path is required")
+		}
+	}
+}
+
+// askEmail returns the email config, or nil when email alerts are not wanted
+func askEmail() *winbackupchecker.EmailConfig {
+	if !askYesNo("\nSend email alerts?", false) {
+		return nil
+	}
+
+	emailCfg := &winbackupchecker.EmailConfig{
+		Enabled:       true,
+		SubjectPrefix: "[Backup Alert]",
+	}
+	emailCfg.SMTPHost = ask("SMTP server", "smtp.gmail.com")
+	for {
+		port, err := strconv.Atoi(ask("SMTP port (587 for STARTTLS, 465 for TLS)", "587"))
+		if err == nil && port > 0 && port <= 65535 {
+			emailCfg.SMTPPort = port
+			break
+		}
+		fmt.Println("  Enter a port number")
+	}
+	emailCfg.From = ask("Sender address", "")
+	for _, to := range strings.Split(ask("Recipients (comma separated)", emailCfg.From), ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			emailCfg.To = append(emailCfg.To, to)
+		}
+	}
+	emailCfg.Username = ask("SMTP username", emailCfg.From)
+
+	fmt.Fprint(os.Stderr, "SMTP password (use an app password): ")
+	password, err := readSecret()
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		log.Printf("Failed to read password: %v", err)
+	}
+
+	if askYesNo("Keep the password in the OS credential store instead of the config file?", true) {
+		if err := winbackupchecker.SetCredential(smtpCredentialName, password); err != nil {
+			fmt.Printf("  %v\n  The password will be written to the config file instead.\n", err)
+			emailCfg.Password = password
+		} else {
+			emailCfg.PasswordCredential = smtpCredentialName
+		}
+	} else {
+		emailCfg.Password = password
+	}
+
+	emailCfg.SendOnErrors = true
+	emailCfg.SendOnWarnings = askYesNo("Email about warnings, such as old backups?", true)
+	emailCfg.SendOnSuccess = askYesNo("Email when all backups are valid?", false)
+
+	// A stored password is only resolved when the config is loaded
+	check := *emailCfg
+	check.Password = password
+	if err := check.Validate(); err != nil {
+		fmt.Printf("  Email settings are incomplete (%v); edit the file before enabling it\n", err)
+		emailCfg.Enabled = false
+	}
+	return emailCfg
+}
+
+// askSchedule registers a daily run as a scheduled task on Windows, or prints
+// the crontab entry elsewhere
+func askSchedule(configPath, emailConfigPath string) {
+	var at time.Time
+	for {
+		answer := ask("\nRun the check daily at (HH:MM, or none)", "02:00")
+		if strings.EqualFold(answer, "none") {
+			return
+		}
+		var err error
+		if at, err = time.Parse("15:04", answer); err == nil {
+			break
+		}
+		fmt.Println("  Enter a time such as 02:00")
+	}
+
+	if runtime.GOOS == "windows" {
+		task, err := checkerTask(winbackupchecker.DefaultTaskName, at, configPath, emailConfigPath, "", nil)
+		if err == nil {
+			err = winbackupchecker.InstallScheduledTask(task)
+		}
+		if err != nil {
+			fmt.Printf("  %v\n  Register it later with:\n", err)
+			fmt.Printf("  checker schedule install --daily %s --config \"%s\"\n", at.Format("15:04"), configPath)
+			return
+		}
+		fmt.Printf("Scheduled task %q runs the check daily at %s\n", task.Name, at.Format("15:04"))
+		return
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "checker"
+	}
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+
+	fmt.Println("To schedule it, run:")
+	fmt.Printf("  (crontab -l; echo '%d %d * * * \"%s\" --config \"%s\"') | crontab -\n",
+		at.Minute(), at.Hour(), exe, configPath)
+}
+
+// writeConfigFile writes v as indented JSON, asking before replacing a file
+func writeConfigFile(path string, v any, force bool) error {
+	if fileExistsAt(path) && !force && !askYesNo(fmt.Sprintf("\n%s already exists. Overwrite?", path), false) {
+		fmt.Printf("  Skipping %s\n", path)
+		return nil
+	}
+
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Printf("Wrote %s\n", path)
+	return nil
+}
+
+// inputClosed is set once standard input has ended
+var inputClosed bool
+
+// ask prompts for a line of input, returning def when it is left empty
+func ask(question, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	answer, err := readLine()
+	if err != nil {
+		// Input ended; take the defaults rather than prompting forever
+		fmt.Println()
+		inputClosed = true
+		return def
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def
+	}
+	return answer
+}
+
+// askYesNo prompts for a yes or no answer
+func askYesNo(question string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer := strings.ToLower(ask(fmt.Sprintf("%s (%s)", question, hint), ""))
+		switch answer {
+		case "":
+			return def
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+	}
+}
+
+func fileExistsAt(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
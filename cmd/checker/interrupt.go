@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// handleInterrupts stops a single check gracefully on SIGINT or SIGTERM. The
+// first signal closes the returned channel, so no new backup sets are
+// started while those being validated finish and are still reported; a
+// second signal cancels the returned context to stop at once. Call the
+// returned function once the check is done.
+func handleInterrupts(ctx context.Context) (context.Context, <-chan struct{}, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	interrupt := make(chan struct{})
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		log.Printf("Interrupted: finishing the backup sets being validated; interrupt again to stop now")
+		close(interrupt)
+
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		log.Printf("Interrupted again: stopping now")
+		cancel()
+	}()
+
+	return ctx, interrupt, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
+)
+
+// loopOptions configure the repeated checks of the long-running modes
+type loopOptions struct {
+	interval time.Duration
+	timeout  time.Duration
+	paths    []string
+	check    checkOptions
+
+	// onlyChanges limits alerts to runs in which a backup set changed
+	// between valid and invalid
+	onlyChanges bool
+}
+
+// checkLoop runs checks every interval, or at the times of the config's
+// schedules when it has any
+type checkLoop struct {
+	opts     loopOptions
+	reloader *winbackupchecker.ConfigReloader
+
+	// profiles holds a reloader for each profile named by a schedule
+	profiles map[string]*winbackupchecker.ConfigReloader
+
+	// previous is the last run of each profile, for onlyChanges
+	previous map[string]*winbackupchecker.RunReport
+
+	schedules []winbackupchecker.Schedule
+	crons     []*winbackupchecker.CronSchedule
+	next      []time.Time
+	lastRun   time.Time
+}
+
+// runCheckLoop checks the backups until ctx is cancelled. The config files
+// are checked for changes every minute and before each check, so edits,
+// including to the schedules, take effect without a restart.
+func runCheckLoop(ctx context.Context, reloader *winbackupchecker.ConfigReloader, opts loopOptions) {
+	l := &checkLoop{
+		opts:     opts,
+		reloader: reloader,
+		profiles: make(map[string]*winbackupchecker.ConfigReloader),
+		previous: make(map[string]*winbackupchecker.RunReport),
+	}
+
+	for {
+		cfg := reloadConfig(reloader)
+		l.updateSchedules(cfg.Schedules)
+
+		for _, profile := range l.due(time.Now()) {
+			if ctx.Err() != nil {
+				return
+			}
+			l.run(ctx, profile)
+		}
+
+		wait := time.Until(l.nextCheck())
+		if wait > time.Minute {
+			wait = time.Minute
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// reloadConfig reloads the config files when they changed, logging the
+// changed settings, and returns the current config
+func reloadConfig(reloader *winbackupchecker.ConfigReloader) *winbackupchecker.Config {
+	changed, err := reloader.Reload()
+	if err != nil {
+		log.Printf("Config not reloaded: %v", err)
+	} else if len(changed) > 0 {
+		log.Printf("Config reloaded, changed: %s", strings.Join(changed, ", "))
+	}
+	cfg, _ := reloader.Current()
+	return cfg
+}
+
+// updateSchedules parses the schedules when they changed. Without schedules
+// the loop falls back to the interval.
+func (l *checkLoop) updateSchedules(schedules []winbackupchecker.Schedule) {
+	if reflect.DeepEqual(schedules, l.schedules) {
+		return
+	}
+	l.schedules = schedules
+	l.crons = make([]*winbackupchecker.CronSchedule, len(schedules))
+	l.next = make([]time.Time, len(schedules))
+
+	now := time.Now()
+	for i, schedule := range schedules {
+		// Validated when the config was loaded
+		l.crons[i], _ = winbackupchecker.ParseCron(schedule.Cron)
+		l.next[i] = l.crons[i].Next(now)
+		log.Printf("Schedule %s: next check at %s", schedule, l.next[i].Format(time.RFC3339))
+	}
+	if len(schedules) == 0 {
+		log.Printf("Checking every %s", l.opts.interval)
+	}
+}
+
+// due returns the profiles of the checks due at now. Schedules sharing a
+// profile that are due together run once.
+func (l *checkLoop) due(now time.Time) []string {
+	if len(l.schedules) == 0 {
+		if l.lastRun.IsZero() || now.Sub(l.lastRun) >= l.opts.interval {
+			return []string{""}
+		}
+		return nil
+	}
+
+	var profiles []string
+	seen := make(map[string]bool)
+	for i, schedule := range l.schedules {
+		if l.next[i].IsZero() || now.Before(l.next[i]) {
+			continue
+		}
+		l.next[i] = l.crons[i].Next(now)
+		if !seen[schedule.Profile] {
+			seen[schedule.Profile] = true
+			profiles = append(profiles, schedule.Profile)
+		}
+	}
+	return profiles
+}
+
+// nextCheck returns when the next check is due
+func (l *checkLoop) nextCheck() time.Time {
+	if len(l.schedules) == 0 {
+		return l.lastRun.Add(l.opts.interval)
+	}
+	var next time.Time
+	for _, at := range l.next {
+		if !at.IsZero() && (next.IsZero() || at.Before(next)) {
+			next = at
+		}
+	}
+	if next.IsZero() {
+		return time.Now().Add(time.Minute)
+	}
+	return next
+}
+
+// run checks the backups with a schedule's profile, or with the loop's own
+// config for an empty profile
+func (l *checkLoop) run(ctx context.Context, profile string) {
+	reloader := l.reloader
+	if profile != "" {
+		var err error
+		if reloader, err = l.profileReloader(profile); err != nil {
+			log.Printf("Skipping scheduled check with profile %s: %v", profile, err)
+			return
+		}
+	}
+	cfg, emailCfg := reloader.Current()
+	if len(l.opts.paths) > 0 {
+		selected := *cfg
+		selected.BackupPaths = winbackupchecker.SelectBackupPaths(cfg.BackupPaths, l.opts.paths)
+		cfg = &selected
+	}
+
+	check := l.opts.check
+	if l.opts.onlyChanges {
+		if _, ok := l.previous[cfg.Profile]; !ok {
+			l.previous[cfg.Profile] = lastLoggedRun(check, cfg.Profile)
+		}
+		check.shouldAlert = func(run winbackupchecker.RunReport) bool {
+			changes := winbackupchecker.StateChanges(l.previous[cfg.Profile], run)
+			l.previous[cfg.Profile] = &run
+			if len(changes) > 0 {
+				log.Printf("Backup state changed: %s", strings.Join(changes, "; "))
+			}
+			return len(changes) > 0
+		}
+	}
+
+	if cfg.Profile != "" {
+		log.Printf("Starting check with profile %s", cfg.Profile)
+	} else {
+		log.Printf("Starting check")
+	}
+	exitCode := runLockedCheck(ctx, l.opts.timeout, cfg, emailCfg, check)
+	l.lastRun = time.Now()
+
+	if len(l.schedules) == 0 {
+		log.Printf("Check finished with exit code %d, next check at %s",
+			exitCode, l.nextCheck().Format(time.RFC3339))
+	} else {
+		log.Printf("Check finished with exit code %d", exitCode)
+	}
+}
+
+// profileReloader returns the reloader for a schedule's profile, reloading
+// it when the config files changed
+func (l *checkLoop) profileReloader(profile string) (*winbackupchecker.ConfigReloader, error) {
+	if cfg, _ := l.reloader.Current(); cfg.Profile == profile {
+		return l.reloader, nil
+	}
+
+	reloader, ok := l.profiles[profile]
+	if !ok {
+		var err error
+		if reloader, err = l.reloader.WithProfile(profile); err != nil {
+			return nil, err
+		}
+		l.profiles[profile] = reloader
+		return reloader, nil
+	}
+	if _, err := reloader.Reload(); err != nil {
+		log.Printf("Config for profile %s not reloaded: %v", profile, err)
+	}
+	return reloader, nil
+}
+
+// lastLoggedRun returns the newest run of profile in the JSON log, so a
+// restarted process does not alert again about failures it already reported
+func lastLoggedRun(opts checkOptions, profile string) *winbackupchecker.RunReport {
+	if opts.noLog {
+		return nil
+	}
+	history, err := winbackupchecker.LoadHistory(opts.jsonOut)
+	if err != nil {
+		log.Printf("Failed to load history: %v", err)
+		return nil
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Profile == profile {
+			return &history[i]
+		}
+	}
+	return nil
+}
@@ -1,196 +1,703 @@
 package main
 
 import (
+	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
 	"time"
 
-	winbackupchecker "github.com/RyanHarang/win-backup-checker/internal/backup"
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
 )
 
-type RunReport struct {
-	Timestamp string                        `json:"timestamp"`
-	Results   []winbackupchecker.ScanReport `json:"results"`
-	Summary   ScanSummary                   `json:"summary"`
-}
-
-type ScanSummary struct {
-	TotalBackups   int `json:"total_backups"`
-	ValidBackups   int `json:"valid_backups"`
-	InvalidBackups int `json:"invalid_backups"`
-	FailedScans    int `json:"failed_scans"`
-}
-
 func main() {
 	jsonOnly := flag.Bool("json", false, "Output results as JSON only (no human-readable logs)")
 	jsonOut := flag.String("json-out", "logs.json", "Write JSON report to a file (NDJSON format)")
 	noLog := flag.Bool("no-log", false, "Disable writing to log file")
-	parallel := flag.Int("parallel", 4, "Number of backup sets to validate concurrently")
-	timeout := flag.Duration("timeout", 30*time.Minute, "Timeout for entire scan operation")
+	throttle := flag.String("throttle", "", "Limit reads of backup data to this many bytes per second, e.g. 20MB, overriding the config; 0 removes the limit")
+	memoryLimit := flag.String("memory-limit", "", "Keep memory use under this size, e.g. 768MB, spilling finished reports to disk, overriding the config's memory_limit; 0 removes the limit")
+	noCache := flag.Bool("no-cache", false, "Revalidate every file instead of skipping those unchanged since they passed")
+	parallel := flag.Int("parallel", 0, "Number of backup sets to validate concurrently; 0 picks it by each path's storage type")
+	timeout := flag.Duration("timeout", 30*time.Minute, "Timeout for entire scan operation (for each check with --daemon or --watch)")
+	daemon := flag.Bool("daemon", false, "Keep running and check every --interval, alerting only when a backup set changes state")
+	interval := flag.Duration("interval", 6*time.Hour, "Time between checks with --daemon")
+	watch := flag.Bool("watch", false, "Keep running and validate each new or changed backup set once its backup finished")
+	watchPoll := flag.Duration("watch-poll", time.Minute, "Time between looks for new backup sets with --watch")
+	settle := flag.Duration("settle", 5*time.Minute, "Time a backup set must be unchanged before --watch validates it")
 	noEmail := flag.Bool("no-email", false, "Disable email notifications even if configured")
+	noNotify := flag.Bool("no-notify", false, "Disable non-email notifications (Slack, etc.) even if configured")
+	format := flag.String("format", "text", "Output format: text, json, influx (InfluxDB line protocol), prtg, or prtg-json")
+	configPath := flag.String("config", winbackupchecker.DefaultConfigPath("config.json"), "Config file")
+	profile := flag.String("profile", "", "Named profile from the config to apply")
+	checks := flag.String("checks", "", "Comma-separated validators to run instead of the config's checks: structure, completeness, content, age")
+	failFast := flag.Bool("fail-fast", false, "Stop validating once a backup set has a critical issue")
+	latestOnly := flag.Bool("latest-only", false, "Validate only the newest backup set of each machine")
+	newest := flag.Int("newest", 0, "Validate only the newest N backup sets of each machine, overriding the config's newest_sets")
+	fast := flag.Bool("fast", false, "Check ZIP files from their central directory only, like content_mode fast")
+	failOn := flag.String("fail-on", "error", "Least severe issue that fails a backup set and the exit code: warning, error, or critical")
+	lockFile := flag.String("lock-file", "checker.lock", "Lock file detecting overlapping checks; empty disables the lock")
+	lockWait := flag.Duration("lock-wait", 0, "Time to wait for an overlapping check to finish before exiting with code 3")
+	emailConfigPath := flag.String("email-config", winbackupchecker.DefaultConfigPath("email.config.json"), "Email config file (optional)")
+	var paths pathList
+	flag.Var(&paths, "path", "Backup path to scan instead of backup_paths (repeatable)")
+	bench := flag.Bool("bench", false, "Time each validator across the backup sets without logging or alerting, to tune sampling and parallelism")
+	version := flag.Bool("version", false, "Print version and build information and exit")
+	cpuProfile := flag.String("cpuprofile", "", "Write a CPU profile of the run to this file")
+	memProfile := flag.String("memprofile", "", "Write a heap profile to this file when the run ends")
+	traceFile := flag.String("trace", "", "Write an execution trace of the run to this file")
+	pprofAddr := flag.String("pprof-addr", "", "Serve net/http/pprof on this address, e.g. localhost:6060, with --daemon or --watch")
+
+	// Dispatch subcommands before parsing the scan flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "find":
+			os.Exit(runFind(os.Args[2:]))
+		case "report":
+			os.Exit(runReport(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		case "credentials":
+			os.Exit(runCredentials(os.Args[2:]))
+		case "quarantine":
+			os.Exit(runQuarantine(os.Args[2:]))
+		case "init":
+			os.Exit(runInit(os.Args[2:]))
+		case "schedule":
+			os.Exit(runSchedule(os.Args[2:]))
+		case "service":
+			os.Exit(runService(os.Args[2:]))
+		case "completion":
+			os.Exit(runCompletion(os.Args[2:]))
+		}
+	}
 	flag.Parse()
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
-	defer cancel()
+	if *version {
+		fmt.Println(winbackupchecker.CurrentBuild())
+		os.Exit(0)
+	}
+
+	if *jsonOnly {
+		*format = "json"
+	}
+	if *bench && (*daemon || *watch) {
+		log.Printf("--bench cannot be combined with --daemon or --watch")
+		os.Exit(2)
+	}
+	switch *format {
+	case "text", "json", "influx", "prtg", "prtg-json":
+	default:
+		log.Printf("Unknown output format: %s", *format)
+		os.Exit(2)
+	}
+
+	failThreshold, err := winbackupchecker.ParseSeverity(*failOn)
+	if err != nil || failThreshold == winbackupchecker.SeverityInfo {
+		log.Printf("Invalid --fail-on: must be warning, error, or critical")
+		os.Exit(2)
+	}
+
+	// A benchmark run only reports timings
+	if *bench {
+		*format = "bench"
+		*noLog, *noEmail, *noNotify = true, true, true
+	}
+
+	if _, err := winbackupchecker.ParseByteSize(*throttle); err != nil {
+		log.Printf("Invalid --throttle: %v", err)
+		os.Exit(2)
+	}
+	if _, err := winbackupchecker.ParseByteSize(*memoryLimit); err != nil {
+		log.Printf("Invalid --memory-limit: %v", err)
+		os.Exit(2)
+	}
 
-	// Config location
-	configPath := filepath.Join("configs", "config.json")
-	emailConfigPath := filepath.Join("configs", "email.config.json")
+	if *newest < 0 {
+		log.Printf("Invalid --newest: cannot be negative")
+		os.Exit(2)
+	}
+	if *latestOnly {
+		if *newest > 1 {
+			log.Printf("--latest-only cannot be combined with --newest")
+			os.Exit(2)
+		}
+		*newest = 1
+	}
+
+	selectedChecks, err := winbackupchecker.ParseChecks(*checks)
+	if err != nil {
+		log.Printf("Invalid --checks: %v", err)
+		os.Exit(2)
+	}
+
+	checkOpts := checkOptions{
+		jsonOut:       *jsonOut,
+		noLog:         *noLog,
+		noCache:       *noCache,
+		parallel:      *parallel,
+		noEmail:       *noEmail,
+		noNotify:      *noNotify,
+		format:        *format,
+		failThreshold: failThreshold,
+		lockFile:      *lockFile,
+		lockWait:      *lockWait,
+		checks:        selectedChecks,
+		throttle:      *throttle,
+		memoryLimit:   *memoryLimit,
+		failFast:      *failFast,
+		newest:        *newest,
+		fast:          *fast,
+	}
+
+	if *daemon && *watch {
+		log.Printf("--daemon and --watch cannot be combined")
+		os.Exit(2)
+	}
+	if *pprofAddr != "" {
+		if !*daemon && !*watch {
+			log.Printf("--pprof-addr requires --daemon or --watch")
+			os.Exit(2)
+		}
+		if err := servePprof(*pprofAddr); err != nil {
+			log.Printf("Error starting pprof: %v", err)
+			os.Exit(2)
+		}
+	}
+
+	stopProfiling, err := startProfiling(profileOptions{cpu: *cpuProfile, mem: *memProfile, trace: *traceFile})
+	if err != nil {
+		log.Printf("Error starting profiling: %v", err)
+		os.Exit(2)
+	}
+	// os.Exit skips deferred calls, so profiles are finished here
+	exit := func(code int) {
+		stopProfiling()
+		os.Exit(code)
+	}
+
+	if *watch {
+		reloader, err := winbackupchecker.NewConfigReloader(*configPath, *emailConfigPath, *profile)
+		if err != nil {
+			log.Printf("Error loading config: %v", err)
+			exit(2)
+		}
+		exit(runWatch(reloader, watchOptions{
+			poll:    *watchPoll,
+			settle:  *settle,
+			timeout: *timeout,
+			paths:   paths,
+			check:   checkOpts,
+		}))
+	}
+
+	if *daemon {
+		reloader, err := winbackupchecker.NewConfigReloader(*configPath, *emailConfigPath, *profile)
+		if err != nil {
+			log.Printf("Error loading config: %v", err)
+			exit(2)
+		}
+		exit(runDaemon(reloader, loopOptions{
+			interval: *interval,
+			timeout:  *timeout,
+			paths:    paths,
+			check:    checkOpts,
+		}))
+	}
 
 	// Load config
-	cfg, err := winbackupchecker.LoadConfig(configPath)
+	cfg, err := winbackupchecker.LoadConfigProfile(*configPath, *profile)
 	if err != nil {
 		log.Printf("Error loading config: %v", err)
-		os.Exit(2)
+		exit(2)
+	}
+	if len(paths) > 0 {
+		cfg.BackupPaths = winbackupchecker.SelectBackupPaths(cfg.BackupPaths, paths)
 	}
 
 	// Load email config (optional)
-	emailCfg, err := winbackupchecker.LoadEmailConfig(emailConfigPath)
+	emailCfg, err := winbackupchecker.LoadEmailConfig(*emailConfigPath)
 	if err != nil {
 		log.Printf("Error loading email config: %v", err)
-		os.Exit(2)
+		exit(2)
+	}
+
+	ctx, interrupt, stopInterrupts := handleInterrupts(context.Background())
+	checkOpts.interrupt = interrupt
+	code := runLockedCheck(ctx, *timeout, cfg, emailCfg, checkOpts)
+	stopInterrupts()
+	exit(code)
+}
+
+// checkOptions are the flags of a check run
+type checkOptions struct {
+	jsonOut       string
+	noLog         bool
+	noCache       bool
+	parallel      int
+	noEmail       bool
+	noNotify      bool
+	format        string
+	failThreshold winbackupchecker.ValidationSeverity
+
+	// lockFile, when set, is locked for the duration of the check, and
+	// lockWait is how long to wait for an overlapping check to finish
+	lockFile string
+	lockWait time.Duration
+
+	// checks, when set, replaces the config's selection of validators
+	checks []string
+
+	// sets limits validation to these backup sets; empty validates all
+	sets []string
+
+	// throttle, when set, replaces the config's read rate limit
+	throttle string
+
+	// memoryLimit, when set, replaces the config's memory_limit
+	memoryLimit string
+
+	// failFast stops the run at the first critical issue, like the
+	// config's fail_fast
+	failFast bool
+
+	// newest, when set, replaces the config's newest_sets
+	newest int
+
+	// fast checks zip files from their central directory only, whatever
+	// the config's content_mode
+	fast bool
+
+	// interrupt, once closed, stops starting new backup sets; the report
+	// covers the sets validated until then
+	interrupt <-chan struct{}
+
+	// shouldAlert, when set, decides whether the run sends alerts. Email
+	// digests and channels tracking every run are sent regardless.
+	shouldAlert func(run winbackupchecker.RunReport) bool
+}
+
+// runLockedCheck runs the check holding the lock file, so overlapping checks
+// are detected. The timeout starts once the lock is taken.
+func runLockedCheck(ctx context.Context, timeout time.Duration, cfg *winbackupchecker.Config, emailCfg *winbackupchecker.EmailConfig, opts checkOptions) int {
+	if opts.lockFile != "" {
+		lock, err := winbackupchecker.TryRunLock(opts.lockFile)
+		if errors.Is(err, winbackupchecker.ErrLocked) && opts.lockWait > 0 {
+			log.Printf("Waiting up to %s for the running check to finish: %v", opts.lockWait, err)
+			lock, err = winbackupchecker.AcquireRunLock(ctx, opts.lockFile, opts.lockWait)
+		}
+		if errors.Is(err, winbackupchecker.ErrLocked) {
+			log.Printf("Not checking: %v", err)
+			return exitLocked
+		}
+		if err != nil {
+			log.Printf("%v", err)
+			return 2
+		}
+		defer func() {
+			if err := lock.Release(); err != nil {
+				log.Printf("%v", err)
+			}
+		}()
 	}
 
-	if !*jsonOnly {
-		fmt.Printf("Loaded config with %d backup paths, parallel workers: %d\n", len(cfg.BackupPaths), *parallel)
-		if emailCfg != nil && emailCfg.Enabled && !*noEmail {
-			fmt.Printf("Email notifications: enabled (to: %v)\n", emailCfg.To)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return runCheck(ctx, cfg, emailCfg, opts)
+}
+
+// runCheck scans the backup paths of cfg, prints and logs the run report and
+// sends notifications, returning the exit code
+func runCheck(ctx context.Context, cfg *winbackupchecker.Config, emailCfg *winbackupchecker.EmailConfig, opts checkOptions) int {
+	quiet := opts.format != "text"
+	scanOpts := cfg.ScanOptions()
+	tr := scanOpts.Localizer
+
+	var err error
+	if !quiet {
+		if opts.parallel > 0 {
+			tr.Printf("Loaded config with %d backup paths, parallel workers: %d\n", len(cfg.BackupPaths), opts.parallel)
+		} else {
+			tr.Printf("Loaded config with %d backup paths, parallel workers by storage type\n", len(cfg.BackupPaths))
+		}
+		if cfg.Profile != "" {
+			tr.Printf("Profile: %s\n", cfg.Profile)
 		}
-		if !*noLog {
-			fmt.Printf("Logging to: %s\n", *jsonOut)
+		if emailCfg != nil && emailCfg.Enabled && !opts.noEmail {
+			tr.Printf("Email notifications: enabled (to: %v)\n", emailCfg.To)
+		}
+		if !opts.noLog {
+			tr.Printf("Logging to: %s\n", opts.jsonOut)
 		}
 	}
 
-	allReports := []winbackupchecker.ScanReport{}
-	fatalErrors := []string{}
+	scanOpts.MaxWorkers = opts.parallel
+	scanOpts.Sets = opts.sets
+	scanOpts.Interrupt = opts.interrupt
+	if len(opts.checks) > 0 {
+		scanOpts.Checks = opts.checks
+	}
+	if opts.newest > 0 {
+		scanOpts.NewestSets = opts.newest
+	}
+	if opts.fast {
+		scanOpts.FastZip = true
+	}
+	if !quiet && len(scanOpts.Checks) > 0 {
+		tr.Printf("Checks: %s\n", strings.Join(scanOpts.Checks, ", "))
+	}
+	if !quiet && scanOpts.NewestSets > 0 {
+		tr.Printf("Newest backup sets per machine: %d\n", scanOpts.NewestSets)
+	}
+	if !quiet && scanOpts.FastZip {
+		tr.Printf("Content mode: %s\n", winbackupchecker.ContentModeFast)
+	}
 
-	// Run scan for each path with controlled concurrency
-	for _, path := range cfg.BackupPaths {
-		report, err := winbackupchecker.ScanFileBackupDir(ctx, path, *parallel)
-		if err != nil {
-			fatalErrors = append(fatalErrors, fmt.Sprintf("Scan failed for %s: %v", path, err))
-			allReports = append(allReports, winbackupchecker.ScanReport{
-				Root: path,
-				Reports: []winbackupchecker.BackupReport{
-					{
-						BackupDir: path,
-						Valid:     false,
-						Issues: []winbackupchecker.ValidationIssue{
-							winbackupchecker.NewValidationIssue(
-								winbackupchecker.SeverityCritical,
-								err.Error(),
-								path,
-								"check path accessibility and permissions",
-							),
-						},
-						CheckedAt: time.Now().Format(time.RFC3339),
-					},
-				},
-			})
-			continue
-		}
-		allReports = append(allReports, *report)
-	}
-
-	summary := calculateSummary(allReports, fatalErrors)
-	runReport := RunReport{
-		Timestamp: time.Now().Format(time.RFC3339),
-		Results:   allReports,
-		Summary:   summary,
-	}
-
-	jsonData, err := json.MarshalIndent(runReport, "", "  ")
+	var throttleCfg winbackupchecker.ThrottleConfig
+	if cfg.Throttle != nil {
+		throttleCfg = *cfg.Throttle
+	}
+	if opts.throttle != "" {
+		throttleCfg.BytesPerSecond = opts.throttle
+	}
+	throttle, err := winbackupchecker.NewThrottleFromConfig(&throttleCfg)
 	if err != nil {
-		log.Printf("Failed to marshal report: %v", err)
-		os.Exit(2)
+		log.Printf("Invalid throttle: %v", err)
+		return 2
+	}
+	winbackupchecker.SetReadThrottle(throttle)
+	if !quiet && throttle != nil {
+		tr.Printf("Read throttle: %s\n", throttle)
+	}
+	if quiet {
+		scanOpts.Progress = io.Discard
 	}
 
-	if *jsonOnly {
-		fmt.Println(string(jsonData))
-	} else {
-		printSummary(summary)
-		fmt.Println("\n===== JSON Validation Report =====")
-		fmt.Println(string(jsonData))
+	memoryLimit := cfg.MemoryLimit
+	if opts.memoryLimit != "" {
+		memoryLimit = opts.memoryLimit
+	}
+	var spill *winbackupchecker.ReportSpill
+	if limit, _ := winbackupchecker.ParseByteSize(memoryLimit); limit > 0 {
+		debug.SetMemoryLimit(limit)
+		if !quiet {
+			tr.Printf("Memory limit: %s\n", memoryLimit)
+		}
+		// Benchmarks need the phase timings of every set in memory
+		if opts.format != "bench" {
+			dir := ""
+			if !opts.noLog {
+				dir = filepath.Dir(opts.jsonOut)
+			}
+			spill, err = winbackupchecker.NewReportSpill(dir)
+			if err != nil {
+				log.Printf("Keeping the report in memory: %v", err)
+			} else {
+				defer func() {
+					if err := spill.Close(); err != nil {
+						log.Printf("%v", err)
+					}
+				}()
+			}
+		}
 	}
 
-	// Write to log file (default behavior unless --no-log is set)
-	if !*noLog {
-		if err := writeJSONOutput(*jsonOut, runReport); err != nil {
-			log.Printf("Failed to write JSON output: %v", err)
-			os.Exit(2)
+	if err := cfg.LoadCaches(&scanOpts); err != nil {
+		log.Printf("Error loading caches: %v", err)
+		return 2
+	}
+	if scanOpts.ScanCache != nil && opts.noCache {
+		scanOpts.ScanCache.Revalidate()
+	}
+
+	var baseline *winbackupchecker.Baseline
+	if cfg.BaselineFile != "" {
+		baseline, err = winbackupchecker.LoadBaseline(cfg.BaselineFile)
+		if err != nil {
+			log.Printf("Error loading baseline: %v", err)
+			return 2
 		}
-		if !*jsonOnly {
-			fmt.Printf("\nAppended report to %s\n", *jsonOut)
+	}
+
+	var quarantine *winbackupchecker.Quarantine
+	if cfg.QuarantineFile != "" {
+		quarantine, err = winbackupchecker.LoadQuarantine(cfg.QuarantineFile)
+		if err != nil {
+			log.Printf("Error loading quarantine: %v", err)
+			return 2
 		}
 	}
 
-	if !*noEmail && emailCfg != nil && emailCfg.Enabled {
-		if !*jsonOnly {
-			fmt.Println("\nSending email notification...")
+	var history []winbackupchecker.RunReport
+	if (cfg.Escalation != nil && cfg.Escalation.Enabled) || (cfg.Flapping != nil && cfg.Flapping.Enabled) {
+		history, err = winbackupchecker.LoadHistory(opts.jsonOut)
+		if err != nil {
+			log.Printf("Failed to load history for escalation and flapping detection: %v", err)
 		}
+	}
+
+	result := winbackupchecker.Run(ctx, cfg, scanOpts, winbackupchecker.RunOptions{
+		Baseline:      baseline,
+		Quarantine:    quarantine,
+		History:       history,
+		FailThreshold: opts.failThreshold,
+		FailFast:      opts.failFast,
+		Spill:         spill,
+	})
+	if spill != nil && spill.Err() != nil {
+		log.Printf("Keeping the rest of the report in memory: %v", spill.Err())
+	}
+	runReport := result.Report
+	summary := runReport.Summary
+	if scanOpts.Interrupted() {
+		log.Printf("Check interrupted: backup sets not validated are reported as not finished")
+	}
 
-		emailSummary := winbackupchecker.ScanSummary{
-			TotalBackups:   summary.TotalBackups,
-			ValidBackups:   summary.ValidBackups,
-			InvalidBackups: summary.InvalidBackups,
-			FailedScans:    summary.FailedScans,
+	if scanOpts.Index != nil {
+		if err := scanOpts.Index.Save(); err != nil {
+			log.Printf("Failed to save catalog index: %v", err)
 		}
+	}
 
-		if err := winbackupchecker.SendEmailAlert(emailCfg, emailSummary, allReports); err != nil {
-			log.Printf("Failed to send email alert: %v", err)
-		} else if !*jsonOnly {
-			fmt.Println("Email notification sent successfully")
+	if scanOpts.ScanCache != nil {
+		if err := scanOpts.ScanCache.Save(); err != nil {
+			log.Printf("Failed to save scan cache: %v", err)
 		}
 	}
 
-	os.Exit(decideExitCode(fatalErrors, allReports))
-}
+	if !quiet {
+		if result.Overridden > 0 {
+			tr.Printf("%d issue(s) given an overridden severity\n", result.Overridden)
+		}
+		if result.Suppressed > 0 {
+			tr.Printf("%d known issue(s) suppressed by the baseline\n", result.Suppressed)
+		}
+		if result.Escalated > 0 {
+			tr.Printf("%d persistent issue(s) escalated\n", result.Escalated)
+		}
+		if result.Flapping > 0 {
+			tr.Printf("%d backup set(s) are unstable across recent runs\n", result.Flapping)
+		}
+		if result.Grouped > 0 {
+			tr.Printf("%d repeated issue(s) folded into groups\n", result.Grouped)
+		}
+	}
+
+	switch opts.format {
+	case "json":
+		if err := printRunReport(runReport, spill, nil); err != nil {
+			log.Printf("Failed to write report: %v", err)
+			return 2
+		}
+	case "influx":
+		prefix := ""
+		if cfg.InfluxDB != nil {
+			prefix = cfg.InfluxDB.MeasurementPrefix
+		}
+		if err := winbackupchecker.WriteInfluxLineProtocol(os.Stdout, runReport, prefix); err != nil {
+			log.Printf("Failed to write line protocol: %v", err)
+			return 2
+		}
+	case "prtg", "prtg-json":
+		if err := winbackupchecker.WritePRTG(os.Stdout, runReport, opts.format == "prtg-json"); err != nil {
+			log.Printf("Failed to write PRTG output: %v", err)
+			return 2
+		}
+	case "bench":
+		printSummary(tr, summary)
+		printBenchmark(tr, runReport, scanOpts, throttle, result.Elapsed)
+	case "summary":
+		// Long-running modes log just the summary of each run
+		printSummary(tr, summary)
+	default:
+		printSummary(tr, summary)
+		printQuarantined(tr, runReport.Quarantined)
+		printPhaseTimings(tr, runReport.PhaseTimings)
+		printCacheStats(tr, runReport.CacheStats)
+		// The report is shown with translated issues, but logged in English
+		tr.Printf("\n===== JSON Validation Report =====\n")
+		if err := printRunReport(runReport, spill, tr); err != nil {
+			log.Printf("Failed to write report: %v", err)
+			return 2
+		}
+	}
 
-func calculateSummary(allReports []winbackupchecker.ScanReport, fatalErrors []string) ScanSummary {
-	summary := ScanSummary{
-		FailedScans: len(fatalErrors),
+	if cfg.InfluxDB != nil && cfg.InfluxDB.Enabled && opts.format != "bench" {
+		if err := winbackupchecker.PostInfluxMetrics(ctx, cfg.InfluxDB, runReport); err != nil {
+			log.Printf("Failed to post InfluxDB metrics: %v", err)
+		} else if !quiet {
+			tr.Printf("Posted metrics to InfluxDB\n")
+		}
 	}
 
-	for _, sr := range allReports {
-		for _, br := range sr.Reports {
-			summary.TotalBackups++
-			if br.Valid {
-				summary.ValidBackups++
-			} else {
-				summary.InvalidBackups++
+	// Failed notifications are recorded in the logged report, so the log is
+	// written after sending
+	alert := opts.shouldAlert == nil || opts.shouldAlert(runReport)
+	var notifyFailures []winbackupchecker.NotificationFailure
+	if !opts.noEmail && emailCfg != nil && emailCfg.Enabled && emailCfg.Digest != nil && emailCfg.Digest.Enabled {
+		if err := sendEmailDigest(emailCfg, opts.jsonOut, runReport, tr, quiet); err != nil {
+			log.Printf("Failed to send email digest: %v", err)
+			notifyFailures = append(notifyFailures, winbackupchecker.NewNotificationFailure("email", err))
+		}
+	} else if alert && !opts.noEmail && emailCfg != nil && emailCfg.Enabled {
+		if !quiet {
+			tr.Printf("\nSending email notification...\n")
+		}
+
+		if err := winbackupchecker.SendEmailAlert(emailCfg, summary, runReport.Results, tr); err != nil {
+			log.Printf("Failed to send email alert: %v", err)
+			notifyFailures = append(notifyFailures, winbackupchecker.NewNotificationFailure("email", err))
+		} else if !quiet {
+			tr.Printf("Email notification sent successfully\n")
+		}
+	}
+
+	if !opts.noNotify {
+		shown := tr.Run(runReport)
+		for _, notifier := range winbackupchecker.BuildNotifiers(cfg.Notifications) {
+			if !alert && !winbackupchecker.ReportsEveryRun(notifier) {
+				continue
 			}
+			if err := notifier.Notify(ctx, shown); err != nil {
+				log.Printf("Failed to send %s notification: %v", notifier.Name(), err)
+				notifyFailures = append(notifyFailures, winbackupchecker.NewNotificationFailure(notifier.Name(), err))
+			}
+		}
+	}
+	runReport.NotificationFailures = notifyFailures
+
+	// Write to log file (default behavior unless --no-log is set)
+	if !opts.noLog {
+		if err := writeJSONOutput(opts.jsonOut, runReport, spill); err != nil {
+			log.Printf("Failed to write JSON output: %v", err)
+			return 2
+		}
+		if !quiet {
+			tr.Printf("\nAppended report to %s\n", opts.jsonOut)
 		}
 	}
 
-	return summary
+	return decideExitCode(result.FatalErrors, runReport.Results)
 }
 
-func printSummary(summary ScanSummary) {
-	fmt.Printf("\n===== Backup Validation Summary =====\n")
-	fmt.Printf("Total Backups: %d\n", summary.TotalBackups)
-	fmt.Printf("Valid Backups: %d\n", summary.ValidBackups)
-	fmt.Printf("Invalid Backups: %d\n", summary.InvalidBackups)
-	fmt.Printf("Failed Scans: %d\n", summary.FailedScans)
+func printSummary(tr *winbackupchecker.Localizer, summary winbackupchecker.ScanSummary) {
+	tr.Printf("\n===== Backup Validation Summary =====\n")
+	tr.Printf("Total Backups: %d\n", summary.TotalBackups)
+	tr.Printf("Valid Backups: %d\n", summary.ValidBackups)
+	tr.Printf("Invalid Backups: %d\n", summary.InvalidBackups)
+	tr.Printf("Failed Scans: %d\n", summary.FailedScans)
+	if summary.SuppressedIssues > 0 {
+		tr.Printf("Suppressed Issues: %d\n", summary.SuppressedIssues)
+	}
+	if summary.QuarantinedFiles > 0 {
+		tr.Printf("Quarantined Files: %d\n", summary.QuarantinedFiles)
+	}
 
 	if summary.TotalBackups > 0 {
 		validPercent := float64(summary.ValidBackups) / float64(summary.TotalBackups) * 100
-		fmt.Printf("Success Rate: %.1f%%\n", validPercent)
+		tr.Printf("Success Rate: %.1f%%\n", validPercent)
+	}
+}
+
+// printQuarantined lists the quarantined files found with issues, once each
+func printQuarantined(tr *winbackupchecker.Localizer, quarantined []winbackupchecker.QuarantinedFile) {
+	if len(quarantined) == 0 {
+		return
+	}
+
+	tr.Printf("\n===== Quarantined Files =====\n")
+	for _, file := range quarantined {
+		fmt.Println(file.Path)
+		tr.Printf("  %d issue(s) in %s, quarantined since %s\n", len(file.Issues), file.BackupDir, file.Added)
+		if file.Reason != "" {
+			tr.Printf("  Reason: %s\n", file.Reason)
+		}
+	}
+}
+
+func printPhaseTimings(tr *winbackupchecker.Localizer, timings winbackupchecker.PhaseTimings) {
+	if len(timings) == 0 {
+		return
+	}
+
+	tr.Printf("\n===== Time Per Phase =====\n")
+	for _, phase := range timings.Phases() {
+		fmt.Printf("%-18s %10.1f ms\n", phase+":", timings[phase])
+	}
+}
+
+func printCacheStats(tr *winbackupchecker.Localizer, stats map[string]winbackupchecker.CacheStats) {
+	if len(stats) == 0 {
+		return
+	}
+
+	tr.Printf("\n===== Cache Effectiveness =====\n")
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s := stats[name]
+		tr.Printf("%-8s %d hits, %d misses (%.1f%% hit rate), %d bytes skipped\n",
+			name+":", s.Hits, s.Misses, s.HitRate, s.BytesSkipped)
+		if s.AllHits() {
+			tr.Printf("  Warning: every %s lookup was a cache hit; make sure changed files are still being picked up\n", name)
+		}
 	}
 }
 
-func writeJSONOutput(filename string, report RunReport) error {
-	// Marshal with indentation for readability
-	line, err := json.MarshalIndent(report, "", "  ")
+// pathList collects the values of a repeatable flag
+type pathList []string
+
+func (p *pathList) String() string {
+	return strings.Join(*p, ", ")
+}
+
+func (p *pathList) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+// sendEmailDigest sends the email digest when one is due, building it from
+// the run history plus the current run, which is not logged yet
+func sendEmailDigest(emailCfg *winbackupchecker.EmailConfig, historyPath string, current winbackupchecker.RunReport, tr *winbackupchecker.Localizer, quiet bool) error {
+	runs, err := winbackupchecker.LoadHistory(historyPath)
 	if err != nil {
-		return fmt.Errorf("failed to marshal run report: %w", err)
+		return err
 	}
+	runs = append(runs, current)
 
+	sent, err := winbackupchecker.SendEmailDigest(emailCfg, runs, time.Now(), tr)
+	if err != nil {
+		return err
+	}
+	if sent && !quiet {
+		tr.Printf("\nEmail digest sent successfully\n")
+	}
+	return nil
+}
+
+// printRunReport writes the report to stdout, with issues translated by tr
+// when it is set
+func printRunReport(report winbackupchecker.RunReport, spill *winbackupchecker.ReportSpill, tr *winbackupchecker.Localizer) error {
+	w := bufio.NewWriter(os.Stdout)
+	if err := winbackupchecker.WriteRunReport(w, report, spill, tr); err != nil {
+		return err
+	}
+	w.WriteString("\n")
+	return w.Flush()
+}
+
+func writeJSONOutput(filename string, report winbackupchecker.RunReport, spill *winbackupchecker.ReportSpill) error {
 	// Check if file exists to determine format
 	fileExists := false
 	if info, err := os.Stat(filename); err == nil && info.Size() > 0 {
@@ -209,13 +716,23 @@ func writeJSONOutput(filename string, report RunReport) error {
 		}
 	}
 
-	if _, err := f.Write(append(line, '\n')); err != nil {
+	// Written with indentation for readability
+	w := bufio.NewWriter(f)
+	if err := winbackupchecker.WriteRunReport(w, report, spill, nil); err != nil {
+		return fmt.Errorf("failed to write to JSON output file: %w", err)
+	}
+	w.WriteString("\n")
+	if err := w.Flush(); err != nil {
 		return fmt.Errorf("failed to write to JSON output file: %w", err)
 	}
 
 	return nil
 }
 
+// exitLocked is the exit code of a check not run because another check
+// held the lock file
+const exitLocked = 3
+
 func decideExitCode(fatalErrors []string, allReports []winbackupchecker.ScanReport) int {
 	if len(fatalErrors) > 0 {
 		return 2
@@ -238,10 +755,30 @@ Usage:
   go run ./cmd/checker/ --no-log                           # Don't write to log file
   go run ./cmd/checker/ --parallel=8                       # Use 8 concurrent workers
   go run ./cmd/checker/ --timeout=1h                       # Set 1 hour timeout
+  go run ./cmd/checker/ --config=D:\bwc\config.json        # Read config from another location
+  go run ./cmd/checker/ --profile=deep-weekly              # Apply a named profile from the config
+  go run ./cmd/checker/ --path=E:\ --path=F:\              # Scan these paths instead of backup_paths
   go run ./cmd/checker/ --no-email                         # Disable email notifications
+  go run ./cmd/checker/ --fail-on=warning                  # Count warnings as failures (or critical)
+  go run ./cmd/checker/ --checks=structure,age             # Run only the cheap validators
+  go run ./cmd/checker/ --no-notify                        # Disable Slack and other notifications
+  go run ./cmd/checker/ --daemon --interval=6h             # Keep checking, alerting when a backup set changes state
+  go run ./cmd/checker/ --watch --settle=10m               # Validate new backup sets shortly after they finish
+  go run ./cmd/checker/ --lock-wait=2h                     # Wait for an overlapping check instead of exiting with 3
+  go run ./cmd/checker/ --format=influx                    # Print InfluxDB line protocol
+  go run ./cmd/checker/ --format=prtg                      # Print PRTG sensor XML (or prtg-json)
+  go run ./cmd/checker/ find "report*.docx"                # Search the catalog index for a file
+  go run ./cmd/checker/ report aggregate --since 30d       # Summarize run history for the period
+  go run ./cmd/checker/ init                               # Create the config files interactively
+  go run ./cmd/checker/ config validate                    # Check config and backup paths without scanning
+  go run ./cmd/checker/ credentials set smtp               # Store the SMTP password in the OS credential store
+  go run ./cmd/checker/ quarantine add <file>              # Report a known-bad file once instead of failing every run
+  checker.exe schedule install --daily=03:00               # Register a daily scheduled task (uninstall to remove)
+  checker.exe service install --interval=6h                # Run the check as a Windows service (uninstall to remove)
 
 Exit codes:
   0 = all backups valid
   1 = some backups invalid
   2 = fatal error (config, scan, or IO failure)
+  3 = not checked, another check was running
 */
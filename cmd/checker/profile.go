@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"runtime/trace"
+)
+
+// profileOptions are the flags profiling a run
+type profileOptions struct {
+	cpu   string
+	mem   string
+	trace string
+}
+
+// startProfiling starts the CPU profile and execution trace requested by
+// opts. The returned function stops them and writes the heap profile; call
+// it before exiting.
+func startProfiling(opts profileOptions) (func(), error) {
+	var stops []func()
+	stop := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+
+	if opts.cpu != "" {
+		f, err := os.Create(opts.cpu)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		if err := runtimepprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		stops = append(stops, func() {
+			runtimepprof.StopCPUProfile()
+			f.Close()
+		})
+	}
+
+	if opts.trace != "" {
+		f, err := os.Create(opts.trace)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("failed to create trace: %w", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			stop()
+			return nil, fmt.Errorf("failed to start trace: %w", err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			f.Close()
+		})
+	}
+
+	if opts.mem != "" {
+		stops = append(stops, func() {
+			if err := writeHeapProfile(opts.mem); err != nil {
+				log.Printf("Failed to write memory profile: %v", err)
+			}
+		})
+	}
+
+	return stop, nil
+}
+
+// writeHeapProfile writes the live heap after a garbage collection, so it
+// shows what the run still holds rather than what it already released
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	runtime.GC()
+	return runtimepprof.WriteHeapProfile(f)
+}
+
+// servePprof serves the net/http/pprof handlers on addr in the background,
+// for profiling a long-running daemon or watch on demand
+func servePprof(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for pprof: %w", err)
+	}
+
+	// A mux of our own keeps the handlers off http.DefaultServeMux
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	log.Printf("Serving pprof on http://%s/debug/pprof/", listener.Addr())
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Printf("pprof server stopped: %v", err)
+		}
+	}()
+	return nil
+}
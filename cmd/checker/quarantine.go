@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
+)
+
+// runQuarantine dispatches the quarantine subcommands
+func runQuarantine(args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: checker quarantine add|remove|list [flags] [path...]")
+		return 2
+	}
+
+	switch args[0] {
+	case "add":
+		return runQuarantineAdd(args[1:])
+	case "remove":
+		return runQuarantineRemove(args[1:])
+	case "list":
+		return runQuarantineList(args[1:])
+	default:
+		log.Printf("Unknown quarantine command: %s", args[0])
+		return 2
+	}
+}
+
+// quarantineFlags adds the flags locating the quarantine file to fs
+func quarantineFlags(fs *flag.FlagSet) (configPath, file *string) {
+	configPath = fs.String("config", winbackupchecker.DefaultConfigPath("config.json"), "Config file naming the quarantine_file")
+	file = fs.String("file", "", "Quarantine file (overrides the config's quarantine_file)")
+	return configPath, file
+}
+
+// quarantinePath returns the quarantine file given by --file, or else the
+// config's quarantine_file
+func quarantinePath(configPath, file string) (string, error) {
+	if file != "" {
+		return file, nil
+	}
+	cfg, err := winbackupchecker.LoadConfig(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg.QuarantineFile == "" {
+		return "", fmt.Errorf("no quarantine file: set quarantine_file in %s or pass --file", configPath)
+	}
+	return cfg.QuarantineFile, nil
+}
+
+// runQuarantineAdd quarantines files known to be corrupt
+func runQuarantineAdd(args []string) int {
+	fs := flag.NewFlagSet("quarantine add", flag.ExitOnError)
+	configPath, file := quarantineFlags(fs)
+	reason := fs.String("reason", "", "Why the files are quarantined, e.g. a ticket number")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: checker quarantine add [--reason=text] <path>...\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	return updateQuarantine(*configPath, *file, func(q *winbackupchecker.Quarantine) {
+		now := time.Now()
+		for _, path := range fs.Args() {
+			if q.Add(path, *reason, now) {
+				fmt.Printf("Quarantined %s\n", path)
+			} else {
+				fmt.Printf("Already quarantined: %s\n", path)
+			}
+		}
+	})
+}
+
+// runQuarantineRemove releases files from the quarantine, typically once
+// they were replaced
+func runQuarantineRemove(args []string) int {
+	fs := flag.NewFlagSet("quarantine remove", flag.ExitOnError)
+	configPath, file := quarantineFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: checker quarantine remove <path>...\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	missing := false
+	code := updateQuarantine(*configPath, *file, func(q *winbackupchecker.Quarantine) {
+		for _, path := range fs.Args() {
+			if q.Remove(path) {
+				fmt.Printf("Released %s from quarantine\n", path)
+			} else {
+				fmt.Printf("Not quarantined: %s\n", path)
+				missing = true
+			}
+		}
+	})
+	if code == 0 && missing {
+		return 1
+	}
+	return code
+}
+
+// updateQuarantine loads the quarantine file, applies update and saves it
+func updateQuarantine(configPath, file string, update func(q *winbackupchecker.Quarantine)) int {
+	path, err := quarantinePath(configPath, file)
+	if err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	quarantine, err := winbackupchecker.LoadQuarantine(path)
+	if err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+
+	update(quarantine)
+
+	if err := quarantine.Save(path); err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	return 0
+}
+
+// runQuarantineList prints the quarantined files
+func runQuarantineList(args []string) int {
+	fs := flag.NewFlagSet("quarantine list", flag.ExitOnError)
+	configPath, file := quarantineFlags(fs)
+	jsonOnly := fs.Bool("json", false, "Output the entries as JSON")
+	fs.Parse(args)
+
+	path, err := quarantinePath(*configPath, *file)
+	if err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	quarantine, err := winbackupchecker.LoadQuarantine(path)
+	if err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+
+	if *jsonOnly {
+		data, err := json.MarshalIndent(quarantine.Files, "", "  ")
+		if err != nil {
+			log.Printf("Failed to marshal quarantine: %v", err)
+			return 2
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	if len(quarantine.Files) == 0 {
+		fmt.Println("No files are quarantined")
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tADDED\tREASON")
+	for _, entry := range quarantine.Files {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", entry.Path, entry.Added, entry.Reason)
+	}
+	w.Flush()
+	return 0
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
+)
+
+// runReport dispatches the report subcommands
+func runReport(args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: checker report aggregate [flags]")
+		return 2
+	}
+
+	switch args[0] {
+	case "aggregate":
+		return runReportAggregate(args[1:])
+	default:
+		log.Printf("Unknown report command: %s", args[0])
+		return 2
+	}
+}
+
+// runReportAggregate summarizes accumulated run history over a period
+func runReportAggregate(args []string) int {
+	fs := flag.NewFlagSet("report aggregate", flag.ExitOnError)
+	since := fs.String("since", "30d", "Period to aggregate (e.g. 7d, 30d, 12h)")
+	history := fs.String("log", "logs.json", "History file written by the checker (see --json-out)")
+	top := fs.Int("top", 5, "Number of flakiest backup sets to list")
+	jsonOnly := fs.Bool("json", false, "Output the aggregate as JSON")
+	fs.Parse(args)
+
+	period, err := winbackupchecker.ParseDuration(*since)
+	if err != nil {
+		log.Printf("Invalid --since duration: %v", err)
+		return 2
+	}
+
+	runs, err := winbackupchecker.LoadHistory(*history)
+	if err != nil {
+		log.Printf("Error loading history: %v", err)
+		return 2
+	}
+
+	start := time.Now().Add(-period)
+	agg := winbackupchecker.AggregateHistory(winbackupchecker.HistorySince(runs, start), start, *top)
+
+	if *jsonOnly {
+		data, err := json.MarshalIndent(agg, "", "  ")
+		if err != nil {
+			log.Printf("Failed to marshal aggregate: %v", err)
+			return 2
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	printAggregate(agg)
+	return 0
+}
+
+func printAggregate(agg winbackupchecker.AggregateReport) {
+	fmt.Printf("\n===== Backup Health: %s to %s =====\n",
+		agg.Since.Format("2006-01-02"), agg.Until.Format("2006-01-02"))
+	fmt.Printf("Runs: %d (%d fully successful)\n", agg.Runs, agg.SuccessfulRuns)
+
+	if agg.Runs == 0 {
+		fmt.Println("No runs recorded in this period")
+		return
+	}
+
+	fmt.Printf("Run Success Rate: %.1f%%\n", agg.SuccessRate)
+	fmt.Printf("Backup Set Success Rate: %.1f%% (%d/%d checks)\n", agg.SetSuccessRate, agg.ValidSetChecks, agg.SetChecks)
+	fmt.Printf("Failures: %d\n", agg.Failures)
+	if agg.MTBF != "" {
+		fmt.Printf("Mean Time Between Failures: %s\n", agg.MTBF)
+	}
+
+	if len(agg.FlakiestSets) > 0 {
+		fmt.Printf("\nFlakiest Backup Sets:\n")
+		for _, set := range agg.FlakiestSets {
+			fmt.Printf("  %s\n", set.BackupDir)
+			fmt.Printf("    failed %d of %d runs (%.1f%%), %d status changes\n",
+				set.Failures, set.Runs, set.FailureRate, set.Transitions)
+		}
+	}
+}
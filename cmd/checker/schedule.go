@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
+)
+
+// runSchedule dispatches the schedule subcommands
+func runSchedule(args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: checker schedule install|uninstall [flags]")
+		return 2
+	}
+
+	switch args[0] {
+	case "install":
+		return runScheduleInstall(args[1:])
+	case "uninstall":
+		return runScheduleUninstall(args[1:])
+	default:
+		log.Printf("Unknown schedule command: %s", args[0])
+		return 2
+	}
+}
+
+// runScheduleInstall creates or updates a scheduled task running the check
+// daily with the current config. Flags after -- are passed on to the check.
+func runScheduleInstall(args []string) int {
+	fs := flag.NewFlagSet("schedule install", flag.ExitOnError)
+	daily := fs.String("daily", "02:00", "Time of day to run the check (HH:MM)")
+	name := fs.String("name", winbackupchecker.DefaultTaskName, "Scheduled task name")
+	configPath := fs.String("config", winbackupchecker.DefaultConfigPath("config.json"), "Config file")
+	emailConfigPath := fs.String("email-config", winbackupchecker.DefaultConfigPath("email.config.json"), "Email config file (optional)")
+	profile := fs.String("profile", "", "Named profile from the config to apply")
+	system := fs.Bool("system", false, "Run as SYSTEM even when nobody is logged on (needs an elevated prompt)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: checker schedule install [flags] [-- check flags]\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	at, err := time.Parse("15:04", *daily)
+	if err != nil {
+		log.Printf("Invalid --daily: enter a time such as 03:00")
+		return 2
+	}
+	if _, err := winbackupchecker.LoadConfigProfile(*configPath, *profile); err != nil {
+		log.Printf("Error loading config: %v", err)
+		return 2
+	}
+
+	task, err := checkerTask(*name, at, *configPath, *emailConfigPath, *profile, fs.Args())
+	if err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	task.System = *system
+
+	if err := winbackupchecker.InstallScheduledTask(task); err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	fmt.Printf("Scheduled task %q runs the check daily at %s in %s\n", task.Name, at.Format("15:04"), task.WorkingDir)
+	return 0
+}
+
+// runScheduleUninstall removes the scheduled task
+func runScheduleUninstall(args []string) int {
+	fs := flag.NewFlagSet("schedule uninstall", flag.ExitOnError)
+	name := fs.String("name", winbackupchecker.DefaultTaskName, "Scheduled task name")
+	fs.Parse(args)
+
+	if err := winbackupchecker.UninstallScheduledTask(*name); err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	fmt.Printf("Removed scheduled task %q\n", *name)
+	return 0
+}
+
+// checkerTask describes a daily check with the given config. Tasks start in
+// the system directory, so the task runs in the current directory, where
+// relative paths such as logs.json resolve as they do now, and the config
+// paths are made absolute.
+func checkerTask(name string, at time.Time, configPath, emailConfigPath, profile string, extra []string) (winbackupchecker.ScheduledTask, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return winbackupchecker.ScheduledTask{}, fmt.Errorf("failed to locate the executable: %w", err)
+	}
+	if strings.Contains(exe, "go-build") {
+		return winbackupchecker.ScheduledTask{}, fmt.Errorf("go run builds a temporary executable; schedule a built one instead")
+	}
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return winbackupchecker.ScheduledTask{}, fmt.Errorf("failed to get the working directory: %w", err)
+	}
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+	if abs, err := filepath.Abs(emailConfigPath); err == nil {
+		emailConfigPath = abs
+	}
+
+	args := []string{"--config", configPath, "--email-config", emailConfigPath}
+	if profile != "" {
+		args = append(args, "--profile", profile)
+	}
+	return winbackupchecker.ScheduledTask{
+		Name:       name,
+		Command:    exe,
+		Arguments:  append(args, extra...),
+		WorkingDir: workingDir,
+		At:         at,
+	}, nil
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// readSecret reads a line from the terminal with echo turned off. Piped input
+// is read as is, since stty fails without a terminal.
+func readSecret() (string, error) {
+	disable := exec.Command("stty", "-echo")
+	disable.Stdin = os.Stdin
+	if err := disable.Run(); err != nil {
+		return readLine()
+	}
+	defer func() {
+		enable := exec.Command("stty", "echo")
+		enable.Stdin = os.Stdin
+		enable.Run()
+	}()
+	return readLine()
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+const enableEchoInput = 0x0004
+
+var (
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode = kernel32.NewProc("SetConsoleMode")
+)
+
+// readSecret reads a line from the console with echo turned off. Piped input
+// is read as is.
+func readSecret() (string, error) {
+	handle := syscall.Handle(syscall.Stdin)
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return readLine()
+	}
+
+	procSetConsoleMode.Call(uintptr(handle), uintptr(mode&^enableEchoInput))
+	defer procSetConsoleMode.Call(uintptr(handle), uintptr(mode))
+	return readLine()
+}
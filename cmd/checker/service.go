@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
+)
+
+// runService dispatches the service subcommands
+func runService(args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: checker service install|uninstall|run [flags]")
+		return 2
+	}
+
+	switch args[0] {
+	case "install":
+		return runServiceInstall(args[1:])
+	case "uninstall":
+		return runServiceUninstall(args[1:])
+	case "run":
+		return runServiceRun(args[1:])
+	default:
+		log.Printf("Unknown service command: %s", args[0])
+		return 2
+	}
+}
+
+// serviceOptions are the flags the service runs with
+type serviceOptions struct {
+	interval        time.Duration
+	timeout         time.Duration
+	configPath      string
+	emailConfigPath string
+	profile         string
+	jsonOut         string
+	logFile         string
+	parallel        int
+	failOn          string
+	lockFile        string
+	lockWait        time.Duration
+}
+
+func serviceFlags(fs *flag.FlagSet) *serviceOptions {
+	opts := &serviceOptions{}
+	fs.DurationVar(&opts.interval, "interval", 24*time.Hour, "Time between checks")
+	fs.DurationVar(&opts.timeout, "timeout", 30*time.Minute, "Timeout for each check")
+	fs.StringVar(&opts.configPath, "config", winbackupchecker.DefaultConfigPath("config.json"), "Config file")
+	fs.StringVar(&opts.emailConfigPath, "email-config", winbackupchecker.DefaultConfigPath("email.config.json"), "Email config file (optional)")
+	fs.StringVar(&opts.profile, "profile", "", "Named profile from the config to apply")
+	fs.StringVar(&opts.jsonOut, "json-out", "logs.json", "Write JSON report to a file (NDJSON format)")
+	fs.StringVar(&opts.logFile, "log-file", "service.log", "File the service writes its log to")
+	fs.IntVar(&opts.parallel, "parallel", 0, "Number of backup sets to validate concurrently; 0 picks it by each path's storage type")
+	fs.StringVar(&opts.failOn, "fail-on", "error", "Least severe issue that fails a backup set: warning, error, or critical")
+	fs.StringVar(&opts.lockFile, "lock-file", "checker.lock", "Lock file detecting overlapping checks; empty disables the lock")
+	fs.DurationVar(&opts.lockWait, "lock-wait", 0, "Time to wait for an overlapping check to finish before skipping a check")
+	return opts
+}
+
+// runServiceInstall registers the checker as a Windows service running the
+// check every interval with the given config
+func runServiceInstall(args []string) int {
+	fs := flag.NewFlagSet("service install", flag.ExitOnError)
+	opts := serviceFlags(fs)
+	fs.Parse(args)
+
+	if opts.interval <= 0 {
+		log.Printf("Invalid --interval: must be positive")
+		return 2
+	}
+	if _, err := winbackupchecker.LoadConfigProfile(opts.configPath, opts.profile); err != nil {
+		log.Printf("Error loading config: %v", err)
+		return 2
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		log.Printf("Failed to locate the executable: %v", err)
+		return 2
+	}
+
+	// Services start in the system directory, so every path must be absolute
+	paths := []*string{&opts.configPath, &opts.emailConfigPath, &opts.jsonOut, &opts.logFile, &opts.lockFile}
+	for _, path := range paths {
+		if *path == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(*path); err == nil {
+			*path = abs
+		}
+	}
+
+	serviceArgs := []string{
+		"service", "run",
+		"--interval", opts.interval.String(),
+		"--timeout", opts.timeout.String(),
+		"--config", opts.configPath,
+		"--email-config", opts.emailConfigPath,
+		"--json-out", opts.jsonOut,
+		"--log-file", opts.logFile,
+		"--parallel", strconv.Itoa(opts.parallel),
+		"--fail-on", opts.failOn,
+		"--lock-file", opts.lockFile,
+		"--lock-wait", opts.lockWait.String(),
+	}
+	if opts.profile != "" {
+		serviceArgs = append(serviceArgs, "--profile", opts.profile)
+	}
+
+	if err := winbackupchecker.InstallService(exe, serviceArgs); err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	fmt.Printf("Installed and started service %s, checking every %s\n", winbackupchecker.ServiceName, opts.interval)
+	fmt.Printf("Log: %s\n", opts.logFile)
+	return 0
+}
+
+// runServiceUninstall stops and removes the service
+func runServiceUninstall(args []string) int {
+	fs := flag.NewFlagSet("service uninstall", flag.ExitOnError)
+	fs.Parse(args)
+
+	if err := winbackupchecker.UninstallService(); err != nil {
+		log.Printf("%v", err)
+		return 2
+	}
+	fmt.Printf("Removed service %s\n", winbackupchecker.ServiceName)
+	return 0
+}
+
+// runServiceRun is started by the service control manager. It checks the
+// backups every interval until the service is stopped, picking up config
+// changes before each check.
+func runServiceRun(args []string) int {
+	fs := flag.NewFlagSet("service run", flag.ExitOnError)
+	opts := serviceFlags(fs)
+	fs.Parse(args)
+
+	failThreshold, err := winbackupchecker.ParseSeverity(opts.failOn)
+	if err != nil || failThreshold == winbackupchecker.SeverityInfo {
+		log.Printf("Invalid --fail-on: must be warning, error, or critical")
+		return 2
+	}
+
+	err = winbackupchecker.RunService(func(ctx context.Context) error {
+		// A service has no console, so everything goes to the log file
+		logFile, err := os.OpenFile(opts.logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open service log: %w", err)
+		}
+		defer logFile.Close()
+		log.SetOutput(logFile)
+		os.Stdout = logFile
+		os.Stderr = logFile
+
+		return runServiceLoop(ctx, opts, failThreshold)
+	})
+	if err != nil {
+		log.Printf("Service failed: %v", err)
+		return 2
+	}
+	return 0
+}
+
+func runServiceLoop(ctx context.Context, opts *serviceOptions, failThreshold winbackupchecker.ValidationSeverity) error {
+	reloader, err := winbackupchecker.NewConfigReloader(opts.configPath, opts.emailConfigPath, opts.profile)
+	if err != nil {
+		// Fail the service so the recovery options retry it
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	log.Printf("Service started")
+
+	runCheckLoop(ctx, reloader, loopOptions{
+		interval: opts.interval,
+		timeout:  opts.timeout,
+		check: checkOptions{
+			jsonOut:       opts.jsonOut,
+			parallel:      opts.parallel,
+			format:        "summary",
+			failThreshold: failThreshold,
+			lockFile:      opts.lockFile,
+			lockWait:      opts.lockWait,
+		},
+	})
+	log.Printf("Service stopping")
+	return nil
+}
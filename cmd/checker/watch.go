@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
+)
+
+// watchOptions configure watch mode
+type watchOptions struct {
+	poll    time.Duration
+	settle  time.Duration
+	timeout time.Duration
+	paths   []string
+	check   checkOptions
+}
+
+// runWatch looks for new or changed backup sets every poll until
+// interrupted, and validates just those sets once they have been unchanged
+// for the settle period. Alerts are sent when a validated set fails.
+func runWatch(reloader *winbackupchecker.ConfigReloader, opts watchOptions) int {
+	if opts.poll <= 0 {
+		log.Printf("Invalid --watch-poll: must be positive")
+		return 2
+	}
+	if opts.settle < 0 {
+		log.Printf("Invalid --settle: must not be negative")
+		return 2
+	}
+	if opts.check.format == "text" {
+		opts.check.format = "summary"
+	}
+	opts.check.shouldAlert = func(run winbackupchecker.RunReport) bool {
+		return run.Summary.InvalidBackups > 0 || run.Summary.FailedScans > 0
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	watcher := winbackupchecker.NewSetWatcher(opts.settle)
+	log.Printf("Watching for new backup sets every %s, validating them after %s without changes", opts.poll, opts.settle)
+
+	for {
+		reloadConfig(reloader)
+		cfg, emailCfg := reloader.Current()
+		backupPaths := cfg.BackupPaths
+		if len(opts.paths) > 0 {
+			backupPaths = winbackupchecker.SelectBackupPaths(backupPaths, opts.paths)
+		}
+
+		roots := make([]string, len(backupPaths))
+		for i, backupPath := range backupPaths {
+			roots[i] = backupPath.Path
+		}
+		sets, err := watcher.Poll(roots, cfg.Links, time.Now())
+		if err != nil {
+			log.Printf("%v", err)
+		}
+
+		if len(sets) > 0 {
+			log.Printf("Validating new or changed backup sets: %s", strings.Join(sets, ", "))
+			selected := *cfg
+			selected.BackupPaths = watchedPaths(backupPaths, sets)
+
+			check := opts.check
+			check.sets = sets
+			exitCode := runLockedCheck(ctx, opts.timeout, &selected, emailCfg, check)
+			if exitCode == exitLocked {
+				watcher.Retry(sets)
+			}
+			log.Printf("Check finished with exit code %d", exitCode)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Watch stopping")
+			return 0
+		case <-time.After(opts.poll):
+		}
+	}
+}
+
+// watchedPaths returns the backup paths holding any of sets
+func watchedPaths(backupPaths []winbackupchecker.BackupPath, sets []string) []winbackupchecker.BackupPath {
+	var paths []winbackupchecker.BackupPath
+	for _, backupPath := range backupPaths {
+		prefix := strings.TrimRight(winbackupchecker.NormalizePath(backupPath.Path), `\/`) + string(filepath.Separator)
+		for _, set := range sets {
+			if strings.HasPrefix(set, prefix) {
+				paths = append(paths, backupPath)
+				break
+			}
+		}
+	}
+	return paths
+}
@@ -1,17 +1,19 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
-	"path/filepath"
 	"time"
 
-	winbackupchecker "github.com/RyanHarang/win-backup-checker/internal/backup"
+	winbackupchecker "github.com/RyanHarang/win-backup-checker/pkg/backup"
 )
 
 func main() {
-	emailConfigPath := filepath.Join("configs", "email.config.json")
-	emailCfg, err := winbackupchecker.LoadEmailConfig(emailConfigPath)
+	emailConfigPath := flag.String("email-config", winbackupchecker.DefaultConfigPath("email.config.json"), "Email config file")
+	flag.Parse()
+
+	emailCfg, err := winbackupchecker.LoadEmailConfig(*emailConfigPath)
 	if err != nil {
 		log.Fatalf("Error loading email config: %v", err)
 	}
@@ -86,7 +88,7 @@ func main() {
 		FailedScans:    0,
 	}
 
-	err = winbackupchecker.SendEmailAlert(emailCfg, mockSummary, mockReports)
+	err = winbackupchecker.SendEmailAlert(emailCfg, mockSummary, mockReports, nil)
 	if err != nil {
 		log.Fatalf("Failed to send email: %v", err)
 	}
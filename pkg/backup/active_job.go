@@ -0,0 +1,244 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Actions taken when a backup job is writing to a backup root
+const (
+	ActiveJobAnnotate = "annotate"
+	ActiveJobWait     = "wait"
+	ActiveJobSkip     = "skip"
+)
+
+// activeJobPollInterval is how often a waiting scan checks the job again
+const activeJobPollInterval = 30 * time.Second
+
+// ActiveJobConfig detects backup jobs writing to a target during validation
+type ActiveJobConfig struct {
+	Enabled         bool     `json:"enabled"`
+	Action          string   `json:"action,omitempty"`
+	RecentWrite     string   `json:"recent_write,omitempty"`
+	WaitTimeout     string   `json:"wait_timeout,omitempty"`
+	EngineProcesses []string `json:"engine_processes,omitempty"`
+}
+
+// Validate checks if active job configuration is valid
+func (c *ActiveJobConfig) Validate() error {
+	switch c.Action {
+	case "", ActiveJobAnnotate, ActiveJobWait, ActiveJobSkip:
+	default:
+		return fmt.Errorf("unknown action %q (use annotate, wait or skip)", c.Action)
+	}
+	if _, _, err := c.timings(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// action returns the configured action, annotating by default
+func (c *ActiveJobConfig) action() string {
+	if c.Action == "" {
+		return ActiveJobAnnotate
+	}
+	return c.Action
+}
+
+// timings returns how recent a write must be to count as an active job and
+// how long to wait for a job to finish
+func (c *ActiveJobConfig) timings() (time.Duration, time.Duration, error) {
+	recent, timeout := 2*time.Minute, time.Hour
+	var err error
+	if c.RecentWrite != "" {
+		if recent, err = ParseDuration(c.RecentWrite); err != nil {
+			return 0, 0, fmt.Errorf("invalid recent_write: %w", err)
+		}
+	}
+	if c.WaitTimeout != "" {
+		if timeout, err = ParseDuration(c.WaitTimeout); err != nil {
+			return 0, 0, fmt.Errorf("invalid wait_timeout: %w", err)
+		}
+	}
+	return recent, timeout, nil
+}
+
+// engineProcesses returns the process names of the backup engine
+func (c *ActiveJobConfig) engineProcesses() []string {
+	if len(c.EngineProcesses) > 0 {
+		return c.EngineProcesses
+	}
+	return []string{"sdclt.exe", "wbengine.exe"}
+}
+
+// activeJob describes a backup job found writing to a backup root
+type activeJob struct {
+	// engine is the running backup engine process, if any
+	engine string
+
+	// sets maps the path of each set being written to why it looks active
+	sets map[string]localText
+
+	// waited is how long the scan waited for the job to finish
+	waited time.Duration
+}
+
+func (j activeJob) active() bool {
+	return j.engine != "" || len(j.sets) > 0
+}
+
+// detectActiveJob looks for a running backup engine, sets with files written
+// in the last recent_write, and files of the newest set held open for writing
+func detectActiveJob(sets []BackupSetInfo, cfg *ActiveJobConfig) activeJob {
+	job := activeJob{sets: make(map[string]localText)}
+	recent, _, _ := cfg.timings()
+
+	if running, err := runningProcesses(); err == nil {
+		for _, name := range cfg.engineProcesses() {
+			if running[strings.ToLower(name)] {
+				job.engine = name
+				break
+			}
+		}
+	}
+
+	newest := -1
+	for i, set := range sets {
+		if age := time.Since(set.ModTime); age < recent {
+			job.sets[set.Path] = msg("files written %s ago", age.Round(time.Second))
+		}
+		if newest < 0 || set.ModTime.After(sets[newest].ModTime) {
+			newest = i
+		}
+	}
+
+	// A job writing a large file may not update modification times until
+	// it closes the file, so check the set it most likely writes to
+	if newest >= 0 {
+		set := sets[newest]
+		for _, path := range append(append([]string{}, set.BackupFiles...), set.CatalogFiles...) {
+			if fileInUse(path) {
+				job.sets[set.Path] = msg("%s is open for writing", filepath.Base(path))
+				break
+			}
+		}
+	}
+
+	return job
+}
+
+// awaitIdleBackupJob checks root for an active backup job, waiting up to
+// wait_timeout for it to finish when the action is wait
+func awaitIdleBackupJob(ctx context.Context, root string, opts ScanOptions) activeJob {
+	cfg := opts.ActiveJobs
+	_, timeout, _ := cfg.timings()
+	start := time.Now()
+
+	for {
+		sets, err := discoverBackupSets(ctx, root, opts.Links, opts.MaxSetFiles)
+		if err != nil {
+			return activeJob{}
+		}
+		job := detectActiveJob(sets, cfg)
+		job.waited = time.Since(start)
+
+		if !job.active() || cfg.action() != ActiveJobWait || job.waited >= timeout {
+			return job
+		}
+
+		poll := activeJobPollInterval
+		if remaining := timeout - job.waited; remaining < poll {
+			poll = remaining
+		}
+
+		opts.logf("Backup job in progress on %s; waiting for it to finish\n", filepath.Base(root))
+		select {
+		case <-ctx.Done():
+			return job
+		case <-time.After(poll):
+		}
+	}
+}
+
+// validateIdleSets validates the sets no job is writing to and reports the
+// others as skipped, keeping reports aligned with backupSets
+func validateIdleSets(ctx context.Context, validateSets, backupSets []BackupSetInfo, job activeJob, opts ScanOptions) []BackupReport {
+	var idle []BackupSetInfo
+	var idleIdx []int
+	for i := range backupSets {
+		if _, busy := job.sets[backupSets[i].Path]; !busy {
+			idle = append(idle, validateSets[i])
+			idleIdx = append(idleIdx, i)
+		}
+	}
+
+	validated := validateBackupSets(ctx, idle, opts)
+
+	reports := make([]BackupReport, len(backupSets))
+	for i, set := range backupSets {
+		if reason, busy := job.sets[set.Path]; busy {
+			reports[i] = BackupReport{
+				BackupDir: validateSets[i].Path,
+				Valid:     true,
+				Issues: []ValidationIssue{newIssue(IssueSetBusy, SeverityInfo,
+					msg("validation skipped: a backup job is writing to this set (%s)", reason),
+					validateSets[i].Path,
+					"")},
+				CheckedAt: NowRFC3339(),
+			}
+			opts.finishSet(reports[i])
+		}
+	}
+	for j, i := range idleIdx {
+		reports[i] = validated[j]
+	}
+	return reports
+}
+
+// annotateActiveJob notes on each set being written, unless it was skipped,
+// and on the root when only the engine was seen, that findings may be transient
+func annotateActiveJob(root string, backupSets []BackupSetInfo, reports []BackupReport, job activeJob, skipped bool) []BackupReport {
+	waited := msg("")
+	if job.waited >= time.Second {
+		waited = msg(" after waiting %s", job.waited.Round(time.Second))
+	}
+
+	for i, set := range backupSets {
+		reason, busy := job.sets[set.Path]
+		if !busy || skipped {
+			continue
+		}
+		reports[i].addIssues(newIssue(IssueWrittenDuringValidation, SeverityWarning,
+			msg("a backup job was writing to this set during validation%s (%s); findings may be transient", waited, reason),
+			set.Path,
+			"schedule the checker outside the backup window, or set active_jobs.action to wait or skip"))
+	}
+
+	if len(job.sets) == 0 && job.engine != "" {
+		reports = append(reports, BackupReport{
+			BackupDir: root,
+			Valid:     true,
+			Issues: []ValidationIssue{newIssue(IssueEngineRunning, SeverityInfo,
+				msg("backup engine %s was running during validation%s", job.engine, waited),
+				root,
+				"")},
+			CheckedAt: NowRFC3339(),
+		})
+	}
+
+	return reports
+}
+
+// activeSetNames lists the names of sets being written, for progress output
+func activeSetNames(job activeJob) string {
+	names := make([]string, 0, len(job.sets))
+	for path := range job.sets {
+		names = append(names, filepath.Base(path))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
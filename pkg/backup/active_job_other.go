@@ -0,0 +1,14 @@
+//go:build !windows
+
+package winbackupchecker
+
+// runningProcesses returns no processes: the Windows Backup engine only runs
+// on Windows, so recent writes are the only signal elsewhere
+func runningProcesses() (map[string]bool, error) {
+	return nil, nil
+}
+
+// fileInUse always reports false; file locks are advisory outside Windows
+func fileInUse(path string) bool {
+	return false
+}
@@ -0,0 +1,52 @@
+//go:build windows
+
+package winbackupchecker
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// errorSharingViolation is returned when another handle denies the
+// requested sharing mode
+const errorSharingViolation syscall.Errno = 32
+
+// runningProcesses returns the lower cased image names of running processes
+func runningProcesses() (map[string]bool, error) {
+	output, err := exec.Command("tasklist", "/FO", "CSV", "/NH").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(string(output))).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse process list: %w", err)
+	}
+
+	running := make(map[string]bool, len(records))
+	for _, record := range records {
+		if len(record) > 0 {
+			running[strings.ToLower(record[0])] = true
+		}
+	}
+	return running, nil
+}
+
+// fileInUse reports whether another process has path open for writing, by
+// opening it with a share mode that denies writers
+func fileInUse(path string) bool {
+	p, err := syscall.UTF16PtrFromString(extendedPath(path))
+	if err != nil {
+		return false
+	}
+	handle, err := syscall.CreateFile(p, syscall.GENERIC_READ, syscall.FILE_SHARE_READ,
+		nil, syscall.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return err == errorSharingViolation
+	}
+	syscall.CloseHandle(handle)
+	return false
+}
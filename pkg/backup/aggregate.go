@@ -0,0 +1,118 @@
+package winbackupchecker
+
+import (
+	"sort"
+	"time"
+)
+
+// AggregateReport summarizes backup health across many runs
+type AggregateReport struct {
+	Since          time.Time      `json:"since"`
+	Until          time.Time      `json:"until"`
+	Runs           int            `json:"runs"`
+	SuccessfulRuns int            `json:"successful_runs"`
+	SuccessRate    float64        `json:"success_rate_percent"`
+	SetChecks      int            `json:"set_checks"`
+	ValidSetChecks int            `json:"valid_set_checks"`
+	SetSuccessRate float64        `json:"set_success_rate_percent"`
+	Failures       int            `json:"failures"`
+	MTBF           string         `json:"mtbf,omitempty"`
+	FlakiestSets   []SetStability `json:"flakiest_sets"`
+}
+
+// SetStability describes how consistently one backup set validated
+type SetStability struct {
+	BackupDir   string  `json:"backup_dir"`
+	Runs        int     `json:"runs"`
+	Failures    int     `json:"failures"`
+	Transitions int     `json:"transitions"`
+	FailureRate float64 `json:"failure_rate_percent"`
+}
+
+// AggregateHistory computes success rates, mean time between failures and the
+// least stable backup sets for the given runs. A failure is counted each time
+// a run fails after a successful one (or the first run fails), so a single
+// outage spanning several runs counts once.
+func AggregateHistory(runs []RunReport, since time.Time, top int) AggregateReport {
+	agg := AggregateReport{
+		Since:        since,
+		Until:        time.Now(),
+		FlakiestSets: []SetStability{},
+	}
+
+	sets := make(map[string]*SetStability)
+	lastValid := make(map[string]bool)
+	previousSuccessful := true
+
+	for _, run := range runs {
+		agg.Runs++
+		successful := run.Successful()
+		if successful {
+			agg.SuccessfulRuns++
+		} else if previousSuccessful {
+			agg.Failures++
+		}
+		previousSuccessful = successful
+
+		for _, scanReport := range run.Results {
+			for _, br := range scanReport.Reports {
+				agg.SetChecks++
+				if br.Valid {
+					agg.ValidSetChecks++
+				}
+
+				set, ok := sets[br.BackupDir]
+				if !ok {
+					set = &SetStability{BackupDir: br.BackupDir}
+					sets[br.BackupDir] = set
+				} else if lastValid[br.BackupDir] != br.Valid {
+					set.Transitions++
+				}
+				set.Runs++
+				if !br.Valid {
+					set.Failures++
+				}
+				lastValid[br.BackupDir] = br.Valid
+			}
+		}
+	}
+
+	if agg.Runs > 0 {
+		agg.SuccessRate = float64(agg.SuccessfulRuns) / float64(agg.Runs) * 100
+	}
+	if agg.SetChecks > 0 {
+		agg.SetSuccessRate = float64(agg.ValidSetChecks) / float64(agg.SetChecks) * 100
+	}
+	if agg.Failures > 0 {
+		start := since
+		if len(runs) > 0 && runs[0].Time().After(start) {
+			start = runs[0].Time()
+		}
+		agg.MTBF = (agg.Until.Sub(start) / time.Duration(agg.Failures)).Round(time.Minute).String()
+	}
+
+	for _, set := range sets {
+		if set.Failures == 0 {
+			continue
+		}
+		set.FailureRate = float64(set.Failures) / float64(set.Runs) * 100
+		agg.FlakiestSets = append(agg.FlakiestSets, *set)
+	}
+
+	sort.Slice(agg.FlakiestSets, func(i, j int) bool {
+		a, b := agg.FlakiestSets[i], agg.FlakiestSets[j]
+		if a.Transitions != b.Transitions {
+			return a.Transitions > b.Transitions
+		}
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		return a.BackupDir < b.BackupDir
+	})
+
+	if top > 0 && len(agg.FlakiestSets) > top {
+		agg.FlakiestSets = agg.FlakiestSets[:top]
+	}
+
+	return agg
+}
@@ -0,0 +1,142 @@
+package winbackupchecker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// AlertDedupConfig stops repeating the same alert every run while the same
+// backup sets keep failing with the same issues
+type AlertDedupConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Cooldown is how long an unchanged alert is suppressed before it is sent
+	// again as a reminder
+	Cooldown  string `json:"cooldown,omitempty"`
+	StateFile string `json:"state_file,omitempty"`
+}
+
+// Validate checks if deduplication configuration is valid
+func (c *AlertDedupConfig) Validate() error {
+	if c.Cooldown != "" {
+		if _, err := ParseDuration(c.Cooldown); err != nil {
+			return fmt.Errorf("invalid cooldown: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *AlertDedupConfig) cooldown() time.Duration {
+	if c.Cooldown == "" {
+		return 24 * time.Hour
+	}
+	d, _ := ParseDuration(c.Cooldown)
+	return d
+}
+
+func (c *AlertDedupConfig) statePath() string {
+	if c.StateFile == "" {
+		return "alert-state.json"
+	}
+	return c.StateFile
+}
+
+// alertDedupState records the issues of the last alert sent
+type alertDedupState struct {
+	Issues   map[string][]string `json:"issues"`
+	LastSent time.Time           `json:"last_sent"`
+}
+
+// alertIssues returns the warning and error fingerprints of each backup set
+// with problems
+func alertIssues(reports []ScanReport) map[string][]string {
+	issues := make(map[string][]string)
+	for _, scanReport := range reports {
+		for _, br := range scanReport.Reports {
+			for _, issue := range br.Issues {
+				if !issue.Suppressed && issue.Severity >= SeverityWarning {
+					issues[br.BackupDir] = append(issues[br.BackupDir], issueFingerprint(issue))
+				}
+			}
+			if fingerprints := issues[br.BackupDir]; len(fingerprints) > 0 {
+				sort.Strings(fingerprints)
+			}
+		}
+	}
+	return issues
+}
+
+// issueFingerprint identifies an issue across runs. Digits are masked, so
+// messages such as "backup is quite old (95 days)" do not count as a new
+// issue each day.
+func issueFingerprint(issue ValidationIssue) string {
+	message := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return '#'
+		}
+		return r
+	}, issue.Message)
+	return issue.Severity.String() + ": " + message
+}
+
+// shouldAlert reports whether the alert for reports differs from the last
+// one sent, or the cooldown since it has passed, and returns the state to
+// record once the alert is sent. A run without problems clears the state, so
+// a failure that returns later is alerted again. An unreadable state file
+// never blocks an alert; it is replaced when the alert is recorded.
+func (c *AlertDedupConfig) shouldAlert(reports []ScanReport, now time.Time) (bool, alertDedupState, error) {
+	current := alertDedupState{Issues: alertIssues(reports), LastSent: now}
+
+	previous, err := loadAlertDedupState(c.statePath())
+	if err != nil {
+		return true, current, nil
+	}
+
+	if len(current.Issues) == 0 {
+		if len(previous.Issues) > 0 {
+			if err := saveAlertDedupState(c.statePath(), alertDedupState{}); err != nil {
+				return true, current, err
+			}
+		}
+		return true, current, nil
+	}
+
+	if !reflect.DeepEqual(previous.Issues, current.Issues) {
+		return true, current, nil
+	}
+	return now.Sub(previous.LastSent) >= c.cooldown(), current, nil
+}
+
+func loadAlertDedupState(path string) (alertDedupState, error) {
+	var state alertDedupState
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, fmt.Errorf("failed to read alert state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse alert state: %w", err)
+	}
+	return state, nil
+}
+
+func saveAlertDedupState(path string, state alertDedupState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write alert state: %w", err)
+	}
+	return nil
+}
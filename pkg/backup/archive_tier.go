@@ -0,0 +1,203 @@
+package winbackupchecker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ArchiveTierConfig marks backup roots whose copies live in cold storage.
+// Those roots are validated from file metadata only; reading content would
+// trigger slow and billed retrievals. Optional sample restores periodically
+// deep-verify a random selection of files within a retrieval budget.
+type ArchiveTierConfig struct {
+	Paths        []string `json:"paths"`
+	SampleEvery  string   `json:"sample_every,omitempty"`
+	SampleBudget string   `json:"sample_budget,omitempty"`
+	StateFile    string   `json:"state_file,omitempty"`
+}
+
+// Validate checks if archive tier configuration is valid
+func (c *ArchiveTierConfig) Validate() error {
+	if len(c.Paths) == 0 {
+		return fmt.Errorf("paths is required")
+	}
+	if c.SampleEvery == "" {
+		return nil
+	}
+	if _, err := ParseDuration(c.SampleEvery); err != nil {
+		return fmt.Errorf("invalid sample_every: %w", err)
+	}
+	budget, err := ParseByteSize(c.SampleBudget)
+	if err != nil {
+		return fmt.Errorf("invalid sample_budget: %w", err)
+	}
+	if budget <= 0 {
+		return fmt.Errorf("sample_budget is required when sample_every is set")
+	}
+	return nil
+}
+
+// covers reports whether root is, or is inside, an archive tier path
+func (c *ArchiveTierConfig) covers(root string) bool {
+	if c == nil {
+		return false
+	}
+	for _, path := range c.Paths {
+		if isSubPath(path, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveSampleState records when each archive root was last sampled
+type archiveSampleState map[string]time.Time
+
+// archiveSample is a file picked for a sample restore
+type archiveSample struct {
+	path   string
+	size   int64
+	setIdx int
+}
+
+// sampleArchiveRestores deep-verifies a random selection of files from an
+// archive root once per sample_every, reading at most sample_budget bytes
+func sampleArchiveRestores(ctx context.Context, root string, backupSets []BackupSetInfo, reports []BackupReport, opts ScanOptions) {
+	cfg := opts.ArchiveTier
+	every, _ := ParseDuration(cfg.SampleEvery)
+	budget, _ := ParseByteSize(cfg.SampleBudget)
+
+	statePath := cfg.StateFile
+	if statePath == "" {
+		statePath = "archive-samples.json"
+	}
+
+	state, err := loadArchiveSampleState(statePath)
+	if err != nil {
+		opts.logf("Warning: %v\n", err)
+		return
+	}
+	if last, ok := state[root]; ok && time.Since(last) < every {
+		return
+	}
+
+	var candidates []archiveSample
+	for i, setInfo := range backupSets {
+		for _, path := range append(append([]string{}, setInfo.BackupFiles...), setInfo.CatalogFiles...) {
+			if info, err := statBackup(path); err == nil {
+				candidates = append(candidates, archiveSample{path: path, size: info.Size(), setIdx: i})
+			}
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	opts.logf("Sampling archive tier files in %s (budget %s)\n", filepath.Base(root), formatByteSize(budget))
+
+	restored := make(map[int][]int64)
+	for _, sample := range candidates {
+		if sample.size > budget {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		budget -= sample.size
+
+		err := deepVerifyFile(ctx, sample.path)
+		if ctx.Err() != nil {
+			// Interrupted mid-file; the sample is retried next time
+			return
+		}
+		if err != nil {
+			reports[sample.setIdx].addIssues(newIssue(IssueSampleRestoreFailed, SeverityError,
+				msg("sample restore from archive tier failed verification: %v", err),
+				sample.path,
+				"restore the backup set from another copy and check the archive storage"))
+		}
+		restored[sample.setIdx] = append(restored[sample.setIdx], sample.size)
+	}
+
+	for setIdx, sizes := range restored {
+		var total int64
+		for _, size := range sizes {
+			total += size
+		}
+		reports[setIdx].addIssues(newIssue(IssueSampleRestoreVerified, SeverityInfo,
+			msg("deep-verified %d sampled file(s) (%s) from archive tier", len(sizes), formatByteSize(total)),
+			backupSets[setIdx].Path,
+			""))
+	}
+
+	state[root] = time.Now()
+	if err := saveArchiveSampleState(statePath, state); err != nil {
+		opts.logf("Warning: %v\n", err)
+	}
+}
+
+// deepVerifyFile reads a sampled file completely. Zip entries are fully
+// decompressed so their CRC32 checksums are verified.
+func deepVerifyFile(ctx context.Context, path string) error {
+	if !strings.EqualFold(filepath.Ext(path), ".zip") {
+		if err := validateCatalogFile(ctx, path); err != nil {
+			return err
+		}
+		_, err := parseCatalog(ctx, path)
+		return err
+	}
+
+	r, zipFile, err := openZip(ctx, path)
+	if err != nil {
+		return fmt.Errorf("cannot open zip: %w", err)
+	}
+	defer zipFile.Close()
+
+	for _, file := range r.File {
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("cannot open file %s in zip: %w", file.Name, err)
+		}
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("cannot read file %s in zip: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+func loadArchiveSampleState(path string) (archiveSampleState, error) {
+	state := make(archiveSampleState)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read archive sample state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse archive sample state: %w", err)
+	}
+	return state, nil
+}
+
+func saveArchiveSampleState(path string, state archiveSampleState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal archive sample state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write archive sample state: %w", err)
+	}
+	return nil
+}
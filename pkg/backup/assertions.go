@@ -0,0 +1,105 @@
+package winbackupchecker
+
+import (
+	"sort"
+	"strings"
+)
+
+// ForbiddenContentRule describes content that must not appear in backups
+type ForbiddenContentRule struct {
+	Pattern string `json:"pattern"`
+	MinSize string `json:"min_size,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// maxForbiddenMatchesListed caps the paths named in one forbidden-content issue
+const maxForbiddenMatchesListed = 5
+
+// checkRequiredPaths verifies that every required path pattern matches at
+// least one file in each machine's newest backup set
+func checkRequiredPaths(backupSets []BackupSetInfo, reports []BackupReport, opts ScanOptions) {
+	if len(opts.RequiredPaths) == 0 {
+		return
+	}
+
+	machines, byMachine := newestSetsByMachine(backupSets, 1)
+	for _, machine := range machines {
+		idx := byMachine[machine][0]
+		entries := collectSetEntries(backupSets[idx], opts.CatalogCache)
+
+		for _, pattern := range opts.RequiredPaths {
+			if !anyEntryMatches(pattern, entries) {
+				reports[idx].addIssues(newIssue(IssueRequiredPathMissing, SeverityError,
+					msg("required path not found in newest backup: %s", pattern),
+					backupSets[idx].Path,
+					"check that the backup job includes this folder and that it is not excluded"))
+			}
+		}
+	}
+}
+
+func anyEntryMatches(pattern string, entries map[string]int64) bool {
+	for entry := range entries {
+		if matchEntryPattern(pattern, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkForbiddenContent warns when files or folders matching a forbidden
+// rule appear in a machine's newest backup set. Matches are totaled per
+// matched file or folder and compared against the rule's minimum size.
+func checkForbiddenContent(backupSets []BackupSetInfo, reports []BackupReport, opts ScanOptions) {
+	if len(opts.ForbiddenContent) == 0 {
+		return
+	}
+
+	machines, byMachine := newestSetsByMachine(backupSets, 1)
+	for _, machine := range machines {
+		idx := byMachine[machine][0]
+		entries := collectSetEntries(backupSets[idx], opts.CatalogCache)
+
+		for _, rule := range opts.ForbiddenContent {
+			minSize, _ := ParseByteSize(rule.MinSize)
+
+			matched := make(map[string]int64)
+			for entry, size := range entries {
+				if prefix, ok := matchEntryPrefix(rule.Pattern, entry); ok {
+					matched[prefix] += size
+				}
+			}
+
+			var offenders []string
+			var total int64
+			for prefix, size := range matched {
+				if size >= minSize {
+					offenders = append(offenders, prefix)
+					total += size
+				}
+			}
+			if len(offenders) == 0 {
+				continue
+			}
+			sort.Strings(offenders)
+
+			listed := offenders
+			if len(listed) > maxForbiddenMatchesListed {
+				listed = listed[:maxForbiddenMatchesListed]
+			}
+			more := msg("")
+			if len(offenders) > len(listed) {
+				more = msg(" and %d more", len(offenders)-len(listed))
+			}
+			message := msg("forbidden content %q found in newest backup (%d matches, %s): %s%s",
+				rule.Pattern, len(offenders), formatByteSize(total), strings.Join(listed, ", "), more)
+
+			suggestion := "exclude this content from the backup job to keep backup size and duration under control"
+			if rule.Reason != "" {
+				suggestion = rule.Reason
+			}
+
+			reports[idx].addIssues(newIssue(IssueForbiddenContent, SeverityWarning, message, backupSets[idx].Path, suggestion))
+		}
+	}
+}
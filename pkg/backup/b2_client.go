@@ -0,0 +1,417 @@
+package winbackupchecker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// B2Options set the application key the bucket of a b2:// backup path is
+// read with. Without one, the B2_APPLICATION_KEY_ID and B2_APPLICATION_KEY
+// environment variables are used, as by the b2 command.
+type B2Options struct {
+	KeyID          string `json:"key_id,omitempty"`
+	ApplicationKey string `json:"application_key,omitempty" secret:"true"`
+
+	// ApplicationKeyCredential names an application key stored with
+	// credentials set, used when ApplicationKey is empty
+	ApplicationKeyCredential string `json:"application_key_credential,omitempty"`
+}
+
+// Validate checks that the key ID comes with a key
+func (o *B2Options) Validate() error {
+	if o.KeyID != "" && o.ApplicationKey == "" && o.ApplicationKeyCredential == "" {
+		return fmt.Errorf("key_id requires application_key or application_key_credential")
+	}
+	if o.KeyID == "" && (o.ApplicationKey != "" || o.ApplicationKeyCredential != "") {
+		return fmt.Errorf("application_key requires key_id")
+	}
+	return nil
+}
+
+// b2AuthURL is where application keys are signed in with
+const b2AuthURL = "https://api.backblazeb2.com/b2api/v3/b2_authorize_account"
+
+// b2RequestTimeout bounds each request, b2ReadAhead is what a file reads at
+// once, and b2PageSize the files listed per request, which B2 bills per
+// thousand
+const (
+	b2RequestTimeout = 2 * time.Minute
+	b2ReadAhead      = 1 << 20
+	b2PageSize       = 1000
+)
+
+// b2Client reads one bucket with the native B2 API
+type b2Client struct {
+	http   *http.Client
+	keyID  string
+	key    string
+	bucket string
+
+	// Set by authorize, and again when the token expires after a day
+	mu          sync.Mutex
+	token       string
+	apiURL      string
+	downloadURL string
+	accountID   string
+	bucketID    string
+}
+
+// b2Error is a B2 request failing with an HTTP status and error code
+type b2Error struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *b2Error) Error() string {
+	if e.message != "" {
+		return fmt.Sprintf("%s: %s", e.code, e.message)
+	}
+	if e.code != "" {
+		return e.code
+	}
+	return fmt.Sprintf("B2 request failed with HTTP status %d", e.status)
+}
+
+// Is lets errors.Is match the fs errors the status stands for
+func (e *b2Error) Is(target error) bool {
+	switch e.status {
+	case http.StatusNotFound:
+		return target == fs.ErrNotExist
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return target == fs.ErrPermission
+	}
+	return false
+}
+
+// b2File is a file or folder of a listing. Action is "upload" for files and
+// "folder" for the folders a delimited listing folds files into.
+type b2File struct {
+	FileID          string            `json:"fileId"`
+	FileName        string            `json:"fileName"`
+	Action          string            `json:"action"`
+	ContentLength   int64             `json:"contentLength"`
+	UploadTimestamp int64             `json:"uploadTimestamp"`
+	FileInfo        map[string]string `json:"fileInfo"`
+}
+
+// info returns the metadata of the file, named by the rest of its name after
+// prefix. Its time is the one the syncing tool recorded for the source
+// file, when there is one, as upload times are those of the last sync.
+func (f *b2File) info(prefix string) *remoteFileInfo {
+	info := &remoteFileInfo{
+		name:    strings.TrimSuffix(strings.TrimPrefix(f.FileName, prefix), "/"),
+		size:    f.ContentLength,
+		modTime: time.UnixMilli(f.UploadTimestamp),
+		dir:     f.Action == "folder",
+	}
+	if millis, err := strconv.ParseInt(f.FileInfo["src_last_modified_millis"], 10, 64); err == nil {
+		info.modTime = time.UnixMilli(millis)
+	}
+	if info.dir {
+		info.size = 0
+	}
+	return info
+}
+
+// newB2Client returns a client for bucket with the key of opts or the
+// environment
+func newB2Client(bucket string, opts *B2Options) (*b2Client, error) {
+	if opts == nil {
+		opts = &B2Options{}
+	}
+	c := &b2Client{http: &http.Client{}, bucket: bucket, keyID: opts.KeyID, key: opts.ApplicationKey}
+	if c.key == "" && opts.ApplicationKeyCredential != "" {
+		key, err := GetCredential(opts.ApplicationKeyCredential)
+		if err != nil {
+			return nil, err
+		}
+		c.key = key
+	}
+	if c.keyID == "" {
+		c.keyID, c.key = os.Getenv("B2_APPLICATION_KEY_ID"), os.Getenv("B2_APPLICATION_KEY")
+	}
+	if c.keyID == "" || c.key == "" {
+		return nil, errors.New("no application key: set key_id and application_key, or B2_APPLICATION_KEY_ID and B2_APPLICATION_KEY")
+	}
+	return c, nil
+}
+
+// authorize signs in with the application key and looks up the bucket
+func (c *b2Client) authorize() error {
+	ctx, cancel := context.WithTimeout(context.Background(), b2RequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b2AuthURL, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.keyID, c.key)
+	var auth struct {
+		AccountID          string `json:"accountId"`
+		AuthorizationToken string `json:"authorizationToken"`
+		APIInfo            struct {
+			StorageAPI struct {
+				APIURL      string `json:"apiUrl"`
+				DownloadURL string `json:"downloadUrl"`
+			} `json:"storageApi"`
+		} `json:"apiInfo"`
+	}
+	if err := c.send(req, &auth); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.token = auth.AuthorizationToken
+	c.apiURL = auth.APIInfo.StorageAPI.APIURL
+	c.downloadURL = auth.APIInfo.StorageAPI.DownloadURL
+	c.accountID = auth.AccountID
+	known := c.bucketID != ""
+	c.mu.Unlock()
+	if known {
+		return nil
+	}
+
+	var buckets struct {
+		Buckets []struct {
+			BucketID string `json:"bucketId"`
+		} `json:"buckets"`
+	}
+	if err := c.call("b2_list_buckets", map[string]any{"accountId": auth.AccountID, "bucketName": c.bucket}, &buckets); err != nil {
+		return err
+	}
+	if len(buckets.Buckets) == 0 {
+		return fmt.Errorf("bucket %s not found", c.bucket)
+	}
+	c.mu.Lock()
+	c.bucketID = buckets.Buckets[0].BucketID
+	c.mu.Unlock()
+	return nil
+}
+
+// send sends req and decodes the JSON response into out, or returns the
+// error of a failed one
+func (c *b2Client) send(req *http.Request, out any) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return readB2Error(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// readB2Error returns the error of a failed response
+func readB2Error(resp *http.Response) error {
+	e := &b2Error{status: resp.StatusCode}
+	var body struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body) == nil {
+		e.code, e.message = body.Code, body.Message
+	}
+	return e
+}
+
+// isExpired reports whether err is the token running out, after which the
+// client signs in again
+func isExpired(err error) bool {
+	var b2Err *b2Error
+	return errors.As(err, &b2Err) && b2Err.status == http.StatusUnauthorized && b2Err.code == "expired_auth_token"
+}
+
+// call posts an API call with params and decodes its response into out
+func (c *b2Client) call(name string, params map[string]any, out any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		c.mu.Lock()
+		endpoint, token := c.apiURL+"/b2api/v3/"+name, c.token
+		c.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), b2RequestTimeout)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			cancel()
+			return err
+		}
+		req.Header.Set("Authorization", token)
+		err = c.send(req, out)
+		cancel()
+		if attempt == 0 && isExpired(err) {
+			if err := c.authorize(); err != nil {
+				return err
+			}
+			continue
+		}
+		return err
+	}
+}
+
+// list returns a page of the files and folders directly under prefix from
+// start, and where the next page starts, or "" after the last
+func (c *b2Client) list(prefix, start string, count int) ([]b2File, string, error) {
+	var page struct {
+		Files        []b2File `json:"files"`
+		NextFileName *string  `json:"nextFileName"`
+	}
+	params := map[string]any{"bucketId": c.bucketID, "prefix": prefix, "delimiter": "/", "maxFileCount": count}
+	if start != "" {
+		params["startFileName"] = start
+	}
+	if err := c.call("b2_list_file_names", params, &page); err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if page.NextFileName != nil {
+		next = *page.NextFileName
+	}
+	return page.Files, next, nil
+}
+
+// file returns the file named name, or an error matching fs.ErrNotExist
+func (c *b2Client) file(name string) (*b2File, error) {
+	files, _, err := c.list(name, name, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 || files[0].FileName != name || files[0].Action != "upload" {
+		return nil, &b2Error{status: http.StatusNotFound, code: "not_found"}
+	}
+	return &files[0], nil
+}
+
+// read returns up to n bytes of the file name from off
+func (c *b2Client) read(name string, off int64, n int) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		c.mu.Lock()
+		endpoint, token := c.downloadURL+"/file/"+b2EscapeName(c.bucket)+"/"+b2EscapeName(name), c.token
+		c.mu.Unlock()
+
+		data, err := c.download(endpoint, token, off, n)
+		if attempt == 0 && isExpired(err) {
+			if err := c.authorize(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return data, err
+	}
+}
+
+func (c *b2Client) download(endpoint, token string, off int64, n int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b2RequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(n)-1))
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// The whole file, as sent for empty ones
+		if _, err := io.CopyN(io.Discard, resp.Body, off); err != nil {
+			return nil, io.EOF
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil, io.EOF
+	default:
+		return nil, readB2Error(resp)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, int64(n)))
+}
+
+// b2EscapeName percent-encodes a file name for a download URL, keeping the
+// / between its folders
+func b2EscapeName(name string) string {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// unfinished returns the large files under prefix whose upload was started
+// but never finished, with the parts uploaded of each
+func (c *b2Client) unfinished(prefix string) ([]UnfinishedUpload, error) {
+	var uploads []UnfinishedUpload
+	start := ""
+	for {
+		var page struct {
+			Files      []b2File `json:"files"`
+			NextFileID *string  `json:"nextFileId"`
+		}
+		params := map[string]any{"bucketId": c.bucketID, "namePrefix": prefix, "maxFileCount": 100}
+		if start != "" {
+			params["startFileId"] = start
+		}
+		if err := c.call("b2_list_unfinished_large_files", params, &page); err != nil {
+			return nil, err
+		}
+		for _, f := range page.Files {
+			upload := UnfinishedUpload{Path: f.FileName, Started: time.UnixMilli(f.UploadTimestamp)}
+			if err := c.countParts(f.FileID, &upload); err != nil {
+				return nil, err
+			}
+			uploads = append(uploads, upload)
+		}
+		if page.NextFileID == nil {
+			return uploads, nil
+		}
+		start = *page.NextFileID
+	}
+}
+
+// countParts adds up the parts uploaded of the unfinished large file id
+func (c *b2Client) countParts(id string, upload *UnfinishedUpload) error {
+	start := 0
+	for {
+		var page struct {
+			Parts []struct {
+				ContentLength int64 `json:"contentLength"`
+			} `json:"parts"`
+			NextPartNumber *int `json:"nextPartNumber"`
+		}
+		params := map[string]any{"fileId": id, "maxPartCount": b2PageSize}
+		if start > 0 {
+			params["startPartNumber"] = start
+		}
+		if err := c.call("b2_list_parts", params, &page); err != nil {
+			return err
+		}
+		for _, part := range page.Parts {
+			upload.Parts++
+			upload.Size += part.ContentLength
+		}
+		if page.NextPartNumber == nil {
+			return nil
+		}
+		start = *page.NextPartNumber
+	}
+}
@@ -0,0 +1,131 @@
+package winbackupchecker
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// b2FS reads a bucket through the B2 client. File names are split into
+// folders at each /, as the B2 web console shows them; the host of a b2://
+// path is the bucket.
+type b2FS struct {
+	client *b2Client
+}
+
+// dialB2 signs in to B2 for the bucket of key, b2://bucket
+func dialB2(key string, opts *B2Options) (*b2FS, error) {
+	client, err := newB2Client(strings.TrimPrefix(key, "b2://"), opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.authorize(); err != nil {
+		return nil, err
+	}
+	return &b2FS{client: client}, nil
+}
+
+func (s *b2FS) Stat(name string) (fs.FileInfo, error) {
+	key := bucketKey(name)
+	if key == "" {
+		return &remoteFileInfo{name: "/", dir: true}, nil
+	}
+	file, err := s.client.file(key)
+	if err == nil {
+		return file.info(key[:strings.LastIndexByte(key, '/')+1]), nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	// Folders have no file of their own but are the prefix of some
+	files, _, err := s.client.list(key+"/", "", 1)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	if len(files) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return &remoteFileInfo{name: key[strings.LastIndexByte(key, '/')+1:], dir: true}, nil
+}
+
+func (s *b2FS) OpenDir(name string) (dirReader, error) {
+	info, err := s.Stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.Unwrap(err)}
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("not a directory")}
+	}
+	prefix := bucketKey(name)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	start, done := "", false
+	list := func() ([]fs.DirEntry, error) {
+		if done {
+			return nil, io.EOF
+		}
+		files, next, err := s.client.list(prefix, start, b2PageSize)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		start, done = next, next == ""
+		var entries []fs.DirEntry
+		for _, f := range files {
+			// Hidden files are listed only by their versions, and the web
+			// console keeps empty folders with a .bzEmpty file
+			if f.Action != "upload" && f.Action != "folder" || strings.HasSuffix(f.FileName, "/.bzEmpty") {
+				continue
+			}
+			entries = append(entries, f.info(prefix))
+		}
+		return entries, nil
+	}
+	return &remoteDir{list: list, close: func() error { return nil }}, nil
+}
+
+func (s *b2FS) Open(name string) (readableFile, error) {
+	key := bucketKey(name)
+	file, err := s.client.file(key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			if dir, statErr := s.Stat(name); statErr == nil && dir.IsDir() {
+				err = errors.New("is a directory")
+			}
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	info := file.info(key[:strings.LastIndexByte(key, '/')+1])
+	return &bufferedFile{
+		info:      info,
+		readAhead: b2ReadAhead,
+		fetch: func(off int64, n int) ([]byte, error) {
+			if off >= info.Size() {
+				return nil, io.EOF
+			}
+			return s.client.read(key, off, n)
+		},
+		close: func() error { return nil },
+	}, nil
+}
+
+// Unfinished returns the large files under the folder name whose upload
+// never finished, named like name
+func (s *b2FS) Unfinished(name string) ([]UnfinishedUpload, error) {
+	prefix := bucketKey(name)
+	if prefix != "" {
+		prefix += "/"
+	}
+	uploads, err := s.client.unfinished(prefix)
+	for i := range uploads {
+		uploads[i].Path = "/" + uploads[i].Path
+	}
+	return uploads, err
+}
+
+func (s *b2FS) Close() error {
+	s.client.http.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,313 @@
+package winbackupchecker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// BackupPath is a backup_paths entry. It is either a plain path string or an
+// object overriding global settings for that path, since a NAS share and a
+// USB drive rarely share thresholds.
+type BackupPath struct {
+	Path           string `json:"path"`
+	MinBackupAge   string `json:"min_backup_age,omitempty"`
+	MaxBackupAge   string `json:"max_backup_age,omitempty"`
+	DeepValidation *bool  `json:"deep_validation,omitempty"`
+
+	// ExpectedMachines must each have at least one backup set under the path
+	ExpectedMachines []string `json:"expected_machines,omitempty"`
+
+	// SampleRate is the fraction of zip files read per set; 0 reads all
+	SampleRate float64 `json:"sample_rate,omitempty"`
+
+	// PathTimeout and SetTimeout limit the scan of the path and of each set
+	PathTimeout string `json:"path_timeout,omitempty"`
+	SetTimeout  string `json:"set_timeout,omitempty"`
+
+	// Storage is hdd, ssd or network, replacing the detected storage type
+	// that Parallel and FileWorkers default by
+	Storage string `json:"storage,omitempty"`
+
+	// Parallel and FileWorkers are the backup sets validated and the files
+	// of each set read at once
+	Parallel    int `json:"parallel,omitempty"`
+	FileWorkers int `json:"file_workers,omitempty"`
+
+	// Credentials connect to the share of a UNC path for the scan
+	Credentials *ShareCredentials `json:"credentials,omitempty"`
+
+	// SSH sets how ssh reaches the server of an sftp:// path
+	SSH *SSHOptions `json:"ssh,omitempty"`
+
+	// S3 sets the endpoint and keys of the bucket of an s3:// path
+	S3 *S3Options `json:"s3,omitempty"`
+
+	// B2 sets the application key of the bucket of a b2:// path
+	B2 *B2Options `json:"b2,omitempty"`
+
+	// Rclone sets how rclone is run for an rclone:// path
+	Rclone *RcloneOptions `json:"rclone,omitempty"`
+
+	// FTP sets how the server of an ftp:// or ftps:// path is signed in to
+	FTP *FTPOptions `json:"ftp,omitempty"`
+
+	// OfflineDrive brings the disk of a path on a drive letter online and
+	// unlocks it for the scan
+	OfflineDrive *OfflineDriveOptions `json:"offline_drive,omitempty"`
+}
+
+// UnmarshalJSON accepts a plain path string as well as an object. The path
+// is normalized, so a \\?\ prefixed path reports like a plain one.
+func (p *BackupPath) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '"' {
+		*p = BackupPath{}
+		if err := json.Unmarshal(trimmed, &p.Path); err != nil {
+			return err
+		}
+		p.Path = NormalizePath(p.Path)
+		return nil
+	}
+
+	// The alias drops this method so the object decodes normally
+	type backupPath BackupPath
+	var decoded backupPath
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*p = BackupPath(decoded)
+	p.Path = NormalizePath(p.Path)
+	return nil
+}
+
+// MarshalJSON writes entries without overrides as plain strings
+func (p BackupPath) MarshalJSON() ([]byte, error) {
+	if p.MinBackupAge == "" && p.MaxBackupAge == "" && p.DeepValidation == nil &&
+		len(p.ExpectedMachines) == 0 && p.SampleRate == 0 && p.PathTimeout == "" && p.SetTimeout == "" &&
+		p.Storage == "" && p.Parallel == 0 && p.FileWorkers == 0 && p.Credentials == nil && p.SSH == nil &&
+		p.S3 == nil && p.B2 == nil && p.Rclone == nil && p.FTP == nil && p.OfflineDrive == nil {
+		return json.Marshal(p.Path)
+	}
+	type backupPath BackupPath
+	return json.Marshal(backupPath(p))
+}
+
+// Validate checks if the path entry is valid
+func (p *BackupPath) Validate() error {
+	if strings.TrimSpace(p.Path) == "" {
+		return fmt.Errorf("path is required")
+	}
+	if p.MinBackupAge != "" {
+		if _, err := ParseDuration(p.MinBackupAge); err != nil {
+			return fmt.Errorf("invalid min_backup_age duration: %w", err)
+		}
+	}
+	if p.MaxBackupAge != "" {
+		if _, err := ParseDuration(p.MaxBackupAge); err != nil {
+			return fmt.Errorf("invalid max_backup_age duration: %w", err)
+		}
+	}
+	for _, machine := range p.ExpectedMachines {
+		if strings.TrimSpace(machine) == "" {
+			return fmt.Errorf("expected_machines entries cannot be empty")
+		}
+	}
+	if p.SampleRate < 0 || p.SampleRate > 1 {
+		return fmt.Errorf("sample_rate must be between 0 and 1")
+	}
+	if p.PathTimeout != "" {
+		if _, err := ParseDuration(p.PathTimeout); err != nil {
+			return fmt.Errorf("invalid path_timeout duration: %w", err)
+		}
+	}
+	if p.SetTimeout != "" {
+		if _, err := ParseDuration(p.SetTimeout); err != nil {
+			return fmt.Errorf("invalid set_timeout duration: %w", err)
+		}
+	}
+	if _, err := ParseStorageType(p.Storage); err != nil {
+		return fmt.Errorf("invalid storage: %w", err)
+	}
+	if p.Parallel < 0 {
+		return fmt.Errorf("parallel cannot be negative")
+	}
+	if p.FileWorkers < 0 {
+		return fmt.Errorf("file_workers cannot be negative")
+	}
+	switch scheme := remoteScheme(p.Path); scheme {
+	case "", "sftp", "s3", "b2", "rclone", "ftp", "ftps":
+	default:
+		return fmt.Errorf("unsupported scheme %s://: backup paths can be sftp://, s3://, b2://, rclone://, ftp:// or ftps:// URLs", scheme)
+	}
+	if p.SSH != nil && remoteScheme(p.Path) != "sftp" {
+		return fmt.Errorf("ssh requires an sftp:// path")
+	}
+	if p.S3 != nil {
+		if remoteScheme(p.Path) != "s3" {
+			return fmt.Errorf("s3 requires an s3:// path")
+		}
+		if err := p.S3.Validate(); err != nil {
+			return fmt.Errorf("invalid s3: %w", err)
+		}
+	}
+	if p.B2 != nil {
+		if remoteScheme(p.Path) != "b2" {
+			return fmt.Errorf("b2 requires a b2:// path")
+		}
+		if err := p.B2.Validate(); err != nil {
+			return fmt.Errorf("invalid b2: %w", err)
+		}
+	}
+	if p.Rclone != nil {
+		if remoteScheme(p.Path) != "rclone" {
+			return fmt.Errorf("rclone requires an rclone:// path")
+		}
+		if err := p.Rclone.Validate(); err != nil {
+			return fmt.Errorf("invalid rclone: %w", err)
+		}
+	}
+	if p.FTP != nil {
+		if scheme := remoteScheme(p.Path); scheme != "ftp" && scheme != "ftps" {
+			return fmt.Errorf("ftp requires an ftp:// or ftps:// path")
+		}
+		if err := p.FTP.Validate(); err != nil {
+			return fmt.Errorf("invalid ftp: %w", err)
+		}
+		if remoteScheme(p.Path) == "ftps" && p.FTP.TLSMode == TLSModeNone {
+			return fmt.Errorf("ftps:// paths cannot use tls_mode %q", TLSModeNone)
+		}
+	}
+	if p.OfflineDrive != nil {
+		if driveLetter(p.Path) == "" {
+			return fmt.Errorf("offline_drive requires a path on a drive letter")
+		}
+		if err := p.OfflineDrive.Validate(); err != nil {
+			return fmt.Errorf("invalid offline_drive: %w", err)
+		}
+	}
+	if p.Credentials != nil {
+		if shareRoot(p.Path) == "" {
+			return fmt.Errorf("credentials require a \\\\server\\share path")
+		}
+		if err := p.Credentials.Validate(); err != nil {
+			return fmt.Errorf("invalid credentials: %w", err)
+		}
+	}
+	return nil
+}
+
+// ScanOptions returns opts with the overrides of this path applied
+func (p BackupPath) ScanOptions(opts ScanOptions) ScanOptions {
+	if p.MinBackupAge != "" {
+		opts.MinBackupAge, _ = ParseDuration(p.MinBackupAge)
+	}
+	if p.MaxBackupAge != "" {
+		opts.MaxBackupAge, _ = ParseDuration(p.MaxBackupAge)
+	}
+	if p.DeepValidation != nil {
+		opts.SkipContent = !*p.DeepValidation
+	}
+	if len(p.ExpectedMachines) > 0 {
+		opts.ExpectedMachines = p.ExpectedMachines
+	}
+	if p.SampleRate > 0 {
+		opts.SampleRate = p.SampleRate
+	}
+	if p.PathTimeout != "" {
+		opts.PathTimeout, _ = ParseDuration(p.PathTimeout)
+	}
+	if p.SetTimeout != "" {
+		opts.SetTimeout, _ = ParseDuration(p.SetTimeout)
+	}
+	if storage, _ := ParseStorageType(p.Storage); storage != StorageUnknown {
+		opts.Storage = storage
+	}
+	if p.Parallel > 0 {
+		opts.MaxWorkers = p.Parallel
+	}
+	if p.FileWorkers > 0 {
+		opts.FileWorkers = p.FileWorkers
+	}
+	return opts
+}
+
+// String returns the path, so entries print like the plain strings they
+// replace
+func (p BackupPath) String() string {
+	return p.Path
+}
+
+// SelectBackupPaths returns entries for paths in place of the configured
+// ones. A path that is also configured keeps that entry's overrides.
+func SelectBackupPaths(configured []BackupPath, paths []string) []BackupPath {
+	selected := make([]BackupPath, 0, len(paths))
+	for _, path := range paths {
+		path = NormalizePath(path)
+		entry := BackupPath{Path: path}
+		for _, c := range configured {
+			if strings.EqualFold(NormalizePath(c.Path), NormalizePath(path)) {
+				entry = c
+				entry.Path = path
+				break
+			}
+		}
+		selected = append(selected, entry)
+	}
+	return selected
+}
+
+// sampleFiles returns a random fraction of files, at least one, in their
+// original order
+func sampleFiles(files []string, rate float64) []string {
+	if rate <= 0 || rate >= 1 || len(files) == 0 {
+		return files
+	}
+	n := int(math.Ceil(float64(len(files)) * rate))
+	picked := make(map[int]bool, n)
+	for _, i := range rand.Perm(len(files))[:n] {
+		picked[i] = true
+	}
+	sample := make([]string, 0, n)
+	for i, file := range files {
+		if picked[i] {
+			sample = append(sample, file)
+		}
+	}
+	return sample
+}
+
+// checkExpectedMachines adds an error for each expected machine without a
+// backup set under root
+func checkExpectedMachines(root string, report *ScanReport, expected []string) {
+	for _, machine := range expected {
+		if report.machines[strings.ToLower(machine)] {
+			continue
+		}
+		report.Reports = append(report.Reports, BackupReport{
+			BackupDir: root,
+			Valid:     false,
+			Issues: []ValidationIssue{newIssue(IssueExpectedMachineMissing, SeverityError,
+				msg("no backup sets found for expected machine %s", machine),
+				root,
+				"check that Windows Backup is still running on that machine and writing to this location")},
+			CheckedAt: NowRFC3339(),
+		})
+	}
+}
+
+// AgeLimits returns the configured age thresholds, falling back to one hour
+// and 90 days
+func (o ScanOptions) AgeLimits() (time.Duration, time.Duration) {
+	minAge, maxAge := o.MinBackupAge, o.MaxBackupAge
+	if minAge == 0 {
+		minAge = time.Hour
+	}
+	if maxAge == 0 {
+		maxAge = 90 * 24 * time.Hour
+	}
+	return minAge, maxAge
+}
@@ -0,0 +1,95 @@
+package winbackupchecker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Baseline lists known issues that were accepted. Matching issues are kept in
+// the report, marked as suppressed, but no longer make a set invalid, affect
+// the exit code or trigger alerts.
+type Baseline struct {
+	Accepted []AcceptedIssue `json:"accepted"`
+}
+
+// AcceptedIssue accepts the issues with Code found on the backup sets at or
+// under Path
+type AcceptedIssue struct {
+	Path   string `json:"path"`
+	Code   string `json:"code"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// LoadBaseline reads and validates a baseline file
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline file: %w", err)
+	}
+
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline file: %w", err)
+	}
+	if err := baseline.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid baseline file: %w", err)
+	}
+	return &baseline, nil
+}
+
+// Validate checks that every entry names a path and a known issue code
+func (b *Baseline) Validate() error {
+	for i, entry := range b.Accepted {
+		if entry.Path == "" {
+			return fmt.Errorf("accepted entry %d: path is required", i+1)
+		}
+		if !issueCodes[entry.Code] {
+			return fmt.Errorf("accepted entry %d: unknown issue code %q", i+1, entry.Code)
+		}
+	}
+	return nil
+}
+
+// match returns the entry accepting issue on the set in backupDir, if any
+func (b *Baseline) match(backupDir string, issue ValidationIssue) *AcceptedIssue {
+	for i, entry := range b.Accepted {
+		if entry.Code != issue.Code {
+			continue
+		}
+		if isSubPath(entry.Path, backupDir) || (issue.Path != "" && isSubPath(entry.Path, issue.Path)) {
+			return &b.Accepted[i]
+		}
+	}
+	return nil
+}
+
+// ApplyBaseline marks the issues accepted by baseline as suppressed. Returns
+// the number of suppressed issues.
+func ApplyBaseline(reports []ScanReport, baseline *Baseline) int {
+	if baseline == nil {
+		return 0
+	}
+
+	suppressed := 0
+	for i := range reports {
+		for j := range reports[i].Reports {
+			br := &reports[i].Reports[j]
+			for k := range br.Issues {
+				issue := &br.Issues[k]
+				if issue.Suppressed {
+					suppressed++
+					continue
+				}
+				entry := baseline.match(br.BackupDir, *issue)
+				if entry == nil {
+					continue
+				}
+				issue.Suppressed = true
+				issue.SuppressedReason = entry.Reason
+				suppressed++
+			}
+		}
+	}
+	return suppressed
+}
@@ -0,0 +1,66 @@
+package winbackupchecker
+
+import "sync/atomic"
+
+// CacheStats describes how effective one cache was during a run
+type CacheStats struct {
+	Hits         int64   `json:"hits"`
+	Misses       int64   `json:"misses"`
+	BytesSkipped int64   `json:"bytes_skipped"`
+	HitRate      float64 `json:"hit_rate_percent"`
+}
+
+// AllHits reports whether every lookup was served from the cache. Seen run
+// after run, it can mean changed files are no longer being picked up.
+func (s CacheStats) AllHits() bool {
+	return s.Hits > 0 && s.Misses == 0
+}
+
+// cacheCounters accumulates cache statistics safely across workers
+type cacheCounters struct {
+	hits         atomic.Int64
+	misses       atomic.Int64
+	bytesSkipped atomic.Int64
+}
+
+func (c *cacheCounters) hit(bytes int64) {
+	c.hits.Add(1)
+	c.bytesSkipped.Add(bytes)
+}
+
+func (c *cacheCounters) miss() {
+	c.misses.Add(1)
+}
+
+// snapshot returns the current statistics and resets the counters so the
+// next run starts from zero
+func (c *cacheCounters) snapshot() CacheStats {
+	stats := CacheStats{
+		Hits:         c.hits.Swap(0),
+		Misses:       c.misses.Swap(0),
+		BytesSkipped: c.bytesSkipped.Swap(0),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total) * 100
+	}
+	return stats
+}
+
+// CacheStats returns the statistics of the caches opts uses, by name, or nil
+// when it uses none
+func (o ScanOptions) CacheStats() map[string]CacheStats {
+	stats := make(map[string]CacheStats)
+	if o.CatalogCache != nil {
+		stats["catalog"] = o.CatalogCache.Stats()
+	}
+	if o.Index != nil {
+		stats["index"] = o.Index.Stats()
+	}
+	if o.ScanCache != nil {
+		stats["scan"] = o.ScanCache.Stats()
+	}
+	if len(stats) == 0 {
+		return nil
+	}
+	return stats
+}
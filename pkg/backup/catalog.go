@@ -0,0 +1,216 @@
+package winbackupchecker
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// Catalog formats recognized by ParseCatalog. They tell catalogs apart by
+// how paths are encoded, not by the Windows version that wrote them.
+const (
+	// CatalogFormatUTF16 catalogs store paths as UTF-16LE strings
+	CatalogFormatUTF16 = "utf16le"
+
+	// CatalogFormatANSI catalogs store paths in the system ANSI code page
+	CatalogFormatANSI = "ansi"
+
+	// CatalogFormatUnknown catalogs are readable but contain no paths in
+	// either encoding
+	CatalogFormatUnknown = "unknown"
+)
+
+// Catalog holds the file list recovered from a backup catalog
+type Catalog struct {
+	Path    string   `json:"path"`
+	Size    int64    `json:"size"`
+	Format  string   `json:"format"`
+	Header  string   `json:"header,omitempty"`
+	Entries []string `json:"entries"`
+}
+
+// catalogHeaderLen is the number of leading bytes kept to identify catalogs
+// of an unknown format
+const catalogHeaderLen = 16
+
+// minCatalogEntryLen is the shortest string treated as a catalog path entry
+const minCatalogEntryLen = 4
+
+// ParseCatalog reads a .wbcat catalog and extracts the file paths it references.
+// Windows Backup stores paths as UTF-16LE strings, so the parser scans the file
+// for printable UTF-16 runs that look like paths. Catalogs without any are
+// rescanned for ANSI code page paths; if neither is found the format is
+// reported as unknown rather than treated as corruption. The Windows version
+// that wrote a catalog is not identified.
+func ParseCatalog(path string) (*Catalog, error) {
+	return parseCatalog(context.Background(), path)
+}
+
+// parseCatalog parses a catalog like ParseCatalog, stopping once ctx ends
+func parseCatalog(ctx context.Context, path string) (*Catalog, error) {
+	file, err := openBackupFile(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open catalog file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("cannot stat catalog file: %w", err)
+	}
+
+	catalog := &Catalog{
+		Path:   path,
+		Size:   info.Size(),
+		Format: CatalogFormatUTF16,
+	}
+
+	catalog.Entries, err = extractCatalogEntries(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read catalog file: %w", err)
+	}
+	if len(catalog.Entries) > 0 {
+		return catalog, nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("cannot read catalog file: %w", err)
+	}
+	header := make([]byte, catalogHeaderLen)
+	n, _ := io.ReadFull(file, header)
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("cannot read catalog file: %w", err)
+	}
+	catalog.Entries, err = extractANSICatalogEntries(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read catalog file: %w", err)
+	}
+
+	switch {
+	case len(catalog.Entries) > 0:
+		catalog.Format = CatalogFormatANSI
+	case info.Size() > 0:
+		catalog.Format = CatalogFormatUnknown
+		catalog.Header = hex.EncodeToString(header[:n])
+	}
+	return catalog, nil
+}
+
+// extractCatalogEntries scans a stream for UTF-16LE path strings. Strings may
+// start on either byte alignment, so both alignments are decoded in parallel.
+func extractCatalogEntries(r io.ByteReader) ([]string, error) {
+	var runs [2][]uint16
+	seen := make(map[string]bool)
+	entries := []string{}
+
+	flush := func(lane int) {
+		if len(runs[lane]) >= minCatalogEntryLen {
+			entry := trimCatalogEntry(string(utf16.Decode(runs[lane])))
+			if strings.ContainsRune(entry, '\\') && !seen[entry] {
+				seen[entry] = true
+				entries = append(entries, entry)
+			}
+		}
+		runs[lane] = runs[lane][:0]
+	}
+
+	prev := -1
+	for offset := 0; ; offset++ {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// The code unit starting at the previous byte is now complete
+		if prev >= 0 {
+			lane := (offset - 1) % 2
+			unit := uint16(b)<<8 | uint16(prev)
+			if isCatalogPathUnit(unit) {
+				runs[lane] = append(runs[lane], unit)
+			} else {
+				flush(lane)
+			}
+		}
+		prev = int(b)
+	}
+
+	flush(0)
+	flush(1)
+
+	return entries, nil
+}
+
+// extractANSICatalogEntries scans a stream for single-byte path strings.
+// Bytes above 0x7f are decoded as Latin-1, which matches Windows-1252 for
+// letters used in Western European locales.
+func extractANSICatalogEntries(r io.ByteReader) ([]string, error) {
+	var run []rune
+	seen := make(map[string]bool)
+	entries := []string{}
+
+	flush := func() {
+		if len(run) >= minCatalogEntryLen {
+			entry := trimCatalogEntry(string(run))
+			if strings.ContainsRune(entry, '\\') && !seen[entry] {
+				seen[entry] = true
+				entries = append(entries, entry)
+			}
+		}
+		run = run[:0]
+	}
+
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if b >= 0xa0 || (b < 0x80 && isCatalogPathUnit(uint16(b))) {
+			run = append(run, rune(b))
+		} else {
+			flush()
+		}
+	}
+	flush()
+
+	return entries, nil
+}
+
+// trimCatalogEntry drops stray leading characters decoded from the bytes that
+// precede an absolute path, so "xC:\Users" becomes "C:\Users"
+func trimCatalogEntry(entry string) string {
+	idx := strings.Index(entry, `:\`)
+	if idx <= 0 {
+		return entry
+	}
+	runes := []rune(entry[:idx])
+	drive := runes[len(runes)-1]
+	if (drive >= 'A' && drive <= 'Z') || (drive >= 'a' && drive <= 'z') {
+		return string(drive) + entry[idx:]
+	}
+	return entry
+}
+
+func isCatalogPathUnit(unit uint16) bool {
+	switch {
+	case unit < 0x20 || unit == 0x7f:
+		return false
+	case unit >= 0xd800 && unit <= 0xdfff:
+		// Surrogates are valid in paths but rare; keep them so names decode
+		return true
+	case unit >= 0xfff0:
+		return false
+	}
+	return !strings.ContainsRune(`"<>|*?`, rune(unit))
+}
@@ -0,0 +1,462 @@
+package winbackupchecker
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CatalogCacheConfig controls the shared catalog parse cache
+type CatalogCacheConfig struct {
+	Enabled     bool   `json:"enabled"`
+	MaxMemoryMB int    `json:"max_memory_mb"`
+	Dir         string `json:"dir"`
+	MaxDiskMB   int    `json:"max_disk_mb"`
+}
+
+// Validate checks if catalog cache configuration is valid
+func (c *CatalogCacheConfig) Validate() error {
+	if c.MaxMemoryMB < 0 {
+		return fmt.Errorf("max_memory_mb cannot be negative")
+	}
+	if c.MaxDiskMB < 0 {
+		return fmt.Errorf("max_disk_mb cannot be negative")
+	}
+	return nil
+}
+
+// CatalogCache caches parsed catalogs in memory and optionally on disk, keyed
+// by the SHA-256 of the catalog contents. Both tiers evict least recently used
+// entries once their size limit is exceeded. The scan and the find command
+// share it; the checker has no extract or compare command.
+type CatalogCache struct {
+	mu        sync.Mutex
+	maxMemory int64
+	memUsed   int64
+	order     *list.List
+	items     map[string]*list.Element
+	dir       string
+	maxDisk   int64
+	counters  cacheCounters
+
+	// keys remembers the content key of the catalogs in memory by path, with
+	// the size and modification time they were hashed at, so a catalog read
+	// again in the same run, as by the path checks and the search index after
+	// content validation, is not read to be hashed again
+	keys map[string]catalogKey
+}
+
+// catalogKey is the content key of a catalog file as last hashed
+type catalogKey struct {
+	key     string
+	size    int64
+	modTime time.Time
+}
+
+// catalogDiskKey is the on-disk form of a catalogKey, so a later run finds
+// the disk entry of an unchanged catalog without hashing it
+type catalogDiskKey struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Key     string    `json:"key"`
+}
+
+// catalogDiskEntry is the on-disk form of a cached catalog
+type catalogDiskEntry struct {
+	Fingerprint string   `json:"fingerprint"`
+	Catalog     *Catalog `json:"catalog"`
+}
+
+type catalogCacheItem struct {
+	key     string
+	catalog *Catalog
+	size    int64
+
+	// paths are the catalog files known to have this content
+	paths []string
+}
+
+// NewCatalogCache creates a catalog cache from configuration
+func NewCatalogCache(cfg CatalogCacheConfig) (*CatalogCache, error) {
+	maxMemoryMB := cfg.MaxMemoryMB
+	if maxMemoryMB == 0 {
+		maxMemoryMB = 64
+	}
+
+	cache := &CatalogCache{
+		maxMemory: int64(maxMemoryMB) * 1024 * 1024,
+		order:     list.New(),
+		items:     make(map[string]*list.Element),
+		keys:      make(map[string]catalogKey),
+		dir:       cfg.Dir,
+		maxDisk:   int64(cfg.MaxDiskMB) * 1024 * 1024,
+	}
+
+	if cache.dir != "" {
+		if err := os.MkdirAll(cache.dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create catalog cache dir: %w", err)
+		}
+	}
+
+	return cache, nil
+}
+
+// Get returns the parsed catalog at path, parsing it only on a cache miss.
+// A nil cache parses the catalog directly. Reading stops once ctx ends.
+func (c *CatalogCache) Get(ctx context.Context, path string) (*Catalog, error) {
+	if c == nil {
+		return parseCatalog(ctx, path)
+	}
+
+	info, err := statBackup(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot hash catalog file: %w", err)
+	}
+	key, ok := c.knownKey(path, info)
+	if !ok {
+		key, ok = c.diskKey(path, info)
+	}
+	if !ok {
+		key, err = hashFile(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot hash catalog file: %w", err)
+		}
+		defer c.putDiskKey(path, info, key)
+	}
+	// Once in memory, the catalog's key is known by its path
+	defer c.rememberKey(path, info, key)
+
+	if catalog := c.getMemory(key); catalog != nil {
+		c.counters.hit(catalog.Size)
+		return withCatalogPath(catalog, path), nil
+	}
+
+	if catalog := c.getDisk(key); catalog != nil {
+		c.counters.hit(catalog.Size)
+		c.putMemory(key, catalog)
+		return withCatalogPath(catalog, path), nil
+	}
+
+	c.counters.miss()
+	catalog, err := parseCatalog(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.putMemory(key, catalog)
+	c.putDisk(key, catalog)
+
+	return catalog, nil
+}
+
+// Stats returns lookups since the previous call; a hit skipped parsing
+func (c *CatalogCache) Stats() CacheStats {
+	return c.counters.snapshot()
+}
+
+// withCatalogPath returns a copy of catalog reporting path as its location,
+// since identical catalogs in different sets share one cache entry
+func withCatalogPath(catalog *Catalog, path string) *Catalog {
+	if catalog.Path == path {
+		return catalog
+	}
+	cp := *catalog
+	cp.Path = path
+	return &cp
+}
+
+// knownKey returns the key path was hashed to, if its catalog is in memory
+// and the file kept its size and modification time since
+func (c *CatalogCache) knownKey(path string, info os.FileInfo) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	known, ok := c.keys[path]
+	if !ok || known.size != info.Size() || !known.modTime.Equal(info.ModTime()) {
+		return "", false
+	}
+	return known.key, true
+}
+
+// rememberKey records the key of the catalog at path while the catalog is
+// in memory
+func (c *CatalogCache) rememberKey(path string, info os.FileInfo, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return
+	}
+	if known, ok := c.keys[path]; !ok || known.key != key {
+		c.forgetKey(path)
+		item := elem.Value.(*catalogCacheItem)
+		item.paths = append(item.paths, path)
+	}
+	c.keys[path] = catalogKey{key: key, size: info.Size(), modTime: info.ModTime()}
+}
+
+// forgetKey drops the key remembered for path. The caller holds c.mu.
+func (c *CatalogCache) forgetKey(path string) {
+	known, ok := c.keys[path]
+	if !ok {
+		return
+	}
+	delete(c.keys, path)
+	if elem, ok := c.items[known.key]; ok {
+		item := elem.Value.(*catalogCacheItem)
+		for i, p := range item.paths {
+			if p == path {
+				item.paths = append(item.paths[:i], item.paths[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (c *CatalogCache) getMemory(key string) *Catalog {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*catalogCacheItem).catalog
+}
+
+func (c *CatalogCache) putMemory(key string, catalog *Catalog) {
+	size := estimateCatalogSize(catalog)
+	if size > c.maxMemory {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&catalogCacheItem{key: key, catalog: catalog, size: size})
+	c.memUsed += size
+
+	for c.memUsed > c.maxMemory {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		item := oldest.Value.(*catalogCacheItem)
+		c.order.Remove(oldest)
+		delete(c.items, item.key)
+		c.memUsed -= item.size
+		for _, path := range item.paths {
+			delete(c.keys, path)
+		}
+	}
+}
+
+func (c *CatalogCache) diskPath(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// diskKeyPath is where the key of the catalog at path is kept. Keys live in
+// a folder of their own, which disk eviction leaves alone, as they are tiny.
+func (c *CatalogCache) diskKeyPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(c.dir, "keys", hex.EncodeToString(sum[:])+".json")
+}
+
+// diskKey returns the key path was hashed to in an earlier run, if the disk
+// entry of that key is still there and the file kept its size and
+// modification time since
+func (c *CatalogCache) diskKey(path string, info os.FileInfo) (string, bool) {
+	if c.dir == "" {
+		return "", false
+	}
+	keyPath := c.diskKeyPath(path)
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return "", false
+	}
+	var known catalogDiskKey
+	if err := json.Unmarshal(data, &known); err != nil || known.Path != path {
+		os.Remove(keyPath)
+		return "", false
+	}
+	if known.Size != info.Size() || !known.ModTime.Equal(info.ModTime()) {
+		return "", false
+	}
+	if _, err := os.Stat(c.diskPath(known.Key)); err != nil {
+		// The entry was evicted, so the key saves no parsing
+		os.Remove(keyPath)
+		return "", false
+	}
+	return known.Key, true
+}
+
+// putDiskKey records the key path was hashed to for later runs
+func (c *CatalogCache) putDiskKey(path string, info os.FileInfo, key string) {
+	if c.dir == "" {
+		return
+	}
+	data, err := json.Marshal(catalogDiskKey{Path: path, Size: info.Size(), ModTime: info.ModTime(), Key: key})
+	if err != nil {
+		return
+	}
+	keyPath := c.diskKeyPath(path)
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(keyPath), filepath.Base(keyPath)+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil || os.Rename(tmp.Name(), keyPath) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func (c *CatalogCache) getDisk(key string) *Catalog {
+	if c.dir == "" {
+		return nil
+	}
+
+	path := c.diskPath(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	// Entries from another checker version or check definition are stale
+	var entry catalogDiskEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Catalog == nil || entry.Fingerprint != CacheFingerprint() {
+		os.Remove(path)
+		return nil
+	}
+
+	// Touch the entry so disk eviction sees it as recently used
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	return entry.Catalog
+}
+
+func (c *CatalogCache) putDisk(key string, catalog *Catalog) {
+	if c.dir == "" {
+		return
+	}
+
+	data, err := json.Marshal(catalogDiskEntry{Fingerprint: CacheFingerprint(), Catalog: catalog})
+	if err != nil {
+		return
+	}
+
+	// Write through a temp file so concurrent readers never see partial entries
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.diskPath(key)); err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	c.evictDisk()
+}
+
+// evictDisk removes least recently used disk entries until under the limit
+func (c *CatalogCache) evictDisk() {
+	if c.maxDisk <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+
+	type diskEntry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []diskEntry
+	var total int64
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, diskEntry{
+			path:    filepath.Join(c.dir, entry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	for _, f := range files {
+		if total <= c.maxDisk {
+			break
+		}
+		if err := os.Remove(f.path); err == nil {
+			total -= f.size
+		}
+	}
+}
+
+// estimateCatalogSize approximates the memory held by a parsed catalog
+func estimateCatalogSize(catalog *Catalog) int64 {
+	size := int64(len(catalog.Path)) + 64
+	for _, entry := range catalog.Entries {
+		size += int64(len(entry)) + 16
+	}
+	return size
+}
+
+// hashFile returns the hex SHA-256 digest of a file's contents
+func hashFile(ctx context.Context, path string) (string, error) {
+	file, err := openBackupFile(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,109 @@
+package winbackupchecker
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// The catalogs under testdata/catalogs are synthetic: they store paths as
+// length-prefixed UTF-16LE or code page records between binary fields, in
+// the locales ParseCatalog has to decode. They are not captured from
+// Windows Backup, so they pin the parser's behaviour rather than the real
+// layout of each Windows version.
+func TestParseCatalog(t *testing.T) {
+	tests := []struct {
+		file    string
+		format  string
+		header  string
+		entries []string
+	}{
+		{
+			file:   "utf16_en.wbcat",
+			format: CatalogFormatUTF16,
+			entries: []string{
+				`C:\Users\alice\Documents\report.docx`,
+				`C:\Users\alice\Pictures\cat.jpg`,
+			},
+		},
+		{
+			file:   "utf16_de_odd.wbcat",
+			format: CatalogFormatUTF16,
+			entries: []string{
+				`C:\Benutzer\Jürgen\Dokumente\Übersicht.xlsx`,
+				`D:\Fotos\Größe.png`,
+			},
+		},
+		{
+			file:   "utf16_fr.wbcat",
+			format: CatalogFormatUTF16,
+			entries: []string{
+				`C:\Utilisateurs\Hélène\Bureau\reçu.pdf`,
+				`C:\Utilisateurs\Hélène\Images\été 2024.jpg`,
+			},
+		},
+		{
+			file:    "utf16_ja.wbcat",
+			format:  CatalogFormatUTF16,
+			entries: []string{`C:\Users\太郎\ドキュメント\報告書.txt`},
+		},
+		{
+			file:   "ansi_de.wbcat",
+			format: CatalogFormatANSI,
+			entries: []string{
+				`C:\Dokumente und Einstellungen\Jürgen\Eigene Dateien\Übersicht.xls`,
+				`C:\Programme\Büro\daten.mdb`,
+			},
+		},
+		{
+			file:    "unknown.wbcat",
+			format:  CatalogFormatUnknown,
+			header:  "ffeeddccbbaa99887766554433221100",
+			entries: []string{},
+		},
+		{
+			file:    "empty.wbcat",
+			format:  CatalogFormatUTF16,
+			entries: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			catalog, err := ParseCatalog(filepath.Join("testdata", "catalogs", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if catalog.Format != tt.format {
+				t.Errorf("format = %q, want %q", catalog.Format, tt.format)
+			}
+			if catalog.Header != tt.header {
+				t.Errorf("header = %q, want %q", catalog.Header, tt.header)
+			}
+			if !slices.Equal(catalog.Entries, tt.entries) {
+				t.Errorf("entries = %q, want %q", catalog.Entries, tt.entries)
+			}
+		})
+	}
+}
+
+func TestParseCatalogMissing(t *testing.T) {
+	if _, err := ParseCatalog(filepath.Join("testdata", "catalogs", "missing.wbcat")); err == nil {
+		t.Error("expected an error for a missing catalog")
+	}
+}
+
+func TestTrimCatalogEntry(t *testing.T) {
+	tests := map[string]string{
+		`xC:\Users`:   `C:\Users`,
+		`$D:\Data\a`:  `D:\Data\a`,
+		`C:\Users`:    `C:\Users`,
+		`\\nas\share`: `\\nas\share`,
+		`1:\x`:        `1:\x`,
+	}
+	for in, want := range tests {
+		if got := trimCatalogEntry(in); got != want {
+			t.Errorf("trimCatalogEntry(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package winbackupchecker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validators run on each backup set, selectable with --checks and the checks
+// config list
+const (
+	CheckStructure    = "structure"
+	CheckCompleteness = "completeness"
+	CheckContent      = "content"
+	CheckAge          = "age"
+)
+
+// AllChecks lists the built-in validators in the order they run
+var AllChecks = []string{CheckStructure, CheckCompleteness, CheckContent, CheckAge}
+
+// Checks lists the validators that can be selected, the built-in ones
+// followed by those added with RegisterValidator
+func Checks() []string {
+	checks := append([]string(nil), AllChecks...)
+	for _, v := range RegisteredValidators() {
+		checks = append(checks, v.Name())
+	}
+	return checks
+}
+
+// ParseChecks parses a comma-separated list of validators
+func ParseChecks(list string) ([]string, error) {
+	var checks []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			checks = append(checks, name)
+		}
+	}
+	if err := validateChecks(checks); err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// validateChecks reports an unknown validator name
+func validateChecks(checks []string) error {
+	for _, name := range checks {
+		if !isCheck(name) {
+			return fmt.Errorf("unknown check %q (valid: %s)", name, strings.Join(Checks(), ", "))
+		}
+	}
+	return nil
+}
+
+func isCheck(name string) bool {
+	for _, check := range Checks() {
+		if strings.EqualFold(name, check) {
+			return true
+		}
+	}
+	return false
+}
+
+// runs reports whether the validator check is selected. No selection runs
+// every validator.
+func (o ScanOptions) runs(check string) bool {
+	if len(o.Checks) == 0 {
+		return true
+	}
+	for _, name := range o.Checks {
+		if strings.EqualFold(name, check) {
+			return true
+		}
+	}
+	return false
+}
+
+// skippedChecks returns the validators not selected
+func (o ScanOptions) skippedChecks() []string {
+	var skipped []string
+	for _, check := range Checks() {
+		if !o.runs(check) {
+			skipped = append(skipped, check)
+		}
+	}
+	return skipped
+}
@@ -0,0 +1,796 @@
+package winbackupchecker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type EmailConfig struct {
+	Enabled            bool               `json:"enabled"`
+	SMTPHost           string             `json:"smtp_host"`
+	SMTPPort           int                `json:"smtp_port"`
+	From               string             `json:"from"`
+	To                 []string           `json:"to"`
+	Username           string             `json:"username"`
+	Password           string             `json:"password" secret:"true"`
+	PasswordCredential string             `json:"password_credential,omitempty"`
+	SubjectPrefix      string             `json:"subject_prefix"`
+	TLSMode            string             `json:"tls_mode,omitempty"`
+	TLSSkipVerify      bool               `json:"tls_skip_verify,omitempty"`
+	TLSServerName      string             `json:"tls_server_name,omitempty"`
+	TLSCAFile          string             `json:"tls_ca_file,omitempty"`
+	AttachJSON         bool               `json:"attach_json,omitempty"`
+	AttachCSV          bool               `json:"attach_csv,omitempty"`
+	TemplateHTML       string             `json:"email_template_html,omitempty"`
+	TemplateText       string             `json:"email_template_text,omitempty"`
+	Digest             *EmailDigestConfig `json:"digest,omitempty"`
+	Dedup              *AlertDedupConfig  `json:"dedup,omitempty"`
+	Retries            *int               `json:"retries,omitempty"`
+	RetryBackoff       string             `json:"retry_backoff,omitempty"`
+	NotifyTriggers
+}
+
+type Config struct {
+	BackupPaths               []BackupPath           `json:"backup_paths"`
+	CheckHash                 bool                   `json:"check_hash"`
+	DeepValidation            bool                   `json:"deep_validation"`
+	MaxZipSampleSize          int64                  `json:"max_zip_sample_size"`
+	RequiredCatalogExtensions []string               `json:"required_catalog_extensions"`
+	MinBackupAge              string                 `json:"min_backup_age"`
+	MaxBackupAge              string                 `json:"max_backup_age"`
+	Email                     *EmailConfig           `json:"email,omitempty" env:"-"` // WBC_EMAIL_* apply to email.config.json
+	CatalogCache              *CatalogCacheConfig    `json:"catalog_cache,omitempty"`
+	IndexFile                 string                 `json:"index_file,omitempty"`
+	ScanCacheFile             string                 `json:"scan_cache_file,omitempty"`
+	InfluxDB                  *InfluxDBConfig        `json:"influxdb,omitempty"`
+	ReportSections            *ReportSectionsConfig  `json:"report_sections,omitempty"`
+	RequiredPaths             []string               `json:"required_paths,omitempty"`
+	ForbiddenContent          []ForbiddenContentRule `json:"forbidden_content,omitempty"`
+	Notifications             *NotificationsConfig   `json:"notifications,omitempty"`
+	Manifests                 *ManifestConfig        `json:"manifests,omitempty"`
+	Parity                    *ParityConfig          `json:"parity,omitempty"`
+	ArchiveTier               *ArchiveTierConfig     `json:"archive_tier,omitempty"`
+	USNJournal                *USNJournalConfig      `json:"usn_journal,omitempty"`
+	Snapshots                 *SnapshotConfig        `json:"snapshots,omitempty"`
+	ActiveJobs                *ActiveJobConfig       `json:"active_jobs,omitempty"`
+	Flapping                  *FlappingConfig        `json:"flapping,omitempty"`
+	Escalation                *EscalationConfig      `json:"escalation,omitempty"`
+	IssueGrouping             *IssueGroupingConfig   `json:"issue_grouping,omitempty"`
+	SampleRate                float64                `json:"sample_rate,omitempty"`
+	PathTimeout               string                 `json:"path_timeout,omitempty"`
+	SetTimeout                string                 `json:"set_timeout,omitempty"`
+	FileWorkers               int                    `json:"file_workers,omitempty"`
+	MaxConcurrentReads        int                    `json:"max_concurrent_reads,omitempty"`
+	Throttle                  *ThrottleConfig        `json:"throttle,omitempty"`
+	ContentMode               string                 `json:"content_mode,omitempty"`
+	IORetry                   *IORetryConfig         `json:"io_retry,omitempty"`
+	Links                     *LinkConfig            `json:"links,omitempty"`
+	MaxSetFiles               int                    `json:"max_set_files,omitempty"`
+	MemoryLimit               string                 `json:"memory_limit,omitempty"`
+	FailFast                  bool                   `json:"fail_fast,omitempty"`
+	NewestSets                int                    `json:"newest_sets,omitempty"`
+	Checks                    []string               `json:"checks,omitempty"`
+	SeverityOverrides         SeverityOverrides      `json:"severity_overrides,omitempty"`
+	BackupFilePatterns        []string               `json:"backup_file_patterns,omitempty"`
+	BaselineFile              string                 `json:"baseline_file,omitempty"`
+	QuarantineFile            string                 `json:"quarantine_file,omitempty"`
+	Language                  string                 `json:"language,omitempty"`
+	Profiles                  ConfigProfiles         `json:"profiles,omitempty" env:"-"`
+	Schedules                 []Schedule             `json:"schedules,omitempty"`
+
+	// Profile is the name of the profile applied by LoadConfigProfile
+	Profile string `json:"-"`
+}
+
+// ConfigProfiles maps profile names to config keys overriding the file's
+type ConfigProfiles map[string]json.RawMessage
+
+// ValidationSeverity represents severity level of validation issues
+type ValidationSeverity int
+
+const (
+	SeverityInfo ValidationSeverity = iota
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+func (s ValidationSeverity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+func (s ValidationSeverity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s *ValidationSeverity) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	severity, err := ParseSeverity(name)
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+// ParseSeverity converts a severity name back to its ValidationSeverity
+func ParseSeverity(name string) (ValidationSeverity, error) {
+	for _, s := range []ValidationSeverity{SeverityInfo, SeverityWarning, SeverityError, SeverityCritical} {
+		if strings.EqualFold(name, s.String()) {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q", name)
+}
+
+// ValidationIssue represents a specific validation problem
+type ValidationIssue struct {
+	Severity   ValidationSeverity `json:"severity"`
+	Code       string             `json:"code,omitempty"`
+	Message    string             `json:"message"`
+	Path       string             `json:"path,omitempty"`
+	Suggestion string             `json:"suggestion,omitempty"`
+	CheckedAt  string             `json:"checked_at"`
+
+	// EscalatedFrom is the original severity of an issue escalated after
+	// persisting for ConsecutiveRuns runs
+	EscalatedFrom   string `json:"escalated_from,omitempty"`
+	ConsecutiveRuns int    `json:"consecutive_runs,omitempty"`
+
+	// OverriddenFrom is the built-in severity of an issue whose code has a
+	// severity_overrides entry
+	OverriddenFrom string `json:"overridden_from,omitempty"`
+
+	// Suppressed issues are accepted in the baseline file. They are reported
+	// but do not count towards validity, the exit code or alerts.
+	Suppressed       bool   `json:"suppressed,omitempty"`
+	SuppressedReason string `json:"suppressed_reason,omitempty"`
+
+	// Count is the number of issues a grouped issue stands for, and
+	// AffectedPaths a sample of their paths (see issue_grouping)
+	Count         int      `json:"count,omitempty"`
+	AffectedPaths []string `json:"affected_paths,omitempty"`
+
+	// Attempts is how often a file was tried before its read failure was
+	// reported, set on issues of files left unreadable by I/O errors
+	Attempts int `json:"attempts,omitempty"`
+
+	// text is Message with its arguments, for localization
+	text localText
+}
+
+// BackupReport represents validation details for single backup folder
+type BackupReport struct {
+	BackupDir       string            `json:"backup_dir"`
+	Valid           bool              `json:"valid"`
+	Issues          []ValidationIssue `json:"issues"`
+	CheckedAt       string            `json:"checked_at"`
+	ValidationStats ValidationStats   `json:"validation_stats"`
+	Insights        *SetInsights      `json:"insights,omitempty"`
+	Flapping        *FlappingStats    `json:"flapping,omitempty"`
+
+	// SkippedChecks lists the validators not selected for this run
+	SkippedChecks []string `json:"skipped_checks,omitempty"`
+}
+
+// ValidationStats provides detailed metrics about validation process
+type ValidationStats struct {
+	TotalFiles       int            `json:"total_files"`
+	ValidatedFiles   int            `json:"validated_files"`
+	CorruptFiles     int            `json:"corrupt_files"`
+	TotalSize        int64          `json:"total_size_bytes"`
+	ValidationTime   string         `json:"validation_time"`
+	CatalogFiles     int            `json:"catalog_files"`
+	BackupFiles      int            `json:"backup_files"`
+	OldestBackupTime *time.Time     `json:"oldest_backup_time,omitempty"`
+	NewestBackupTime *time.Time     `json:"newest_backup_time,omitempty"`
+	CatalogEntries   int            `json:"catalog_entries,omitempty"`
+	ManifestVerified int            `json:"manifest_verified,omitempty"`
+	RetriedFiles     int            `json:"retried_files,omitempty"`
+	ContentDeferred  bool           `json:"content_deferred,omitempty"`
+	ContentBreakdown map[string]int `json:"content_breakdown,omitempty"`
+	StructuralChecks int            `json:"structural_checks_passed"`
+	ContentChecks    int            `json:"content_checks_passed"`
+	PhaseTimings     PhaseTimings   `json:"phase_timings_ms,omitempty"`
+
+	// IO describes the backup data read, when any was
+	IO *IOStats `json:"io,omitempty"`
+}
+
+// ScanReport represents results for one root path
+type ScanReport struct {
+	Root         string         `json:"root"`
+	Storage      StorageType    `json:"storage,omitempty"`
+	Reports      []BackupReport `json:"reports"`
+	PhaseTimings PhaseTimings   `json:"phase_timings_ms,omitempty"`
+
+	// machines holds the lowercased names of machines with backup sets
+	machines map[string]bool
+}
+
+func (r *ScanReport) addMachine(name string) {
+	if r.machines == nil {
+		r.machines = make(map[string]bool)
+	}
+	r.machines[strings.ToLower(name)] = true
+}
+
+// DefaultConfig returns the settings used for keys missing from the config
+// file
+func DefaultConfig() *Config {
+	return &Config{
+		CheckHash:                 false,
+		DeepValidation:            true,
+		MaxZipSampleSize:          100 * 1024 * 1024, // 100MB
+		RequiredCatalogExtensions: []string{".wbcat"},
+		MinBackupAge:              "1h",
+		MaxBackupAge:              "90d",
+	}
+}
+
+// LoadConfig loads JSON config file from given path with defaults
+func LoadConfig(path string) (*Config, error) {
+	return LoadConfigProfile(path, "")
+}
+
+// LoadConfigProfile loads the config file with the named profile applied. A
+// profile holds config keys that replace the file's top-level values, so one
+// installation can run e.g. quick sampled scans hourly and deep scans weekly.
+// An empty profile loads the file as is.
+func LoadConfigProfile(path, profile string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if profile != "" {
+		overlay, ok := cfg.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q", profile)
+		}
+		var keys map[string]json.RawMessage
+		if err := json.Unmarshal(overlay, &keys); err != nil {
+			return nil, fmt.Errorf("failed to parse profile %s: %w", profile, err)
+		}
+		if _, nested := keys["profiles"]; nested {
+			return nil, fmt.Errorf("profile %s cannot define profiles", profile)
+		}
+		if err := json.Unmarshal(overlay, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse profile %s: %w", profile, err)
+		}
+		cfg.Profile = profile
+	}
+
+	if err := applyEnvOverrides(cfg, EnvPrefix); err != nil {
+		return nil, fmt.Errorf("invalid environment override: %w", err)
+	}
+
+	if err := resolveSecrets(cfg); err != nil {
+		return nil, fmt.Errorf("invalid secret reference: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		if profile != "" {
+			return nil, fmt.Errorf("invalid config with profile %s: %w", profile, err)
+		}
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// ProfileNames returns the names of the profiles defined in the config
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ScanOptions returns the scan options set by the config. Callers add
+// workers, caches and progress output, and apply per-path overrides with
+// BackupPath.ScanOptions.
+func (c *Config) ScanOptions() ScanOptions {
+	// Validate has checked the durations, patterns and language
+	minAge, _ := ParseDuration(c.MinBackupAge)
+	maxAge, _ := ParseDuration(c.MaxBackupAge)
+	pathTimeout, _ := ParseDuration(c.PathTimeout)
+	setTimeout, _ := ParseDuration(c.SetTimeout)
+	patterns, _ := compileBackupFilePatterns(c.BackupFilePatterns)
+	localizer, _ := NewLocalizer(c.Language)
+
+	return ScanOptions{
+		Sections:           c.ReportSections,
+		RequiredPaths:      c.RequiredPaths,
+		ForbiddenContent:   c.ForbiddenContent,
+		Manifests:          c.Manifests,
+		Parity:             c.Parity,
+		ArchiveTier:        c.ArchiveTier,
+		USNJournal:         c.USNJournal,
+		Snapshots:          c.Snapshots,
+		ActiveJobs:         c.ActiveJobs,
+		SkipContent:        !c.DeepValidation,
+		MinBackupAge:       minAge,
+		MaxBackupAge:       maxAge,
+		SampleRate:         c.SampleRate,
+		PathTimeout:        pathTimeout,
+		SetTimeout:         setTimeout,
+		FileWorkers:        c.FileWorkers,
+		FastZip:            c.ContentMode == ContentModeFast,
+		IORetry:            c.IORetry,
+		Links:              c.Links,
+		MaxSetFiles:        c.MaxSetFiles,
+		MaxReads:           c.MaxConcurrentReads,
+		NewestSets:         c.NewestSets,
+		Checks:             c.Checks,
+		SeverityOverrides:  c.SeverityOverrides,
+		BackupFilePatterns: patterns,
+		Localizer:          localizer,
+	}
+}
+
+// LoadCaches adds the catalog cache, catalog index and scan cache the
+// config enables to opts. The caller saves the index and scan cache after
+// the run.
+func (c *Config) LoadCaches(opts *ScanOptions) error {
+	var err error
+	if c.CatalogCache != nil && c.CatalogCache.Enabled {
+		opts.CatalogCache, err = NewCatalogCache(*c.CatalogCache)
+		if err != nil {
+			return fmt.Errorf("failed to create catalog cache: %w", err)
+		}
+	}
+
+	if c.IndexFile != "" {
+		opts.Index, err = LoadCatalogIndex(c.IndexFile)
+		if err != nil {
+			return fmt.Errorf("failed to load catalog index: %w", err)
+		}
+	}
+
+	if c.ScanCacheFile != "" {
+		opts.ScanCache, err = LoadScanCache(c.ScanCacheFile)
+		if err != nil {
+			return fmt.Errorf("failed to load scan cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// LoadEmailConfig loads email configuration from a separate file. WBC_EMAIL_*
+// environment variables override its keys, and can configure email without
+// a file.
+func LoadEmailConfig(path string) (*EmailConfig, error) {
+	envPrefix := EnvPrefix + "EMAIL_"
+
+	var emailCfg EmailConfig
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to open email config file: %w", err)
+		}
+		if !hasEnvPrefix(envPrefix) {
+			return nil, nil
+		}
+	} else {
+		defer file.Close()
+		decoder := json.NewDecoder(file)
+		if err := decoder.Decode(&emailCfg); err != nil {
+			return nil, fmt.Errorf("failed to parse email config file: %w", err)
+		}
+	}
+
+	if err := applyEnvOverrides(&emailCfg, envPrefix); err != nil {
+		return nil, fmt.Errorf("invalid environment override: %w", err)
+	}
+
+	if err := resolveSecrets(&emailCfg); err != nil {
+		return nil, fmt.Errorf("invalid secret reference: %w", err)
+	}
+
+	if emailCfg.Enabled {
+		if emailCfg.Password == "" && emailCfg.PasswordCredential != "" {
+			emailCfg.Password, err = GetCredential(emailCfg.PasswordCredential)
+			if err != nil {
+				return nil, fmt.Errorf("invalid email config: %w", err)
+			}
+		}
+		if err := emailCfg.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid email config: %w", err)
+		}
+	}
+
+	return &emailCfg, nil
+}
+
+// Validate checks if configuration is valid
+func (c *Config) Validate() error {
+	if len(c.BackupPaths) == 0 {
+		return fmt.Errorf("no backup paths specified in config")
+	}
+
+	for i := range c.BackupPaths {
+		if err := c.BackupPaths[i].Validate(); err != nil {
+			return fmt.Errorf("invalid backup_paths entry %d: %w", i+1, err)
+		}
+	}
+
+	// Validate duration strings
+	if c.MinBackupAge != "" {
+		if _, err := ParseDuration(c.MinBackupAge); err != nil {
+			return fmt.Errorf("invalid min_backup_age duration: %w", err)
+		}
+	}
+
+	if c.MaxBackupAge != "" {
+		if _, err := ParseDuration(c.MaxBackupAge); err != nil {
+			return fmt.Errorf("invalid max_backup_age duration: %w", err)
+		}
+	}
+
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return fmt.Errorf("sample_rate must be between 0 and 1")
+	}
+
+	if c.PathTimeout != "" {
+		if _, err := ParseDuration(c.PathTimeout); err != nil {
+			return fmt.Errorf("invalid path_timeout duration: %w", err)
+		}
+	}
+
+	if c.SetTimeout != "" {
+		if _, err := ParseDuration(c.SetTimeout); err != nil {
+			return fmt.Errorf("invalid set_timeout duration: %w", err)
+		}
+	}
+
+	if c.FileWorkers < 0 {
+		return fmt.Errorf("file_workers cannot be negative")
+	}
+
+	if c.MaxConcurrentReads < 0 {
+		return fmt.Errorf("max_concurrent_reads cannot be negative")
+	}
+
+	if c.NewestSets < 0 {
+		return fmt.Errorf("newest_sets cannot be negative")
+	}
+
+	if c.Throttle != nil {
+		if err := c.Throttle.Validate(); err != nil {
+			return fmt.Errorf("invalid throttle: %w", err)
+		}
+	}
+
+	if c.MaxSetFiles < 0 {
+		return fmt.Errorf("max_set_files cannot be negative")
+	}
+
+	if err := ValidateContentMode(c.ContentMode); err != nil {
+		return fmt.Errorf("invalid content_mode: %w", err)
+	}
+
+	if c.IORetry != nil {
+		if err := c.IORetry.Validate(); err != nil {
+			return fmt.Errorf("invalid io_retry: %w", err)
+		}
+	}
+
+	if _, err := ParseByteSize(c.MemoryLimit); err != nil {
+		return fmt.Errorf("invalid memory_limit: %w", err)
+	}
+
+	if err := validateChecks(c.Checks); err != nil {
+		return fmt.Errorf("invalid checks: %w", err)
+	}
+
+	if err := c.SeverityOverrides.Validate(); err != nil {
+		return fmt.Errorf("invalid severity_overrides: %w", err)
+	}
+
+	if _, err := compileBackupFilePatterns(c.BackupFilePatterns); err != nil {
+		return fmt.Errorf("invalid backup_file_patterns: %w", err)
+	}
+
+	if _, err := NewLocalizer(c.Language); err != nil {
+		return fmt.Errorf("invalid language: %w", err)
+	}
+
+	if minAge, maxAge := c.ScanOptions().AgeLimits(); minAge >= maxAge {
+		return fmt.Errorf("min_backup_age must be shorter than max_backup_age")
+	}
+
+	for i, schedule := range c.Schedules {
+		if err := schedule.Validate(); err != nil {
+			return fmt.Errorf("invalid schedules entry %d: %w", i+1, err)
+		}
+		if _, ok := c.Profiles[schedule.Profile]; schedule.Profile != "" && !ok {
+			return fmt.Errorf("invalid schedules entry %d: unknown profile %q", i+1, schedule.Profile)
+		}
+	}
+
+	for _, pattern := range c.RequiredPaths {
+		if err := validateEntryPattern(pattern); err != nil {
+			return fmt.Errorf("invalid required_paths entry %q: %w", pattern, err)
+		}
+	}
+
+	for _, rule := range c.ForbiddenContent {
+		if err := validateEntryPattern(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid forbidden_content pattern %q: %w", rule.Pattern, err)
+		}
+		if _, err := ParseByteSize(rule.MinSize); err != nil {
+			return fmt.Errorf("invalid forbidden_content min_size %q: %w", rule.MinSize, err)
+		}
+	}
+
+	if c.MaxZipSampleSize < 0 {
+		return fmt.Errorf("max_zip_sample_size cannot be negative")
+	}
+
+	if c.Email != nil && c.Email.Enabled {
+		if err := c.Email.Validate(); err != nil {
+			return fmt.Errorf("invalid email config: %w", err)
+		}
+	}
+
+	if c.Notifications != nil {
+		if err := c.Notifications.Validate(); err != nil {
+			return fmt.Errorf("invalid notifications config: %w", err)
+		}
+	}
+
+	if c.InfluxDB != nil && c.InfluxDB.Enabled {
+		if err := c.InfluxDB.Validate(); err != nil {
+			return fmt.Errorf("invalid influxdb config: %w", err)
+		}
+	}
+
+	if c.CatalogCache != nil {
+		if err := c.CatalogCache.Validate(); err != nil {
+			return fmt.Errorf("invalid catalog_cache config: %w", err)
+		}
+	}
+
+	if c.Manifests != nil {
+		if err := c.Manifests.Validate(); err != nil {
+			return fmt.Errorf("invalid manifests config: %w", err)
+		}
+	}
+
+	if c.Parity != nil {
+		if err := c.Parity.Validate(); err != nil {
+			return fmt.Errorf("invalid parity config: %w", err)
+		}
+	}
+
+	if c.ArchiveTier != nil {
+		if err := c.ArchiveTier.Validate(); err != nil {
+			return fmt.Errorf("invalid archive_tier config: %w", err)
+		}
+	}
+
+	if c.USNJournal != nil && c.USNJournal.Enabled {
+		if err := c.USNJournal.Validate(); err != nil {
+			return fmt.Errorf("invalid usn_journal config: %w", err)
+		}
+	}
+
+	if c.Snapshots != nil && c.Snapshots.Enabled {
+		if err := c.Snapshots.Validate(); err != nil {
+			return fmt.Errorf("invalid snapshots config: %w", err)
+		}
+	}
+
+	if c.ActiveJobs != nil && c.ActiveJobs.Enabled {
+		if err := c.ActiveJobs.Validate(); err != nil {
+			return fmt.Errorf("invalid active_jobs config: %w", err)
+		}
+	}
+
+	if c.Flapping != nil && c.Flapping.Enabled {
+		if err := c.Flapping.Validate(); err != nil {
+			return fmt.Errorf("invalid flapping config: %w", err)
+		}
+	}
+
+	if c.Escalation != nil && c.Escalation.Enabled {
+		if err := c.Escalation.Validate(); err != nil {
+			return fmt.Errorf("invalid escalation config: %w", err)
+		}
+	}
+
+	if c.IssueGrouping != nil && c.IssueGrouping.Enabled {
+		if err := c.IssueGrouping.Validate(); err != nil {
+			return fmt.Errorf("invalid issue_grouping config: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks if email configuration is valid
+func (e *EmailConfig) Validate() error {
+	if e.SMTPHost == "" {
+		return fmt.Errorf("smtp_host is required")
+	}
+	if e.SMTPPort <= 0 || e.SMTPPort > 65535 {
+		return fmt.Errorf("smtp_port must be between 1 and 65535")
+	}
+	if e.From == "" {
+		return fmt.Errorf("from address is required")
+	}
+	if len(e.To) == 0 {
+		return fmt.Errorf("at least one recipient (to) is required")
+	}
+	if e.Username == "" {
+		return fmt.Errorf("username is required for SMTP authentication")
+	}
+	if e.Password == "" {
+		return fmt.Errorf("password is required for SMTP authentication")
+	}
+	switch e.TLSMode {
+	case "", TLSModeSTARTTLS, TLSModeImplicit, TLSModeNone:
+	default:
+		return fmt.Errorf("tls_mode must be starttls, tls or none")
+	}
+	if e.TLSCAFile != "" {
+		if _, err := e.tlsConfig(); err != nil {
+			return err
+		}
+	}
+	if e.Retries != nil && (*e.Retries < 0 || *e.Retries > 10) {
+		return fmt.Errorf("retries must be between 0 and 10")
+	}
+	if e.RetryBackoff != "" {
+		if d, err := ParseDuration(e.RetryBackoff); err != nil || d <= 0 {
+			return fmt.Errorf("retry_backoff must be a positive duration")
+		}
+	}
+	if err := e.validateTemplates(); err != nil {
+		return err
+	}
+	if e.Digest != nil && e.Digest.Enabled {
+		if err := e.Digest.Validate(); err != nil {
+			return fmt.Errorf("digest: %w", err)
+		}
+	}
+	if e.Dedup != nil && e.Dedup.Enabled {
+		if err := e.Dedup.Validate(); err != nil {
+			return fmt.Errorf("dedup: %w", err)
+		}
+	}
+	return e.NotifyTriggers.Validate()
+}
+
+// GetMinBackupAge returns parsed minimum backup age duration
+func (c *Config) GetMinBackupAge() (time.Duration, error) {
+	return ParseDuration(c.MinBackupAge)
+}
+
+// GetMaxBackupAge returns parsed maximum backup age duration
+func (c *Config) GetMaxBackupAge() (time.Duration, error) {
+	return ParseDuration(c.MaxBackupAge)
+}
+
+// ParseDuration parses a Go duration string, additionally accepting a "d" day suffix
+func ParseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	// Handle day suffix
+	if len(s) > 1 && s[len(s)-1] == 'd' {
+		days := s[:len(s)-1]
+		d, err := time.ParseDuration(days + "h")
+		if err != nil {
+			return 0, err
+		}
+		return d * 24, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+// FormatDuration formats d the way ParseDuration reads it, in days when d is
+// a whole number of days
+func FormatDuration(d time.Duration) string {
+	if d != 0 && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// ParseByteSize parses sizes such as "512", "100KB", "50GB" or "1.5TB" using
+// binary (1024-based) units. An empty string is zero.
+func ParseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	if s == "" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix string
+		mult   float64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+	}
+
+	mult := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * mult), nil
+}
+
+// formatByteSize renders a byte count with a binary unit suffix
+func formatByteSize(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// Helper to create timestamp consistently
+func NowRFC3339() string {
+	return time.Now().Format(time.RFC3339)
+}
+
+// NewValidationIssue creates a new validation issue with timestamp
+func NewValidationIssue(severity ValidationSeverity, message, path, suggestion string) ValidationIssue {
+	return ValidationIssue{
+		Severity:   severity,
+		Message:    message,
+		Path:       path,
+		Suggestion: suggestion,
+		CheckedAt:  NowRFC3339(),
+	}
+}
@@ -0,0 +1,151 @@
+package winbackupchecker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigReloader keeps the config of a long-running checker current. Reload
+// is called between scans and swaps in both config files at once when either
+// changed on disk, so a scan never sees half of an edit. A file that fails to
+// load leaves the previous config in place.
+type ConfigReloader struct {
+	configPath      string
+	emailConfigPath string
+	profile         string
+
+	mu     sync.Mutex
+	config *Config
+	email  *EmailConfig
+	stamps map[string]fileStamp
+}
+
+// fileStamp identifies a version of a file on disk
+type fileStamp struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func statStamp(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
+
+// NewConfigReloader loads both config files, applying profile to the main
+// one. The email config is optional, as with LoadEmailConfig.
+func NewConfigReloader(configPath, emailConfigPath, profile string) (*ConfigReloader, error) {
+	r := &ConfigReloader{configPath: configPath, emailConfigPath: emailConfigPath, profile: profile}
+	stamps := r.currentStamps()
+
+	cfg, email, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.config, r.email, r.stamps = cfg, email, stamps
+	return r, nil
+}
+
+// WithProfile returns a reloader for the same config files with another
+// profile applied
+func (r *ConfigReloader) WithProfile(profile string) (*ConfigReloader, error) {
+	return NewConfigReloader(r.configPath, r.emailConfigPath, profile)
+}
+
+// Current returns the config in effect
+func (r *ConfigReloader) Current() (*Config, *EmailConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.config, r.email
+}
+
+// Reload loads the config files again if either changed since the last load,
+// and returns the keys whose values changed, such as "max_backup_age" or
+// "email.smtp_host". Values are not returned, since they may be secrets.
+func (r *ConfigReloader) Reload() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stamps := r.currentStamps()
+	if reflect.DeepEqual(stamps, r.stamps) {
+		return nil, nil
+	}
+
+	cfg, email, err := r.load()
+	if err != nil {
+		// Retry once the file is fixed, even if its stamp does not change again
+		return nil, fmt.Errorf("keeping previous config: %w", err)
+	}
+
+	changed := changedKeys("", r.config, cfg)
+	changed = append(changed, changedKeys("email.", r.email, email)...)
+	sort.Strings(changed)
+
+	r.config, r.email, r.stamps = cfg, email, stamps
+	return changed, nil
+}
+
+func (r *ConfigReloader) currentStamps() map[string]fileStamp {
+	return map[string]fileStamp{
+		r.configPath:      statStamp(r.configPath),
+		r.emailConfigPath: statStamp(r.emailConfigPath),
+	}
+}
+
+func (r *ConfigReloader) load() (*Config, *EmailConfig, error) {
+	cfg, err := LoadConfigProfile(r.configPath, r.profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	email, err := LoadEmailConfig(r.emailConfigPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, email, nil
+}
+
+// changedKeys compares the top-level JSON keys of two configs, each key
+// prefixed with prefix
+func changedKeys(prefix string, before, after any) []string {
+	beforeKeys := jsonKeys(before)
+	afterKeys := jsonKeys(after)
+	if prefix != "" && (len(beforeKeys) == 0) != (len(afterKeys) == 0) {
+		// A whole optional file was added or removed
+		return []string{strings.TrimSuffix(prefix, ".")}
+	}
+
+	var changed []string
+	for key, value := range afterKeys {
+		if previous, ok := beforeKeys[key]; !ok || string(previous) != string(value) {
+			changed = append(changed, prefix+key)
+		}
+	}
+	for key := range beforeKeys {
+		if _, ok := afterKeys[key]; !ok {
+			changed = append(changed, prefix+key)
+		}
+	}
+	return changed
+}
+
+func jsonKeys(v any) map[string]json.RawMessage {
+	keys := make(map[string]json.RawMessage)
+	if reflect.ValueOf(v).IsNil() {
+		return keys
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return keys
+	}
+	json.Unmarshal(data, &keys)
+	return keys
+}
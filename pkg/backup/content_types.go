@@ -0,0 +1,61 @@
+package winbackupchecker
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Content categories reported in ValidationStats.ContentBreakdown
+const (
+	CategoryDocuments = "documents"
+	CategoryPhotos    = "photos"
+	CategoryVideo     = "video"
+	CategoryOther     = "other"
+)
+
+var categoryByExtension = map[string]string{
+	".doc": CategoryDocuments, ".docx": CategoryDocuments, ".odt": CategoryDocuments,
+	".rtf": CategoryDocuments, ".txt": CategoryDocuments, ".pdf": CategoryDocuments,
+	".xls": CategoryDocuments, ".xlsx": CategoryDocuments, ".ods": CategoryDocuments,
+	".csv": CategoryDocuments, ".ppt": CategoryDocuments, ".pptx": CategoryDocuments,
+	".odp": CategoryDocuments, ".md": CategoryDocuments, ".one": CategoryDocuments,
+
+	".jpg": CategoryPhotos, ".jpeg": CategoryPhotos, ".png": CategoryPhotos,
+	".gif": CategoryPhotos, ".bmp": CategoryPhotos, ".tif": CategoryPhotos,
+	".tiff": CategoryPhotos, ".heic": CategoryPhotos, ".webp": CategoryPhotos,
+	".raw": CategoryPhotos, ".cr2": CategoryPhotos, ".nef": CategoryPhotos,
+	".arw": CategoryPhotos, ".dng": CategoryPhotos,
+
+	".mp4": CategoryVideo, ".mov": CategoryVideo, ".avi": CategoryVideo,
+	".mkv": CategoryVideo, ".wmv": CategoryVideo, ".m4v": CategoryVideo,
+	".mpg": CategoryVideo, ".mpeg": CategoryVideo, ".mts": CategoryVideo,
+	".3gp": CategoryVideo, ".webm": CategoryVideo,
+}
+
+// ContentCategory classifies a backed-up file by its extension
+func ContentCategory(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if category, ok := categoryByExtension[strings.ToLower(filepath.Ext(name))]; ok {
+		return category
+	}
+	return CategoryOther
+}
+
+// contentBreakdown counts catalog entries per content category, skipping
+// directory entries and entries repeated across catalogs
+func contentBreakdown(catalogs []*Catalog) map[string]int {
+	breakdown := make(map[string]int)
+	seen := make(map[string]bool)
+
+	for _, catalog := range catalogs {
+		for _, entry := range catalog.Entries {
+			if strings.HasSuffix(entry, `\`) || seen[entry] {
+				continue
+			}
+			seen[entry] = true
+			breakdown[ContentCategory(entry)]++
+		}
+	}
+
+	return breakdown
+}
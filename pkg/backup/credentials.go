@@ -0,0 +1,62 @@
+package winbackupchecker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// credentialService groups the checker's secrets in the OS credential store
+const credentialService = "win-backup-checker"
+
+// ErrCredentialNotFound is returned when no secret is stored under a name
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// SetCredential stores secret under name in the OS credential store: the
+// Windows Credential Manager (protected with DPAPI for the current user), the
+// macOS keychain, or the Secret Service keyring (secret-tool) elsewhere
+func SetCredential(name, secret string) error {
+	if err := validateCredentialName(name); err != nil {
+		return err
+	}
+	if err := keyringSet(name, secret); err != nil {
+		return fmt.Errorf("failed to store credential %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetCredential returns the secret stored under name
+func GetCredential(name string) (string, error) {
+	if err := validateCredentialName(name); err != nil {
+		return "", err
+	}
+	secret, err := keyringGet(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read credential %s: %w", name, err)
+	}
+	return secret, nil
+}
+
+// DeleteCredential removes the secret stored under name
+func DeleteCredential(name string) error {
+	if err := validateCredentialName(name); err != nil {
+		return err
+	}
+	if err := keyringDelete(name); err != nil {
+		return fmt.Errorf("failed to delete credential %s: %w", name, err)
+	}
+	return nil
+}
+
+func validateCredentialName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("credential name cannot be empty")
+	}
+	return nil
+}
+
+// credentialTarget is the name a secret is stored under in stores without
+// a separate service field
+func credentialTarget(name string) string {
+	return credentialService + ":" + name
+}
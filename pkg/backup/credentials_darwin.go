@@ -0,0 +1,62 @@
+//go:build darwin
+
+package winbackupchecker
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keyringSet stores the secret in the login keychain with the security tool.
+// The command is written to security -i on stdin, as command lines are
+// visible to other processes.
+func keyringSet(name, secret string) error {
+	if strings.ContainsAny(secret, "\r\n") {
+		return fmt.Errorf("secrets containing line breaks cannot be stored in the keychain")
+	}
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		securityQuote(credentialService), securityQuote(name), securityQuote(secret)))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	// security -i carries on after a failed command, so read the item back
+	if stored, err := keyringGet(name); err != nil || stored != secret {
+		return fmt.Errorf("failed to add the keychain item: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func keyringGet(name string) (string, error) {
+	output, err := exec.Command("security", "find-generic-password",
+		"-s", credentialService, "-a", name, "-w").Output()
+	if err != nil {
+		return "", keychainError(err)
+	}
+	return strings.TrimSuffix(string(output), "\n"), nil
+}
+
+func keyringDelete(name string) error {
+	if err := exec.Command("security", "delete-generic-password",
+		"-s", credentialService, "-a", name).Run(); err != nil {
+		return keychainError(err)
+	}
+	return nil
+}
+
+// keychainError maps the exit code security uses for missing items
+func keychainError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+		return ErrCredentialNotFound
+	}
+	return err
+}
+
+// securityQuote quotes s as an argument of a command read by security -i
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
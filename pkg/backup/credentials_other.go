@@ -0,0 +1,57 @@
+//go:build !windows && !darwin
+
+package winbackupchecker
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keyringSet stores the secret in the Secret Service keyring (GNOME Keyring,
+// KWallet) with secret-tool from libsecret
+func keyringSet(name, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label="+credentialTarget(name),
+		"service", credentialService, "account", name)
+	cmd.Stdin = strings.NewReader(secret)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return secretToolError(err, output)
+	}
+	return nil
+}
+
+func keyringGet(name string) (string, error) {
+	cmd := exec.Command("secret-tool", "lookup", "service", credentialService, "account", name)
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", secretToolError(err, nil)
+		}
+		if len(exitErr.Stderr) == 0 {
+			// lookup exits silently with 1 when nothing matches
+			return "", ErrCredentialNotFound
+		}
+		return "", secretToolError(err, exitErr.Stderr)
+	}
+	return string(output), nil
+}
+
+func keyringDelete(name string) error {
+	cmd := exec.Command("secret-tool", "clear", "service", credentialService, "account", name)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return secretToolError(err, output)
+	}
+	return nil
+}
+
+func secretToolError(err error, output []byte) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("secret-tool not found; install libsecret-tools or use WBC_EMAIL_PASSWORD instead")
+	}
+	if msg := strings.TrimSpace(string(output)); msg != "" {
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+package winbackupchecker
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// writeCSVReport writes one row per issue, and one row for each backup set
+// without issues, so the report can be filtered in a spreadsheet or pasted
+// into a ticket
+func writeCSVReport(w io.Writer, report RunReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"root", "machine", "backup_set", "valid", "total_files", "corrupt_files",
+		"total_size_bytes", "severity", "message", "path", "suggestion",
+	})
+
+	for _, scanReport := range report.Results {
+		for _, br := range scanReport.Reports {
+			set := []string{
+				scanReport.Root,
+				MachineName(br.BackupDir),
+				br.BackupDir,
+				strconv.FormatBool(br.Valid),
+				strconv.Itoa(br.ValidationStats.TotalFiles),
+				strconv.Itoa(br.ValidationStats.CorruptFiles),
+				strconv.FormatInt(br.ValidationStats.TotalSize, 10),
+			}
+
+			if len(br.Issues) == 0 {
+				cw.Write(append(set, "", "", "", ""))
+				continue
+			}
+			for _, issue := range br.Issues {
+				row := append(append([]string(nil), set...),
+					issue.Severity.String(), issue.Message, issue.Path, issue.Suggestion)
+				cw.Write(row)
+			}
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV report: %w", err)
+	}
+	return nil
+}
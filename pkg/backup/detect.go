@@ -0,0 +1,76 @@
+package winbackupchecker
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Kinds of backup found by DetectBackupLocations
+const (
+	BackupKindFile        = "file"
+	BackupKindSystemImage = "system image"
+)
+
+// DetectedBackup is a backup location found on a mounted volume
+type DetectedBackup struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+}
+
+// DetectBackupLocations looks for file backup roots (folders holding
+// MediaID.bin) and WindowsImageBackup folders at the top two levels of each
+// local volume. Network drives are skipped, since listing an offline share
+// can block for minutes. System images are reported so they can be told
+// apart, but only file backups are validated by the checker.
+func DetectBackupLocations() []DetectedBackup {
+	var found []DetectedBackup
+	seen := make(map[string]bool)
+	for _, volume := range mountedVolumes() {
+		for _, backup := range detectBackups(volume, 2) {
+			if !seen[backup.Path] {
+				seen[backup.Path] = true
+				found = append(found, backup)
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+	return found
+}
+
+func detectBackups(dir string, depth int) []DetectedBackup {
+	if fileExists(filepath.Join(dir, "MediaID.bin")) {
+		return []DetectedBackup{{Path: dir, Kind: BackupKindFile}}
+	}
+
+	var found []DetectedBackup
+	if dirExists(filepath.Join(dir, "WindowsImageBackup")) {
+		found = append(found, DetectedBackup{Path: filepath.Join(dir, "WindowsImageBackup"), Kind: BackupKindSystemImage})
+	}
+	if depth <= 1 {
+		return found
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return found
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == "WindowsImageBackup" || isSystemFolder(entry.Name()) {
+			continue
+		}
+		found = append(found, detectBackups(filepath.Join(dir, entry.Name()), depth-1)...)
+	}
+	return found
+}
+
+// isSystemFolder reports folders that never hold backups and are slow or
+// denied to list
+func isSystemFolder(name string) bool {
+	switch name {
+	case "$RECYCLE.BIN", "System Volume Information", "Windows", "Program Files",
+		"Program Files (x86)", "ProgramData", "proc", "sys", "dev":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiscordConfig configures a Discord webhook notifier
+type DiscordConfig struct {
+	Enabled    bool   `json:"enabled"`
+	WebhookURL string `json:"webhook_url" secret:"true"`
+	Username   string `json:"username,omitempty"`
+	MaxIssues  int    `json:"max_issues,omitempty"`
+	NotifyTriggers
+}
+
+// Validate checks if Discord configuration is valid
+func (c *DiscordConfig) Validate() error {
+	if c.WebhookURL == "" {
+		return fmt.Errorf("webhook_url is required")
+	}
+	if c.MaxIssues < 0 {
+		return fmt.Errorf("max_issues cannot be negative")
+	}
+	return c.NotifyTriggers.Validate()
+}
+
+// discordDescriptionLimit is Discord's maximum embed description length
+const discordDescriptionLimit = 4096
+
+// DiscordNotifier posts run summaries to a Discord webhook as an embed
+type DiscordNotifier struct {
+	cfg *DiscordConfig
+}
+
+func (n *DiscordNotifier) Name() string { return "discord" }
+
+// Notify posts an embed with valid/invalid counts and the most severe issues
+func (n *DiscordNotifier) Notify(ctx context.Context, report RunReport) error {
+	status := NewRunStatus(report.Summary, report.Results)
+	if !n.cfg.ShouldSend(status) {
+		return nil
+	}
+
+	maxIssues := n.cfg.MaxIssues
+	if maxIssues == 0 {
+		maxIssues = 10
+	}
+
+	color := "#28a745"
+	switch {
+	case status.HasErrors:
+		color = severityColor(SeverityCritical)
+	case status.HasWarnings:
+		color = severityColor(SeverityWarning)
+	}
+
+	summary := report.Summary
+	embed := map[string]any{
+		"title":       fmt.Sprintf("Backup Validation: %s", status.Label()),
+		"description": discordTopIssues(report, maxIssues),
+		"color":       hexColorInt(color),
+		"timestamp":   report.Timestamp,
+		"fields": []any{
+			discordField("Total", summary.TotalBackups),
+			discordField("Valid", summary.ValidBackups),
+			discordField("Invalid", summary.InvalidBackups),
+			discordField("Failed Scans", summary.FailedScans),
+		},
+	}
+
+	payload := map[string]any{
+		"embeds": []any{embed},
+	}
+	if n.cfg.Username != "" {
+		payload["username"] = n.cfg.Username
+	}
+
+	if err := postJSON(ctx, n.cfg.WebhookURL, payload, nil); err != nil {
+		return fmt.Errorf("failed to post to Discord: %w", err)
+	}
+	return nil
+}
+
+// discordTopIssues lists the most severe issues across all backup sets
+func discordTopIssues(report RunReport, limit int) string {
+	type setIssue struct {
+		set   string
+		issue ValidationIssue
+	}
+
+	var all []setIssue
+	for _, br := range reportsWithIssues(report) {
+		for _, issue := range br.Issues {
+			all = append(all, setIssue{set: filepath.Base(br.BackupDir), issue: issue})
+		}
+	}
+	if len(all) == 0 {
+		return "No issues found."
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].issue.Severity > all[j].issue.Severity
+	})
+
+	var b strings.Builder
+	for i, si := range all {
+		if i == limit {
+			fmt.Fprintf(&b, "…and %d more issues", len(all)-limit)
+			break
+		}
+		line := fmt.Sprintf("**%s** `%s`: %s\n", strings.ToUpper(si.issue.Severity.String()), si.set, si.issue.Message)
+		if b.Len()+len(line) > discordDescriptionLimit-32 {
+			fmt.Fprintf(&b, "…and %d more issues", len(all)-i)
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func discordField(name string, value int) map[string]any {
+	return map[string]any{"name": name, "value": strconv.Itoa(value), "inline": true}
+}
+
+// hexColorInt converts "#rrggbb" to the integer color Discord expects
+func hexColorInt(hex string) int {
+	n, _ := strconv.ParseInt(strings.TrimPrefix(hex, "#"), 16, 32)
+	return int(n)
+}
@@ -0,0 +1,45 @@
+// Package winbackupchecker validates Windows Backup sets on disk: their
+// structure, completeness, ZIP and catalog contents and age. It also holds
+// the reports, history and notifications built on the results, and is the
+// library behind the checker command.
+//
+// A program embedding the checker loads a config, as the command does, and
+// runs it:
+//
+//	cfg, err := winbackupchecker.LoadConfig("config.json")
+//	if err != nil {
+//		return err
+//	}
+//	opts := cfg.ScanOptions()
+//	opts.MaxWorkers = 4
+//	opts.Progress = io.Discard // progress lines go to stdout by default
+//	result := winbackupchecker.Run(ctx, cfg, opts, winbackupchecker.RunOptions{})
+//	for _, scan := range result.Report.Results {
+//		for _, set := range scan.Reports {
+//			fmt.Println(set.BackupDir, set.Valid)
+//		}
+//	}
+//
+// To show progress live, set ScanOptions.OnEvent before running. It is
+// called as sets are discovered, started and finished and as issues are
+// found, from several goroutines at once:
+//
+//	events := make(chan winbackupchecker.Event, 64)
+//	opts.OnEvent = func(e winbackupchecker.Event) { events <- e }
+//	go func() {
+//		defer close(events)
+//		winbackupchecker.Run(ctx, cfg, opts, winbackupchecker.RunOptions{})
+//	}()
+//	for e := range events {
+//		if e.Kind == winbackupchecker.EventSetFinished {
+//			fmt.Println(e.Set, e.Report.Valid)
+//		}
+//	}
+//
+// ScanFileBackupDir validates a single backup path instead. The result can
+// be sent with BuildNotifiers and SendEmailAlert, or written with
+// WriteInfluxLineProtocol and WritePRTG.
+//
+// Exported identifiers, and the JSON fields of configs and reports, are
+// kept backward compatible; unexported ones may change at any time.
+package winbackupchecker
@@ -0,0 +1,546 @@
+package winbackupchecker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+type EmailData struct {
+	Timestamp   string
+	Summary     ScanSummary
+	HasErrors   bool
+	HasWarnings bool
+	Reports     []BackupReport
+	ScanRoots   []string
+
+	// Digest is set for digest emails, which show the latest run's report
+	// below the period summary
+	Digest *EmailDigest
+
+	// localizer translates the templates and issues
+	localizer *Localizer
+}
+
+// SendEmailAlert sends an email notification based on the scan results, in
+// the language of l
+func SendEmailAlert(cfg *EmailConfig, summary ScanSummary, reports []ScanReport, l *Localizer) error {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	// Skip alerts repeating the previous one within the cooldown
+	var dedupState *alertDedupState
+	if cfg.Dedup != nil && cfg.Dedup.Enabled {
+		send, state, err := cfg.Dedup.shouldAlert(reports, time.Now())
+		if err != nil {
+			return err
+		}
+		if !send {
+			return nil
+		}
+		dedupState = &state
+	}
+
+	// Determine if we should send based on results
+	status := NewRunStatus(summary, reports)
+	if !cfg.ShouldSend(status) {
+		return nil
+	}
+	hasErrors, hasWarnings := status.HasErrors, status.HasWarnings
+
+	// Generate subject
+	subject := generateSubject(cfg, hasErrors, hasWarnings, summary, l)
+
+	emailData := newEmailData(summary, reports, hasErrors, hasWarnings, l)
+	err := sendEmailReport(cfg, subject, emailData, RunReport{
+		Timestamp: time.Now().Format(time.RFC3339),
+		Results:   reports,
+		Summary:   summary,
+	})
+	if err != nil {
+		return err
+	}
+
+	if dedupState != nil && len(dedupState.Issues) > 0 {
+		return saveAlertDedupState(cfg.Dedup.statePath(), *dedupState)
+	}
+	return nil
+}
+
+// newEmailData flattens scan reports for the email templates, translating
+// the issues
+func newEmailData(summary ScanSummary, reports []ScanReport, hasErrors, hasWarnings bool, l *Localizer) EmailData {
+	emailData := EmailData{
+		Timestamp:   time.Now().Format(time.RFC1123),
+		Summary:     summary,
+		HasErrors:   hasErrors,
+		HasWarnings: hasWarnings,
+		ScanRoots:   make([]string, 0),
+		localizer:   l,
+	}
+
+	// Flatten reports and collect roots
+	for _, scanReport := range l.Reports(reports) {
+		emailData.ScanRoots = append(emailData.ScanRoots, scanReport.Root)
+		emailData.Reports = append(emailData.Reports, scanReport.Reports...)
+	}
+	return emailData
+}
+
+// sendEmailReport renders both email bodies and sends them with the report
+// attachments enabled in cfg
+func sendEmailReport(cfg *EmailConfig, subject string, emailData EmailData, report RunReport) error {
+	// Generate email body
+	body, err := generateEmailBody(emailData, cfg.TemplateHTML)
+	if err != nil {
+		return fmt.Errorf("failed to generate email body: %w", err)
+	}
+
+	textBody, err := generateEmailText(emailData, cfg.TemplateText)
+	if err != nil {
+		return fmt.Errorf("failed to generate email text: %w", err)
+	}
+
+	attachments, err := reportAttachments(cfg, report)
+	if err != nil {
+		return err
+	}
+
+	// Send email
+	return sendEmail(cfg, subject, textBody, body, attachments)
+}
+
+// emailAttachment is a file attached to the alert email
+type emailAttachment struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// reportAttachments renders the report in each format enabled in cfg, so
+// the raw data survives clients that truncate the HTML body
+func reportAttachments(cfg *EmailConfig, report RunReport) ([]emailAttachment, error) {
+	name := "backup-report-" + time.Now().Format("20060102-150405")
+	var attachments []emailAttachment
+
+	if cfg.AttachJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal report: %w", err)
+		}
+		attachments = append(attachments, emailAttachment{FileName: name + ".json", ContentType: "application/json", Data: data})
+	}
+
+	if cfg.AttachCSV {
+		var buf bytes.Buffer
+		if err := writeCSVReport(&buf, report); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, emailAttachment{FileName: name + ".csv", ContentType: "text/csv", Data: buf.Bytes()})
+	}
+
+	return attachments, nil
+}
+
+func generateSubject(cfg *EmailConfig, hasErrors, hasWarnings bool, summary ScanSummary, l *Localizer) string {
+	prefix := cfg.SubjectPrefix
+	if prefix == "" {
+		prefix = l.T("[Backup Alert]")
+	}
+
+	status := RunStatus{HasErrors: hasErrors, HasWarnings: hasWarnings}.Label()
+
+	return l.Sprintf("%s %s - %d/%d Backups Valid", prefix, l.T(status), summary.ValidBackups, summary.TotalBackups)
+}
+
+// defaultEmailHTML is the HTML email template used unless
+// email_template_html is set
+const defaultEmailHTML = `
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .header { background-color: {{if .HasErrors}}#dc3545{{else if .HasWarnings}}#ffc107{{else}}#28a745{{end}}; 
+                  color: white; padding: 20px; border-radius: 5px; }
+        .summary { background-color: #f8f9fa; padding: 15px; margin: 20px 0; border-radius: 5px; }
+        .backup-set { margin: 20px 0; padding: 15px; border: 1px solid #dee2e6; border-radius: 5px; }
+        .valid { border-left: 4px solid #28a745; }
+        .invalid { border-left: 4px solid #dc3545; }
+        .issue { margin: 10px 0; padding: 10px; background-color: #f8f9fa; border-radius: 3px; }
+        .critical { border-left: 3px solid #dc3545; }
+        .error { border-left: 3px solid #fd7e14; }
+        .warning { border-left: 3px solid #ffc107; }
+        .info { border-left: 3px solid #17a2b8; }
+        .stats { display: grid; grid-template-columns: repeat(2, 1fr); gap: 10px; margin: 10px 0; }
+        .stat-item { background-color: #e9ecef; padding: 8px; border-radius: 3px; }
+        h2 { color: #495057; border-bottom: 2px solid #dee2e6; padding-bottom: 10px; }
+        h3 { color: #6c757d; }
+        .path { font-family: monospace; background-color: #e9ecef; padding: 2px 5px; border-radius: 3px; }
+    </style>
+</head>
+<body>
+    <div class="header">
+        <h1>{{t "Backup Validation Report"}}</h1>
+        <p>{{t "Scan completed at: %s" .Timestamp}}</p>
+    </div>
+
+    {{with .Digest}}
+    <div class="summary">
+        <h2>{{t (printf "%s Digest" .Period)}}</h2>
+        <p>{{t "%s to %s" (.Since.Local.Format "Mon Jan 2 15:04") (.Until.Local.Format "Mon Jan 2 15:04")}}</p>
+        <div class="stats">
+            <div class="stat-item"><strong>{{t "Runs:"}}</strong> {{.Runs}}</div>
+            <div class="stat-item"><strong>{{t "Successful Runs:"}}</strong> {{.SuccessfulRuns}} ({{printf "%.1f" .SuccessRate}}%)</div>
+            <div class="stat-item"><strong>{{t "Set Checks Passed:"}}</strong> {{printf "%.1f" .SetSuccessRate}}%</div>
+            <div class="stat-item"><strong>{{t "Failures:"}}</strong> {{.Failures}}</div>
+        </div>
+        {{if .FlakiestSets}}
+        <h3>{{t "Least Stable Sets"}}</h3>
+        <ul>
+        {{range .FlakiestSets}}<li><span class="path">{{.BackupDir}}</span>: {{t "failed %d of %d runs" .Failures .Runs}}</li>{{end}}
+        </ul>
+        {{end}}
+    </div>
+    <h2>{{t "Latest Run"}}</h2>
+    {{end}}
+
+    <div class="summary">
+        <h2>{{t "Summary"}}</h2>
+        <div class="stats">
+            <div class="stat-item"><strong>{{t "Total Backups:"}}</strong> {{.Summary.TotalBackups}}</div>
+            <div class="stat-item"><strong>{{t "Valid Backups:"}}</strong> {{.Summary.ValidBackups}}</div>
+            <div class="stat-item"><strong>{{t "Invalid Backups:"}}</strong> {{.Summary.InvalidBackups}}</div>
+            <div class="stat-item"><strong>{{t "Failed Scans:"}}</strong> {{.Summary.FailedScans}}</div>
+        </div>
+        {{if gt .Summary.TotalBackups 0}}
+        <p><strong>{{t "Success Rate:"}}</strong> {{printf "%.1f" (div (mul (float64 .Summary.ValidBackups) 100.0) (float64 .Summary.TotalBackups))}}%</p>
+        {{end}}
+    </div>
+
+    <h2>{{t "Scanned Paths"}}</h2>
+    <ul>
+    {{range .ScanRoots}}
+        <li><span class="path">{{.}}</span></li>
+    {{end}}
+    </ul>
+
+    <h2>{{t "Backup Details"}}</h2>
+    {{range .Reports}}
+    <div class="backup-set {{if .Valid}}valid{{else}}invalid{{end}}">
+        <h3>{{base .BackupDir}}</h3>
+        <p><span class="path">{{.BackupDir}}</span></p>
+        <p><strong>{{t "Status:"}}</strong> {{if .Valid}}{{t "Valid"}}{{else}}{{t "Invalid"}}{{end}}{{with .Flapping}} {{t "(flapping: %d changes in %d runs)" .Transitions .Runs}}{{end}}</p>
+        
+        <div class="stats">
+            <div class="stat-item"><strong>{{t "Total Files:"}}</strong> {{.ValidationStats.TotalFiles}}</div>
+            <div class="stat-item"><strong>{{t "Validated:"}}</strong> {{.ValidationStats.ValidatedFiles}}</div>
+            <div class="stat-item"><strong>{{t "Corrupt:"}}</strong> {{.ValidationStats.CorruptFiles}}</div>
+            <div class="stat-item"><strong>{{t "Total Size:"}}</strong> {{formatBytes .ValidationStats.TotalSize}}</div>
+            <div class="stat-item"><strong>{{t "Catalog Files:"}}</strong> {{.ValidationStats.CatalogFiles}}</div>
+            <div class="stat-item"><strong>{{t "Backup Files:"}}</strong> {{.ValidationStats.BackupFiles}}</div>
+        </div>
+
+        {{with .Insights}}
+        {{if .LargestFiles}}
+        <h4>{{t "Largest Files"}}</h4>
+        <ul>
+        {{range .LargestFiles}}<li><span class="path">{{.Path}}</span> ({{formatBytes .Size}})</li>{{end}}
+        </ul>
+        {{end}}
+        {{if .LargestDirectories}}
+        <h4>{{t "Largest Directories"}}</h4>
+        <ul>
+        {{range .LargestDirectories}}<li><span class="path">{{.Path}}</span> ({{formatBytes .Size}})</li>{{end}}
+        </ul>
+        {{end}}
+        {{if .LargestGrowth}}
+        <h4>{{t "Largest Growth Since %s" (base .ComparedTo)}}</h4>
+        <ul>
+        {{range .LargestGrowth}}<li><span class="path">{{.Path}}</span> ({{t "+%s, now %s" (formatBytes .Growth) (formatBytes .Size)}})</li>{{end}}
+        </ul>
+        {{end}}
+        {{end}}
+
+        {{if .Issues}}
+        <h4>{{t "Issues Found (%d)" (len .Issues)}}</h4>
+        {{range .Issues}}
+        <div class="issue {{severityClass .Severity}}">
+            <strong>{{severityString .Severity}}:</strong> {{.Message}}{{if .EscalatedFrom}} <em>{{t "(escalated from %s after %d runs)" (t .EscalatedFrom) .ConsecutiveRuns}}</em>{{end}}{{if .Suppressed}} <em>{{t "(accepted in baseline)"}}</em>{{end}}<br>
+            {{if .Path}}<span class="path">{{.Path}}</span><br>{{end}}
+            {{if .AffectedPaths}}<span class="path">{{t "Sample of affected paths: %s" (join .AffectedPaths ", ")}}</span><br>{{end}}
+            {{if .Suggestion}}<em>{{t "Suggestion: %s" .Suggestion}}</em>{{end}}
+        </div>
+        {{end}}
+        {{end}}
+    </div>
+    {{end}}
+
+    <hr>
+    <p style="color: #6c757d; font-size: 0.9em;">
+        {{t "This is an automated message from the Windows Backup Checker system."}}
+    </p>
+</body>
+</html>
+`
+
+// defaultEmailText is the plaintext email template used unless
+// email_template_text is set
+const defaultEmailText = `{{t "Backup Validation Report"}}
+{{- with .Digest}}
+
+{{upper (t (printf "%s Digest" .Period))}}
+  {{t "%s to %s" (.Since.Local.Format "Mon Jan 2 15:04") (.Until.Local.Format "Mon Jan 2 15:04")}}
+  {{printf "%-18s" (t "Runs:")}} {{.Runs}}
+  {{printf "%-18s" (t "Successful Runs:")}} {{.SuccessfulRuns}} ({{printf "%.1f" .SuccessRate}}%)
+  {{printf "%-18s" (t "Set Checks Passed:")}} {{printf "%.1f" .SetSuccessRate}}%
+  {{printf "%-18s" (t "Failures:")}} {{.Failures}}
+{{- range .FlakiestSets}}
+  {{t "Unstable: %s failed %d of %d runs" .BackupDir .Failures .Runs}}
+{{- end}}
+
+{{upper (t "Latest Run")}}
+{{- end}}
+{{t "Scan completed at: %s" .Timestamp}}
+
+{{upper (t "Summary")}}
+  {{printf "%-16s" (t "Total Backups:")}} {{.Summary.TotalBackups}}
+  {{printf "%-16s" (t "Valid Backups:")}} {{.Summary.ValidBackups}}
+  {{printf "%-16s" (t "Invalid Backups:")}} {{.Summary.InvalidBackups}}
+  {{printf "%-16s" (t "Failed Scans:")}} {{.Summary.FailedScans}}
+{{- if gt .Summary.TotalBackups 0}}
+  {{printf "%-16s" (t "Success Rate:")}} {{printf "%.1f" (div (mul (float64 .Summary.ValidBackups) 100.0) (float64 .Summary.TotalBackups))}}%
+{{- end}}
+
+{{upper (t "Scanned Paths")}}
+{{- range .ScanRoots}}
+  {{.}}
+{{- end}}
+
+{{upper (t "Backup Details")}}
+{{- range .Reports}}
+
+{{base .BackupDir}}: {{if .Valid}}{{t "Valid"}}{{else}}{{t "Invalid"}}{{end}}{{with .Flapping}} {{t "(flapping: %d changes in %d runs)" .Transitions .Runs}}{{end}}
+  {{.BackupDir}}
+  {{t "%d files, %d validated, %d corrupt, %s" .ValidationStats.TotalFiles .ValidationStats.ValidatedFiles .ValidationStats.CorruptFiles (formatBytes .ValidationStats.TotalSize)}}
+{{- range .Issues}}
+  - {{severityString .Severity}}: {{.Message}}{{if .EscalatedFrom}} {{t "(escalated from %s after %d runs)" (t .EscalatedFrom) .ConsecutiveRuns}}{{end}}{{if .Suppressed}} {{t "(accepted in baseline)"}}{{end}}
+{{- if .Path}}
+    {{t "Path: %s" .Path}}
+{{- end}}
+{{- if .AffectedPaths}}
+    {{t "Sample of affected paths: %s" (join .AffectedPaths ", ")}}
+{{- end}}
+{{- if .Suggestion}}
+    {{t "Suggestion: %s" .Suggestion}}
+{{- end}}
+{{- end}}
+{{- end}}
+
+--
+{{t "This is an automated message from the Windows Backup Checker system."}}
+`
+
+// loadEmailTemplate reads the template at path, or returns fallback when no
+// path is configured
+func loadEmailTemplate(path, fallback string) (string, error) {
+	if path == "" {
+		return fallback, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read email template: %w", err)
+	}
+	return string(data), nil
+}
+
+// validateTemplates checks that configured template files can be read and
+// parsed, so mistakes show up when the config is loaded rather than when an
+// alert is due
+func (e *EmailConfig) validateTemplates() error {
+	if e.TemplateHTML != "" {
+		tmpl, err := loadEmailTemplate(e.TemplateHTML, "")
+		if err != nil {
+			return fmt.Errorf("email_template_html: %w", err)
+		}
+		if _, err := template.New("email").Funcs(emailFuncs(nil)).Parse(tmpl); err != nil {
+			return fmt.Errorf("email_template_html: %w", err)
+		}
+	}
+	if e.TemplateText != "" {
+		tmpl, err := loadEmailTemplate(e.TemplateText, "")
+		if err != nil {
+			return fmt.Errorf("email_template_text: %w", err)
+		}
+		if _, err := texttemplate.New("email").Funcs(emailFuncs(nil)).Parse(tmpl); err != nil {
+			return fmt.Errorf("email_template_text: %w", err)
+		}
+	}
+	return nil
+}
+
+func generateEmailBody(data EmailData, templatePath string) (string, error) {
+	tmpl, err := loadEmailTemplate(templatePath, defaultEmailHTML)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := template.New("email").Funcs(emailFuncs(data.localizer)).Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// generateEmailText renders the plaintext alternative of the email body
+func generateEmailText(data EmailData, templatePath string) (string, error) {
+	tmpl, err := loadEmailTemplate(templatePath, defaultEmailText)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := texttemplate.New("email").Funcs(emailFuncs(data.localizer)).Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// emailFuncs returns the functions available to the HTML and text
+// templates. t translates a text, formatting it with any further arguments.
+func emailFuncs(l *Localizer) map[string]any {
+	return map[string]any{
+		"base":  filepath.Base,
+		"upper": strings.ToUpper,
+		"join":  strings.Join,
+		"t":     l.Sprintf,
+		"severityString": func(s ValidationSeverity) string {
+			return strings.ToUpper(l.T(s.String()))
+		},
+		"severityClass": func(s ValidationSeverity) string {
+			return s.String()
+		},
+		"formatBytes": formatByteSize,
+		"float64":     func(i int) float64 { return float64(i) },
+		"mul":         func(a, b float64) float64 { return a * b },
+		"div": func(a, b float64) float64 {
+			if b == 0 {
+				return 0
+			}
+			return a / b
+		},
+	}
+}
+
+func sendEmail(cfg *EmailConfig, subject, textBody, htmlBody string, attachments []emailAttachment) error {
+	// Compose message
+	headers := make(map[string]string)
+	headers["From"] = cfg.From
+	headers["To"] = strings.Join(cfg.To, ", ")
+	headers["Subject"] = subject
+	headers["Date"] = time.Now().Format(time.RFC1123Z)
+	headers["MIME-Version"] = "1.0"
+
+	// The plaintext and HTML versions form a multipart/alternative body, so
+	// clients without HTML support show readable text
+	var alternative bytes.Buffer
+	aw := multipart.NewWriter(&alternative)
+	for _, p := range []struct{ contentType, text string }{
+		{"text/plain; charset=UTF-8", textBody},
+		{"text/html; charset=UTF-8", htmlBody},
+	} {
+		part, err := aw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to compose email: %w", err)
+		}
+		qp := quotedprintable.NewWriter(part)
+		qp.Write([]byte(p.text))
+		qp.Close()
+	}
+	if err := aw.Close(); err != nil {
+		return fmt.Errorf("failed to compose email: %w", err)
+	}
+	alternativeType := "multipart/alternative; boundary=" + aw.Boundary()
+
+	var body bytes.Buffer
+	if len(attachments) == 0 {
+		headers["Content-Type"] = alternativeType
+		body.Write(alternative.Bytes())
+	} else {
+		w := multipart.NewWriter(&body)
+		headers["Content-Type"] = "multipart/mixed; boundary=" + w.Boundary()
+
+		part, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {alternativeType}})
+		if err != nil {
+			return fmt.Errorf("failed to compose email: %w", err)
+		}
+		part.Write(alternative.Bytes())
+
+		for _, a := range attachments {
+			part, err := w.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {fmt.Sprintf("%s; name=%q", a.ContentType, a.FileName)},
+				"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", a.FileName)},
+				"Content-Transfer-Encoding": {"base64"},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to compose email: %w", err)
+			}
+			writeBase64Lines(part, a.Data)
+		}
+
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("failed to compose email: %w", err)
+		}
+	}
+
+	message := ""
+	for k, v := range headers {
+		message += fmt.Sprintf("%s: %s\r\n", k, v)
+	}
+	message += "\r\n" + body.String()
+
+	// Send email
+	if err := deliverSMTPWithRetry(cfg, []byte(message)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// writeBase64Lines writes data base64 encoded in 76 character lines, the
+// maximum allowed in MIME bodies
+func writeBase64Lines(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
@@ -0,0 +1,195 @@
+package winbackupchecker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Digest frequencies
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// EmailDigestConfig replaces per-run emails with one summary per day or week
+type EmailDigestConfig struct {
+	Enabled   bool   `json:"enabled"`
+	Frequency string `json:"frequency,omitempty"`
+	SendAt    string `json:"send_at,omitempty"`
+	Weekday   string `json:"weekday,omitempty"`
+	StateFile string `json:"state_file,omitempty"`
+}
+
+// Validate checks if digest configuration is valid
+func (c *EmailDigestConfig) Validate() error {
+	switch c.Frequency {
+	case "", DigestDaily, DigestWeekly:
+	default:
+		return fmt.Errorf("frequency must be daily or weekly")
+	}
+	if c.SendAt != "" {
+		if _, err := parseClock(c.SendAt); err != nil {
+			return fmt.Errorf("send_at: %w", err)
+		}
+	}
+	if c.Weekday != "" {
+		if _, ok := weekdayNames[strings.ToLower(c.Weekday)]; !ok {
+			return fmt.Errorf("unknown weekday %q", c.Weekday)
+		}
+	}
+	return nil
+}
+
+func (c *EmailDigestConfig) frequency() string {
+	if c.Frequency == "" {
+		return DigestDaily
+	}
+	return c.Frequency
+}
+
+func (c *EmailDigestConfig) statePath() string {
+	if c.StateFile == "" {
+		return "email-digest.json"
+	}
+	return c.StateFile
+}
+
+// lastScheduled returns the most recent scheduled send time at or before now,
+// and the length of a digest period
+func (c *EmailDigestConfig) lastScheduled(now time.Time) (time.Time, time.Duration) {
+	sendAt := 8 * time.Hour
+	if c.SendAt != "" {
+		sendAt, _ = parseClock(c.SendAt)
+	}
+
+	now = now.Local()
+	scheduled := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Add(sendAt)
+	if scheduled.After(now) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+	if c.frequency() == DigestDaily {
+		return scheduled, 24 * time.Hour
+	}
+
+	weekday := time.Monday
+	if c.Weekday != "" {
+		weekday = weekdayNames[strings.ToLower(c.Weekday)]
+	}
+	for scheduled.Weekday() != weekday {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+	return scheduled, 7 * 24 * time.Hour
+}
+
+// emailDigestState records when the last digest was sent
+type emailDigestState struct {
+	LastSent time.Time `json:"last_sent"`
+}
+
+// EmailDigest summarizes the runs of one digest period for the email
+// templates
+type EmailDigest struct {
+	Period string
+	AggregateReport
+}
+
+// SendEmailDigest sends a summary of the runs since the previous digest once
+// the scheduled send time has passed, and reports whether one was sent. The
+// body is the latest run's report preceded by success rates and the least
+// stable sets of the period. The send_on_* triggers apply to the worst run of
+// the period; a period without matching runs is skipped. The email is in
+// the language of l.
+func SendEmailDigest(cfg *EmailConfig, runs []RunReport, now time.Time, l *Localizer) (bool, error) {
+	if cfg == nil || !cfg.Enabled || cfg.Digest == nil || !cfg.Digest.Enabled || len(runs) == 0 {
+		return false, nil
+	}
+
+	state, err := loadEmailDigestState(cfg.Digest.statePath())
+	if err != nil {
+		return false, err
+	}
+
+	scheduled, period := cfg.Digest.lastScheduled(now)
+	if !state.LastSent.Before(scheduled) {
+		return false, nil
+	}
+
+	since := state.LastSent
+	if since.IsZero() {
+		since = scheduled.Add(-period)
+	}
+	runs = HistorySince(runs, since)
+
+	var status RunStatus
+	for _, run := range runs {
+		runStatus := NewRunStatus(run.Summary, run.Results)
+		status.HasErrors = status.HasErrors || runStatus.HasErrors
+		status.HasWarnings = status.HasWarnings || runStatus.HasWarnings
+		status.HasIssues = status.HasIssues || runStatus.HasIssues
+		if runStatus.Worst > status.Worst {
+			status.Worst = runStatus.Worst
+		}
+	}
+
+	sent := false
+	if len(runs) > 0 && cfg.ShouldSend(status) {
+		latest := runs[len(runs)-1]
+		agg := AggregateHistory(runs, since, 5)
+
+		data := newEmailData(latest.Summary, latest.Results, status.HasErrors, status.HasWarnings, l)
+		data.Timestamp = latest.Time().Local().Format(time.RFC1123)
+		data.Digest = &EmailDigest{Period: "Daily", AggregateReport: agg}
+		if cfg.Digest.frequency() == DigestWeekly {
+			data.Digest.Period = "Weekly"
+		}
+
+		prefix := cfg.SubjectPrefix
+		if prefix == "" {
+			prefix = l.T("[Backup Alert]")
+		}
+		subject := l.Sprintf("%s %s: %s - %d/%d Runs Successful",
+			prefix, l.T(data.Digest.Period+" Digest"), l.T(status.Label()), agg.SuccessfulRuns, agg.Runs)
+
+		if err := sendEmailReport(cfg, subject, data, latest); err != nil {
+			return false, err
+		}
+		sent = true
+	}
+
+	state.LastSent = now
+	if err := saveEmailDigestState(cfg.Digest.statePath(), state); err != nil {
+		return sent, err
+	}
+	return sent, nil
+}
+
+func loadEmailDigestState(path string) (emailDigestState, error) {
+	var state emailDigestState
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, fmt.Errorf("failed to read email digest state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse email digest state: %w", err)
+	}
+	return state, nil
+}
+
+func saveEmailDigestState(path string, state emailDigestState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal email digest state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write email digest state: %w", err)
+	}
+	return nil
+}
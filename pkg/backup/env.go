@@ -0,0 +1,180 @@
+package winbackupchecker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the names of environment variables overriding config keys
+const EnvPrefix = "WBC_"
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// applyEnvOverrides sets config fields from environment variables named after
+// their JSON keys: prefix plus the uppercased key path joined with
+// underscores, e.g. WBC_MAX_BACKUP_AGE or WBC_NOTIFICATIONS_SLACK_WEBHOOK_URL.
+// Lists are comma separated. Sections missing from the file are created when
+// one of their keys is set. Lists of objects can only be set in the file, and
+// fields tagged env:"-" are skipped.
+func applyEnvOverrides(v any, prefix string) error {
+	_, err := applyEnvToStruct(reflect.ValueOf(v).Elem(), prefix)
+	return err
+}
+
+// applyEnvToStruct reports whether any field of s was set
+func applyEnvToStruct(s reflect.Value, prefix string) (bool, error) {
+	set := false
+	t := s.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := s.Field(i)
+
+		// Embedded structs such as NotifyTriggers share the parent's keys
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			ok, err := applyEnvToStruct(value, prefix)
+			if err != nil {
+				return false, err
+			}
+			set = set || ok
+			continue
+		}
+
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if key == "" || key == "-" || field.Tag.Get("env") == "-" {
+			continue
+		}
+		name := prefix + strings.ToUpper(key)
+
+		ok, err := applyEnvToValue(value, name)
+		if err != nil {
+			return false, err
+		}
+		set = set || ok
+	}
+	return set, nil
+}
+
+func applyEnvToValue(value reflect.Value, name string) (bool, error) {
+	switch value.Kind() {
+	case reflect.Struct:
+		return applyEnvToStruct(value, name+"_")
+
+	case reflect.Pointer:
+		if value.Type().Elem().Kind() == reflect.Struct {
+			if !value.IsNil() {
+				return applyEnvToStruct(value.Elem(), name+"_")
+			}
+			section := reflect.New(value.Type().Elem())
+			ok, err := applyEnvToStruct(section.Elem(), name+"_")
+			if ok {
+				value.Set(section)
+			}
+			return ok, err
+		}
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			return false, nil
+		}
+		elem := reflect.New(value.Type().Elem())
+		if err := setFromEnv(elem.Elem(), name, raw); err != nil {
+			return false, err
+		}
+		value.Set(elem)
+		return true, nil
+
+	default:
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			return false, nil
+		}
+		return true, setFromEnv(value, name, raw)
+	}
+}
+
+// setFromEnv parses raw into a scalar or a list of scalars
+func setFromEnv(value reflect.Value, name, raw string) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s: expected true or false", name)
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: expected an integer", name)
+		}
+		value.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%s: expected a number", name)
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		elemType := value.Type().Elem()
+		fromString := reflect.PointerTo(elemType).Implements(jsonUnmarshalerType)
+		if elemType.Kind() != reflect.String && !fromString {
+			return fmt.Errorf("%s: this list can only be set in the config file", name)
+		}
+		items := reflect.MakeSlice(value.Type(), 0, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			elem := reflect.New(elemType).Elem()
+			if elemType.Kind() == reflect.String {
+				elem.SetString(item)
+			} else {
+				// Entries such as backup_paths accept a plain string in JSON
+				data, _ := json.Marshal(item)
+				if err := elem.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(data); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			}
+			items = reflect.Append(items, elem)
+		}
+		value.Set(items)
+	default:
+		return fmt.Errorf("%s: this setting can only be set in the config file", name)
+	}
+	return nil
+}
+
+// hasEnvPrefix reports whether any environment variable starts with prefix
+func hasEnvPrefix(prefix string) bool {
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultConfigPath returns configs/<name> in the working directory, or next
+// to the executable when the working directory has none, as when started by
+// Task Scheduler from C:\Windows\System32
+func DefaultConfigPath(name string) string {
+	path := filepath.Join("configs", name)
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	if exe, err := os.Executable(); err == nil {
+		candidate := filepath.Join(filepath.Dir(exe), "configs", name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return path
+}
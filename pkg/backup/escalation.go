@@ -0,0 +1,142 @@
+package winbackupchecker
+
+import (
+	"fmt"
+)
+
+// EscalationConfig raises the severity of issues that persist across
+// consecutive runs
+type EscalationConfig struct {
+	Enabled bool             `json:"enabled"`
+	Rules   []EscalationRule `json:"rules"`
+}
+
+// EscalationRule escalates issues of at least From severity to To once the
+// same issue was found on the same backup set in AfterRuns consecutive runs
+type EscalationRule struct {
+	AfterRuns int    `json:"after_runs"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to"`
+}
+
+// Validate checks if escalation configuration is valid
+func (c *EscalationConfig) Validate() error {
+	if len(c.Rules) == 0 {
+		return fmt.Errorf("at least one rule is required")
+	}
+	for i, rule := range c.Rules {
+		from, to, err := rule.severities()
+		if err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if rule.AfterRuns < 2 {
+			return fmt.Errorf("rule %d: after_runs must be at least 2", i+1)
+		}
+		if to <= from {
+			return fmt.Errorf("rule %d: to must be more severe than from", i+1)
+		}
+	}
+	return nil
+}
+
+func (r EscalationRule) severities() (ValidationSeverity, ValidationSeverity, error) {
+	from := SeverityWarning
+	if r.From != "" {
+		var err error
+		if from, err = ParseSeverity(r.From); err != nil {
+			return 0, 0, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	to, err := ParseSeverity(r.To)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid to: %w", err)
+	}
+	return from, to, nil
+}
+
+// ApplyEscalation raises the severity of current issues that were also found
+// on the same backup set in the immediately preceding runs of history, using
+// the most severe matching rule. Issues escalated to error or critical make
+// their set invalid. The original severity and the run count are kept on the
+// issue, so persistence is tracked from the original issue in later runs.
+// Suppressed issues are not escalated. Returns the number of escalated issues.
+func ApplyEscalation(history []RunReport, current []ScanReport, cfg *EscalationConfig) int {
+	// Issue fingerprints of each set in every earlier run, newest first
+	var past []map[string]map[string]bool
+	for i := len(history) - 1; i >= 0; i-- {
+		sets := make(map[string]map[string]bool)
+		for _, scanReport := range history[i].Results {
+			for _, br := range scanReport.Reports {
+				fingerprints := make(map[string]bool)
+				for _, issue := range br.Issues {
+					fingerprints[issueFingerprint(originalIssue(issue))] = true
+					if issue.Count > 0 {
+						// A group stands for every issue with its code
+						fingerprints[groupFingerprint(originalIssue(issue))] = true
+					}
+				}
+				sets[br.BackupDir] = fingerprints
+			}
+		}
+		past = append(past, sets)
+	}
+
+	escalated := 0
+	for i := range current {
+		reports := current[i].Reports
+		for j := range reports {
+			br := &reports[j]
+			for k := range br.Issues {
+				issue := &br.Issues[k]
+				if issue.Suppressed {
+					continue
+				}
+				fingerprint := issueFingerprint(*issue)
+
+				runs := 1
+				for _, sets := range past {
+					if !sets[br.BackupDir][fingerprint] && !sets[br.BackupDir][groupFingerprint(*issue)] {
+						break
+					}
+					runs++
+				}
+
+				severity := issue.Severity
+				for _, rule := range cfg.Rules {
+					from, to, _ := rule.severities()
+					if runs >= rule.AfterRuns && issue.Severity >= from && to > severity {
+						severity = to
+					}
+				}
+				if severity == issue.Severity {
+					continue
+				}
+
+				issue.EscalatedFrom = issue.Severity.String()
+				issue.ConsecutiveRuns = runs
+				issue.Severity = severity
+				if severity >= SeverityError {
+					br.Valid = false
+				}
+				escalated++
+			}
+		}
+	}
+	return escalated
+}
+
+// groupFingerprint identifies the issues that a group of an earlier run,
+// with the same code and severity, stood for
+func groupFingerprint(issue ValidationIssue) string {
+	return issue.Severity.String() + ": group " + issue.Code
+}
+
+// originalIssue returns issue with its severity before escalation
+func originalIssue(issue ValidationIssue) ValidationIssue {
+	if issue.EscalatedFrom != "" {
+		if severity, err := ParseSeverity(issue.EscalatedFrom); err == nil {
+			issue.Severity = severity
+		}
+	}
+	return issue
+}
@@ -0,0 +1,103 @@
+package winbackupchecker
+
+// EventKind is the kind of progress event sent to ScanOptions.OnEvent
+type EventKind string
+
+// Progress events, in the order they occur for each backup set
+const (
+	// EventSetDiscovered is sent for each set of a root about to be
+	// validated, once the root's sets are known
+	EventSetDiscovered EventKind = "set_discovered"
+
+	// EventSetStarted is sent when the validation of a set starts
+	EventSetStarted EventKind = "set_started"
+
+	// EventIssueFound is sent for each issue found. The issues of a set are
+	// sent as it finishes, and those added by the checks across the sets of
+	// a root, such as required_paths, once the root is done.
+	EventIssueFound EventKind = "issue_found"
+
+	// EventSetFinished is sent once a set is validated, or could not be
+	// because the scan stopped or a backup job was writing to it
+	EventSetFinished EventKind = "set_finished"
+)
+
+// Event reports the progress of a scan to programs embedding the checker,
+// so they can show it live instead of waiting for the report
+type Event struct {
+	Kind EventKind
+
+	// Root is the backup root being scanned
+	Root string
+
+	// Set is the path of the backup set, or the root for issues on the root
+	// itself
+	Set string
+
+	// Issue is the issue found, for EventIssueFound. It has its built-in
+	// severity: the severity overrides, baseline and quarantine are applied
+	// to the final report only.
+	Issue *ValidationIssue
+
+	// Report is the report on the set, for EventSetFinished
+	Report *BackupReport
+}
+
+// emit sends event to OnEvent, if set
+func (o ScanOptions) emit(event Event) {
+	if o.OnEvent == nil {
+		return
+	}
+	event.Root = o.root
+	o.OnEvent(event)
+}
+
+// setEvent sends an event on the set at setPath, which is under the
+// snapshot when the root is validated through one. Events carry the live
+// paths, as the report does.
+func (o ScanOptions) setEvent(kind EventKind, setPath string) {
+	if o.OnEvent == nil {
+		return
+	}
+	if o.snapshotRoot != "" {
+		setPath = rebasePath(setPath, o.snapshotRoot, o.root)
+	}
+	o.emit(Event{Kind: kind, Set: setPath})
+}
+
+// finishSet sends the issues of a set and then EventSetFinished
+func (o ScanOptions) finishSet(report BackupReport) {
+	if o.OnEvent == nil {
+		return
+	}
+	// The report goes on to be changed by the checks that follow
+	report.Issues = append([]ValidationIssue(nil), report.Issues...)
+	if o.snapshotRoot != "" {
+		reports := []BackupReport{report}
+		rebaseReports(reports, o.snapshotRoot, o.root)
+		report = reports[0]
+	}
+	for i := range report.Issues {
+		o.emit(Event{Kind: EventIssueFound, Set: report.BackupDir, Issue: &report.Issues[i]})
+	}
+	o.emit(Event{Kind: EventSetFinished, Set: report.BackupDir, Report: &report})
+}
+
+// emitLateIssues sends the issues of reports not sent as their set
+// finished: the first sent[i] issues of reports[i] were, and none of the
+// reports past the end of sent
+func (o ScanOptions) emitLateIssues(reports []BackupReport, sent []int) {
+	if o.OnEvent == nil {
+		return
+	}
+	for i, report := range reports {
+		start := 0
+		if i < len(sent) {
+			start = sent[i]
+		}
+		for j := start; j < len(report.Issues); j++ {
+			issue := report.Issues[j]
+			o.emit(Event{Kind: EventIssueFound, Set: report.BackupDir, Issue: &issue})
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package winbackupchecker
+
+import (
+	"fmt"
+)
+
+// FlappingConfig detects backup sets alternating between valid and invalid
+// across runs
+type FlappingConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Window is how many recent runs, including the current one, are compared
+	Window int `json:"window,omitempty"`
+
+	// Threshold is how many valid/invalid changes within the window mark a
+	// set as unstable
+	Threshold int `json:"threshold,omitempty"`
+}
+
+// Validate checks if flapping configuration is valid
+func (c *FlappingConfig) Validate() error {
+	window, threshold := c.limits()
+	if window < 3 {
+		return fmt.Errorf("window must be at least 3 runs")
+	}
+	if threshold < 2 || threshold >= window {
+		return fmt.Errorf("threshold must be at least 2 and less than window")
+	}
+	return nil
+}
+
+func (c *FlappingConfig) limits() (int, int) {
+	window, threshold := c.Window, c.Threshold
+	if window == 0 {
+		window = 10
+	}
+	if threshold == 0 {
+		threshold = 4
+	}
+	return window, threshold
+}
+
+// FlappingStats describes how often a set changed state in recent runs
+type FlappingStats struct {
+	Runs        int `json:"runs"`
+	InvalidRuns int `json:"invalid_runs"`
+	Transitions int `json:"transitions"`
+}
+
+// DetectFlapping marks backup sets of the current run that changed between
+// valid and invalid at least threshold times within the window of recent
+// runs. Each unstable set gets a single warning, and is counted as a warning
+// rather than an error for notifications, so it does not alternate between
+// failure and recovery alerts. Set validity itself is left unchanged.
+func DetectFlapping(history []RunReport, current *RunReport, cfg *FlappingConfig) int {
+	window, threshold := cfg.limits()
+	if len(history) > window-1 {
+		history = history[len(history)-(window-1):]
+	}
+
+	// Validity of each set in the earlier runs, oldest first
+	past := make(map[string][]bool)
+	for _, run := range history {
+		for _, scanReport := range run.Results {
+			for _, br := range scanReport.Reports {
+				past[br.BackupDir] = append(past[br.BackupDir], br.Valid)
+			}
+		}
+	}
+
+	flapping := 0
+	for i := range current.Results {
+		reports := current.Results[i].Reports
+		for j := range reports {
+			br := &reports[j]
+			states := append(past[br.BackupDir], br.Valid)
+
+			stats := FlappingStats{Runs: len(states)}
+			for k, valid := range states {
+				if !valid {
+					stats.InvalidRuns++
+				}
+				if k > 0 && valid != states[k-1] {
+					stats.Transitions++
+				}
+			}
+			if stats.Transitions < threshold {
+				continue
+			}
+
+			br.Flapping = &stats
+			br.Issues = append(br.Issues, newIssue(IssueFlapping, SeverityWarning,
+				msg("unstable backup set: changed between valid and invalid %d times in the last %d runs (invalid in %d)",
+					stats.Transitions, stats.Runs, stats.InvalidRuns),
+				br.BackupDir,
+				"intermittent failures usually point to an unreliable network share, a failing disk or cable, or scans overlapping backup jobs, rather than corrupt data"))
+			flapping++
+		}
+	}
+	return flapping
+}
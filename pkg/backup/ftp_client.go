@@ -0,0 +1,556 @@
+package winbackupchecker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FTPOptions set how the server of an ftp:// or ftps:// backup path is
+// signed in to. The user is the one of the URL, or anonymous.
+type FTPOptions struct {
+	Password string `json:"password,omitempty" secret:"true"`
+
+	// PasswordCredential names a password stored with credentials set,
+	// used when Password is empty
+	PasswordCredential string `json:"password_credential,omitempty"`
+
+	// TLSMode is TLSModeImplicit, the default of ftps:// paths,
+	// TLSModeSTARTTLS to upgrade the connection with AUTH TLS, or
+	// TLSModeNone, the default of ftp:// paths
+	TLSMode       string `json:"tls_mode,omitempty"`
+	TLSSkipVerify bool   `json:"tls_skip_verify,omitempty"`
+	TLSCAFile     string `json:"tls_ca_file,omitempty"`
+
+	// MaxConnections limits the connections opened to the server, as each
+	// file read at once needs its own
+	MaxConnections int `json:"max_connections,omitempty"`
+}
+
+// Validate checks the TLS mode and connection limit
+func (o *FTPOptions) Validate() error {
+	switch o.TLSMode {
+	case "", TLSModeImplicit, TLSModeSTARTTLS, TLSModeNone:
+	default:
+		return fmt.Errorf("tls_mode must be %q, %q or %q", TLSModeImplicit, TLSModeSTARTTLS, TLSModeNone)
+	}
+	if o.MaxConnections < 0 {
+		return fmt.Errorf("max_connections cannot be negative")
+	}
+	return nil
+}
+
+// ftpTimeout bounds each command and transfer, ftpReadAhead is what a file
+// reads at once, and ftpMaxConnections the default connection limit
+const (
+	ftpTimeout        = 2 * time.Minute
+	ftpReadAhead      = 1 << 20
+	ftpMaxConnections = 4
+)
+
+// ftpClient reads a server over FTP with a pool of connections, as a
+// connection transfers one file at a time
+type ftpClient struct {
+	addr      string
+	user      string
+	password  string
+	tlsMode   string
+	tlsConfig *tls.Config
+
+	// mlst is whether the server lists with MLST and MLSD, whose times and
+	// sizes are exact, rather than LIST
+	mlst bool
+
+	slots chan struct{}
+	mu    sync.Mutex
+	idle  []*ftpConn
+}
+
+// ftpConn is a control connection signed in to the server
+type ftpConn struct {
+	conn net.Conn
+	text *textproto.Conn
+	tls  bool
+}
+
+// ftpError is an FTP command failing with a reply
+type ftpError struct {
+	code    int
+	message string
+}
+
+func (e *ftpError) Error() string {
+	return fmt.Sprintf("%d %s", e.code, e.message)
+}
+
+// Is lets errors.Is match the fs errors the reply stands for. Servers
+// answer 550 for missing files as well as denied ones.
+func (e *ftpError) Is(target error) bool {
+	switch e.code {
+	case 550:
+		return target == fs.ErrNotExist
+	case 530, 532:
+		return target == fs.ErrPermission
+	}
+	return false
+}
+
+// dialFTP signs in to server, given as ftp[s]://[user@]host[:port], with
+// opts
+func dialFTP(server string, opts *FTPOptions) (*ftpClient, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &FTPOptions{}
+	}
+	c := &ftpClient{user: u.User.Username(), password: opts.Password, tlsMode: opts.TLSMode}
+	if c.user == "" {
+		c.user, c.password = "anonymous", "anonymous@"
+	}
+	if c.password == "" && opts.PasswordCredential != "" {
+		if c.password, err = GetCredential(opts.PasswordCredential); err != nil {
+			return nil, err
+		}
+	}
+	if c.tlsMode == "" {
+		c.tlsMode = TLSModeNone
+		if u.Scheme == "ftps" {
+			c.tlsMode = TLSModeImplicit
+		}
+	}
+	port := u.Port()
+	if port == "" {
+		port = "21"
+		if c.tlsMode == TLSModeImplicit {
+			port = "990"
+		}
+	}
+	c.addr = net.JoinHostPort(u.Hostname(), port)
+	if c.tlsMode != TLSModeNone {
+		if c.tlsConfig, err = opts.tlsConfig(u.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	connections := opts.MaxConnections
+	if connections == 0 {
+		connections = ftpMaxConnections
+	}
+	c.slots = make(chan struct{}, connections)
+
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	if _, features, err := conn.reply(211, "FEAT"); err == nil {
+		for _, line := range strings.Split(features, "\n") {
+			if f := strings.Fields(line); len(f) > 0 && strings.EqualFold(f[0], "MLST") {
+				c.mlst = true
+			}
+		}
+	}
+	c.idle = append(c.idle, conn)
+	return c, nil
+}
+
+// tlsConfig builds the TLS settings used to verify host. Sessions are
+// cached so data connections resume the session of their control
+// connection, which many servers require.
+func (o *FTPOptions) tlsConfig(host string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: o.TLSSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
+	}
+	if o.TLSCAFile != "" {
+		pem, err := os.ReadFile(o.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls_ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls_ca_file contains no PEM certificates")
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// connect opens a control connection and signs in
+func (c *ftpClient) connect() (*ftpConn, error) {
+	conn, err := net.DialTimeout("tcp", c.addr, ftpTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if c.tlsMode == TLSModeImplicit {
+		conn = tls.Client(conn, c.tlsConfig)
+	}
+	fc := &ftpConn{conn: conn, text: textproto.NewConn(conn), tls: c.tlsMode == TLSModeImplicit}
+	if err := c.signIn(fc); err != nil {
+		fc.conn.Close()
+		return nil, err
+	}
+	return fc, nil
+}
+
+// signIn reads the greeting of a new connection and signs in on it
+func (c *ftpClient) signIn(fc *ftpConn) error {
+	fc.conn.SetDeadline(time.Now().Add(ftpTimeout))
+	if _, _, err := fc.text.ReadResponse(220); err != nil {
+		return ftpReplyError(err)
+	}
+	if c.tlsMode == TLSModeSTARTTLS {
+		if _, err := fc.cmd(234, "AUTH TLS"); err != nil {
+			return err
+		}
+		fc.conn = tls.Client(fc.conn, c.tlsConfig)
+		fc.text = textproto.NewConn(fc.conn)
+		fc.tls = true
+	}
+
+	code, message, err := fc.reply(0, "USER %s", c.user)
+	if err == nil && code == 331 {
+		code, message, err = fc.reply(0, "PASS %s", c.password)
+	}
+	if err != nil {
+		return err
+	}
+	if code != 230 && code != 202 {
+		return &ftpError{code: code, message: message}
+	}
+
+	if fc.tls {
+		if _, err := fc.cmd(200, "PBSZ 0"); err != nil {
+			return err
+		}
+		if _, err := fc.cmd(200, "PROT P"); err != nil {
+			return err
+		}
+	}
+	_, err = fc.cmd(200, "TYPE I")
+	return err
+}
+
+// cmd sends a command and reads its reply, failing unless its code is
+// expect or starts with it, or expect is 0
+func (fc *ftpConn) cmd(expect int, format string, args ...any) (int, error) {
+	code, _, err := fc.reply(expect, format, args...)
+	return code, err
+}
+
+// reply sends a command and returns its reply as cmd does, with its message
+func (fc *ftpConn) reply(expect int, format string, args ...any) (int, string, error) {
+	fc.conn.SetDeadline(time.Now().Add(ftpTimeout))
+	if _, err := fc.text.Cmd(format, args...); err != nil {
+		return 0, "", err
+	}
+	code, message, err := fc.text.ReadResponse(expect)
+	return code, message, ftpReplyError(err)
+}
+
+// ftpReplyError returns an ftpError for an unexpected reply, and other
+// errors as they are
+func ftpReplyError(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return &ftpError{code: reply.Code, message: reply.Msg}
+	}
+	return err
+}
+
+// Close signs out of the idle connections
+func (c *ftpClient) Close() error {
+	c.mu.Lock()
+	idle := c.idle
+	c.idle = nil
+	c.mu.Unlock()
+	for _, fc := range idle {
+		fc.cmd(0, "QUIT")
+		fc.conn.Close()
+	}
+	return nil
+}
+
+// do runs fn on a free connection, opening one when none is idle. A
+// connection the server dropped while idle is replaced and fn retried.
+func (c *ftpClient) do(fn func(fc *ftpConn) error) error {
+	c.slots <- struct{}{}
+	defer func() { <-c.slots }()
+
+	for {
+		c.mu.Lock()
+		var fc *ftpConn
+		if n := len(c.idle); n > 0 {
+			fc, c.idle = c.idle[n-1], c.idle[:n-1]
+		}
+		c.mu.Unlock()
+		reused := fc != nil
+		if !reused {
+			var err error
+			if fc, err = c.connect(); err != nil {
+				return err
+			}
+		}
+
+		err := fn(fc)
+		var reply *ftpError
+		if err != nil && !errors.As(err, &reply) {
+			fc.conn.Close()
+			if reused {
+				continue
+			}
+			return err
+		}
+		c.mu.Lock()
+		c.idle = append(c.idle, fc)
+		c.mu.Unlock()
+		return err
+	}
+}
+
+// data opens a passive data connection for the next transfer
+func (c *ftpClient) data(fc *ftpConn) (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(fc.conn.RemoteAddr().String())
+	var port string
+	_, message, err := fc.reply(229, "EPSV")
+	if err == nil {
+		// (|||port|)
+		start, end := strings.Index(message, "(|||"), strings.LastIndex(message, "|)")
+		if start < 0 || end < start+4 {
+			return nil, fmt.Errorf("unexpected EPSV reply: %s", message)
+		}
+		port = message[start+4 : end]
+	} else {
+		// Servers that only speak IPv4 may lack EPSV; the address in the
+		// PASV reply is ignored for the one of the control connection, as it
+		// is often private behind NAT
+		if _, message, err = fc.reply(227, "PASV"); err != nil {
+			return nil, err
+		}
+		start, end := strings.Index(message, "("), strings.Index(message, ")")
+		if start < 0 || end < start {
+			return nil, fmt.Errorf("unexpected PASV reply: %s", message)
+		}
+		fields := strings.Split(message[start+1:end], ",")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("unexpected PASV reply: %s", message)
+		}
+		hi, _ := strconv.Atoi(strings.TrimSpace(fields[4]))
+		lo, _ := strconv.Atoi(strings.TrimSpace(fields[5]))
+		port = strconv.Itoa(hi<<8 | lo)
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), ftpTimeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(ftpTimeout))
+	if fc.tls {
+		conn = tls.Client(conn, c.tlsConfig)
+	}
+	return conn, nil
+}
+
+// transfer sends a command reading from a data connection and passes the
+// connection to read, then reads the reply ending the transfer. read
+// returns whether it read all the server sent; when it did not, the
+// transfer is cut off by closing the connection.
+func (c *ftpClient) transfer(fc *ftpConn, read func(r io.Reader) (bool, error), format string, args ...any) error {
+	conn, err := c.data(fc)
+	if err != nil {
+		return err
+	}
+	if _, err := fc.cmd(1, format, args...); err != nil {
+		conn.Close()
+		return err
+	}
+	complete, readErr := read(conn)
+	conn.Close()
+
+	// A cut off transfer is answered with 426 by most servers, and 226 by
+	// those that sent it all before noticing
+	expect := 2
+	if !complete {
+		expect = 0
+	}
+	fc.conn.SetDeadline(time.Now().Add(ftpTimeout))
+	if _, _, err := fc.text.ReadResponse(expect); err != nil {
+		return ftpReplyError(err)
+	}
+	return readErr
+}
+
+// stat returns the metadata of name
+func (c *ftpClient) stat(name string) (*remoteFileInfo, error) {
+	var info *remoteFileInfo
+	err := c.do(func(fc *ftpConn) error {
+		if c.mlst {
+			_, message, err := fc.reply(250, "MLST %s", name)
+			if err != nil {
+				return err
+			}
+			lines := strings.Split(message, "\n")
+			if len(lines) < 2 {
+				return fmt.Errorf("unexpected MLST reply: %s", message)
+			}
+			info = parseMLSx(strings.TrimLeft(lines[1], " "))
+			if info == nil {
+				return fmt.Errorf("unexpected MLST reply: %s", message)
+			}
+			return nil
+		}
+
+		// Without MLST, only files have a size, and folders are found by
+		// changing to them
+		_, message, err := fc.reply(213, "SIZE %s", name)
+		if err == nil {
+			size, _ := strconv.ParseInt(strings.TrimSpace(message), 10, 64)
+			info = &remoteFileInfo{size: size}
+			if _, message, err := fc.reply(213, "MDTM %s", name); err == nil {
+				info.modTime, _ = time.Parse("20060102150405", strings.TrimSpace(message))
+			}
+			return nil
+		}
+		if _, cwdErr := fc.cmd(250, "CWD %s", name); cwdErr != nil {
+			return err
+		}
+		info = &remoteFileInfo{dir: true}
+		return nil
+	})
+	return info, err
+}
+
+// list returns the entries of the folder name
+func (c *ftpClient) list(name string) ([]*remoteFileInfo, error) {
+	var infos []*remoteFileInfo
+	err := c.do(func(fc *ftpConn) error {
+		infos = nil
+		command, parse := "LIST", parseLIST
+		if c.mlst {
+			command, parse = "MLSD", parseMLSx
+		}
+		return c.transfer(fc, func(r io.Reader) (bool, error) {
+			data, err := io.ReadAll(r)
+			for _, line := range strings.Split(string(data), "\n") {
+				if info := parse(strings.TrimRight(line, "\r")); info != nil && info.name != "." && info.name != ".." {
+					infos = append(infos, info)
+				}
+			}
+			return true, err
+		}, "%s %s", command, name)
+	})
+	return infos, err
+}
+
+// read returns up to n bytes of the file name from off
+func (c *ftpClient) read(name string, off int64, n int) ([]byte, error) {
+	var data []byte
+	err := c.do(func(fc *ftpConn) error {
+		if _, err := fc.cmd(350, "REST %d", off); err != nil {
+			return err
+		}
+		return c.transfer(fc, func(r io.Reader) (bool, error) {
+			var err error
+			data, err = io.ReadAll(io.LimitReader(r, int64(n)))
+			return len(data) < n, err
+		}, "RETR %s", name)
+	})
+	return data, err
+}
+
+// parseMLSx parses an entry of MLST or MLSD, facts such as
+// type=file;size=1024;modify=20240601120000; and the name after a space
+func parseMLSx(line string) *remoteFileInfo {
+	facts, name, ok := strings.Cut(line, " ")
+	if !ok {
+		return nil
+	}
+	info := &remoteFileInfo{name: name[strings.LastIndexByte(name, '/')+1:]}
+	for _, fact := range strings.Split(facts, ";") {
+		key, value, _ := strings.Cut(fact, "=")
+		switch strings.ToLower(key) {
+		case "type":
+			switch strings.ToLower(value) {
+			case "file":
+			case "dir", "cdir", "pdir":
+				info.dir = true
+			default:
+				return nil
+			}
+		case "size":
+			info.size, _ = strconv.ParseInt(value, 10, 64)
+		case "modify":
+			info.modTime, _ = time.Parse("20060102150405", value[:min(len(value), 14)])
+		}
+	}
+	return info
+}
+
+// parseLIST parses an entry of LIST in the ls -l form of Unix servers, or
+// the form of IIS:
+//
+//	-rw-r--r--   1 owner group     1024 Jun  1 12:00 Backup Set 2024-06-01
+//	06-01-24  12:00PM       <DIR>          Backup Set 2024-06-01
+func parseLIST(line string) *remoteFileInfo {
+	fields, rest := fieldsAndRest(line, 8)
+	if len(fields) == 8 && rest != "" && strings.ContainsRune("-d", rune(fields[0][0])) {
+		size, _ := strconv.ParseInt(fields[4], 10, 64)
+		info := &remoteFileInfo{name: rest, size: size, dir: fields[0][0] == 'd'}
+		stamp := fields[5] + " " + fields[6] + " " + fields[7]
+		if t, err := time.Parse("Jan 2 2006", stamp); err == nil {
+			info.modTime = t
+		} else if t, err := time.Parse("Jan 2 15:04", stamp); err == nil {
+			// Recent entries leave out the year
+			now := time.Now().UTC()
+			info.modTime = t.AddDate(now.Year(), 0, 0)
+			if info.modTime.After(now.AddDate(0, 0, 1)) {
+				info.modTime = info.modTime.AddDate(-1, 0, 0)
+			}
+		}
+		return info
+	}
+
+	fields, rest = fieldsAndRest(line, 3)
+	if len(fields) == 3 && rest != "" {
+		modTime, err := time.Parse("01-02-06 03:04PM", fields[0]+" "+fields[1])
+		if err != nil {
+			return nil
+		}
+		info := &remoteFileInfo{name: rest, modTime: modTime, dir: fields[2] == "<DIR>"}
+		if !info.dir {
+			info.size, _ = strconv.ParseInt(fields[2], 10, 64)
+		}
+		return info
+	}
+	return nil
+}
+
+// fieldsAndRest splits the first n fields off line, returning the rest with
+// its spaces kept, as names can hold them
+func fieldsAndRest(line string, n int) ([]string, string) {
+	var fields []string
+	rest := line
+	for len(fields) < n {
+		rest = strings.TrimLeft(rest, " ")
+		end := strings.IndexByte(rest, ' ')
+		if rest == "" || end < 0 {
+			return fields, ""
+		}
+		fields = append(fields, rest[:end])
+		rest = rest[end:]
+	}
+	return fields, strings.TrimLeft(rest, " ")
+}
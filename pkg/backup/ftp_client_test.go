@@ -0,0 +1,81 @@
+package winbackupchecker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMLSx(t *testing.T) {
+	tests := []struct {
+		line string
+		want *remoteFileInfo
+	}{
+		{
+			"type=file;size=1024;modify=20240601120000.123;perm=r; Backup files 1.zip",
+			&remoteFileInfo{name: "Backup files 1.zip", size: 1024, modTime: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)},
+		},
+		{
+			"Type=dir;Modify=20240601000000; /backups/PC1/Backup Set 2024-06-01 000000",
+			&remoteFileInfo{name: "Backup Set 2024-06-01 000000", dir: true, modTime: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{"type=OS.unix=symlink; link", nil},
+		{"type=file;size=1", nil},
+	}
+	for _, tt := range tests {
+		got := parseMLSx(tt.line)
+		if !sameRemoteInfo(got, tt.want) {
+			t.Errorf("parseMLSx(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseLIST(t *testing.T) {
+	tests := []struct {
+		line string
+		want *remoteFileInfo
+	}{
+		{
+			"-rw-r--r--   1 owner group     1024 Jun  1  2023 Backup files 1.zip",
+			&remoteFileInfo{name: "Backup files 1.zip", size: 1024, modTime: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			"drwxr-xr-x   2 owner group     4096 Jan 15  2024 Backup Set 2024-01-15 000000",
+			&remoteFileInfo{name: "Backup Set 2024-01-15 000000", size: 4096, dir: true, modTime: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			"06-01-24  12:00PM       <DIR>          Backup Set 2024-06-01 000000",
+			&remoteFileInfo{name: "Backup Set 2024-06-01 000000", dir: true, modTime: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)},
+		},
+		{
+			"06-01-24  09:30AM              2048 Backup files 2.zip",
+			&remoteFileInfo{name: "Backup files 2.zip", size: 2048, modTime: time.Date(2024, 6, 1, 9, 30, 0, 0, time.UTC)},
+		},
+		{"lrwxrwxrwx   1 owner group       10 Jun  1  2023 link -> target", nil},
+		{"total 8", nil},
+	}
+	for _, tt := range tests {
+		got := parseLIST(tt.line)
+		if !sameRemoteInfo(got, tt.want) {
+			t.Errorf("parseLIST(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseLISTRecent(t *testing.T) {
+	// Entries of the last six months have a time in place of the year, and
+	// are never dated in the future
+	info := parseLIST("-rw-r--r--   1 owner group     1024 Jan  1 00:00 a.zip")
+	if info == nil {
+		t.Fatal("entry not parsed")
+	}
+	if now := time.Now().UTC(); info.modTime.After(now.AddDate(0, 0, 1)) || info.modTime.Before(now.AddDate(-1, 0, -1)) {
+		t.Errorf("modTime = %v, want within the last year", info.modTime)
+	}
+}
+
+func sameRemoteInfo(a, b *remoteFileInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.name == b.name && a.size == b.size && a.dir == b.dir && a.modTime.Equal(b.modTime)
+}
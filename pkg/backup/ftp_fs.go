@@ -0,0 +1,69 @@
+package winbackupchecker
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+)
+
+// ftpFS reads a server through the FTP client, for validating backups
+// copied to it offsite
+type ftpFS struct {
+	client *ftpClient
+}
+
+func (s *ftpFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := s.client.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	info.name = path.Base(name)
+	return info, nil
+}
+
+// OpenDir lists the folder at once, as FTP sends listings whole
+func (s *ftpFS) OpenDir(name string) (dirReader, error) {
+	infos, err := s.client.list(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	listed := false
+	list := func() ([]fs.DirEntry, error) {
+		if listed {
+			return nil, io.EOF
+		}
+		listed = true
+		entries := make([]fs.DirEntry, len(infos))
+		for i, info := range infos {
+			entries[i] = info
+		}
+		return entries, nil
+	}
+	return &remoteDir{list: list, close: func() error { return nil }}, nil
+}
+
+func (s *ftpFS) Open(name string) (readableFile, error) {
+	info, err := s.Stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.Unwrap(err)}
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	return &bufferedFile{
+		info:      info,
+		readAhead: ftpReadAhead,
+		fetch: func(off int64, n int) ([]byte, error) {
+			if off >= info.Size() {
+				return nil, io.EOF
+			}
+			return s.client.read(name, off, n)
+		},
+		close: func() error { return nil },
+	}, nil
+}
+
+func (s *ftpFS) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,97 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Gotify priorities used when none are configured; clients show priorities
+// of 8 and above as urgent
+const (
+	gotifyDefaultPriority      = 5
+	gotifyDefaultErrorPriority = 8
+)
+
+// GotifyConfig configures a self-hosted Gotify server notifier
+type GotifyConfig struct {
+	Enabled       bool   `json:"enabled"`
+	ServerURL     string `json:"server_url"`
+	AppToken      string `json:"app_token" secret:"true"`
+	Priority      *int   `json:"priority,omitempty"`
+	ErrorPriority *int   `json:"error_priority,omitempty"`
+	MaxIssues     int    `json:"max_issues,omitempty"`
+	NotifyTriggers
+}
+
+// Validate checks if Gotify configuration is valid
+func (c *GotifyConfig) Validate() error {
+	if c.ServerURL == "" {
+		return fmt.Errorf("server_url is required")
+	}
+	if c.AppToken == "" {
+		return fmt.Errorf("app_token is required")
+	}
+	for _, p := range []*int{c.Priority, c.ErrorPriority} {
+		if p != nil && (*p < 0 || *p > 10) {
+			return fmt.Errorf("priorities must be between 0 and 10")
+		}
+	}
+	if c.MaxIssues < 0 {
+		return fmt.Errorf("max_issues cannot be negative")
+	}
+	return c.NotifyTriggers.Validate()
+}
+
+// GotifyNotifier pushes run summaries to a Gotify server
+type GotifyNotifier struct {
+	cfg *GotifyConfig
+}
+
+func (n *GotifyNotifier) Name() string { return "gotify" }
+
+// Notify pushes a markdown summary of the most severe issues, using the
+// error priority for runs with errors
+func (n *GotifyNotifier) Notify(ctx context.Context, report RunReport) error {
+	status := NewRunStatus(report.Summary, report.Results)
+	if !n.cfg.ShouldSend(status) {
+		return nil
+	}
+
+	maxIssues := n.cfg.MaxIssues
+	if maxIssues == 0 {
+		maxIssues = 10
+	}
+
+	priority := gotifyDefaultPriority
+	if n.cfg.Priority != nil {
+		priority = *n.cfg.Priority
+	}
+	if status.HasErrors {
+		priority = gotifyDefaultErrorPriority
+		if n.cfg.ErrorPriority != nil {
+			priority = *n.cfg.ErrorPriority
+		}
+	}
+
+	summary := report.Summary
+	message := fmt.Sprintf("%d/%d backups valid, %d invalid, %d failed scans\n\n%s",
+		summary.ValidBackups, summary.TotalBackups, summary.InvalidBackups, summary.FailedScans,
+		discordTopIssues(report, maxIssues))
+
+	payload := map[string]any{
+		"title":    fmt.Sprintf("Backup Validation: %s", status.Label()),
+		"message":  message,
+		"priority": priority,
+		"extras": map[string]any{
+			"client::display": map[string]any{"contentType": "text/markdown"},
+		},
+	}
+
+	endpoint := strings.TrimRight(n.cfg.ServerURL, "/") + "/message"
+	headers := map[string]string{"X-Gotify-Key": n.cfg.AppToken}
+	if err := postJSON(ctx, endpoint, payload, headers); err != nil {
+		return fmt.Errorf("failed to send Gotify notification: %w", err)
+	}
+	return nil
+}
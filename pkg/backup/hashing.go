@@ -0,0 +1,174 @@
+package winbackupchecker
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+)
+
+// hashChunkSize is how much of a file is read at once while hashing it
+const hashChunkSize = 1 << 20
+
+// blake3SegmentSize is the part of a file hashed by one goroutine with
+// BLAKE3: a whole power-of-two number of chunks, so each part is a subtree
+const blake3SegmentSize = 256 * blake3ChunkLen
+
+// checksumFile returns the hex digest of a file using algorithm, stopping
+// as soon as ctx is cancelled. The next part of the file is read while the
+// previous one is hashed, and BLAKE3 hashes up to hashWorkers parts at once.
+func checksumFile(ctx context.Context, path, algorithm string, hashWorkers int) (string, error) {
+	file, err := openBackupFile(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var sum []byte
+	if algorithm == "blake3" && hashWorkers > 1 {
+		sum, err = blake3Parallel(file, hashWorkers)
+	} else {
+		h := hashAlgorithms[algorithm].new()
+		err = hashReadAhead(h, file)
+		sum = h.Sum(nil)
+	}
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// hashReadAhead writes r to h, reading the next chunk on another goroutine
+// while the current one is hashed, so storage and the CPU work in parallel
+// on checksums that cannot be split
+func hashReadAhead(h hash.Hash, r io.Reader) error {
+	type chunk struct {
+		data []byte
+		err  error
+	}
+	chunks := make(chan chunk)
+	free := make(chan []byte, 2)
+	free <- make([]byte, hashChunkSize)
+	free <- make([]byte, hashChunkSize)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		defer close(chunks)
+		for {
+			var buf []byte
+			select {
+			case buf = <-free:
+			case <-stop:
+				return
+			}
+			n, err := io.ReadFull(r, buf)
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				err = io.EOF
+			}
+			select {
+			case chunks <- chunk{data: buf[:n], err: err}:
+			case <-stop:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-stopped
+	}()
+
+	for c := range chunks {
+		h.Write(c.data)
+		if c.err == io.EOF {
+			return nil
+		}
+		if c.err != nil {
+			return c.err
+		}
+		free <- c.data[:cap(c.data)]
+	}
+	return nil
+}
+
+// blake3Parallel returns the BLAKE3 digest of r, hashing each segment but
+// the last on up to workers goroutines while the next ones are read. The
+// subtree of each segment is added in order once it is hashed.
+func blake3Parallel(r io.Reader, workers int) ([]byte, error) {
+	h := newBlake3().(*blake3Hasher)
+	const segmentChunks = blake3SegmentSize / blake3ChunkLen
+
+	// The collector adds the subtrees in order, each waiting on its own
+	// channel, with at most workers segments in flight
+	subtrees := make(chan chan [8]uint32, workers)
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for cv := range subtrees {
+			h.writeSubtree(<-cv, segmentChunks)
+		}
+	}()
+
+	free := make(chan []byte, workers+2)
+	buffer := func() []byte {
+		select {
+		case buf := <-free:
+			return buf
+		default:
+			return make([]byte, blake3SegmentSize)
+		}
+	}
+
+	var counter uint64
+	dispatch := func(segment []byte) {
+		cv := make(chan [8]uint32, 1)
+		subtrees <- cv
+		go func(counter uint64) {
+			cv <- blake3Subtree(segment, counter)
+			select {
+			case free <- segment:
+			default:
+			}
+		}(counter)
+		counter += segmentChunks
+	}
+
+	// A full segment is held back until more input follows, since the
+	// last one is part of the root and hashed by h itself
+	var held, last []byte
+	var err error
+	for {
+		buf := buffer()
+		var n int
+		n, err = io.ReadFull(r, buf)
+		if n > 0 && held != nil {
+			dispatch(held)
+			held = nil
+		}
+		if err == nil {
+			held = buf
+			continue
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = nil
+			last = buf[:n]
+		}
+		break
+	}
+	close(subtrees)
+	<-collected
+	if err != nil {
+		return nil, err
+	}
+
+	if held != nil {
+		last = held
+	}
+	h.Write(last)
+	return h.Sum(nil), nil
+}
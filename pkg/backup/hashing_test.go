@@ -0,0 +1,113 @@
+package winbackupchecker
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash"
+	"testing"
+)
+
+// patternInput returns n bytes of the i % 251 pattern the BLAKE3 test
+// vectors hash
+func patternInput(n int) []byte {
+	in := make([]byte, n)
+	for i := range in {
+		in[i] = byte(i % 251)
+	}
+	return in
+}
+
+// sumInPieces hashes in with writes of size bytes
+func sumInPieces(h hash.Hash, in []byte, size int) string {
+	for i := 0; i < len(in); i += size {
+		h.Write(in[i:min(i+size, len(in))])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestBlake3(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want string
+	}{
+		{[]byte(""), "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+		{[]byte("abc"), "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"},
+		{patternInput(1), "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213"},
+		{patternInput(1023), "10108970eeda3eb932baac1428c7a2163b0e924c9a9e25b35bba72b28f70bd11"},
+		{patternInput(1024), "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7"},
+		{patternInput(1025), "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444"},
+		{patternInput(2048), "e776b6028c7cd22a4d0ba182a8bf62205d2ef576467e838ed6f2529b85fba24a"},
+		{patternInput(2049), "5f4d72f40d7a5f82b15ca2b2e44b1de3c2ef86c426c95c1af0b6879522563030"},
+		{patternInput(3072), "b98cb0ff3623be03326b373de6b9095218513e64f1ee2edd2525c7ad1e5cffd2"},
+		{patternInput(3073), "7124b49501012f81cc7f11ca069ec9226cecb8a2c850cfe644e327d22d3e1cd3"},
+		{patternInput(4096), "015094013f57a5277b59d8475c0501042c0b642e531b0a1c8f58d2163229e969"},
+		{patternInput(4097), "9b4052b38f1c5fc8b1f9ff7ac7b27cd242487b3d890d15c96a1c25b8aa0fb995"},
+		{patternInput(5120), "9cadc15fed8b5d854562b26a9536d9707cadeda9b143978f319ab34230535833"},
+		{patternInput(8193), "bab6c09cb8ce8cf459261398d2e7aef35700bf488116ceb94a36d0f5f1b7bc3b"},
+		{patternInput(31744), "62b6960e1a44bcc1eb1a611a8d6235b6b4b78f32e7abc4fb4c6cdcce94895c47"},
+		{patternInput(102400), "bc3e3d41a1146b069abffad3c0d44860cf664390afce4d9661f7902e7943e085"},
+	}
+	for _, tt := range tests {
+		for _, size := range []int{len(tt.in) + 1, 7, 64, 1000} {
+			if got := sumInPieces(newBlake3(), tt.in, size); got != tt.want {
+				t.Errorf("blake3 of %d bytes in writes of %d = %s, want %s", len(tt.in), size, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestBlake3Parallel(t *testing.T) {
+	in := patternInput(5*blake3SegmentSize + 777)
+	for _, n := range []int{0, 10, blake3SegmentSize - 1, blake3SegmentSize, blake3SegmentSize + 1, 2 * blake3SegmentSize, 3*blake3SegmentSize + 5, len(in)} {
+		want := sumInPieces(newBlake3(), in[:n], len(in))
+		for _, workers := range []int{1, 2, 3, 8} {
+			got, err := blake3Parallel(bytes.NewReader(in[:n]), workers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hex.EncodeToString(got) != want {
+				t.Errorf("blake3Parallel of %d bytes with %d workers = %x, want %s", n, workers, got, want)
+			}
+		}
+	}
+}
+
+func TestXXH64(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want string
+	}{
+		{[]byte(""), "ef46db3751d8e999"},
+		{[]byte("a"), "d24ec4f1a98c6e5b"},
+		{[]byte("abc"), "44bc2cf5ad770999"},
+		{[]byte("Nobody inspects the spammish repetition"), "fbcea83c8a378bf1"},
+		{patternInput(3), "e5c7bb4533bc65dd"},
+		{patternInput(4), "ffced8604453cc1e"},
+		{patternInput(8), "884a173614b81b8d"},
+		{patternInput(31), "c346d2b59b4d8ee1"},
+		{patternInput(32), "cbf59c5116ff32b4"},
+		{patternInput(33), "0c535d1acafb8ead"},
+		{patternInput(64), "f7c67301db6713f0"},
+		{patternInput(100), "6ac1e58032166597"},
+		{patternInput(1000), "f306f04aa88b54d3"},
+	}
+	for _, tt := range tests {
+		for _, size := range []int{len(tt.in) + 1, 5, 32} {
+			if got := sumInPieces(newXXH64(), tt.in, size); got != tt.want {
+				t.Errorf("xxh64 of %d bytes in writes of %d = %s, want %s", len(tt.in), size, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestHashReadAhead(t *testing.T) {
+	in := patternInput(3<<20 + 123)
+	want := sumInPieces(newXXH64(), in, len(in))
+	h := newXXH64()
+	if err := hashReadAhead(h, bytes.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		t.Errorf("hashReadAhead = %s, want %s", got, want)
+	}
+}
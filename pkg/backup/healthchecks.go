@@ -0,0 +1,58 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// HealthchecksConfig configures a dead man's switch ping to Healthchecks.io
+// or a compatible service
+type HealthchecksConfig struct {
+	Enabled        bool   `json:"enabled"`
+	PingURL        string `json:"ping_url" secret:"true"`
+	FailOnWarnings bool   `json:"fail_on_warnings"`
+}
+
+// Validate checks if Healthchecks configuration is valid
+func (c *HealthchecksConfig) Validate() error {
+	if c.PingURL == "" {
+		return fmt.Errorf("ping_url is required")
+	}
+	if _, err := url.Parse(c.PingURL); err != nil {
+		return fmt.Errorf("invalid ping_url: %w", err)
+	}
+	return nil
+}
+
+// HealthchecksNotifier pings the check URL after every run, so a scheduled
+// task that stops running is itself detected when the pings stop
+type HealthchecksNotifier struct {
+	cfg *HealthchecksConfig
+}
+
+func (n *HealthchecksNotifier) Name() string { return "healthchecks" }
+
+// Notify pings the check URL on success and its /fail endpoint on failure,
+// with the run summary as the request body
+func (n *HealthchecksNotifier) Notify(ctx context.Context, report RunReport) error {
+	status := NewRunStatus(report.Summary, report.Results)
+	failed := status.HasErrors || (n.cfg.FailOnWarnings && status.HasWarnings)
+
+	pingURL := n.cfg.PingURL
+	if failed {
+		u, _ := url.Parse(pingURL)
+		u.Path = strings.TrimRight(u.Path, "/") + "/fail"
+		pingURL = u.String()
+	}
+
+	payload := map[string]any{
+		"status":  status.Label(),
+		"summary": report.Summary,
+	}
+	if err := postJSON(ctx, pingURL, payload, nil); err != nil {
+		return fmt.Errorf("failed to ping healthcheck: %w", err)
+	}
+	return nil
+}
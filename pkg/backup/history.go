@@ -0,0 +1,102 @@
+package winbackupchecker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// historySeparator separates run reports appended to the JSON log file
+const historySeparator = "\n---\n"
+
+// LoadHistory reads all run reports appended to a JSON log file, oldest first.
+// A missing log file yields an empty history.
+func LoadHistory(path string) ([]RunReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	var runs []RunReport
+	for i, chunk := range strings.Split(string(data), historySeparator) {
+		chunk = strings.TrimSpace(chunk)
+		if chunk == "" {
+			continue
+		}
+
+		var run RunReport
+		if err := json.Unmarshal([]byte(chunk), &run); err != nil {
+			return nil, fmt.Errorf("failed to parse history entry %d: %w", i+1, err)
+		}
+		runs = append(runs, run)
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Time().Before(runs[j].Time())
+	})
+
+	return runs, nil
+}
+
+// HistorySince returns the runs at or after since
+func HistorySince(runs []RunReport, since time.Time) []RunReport {
+	var filtered []RunReport
+	for _, run := range runs {
+		if !run.Time().Before(since) {
+			filtered = append(filtered, run)
+		}
+	}
+	return filtered
+}
+
+// Time returns the parsed run timestamp, or the zero time if it is invalid
+func (r RunReport) Time() time.Time {
+	t, _ := time.Parse(time.RFC3339, r.Timestamp)
+	return t
+}
+
+// Successful reports whether every backup in the run was valid
+func (r RunReport) Successful() bool {
+	return r.Summary.InvalidBackups == 0 && r.Summary.FailedScans == 0
+}
+
+// StateChanges describes each backup set of current that changed between
+// valid and invalid since previous. A set not in previous counts as a change
+// only when invalid, since Windows Backup regularly starts new sets and
+// removes old ones. Without a previous run, every set is taken to have been
+// valid, so existing failures count as changes.
+func StateChanges(previous *RunReport, current RunReport) []string {
+	before := make(map[string]bool)
+	if previous != nil {
+		for _, scanReport := range previous.Results {
+			for _, br := range scanReport.Reports {
+				before[br.BackupDir] = br.Valid
+			}
+		}
+	}
+
+	var changes []string
+	for _, scanReport := range current.Results {
+		for _, br := range scanReport.Reports {
+			wasValid, known := before[br.BackupDir]
+			if !known {
+				wasValid = true
+			}
+			switch {
+			case wasValid && !br.Valid:
+				changes = append(changes, br.BackupDir+": now invalid")
+			case !wasValid && br.Valid:
+				changes = append(changes, br.BackupDir+": valid again")
+			}
+		}
+	}
+
+	sort.Strings(changes)
+	return changes
+}
@@ -0,0 +1,284 @@
+package winbackupchecker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// catalogIndexVersion is bumped whenever the on-disk index layout changes
+const catalogIndexVersion = 1
+
+// IndexHit is a single search result from the catalog index
+type IndexHit struct {
+	BackupSet string `json:"backup_set"`
+	Catalog   string `json:"catalog"`
+	Path      string `json:"path"`
+}
+
+type indexedCatalog struct {
+	BackupSet string    `json:"backup_set"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	Keys      []string  `json:"keys"`
+}
+
+// CatalogIndex is an inverted index from file names to the backup sets whose
+// catalogs reference them. It is updated incrementally: only catalogs whose
+// size or modification time changed are re-parsed.
+type CatalogIndex struct {
+	mu          sync.Mutex
+	path        string
+	dirty       bool
+	counters    cacheCounters
+	Version     int                       `json:"version"`
+	Fingerprint string                    `json:"fingerprint"`
+	Catalogs    map[string]indexedCatalog `json:"catalogs"`
+	Names       map[string][]IndexHit     `json:"names"`
+}
+
+// LoadCatalogIndex loads the index at path, returning an empty index if the
+// file does not exist or was written by another checker version, so upgrades
+// re-index every catalog
+func LoadCatalogIndex(path string) (*CatalogIndex, error) {
+	index := &CatalogIndex{
+		path:        path,
+		Version:     catalogIndexVersion,
+		Fingerprint: CacheFingerprint(),
+		Catalogs:    make(map[string]indexedCatalog),
+		Names:       make(map[string][]IndexHit),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, fmt.Errorf("failed to read index file: %w", err)
+	}
+
+	var stored CatalogIndex
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse index file: %w", err)
+	}
+
+	if stored.Version != catalogIndexVersion || stored.Fingerprint != CacheFingerprint() ||
+		stored.Catalogs == nil || stored.Names == nil {
+		// Saving the empty index replaces the stale one even if nothing changes
+		index.dirty = true
+		return index, nil
+	}
+
+	index.Catalogs = stored.Catalogs
+	index.Names = stored.Names
+	return index, nil
+}
+
+// Update refreshes the index entries for the catalogs of the given backup
+// sets and returns how many catalogs were re-parsed. Catalogs in those sets
+// that no longer exist are dropped. A catalog that cannot be read keeps its
+// previous entries and is retried on the next update; its error is returned
+// with the others once the rest are indexed.
+func (ix *CatalogIndex) Update(ctx context.Context, sets []BackupSetInfo, cache *CatalogCache) (int, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	updated := 0
+	seen := make(map[string]bool)
+	setPaths := make(map[string]bool)
+	var errs []error
+
+	for _, set := range sets {
+		setPaths[set.Path] = true
+
+		for _, catPath := range set.CatalogFiles {
+			seen[catPath] = true
+
+			info, err := statBackup(catPath)
+			if err != nil {
+				continue
+			}
+
+			existing, ok := ix.Catalogs[catPath]
+			if ok && existing.Size == info.Size() && existing.ModTime.Equal(info.ModTime()) {
+				ix.counters.hit(info.Size())
+				continue
+			}
+			ix.counters.miss()
+
+			catalog, err := cache.Get(ctx, catPath)
+			if err != nil {
+				if ctx.Err() != nil {
+					return updated, ctx.Err()
+				}
+				errs = append(errs, fmt.Errorf("failed to index catalog %s: %w", catPath, err))
+				continue
+			}
+
+			ix.removeCatalog(catPath)
+			ix.addCatalog(catPath, set.Path, info, catalog)
+			updated++
+		}
+	}
+
+	for catPath, entry := range ix.Catalogs {
+		if setPaths[entry.BackupSet] && !seen[catPath] {
+			ix.removeCatalog(catPath)
+		}
+	}
+
+	return updated, errors.Join(errs...)
+}
+
+func (ix *CatalogIndex) addCatalog(catPath, setPath string, info os.FileInfo, catalog *Catalog) {
+	keySet := make(map[string]bool)
+	for _, entry := range catalog.Entries {
+		key := indexKey(entry)
+		if key == "" {
+			continue
+		}
+		keySet[key] = true
+		ix.Names[key] = append(ix.Names[key], IndexHit{
+			BackupSet: setPath,
+			Catalog:   catPath,
+			Path:      entry,
+		})
+	}
+
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ix.Catalogs[catPath] = indexedCatalog{
+		BackupSet: setPath,
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+		Keys:      keys,
+	}
+	ix.dirty = true
+}
+
+func (ix *CatalogIndex) removeCatalog(catPath string) {
+	existing, ok := ix.Catalogs[catPath]
+	if !ok {
+		return
+	}
+
+	for _, key := range existing.Keys {
+		hits := ix.Names[key][:0]
+		for _, hit := range ix.Names[key] {
+			if hit.Catalog != catPath {
+				hits = append(hits, hit)
+			}
+		}
+		if len(hits) == 0 {
+			delete(ix.Names, key)
+		} else {
+			ix.Names[key] = hits
+		}
+	}
+
+	delete(ix.Catalogs, catPath)
+	ix.dirty = true
+}
+
+// Search returns all indexed entries whose file name matches pattern. The
+// match is case-insensitive; patterns containing wildcards use glob syntax.
+func (ix *CatalogIndex) Search(pattern string) ([]IndexHit, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	pattern = strings.ToLower(pattern)
+	if !strings.ContainsAny(pattern, "*?[") {
+		hits := append([]IndexHit(nil), ix.Names[pattern]...)
+		sortIndexHits(hits)
+		return hits, nil
+	}
+
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid search pattern: %w", err)
+	}
+
+	var hits []IndexHit
+	for key, keyHits := range ix.Names {
+		if ok, _ := filepath.Match(pattern, key); ok {
+			hits = append(hits, keyHits...)
+		}
+	}
+	sortIndexHits(hits)
+	return hits, nil
+}
+
+// Stats returns catalog lookups since the previous call; a hit means an
+// unchanged catalog was not re-indexed
+func (ix *CatalogIndex) Stats() CacheStats {
+	return ix.counters.snapshot()
+}
+
+// CatalogCount returns the number of catalogs in the index
+func (ix *CatalogIndex) CatalogCount() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.Catalogs)
+}
+
+// Save writes the index back to disk if it changed
+func (ix *CatalogIndex) Save() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if !ix.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %w", err)
+	}
+
+	if dir := filepath.Dir(ix.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create index dir: %w", err)
+		}
+	}
+
+	tmpPath := ix.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write index file: %w", err)
+	}
+	if err := os.Rename(tmpPath, ix.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace index file: %w", err)
+	}
+
+	ix.dirty = false
+	return nil
+}
+
+// indexKey returns the lowercase file name used as the index key for a
+// catalog entry; entries ending in a separator are directories and skipped
+func indexKey(entry string) string {
+	entry = strings.ReplaceAll(entry, `\`, "/")
+	if strings.HasSuffix(entry, "/") {
+		return ""
+	}
+	return strings.ToLower(entry[strings.LastIndex(entry, "/")+1:])
+}
+
+func sortIndexHits(hits []IndexHit) {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].BackupSet != hits[j].BackupSet {
+			return hits[i].BackupSet < hits[j].BackupSet
+		}
+		return hits[i].Path < hits[j].Path
+	})
+}
@@ -0,0 +1,230 @@
+package winbackupchecker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InfluxDBConfig controls publishing run metrics to an InfluxDB endpoint.
+// Bucket/Org/Token target the v2 write API; Database targets the v1 API.
+type InfluxDBConfig struct {
+	Enabled           bool   `json:"enabled"`
+	URL               string `json:"url"`
+	Token             string `json:"token" secret:"true"`
+	Org               string `json:"org"`
+	Bucket            string `json:"bucket"`
+	Database          string `json:"database"`
+	Username          string `json:"username"`
+	Password          string `json:"password" secret:"true"`
+	MeasurementPrefix string `json:"measurement_prefix"`
+}
+
+// Validate checks if InfluxDB configuration is valid
+func (c *InfluxDBConfig) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if c.Bucket == "" && c.Database == "" {
+		return fmt.Errorf("either bucket (v2) or database (v1) is required")
+	}
+	if c.Bucket != "" && c.Org == "" {
+		return fmt.Errorf("org is required when bucket is set")
+	}
+	return nil
+}
+
+// WriteInfluxLineProtocol writes run and per-set metrics in InfluxDB line
+// protocol. Measurements are named <prefix>_run and <prefix>_set.
+func WriteInfluxLineProtocol(w io.Writer, report RunReport, prefix string) error {
+	if prefix == "" {
+		prefix = "backup"
+	}
+
+	ts := time.Now()
+	if parsed, err := time.Parse(time.RFC3339, report.Timestamp); err == nil {
+		ts = parsed
+	}
+	stamp := ts.UnixNano()
+
+	runFields := []string{
+		fmt.Sprintf("total_backups=%di", report.Summary.TotalBackups),
+		fmt.Sprintf("valid_backups=%di", report.Summary.ValidBackups),
+		fmt.Sprintf("invalid_backups=%di", report.Summary.InvalidBackups),
+		fmt.Sprintf("failed_scans=%di", report.Summary.FailedScans),
+	}
+	runFields = append(runFields, phaseTimingFields(report.PhaseTimings)...)
+	for _, name := range sortedCacheNames(report.CacheStats) {
+		stats := report.CacheStats[name]
+		runFields = append(runFields,
+			fmt.Sprintf("cache_%s_hits=%di", name, stats.Hits),
+			fmt.Sprintf("cache_%s_misses=%di", name, stats.Misses),
+			fmt.Sprintf("cache_%s_bytes_skipped=%di", name, stats.BytesSkipped))
+	}
+
+	_, err := fmt.Fprintf(w, "%s_run %s %d\n", escapeInfluxName(prefix), strings.Join(runFields, ","), stamp)
+	if err != nil {
+		return err
+	}
+
+	for _, scanReport := range report.Results {
+		for _, br := range scanReport.Reports {
+			counts := make(map[ValidationSeverity]int)
+			suppressed := 0
+			for _, issue := range br.Issues {
+				if issue.Suppressed {
+					suppressed++
+					continue
+				}
+				counts[issue.Severity]++
+			}
+
+			stats := br.ValidationStats
+			fields := []string{
+				fmt.Sprintf("valid=%t", br.Valid),
+				fmt.Sprintf("issues=%di", len(br.Issues)),
+				fmt.Sprintf("critical=%di", counts[SeverityCritical]),
+				fmt.Sprintf("errors=%di", counts[SeverityError]),
+				fmt.Sprintf("warnings=%di", counts[SeverityWarning]),
+				fmt.Sprintf("suppressed=%di", suppressed),
+				fmt.Sprintf("total_files=%di", stats.TotalFiles),
+				fmt.Sprintf("validated_files=%di", stats.ValidatedFiles),
+				fmt.Sprintf("corrupt_files=%di", stats.CorruptFiles),
+				fmt.Sprintf("size_bytes=%di", stats.TotalSize),
+				fmt.Sprintf("catalog_files=%di", stats.CatalogFiles),
+				fmt.Sprintf("backup_files=%di", stats.BackupFiles),
+			}
+			fields = append(fields, phaseTimingFields(stats.PhaseTimings)...)
+			if stats.NewestBackupTime != nil {
+				fields = append(fields, fmt.Sprintf("age_seconds=%di", int64(ts.Sub(*stats.NewestBackupTime).Seconds())))
+			}
+
+			tags := map[string]string{
+				"root":    scanReport.Root,
+				"machine": MachineName(br.BackupDir),
+				"set":     filepath.Base(br.BackupDir),
+			}
+
+			_, err := fmt.Fprintf(w, "%s_set%s %s %d\n",
+				escapeInfluxName(prefix), formatInfluxTags(tags), strings.Join(fields, ","), stamp)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// PostInfluxMetrics writes run metrics directly to the configured InfluxDB
+func PostInfluxMetrics(ctx context.Context, cfg *InfluxDBConfig, report RunReport) error {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	var body bytes.Buffer
+	if err := WriteInfluxLineProtocol(&body, report, cfg.MeasurementPrefix); err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+
+	endpoint := strings.TrimRight(cfg.URL, "/")
+	query := url.Values{}
+	query.Set("precision", "ns")
+	if cfg.Bucket != "" {
+		endpoint += "/api/v2/write"
+		query.Set("org", cfg.Org)
+		query.Set("bucket", cfg.Bucket)
+	} else {
+		endpoint += "/write"
+		query.Set("db", cfg.Database)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"?"+query.Encode(), &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+cfg.Token)
+	} else if cfg.Username != "" {
+		req.SetBasicAuth(cfg.Username, cfg.Password)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("influxdb returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// phaseTimingFields renders phase timings as <phase>_ms float fields
+func phaseTimingFields(timings PhaseTimings) []string {
+	phases := timings.Phases()
+	sort.Strings(phases)
+
+	fields := make([]string, 0, len(phases))
+	for _, phase := range phases {
+		fields = append(fields, fmt.Sprintf("%s_ms=%g", phase, timings[phase]))
+	}
+	return fields
+}
+
+func sortedCacheNames(stats map[string]CacheStats) []string {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formatInfluxTags renders tags sorted by key, as InfluxDB recommends
+func formatInfluxTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		if tags[k] == "" {
+			continue
+		}
+		b.WriteString(",")
+		b.WriteString(escapeInfluxTag(k))
+		b.WriteString("=")
+		b.WriteString(escapeInfluxTag(tags[k]))
+	}
+	return b.String()
+}
+
+var (
+	influxNameEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper  = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
+
+func escapeInfluxName(s string) string {
+	return influxNameEscaper.Replace(s)
+}
+
+func escapeInfluxTag(s string) string {
+	// Backslashes are literal in tags except before an escaped character, so
+	// normalize Windows separators to avoid ambiguous trailing backslashes
+	s = strings.ReplaceAll(s, `\`, "/")
+	return influxTagEscaper.Replace(s)
+}
@@ -0,0 +1,130 @@
+package winbackupchecker
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// ReportSectionsConfig enables optional report sections
+type ReportSectionsConfig struct {
+	LargestFiles   int `json:"largest_files"`
+	LargestGrowth  int `json:"largest_growth"`
+	DirectoryDepth int `json:"directory_depth"`
+}
+
+// SetInsights holds the optional report sections for a machine's newest set
+type SetInsights struct {
+	ComparedTo         string     `json:"compared_to,omitempty"`
+	LargestFiles       []SizeItem `json:"largest_files,omitempty"`
+	LargestDirectories []SizeItem `json:"largest_directories,omitempty"`
+	LargestGrowth      []SizeItem `json:"largest_growth,omitempty"`
+}
+
+// SizeItem is a file or directory with its size in the backup
+type SizeItem struct {
+	Path         string `json:"path"`
+	Size         int64  `json:"size_bytes"`
+	PreviousSize int64  `json:"previous_size_bytes,omitempty"`
+	Growth       int64  `json:"growth_bytes,omitempty"`
+}
+
+// addInsights attaches largest-item and growth sections to the report of
+// each machine's newest backup set, comparing against the set before it
+func addInsights(backupSets []BackupSetInfo, reports []BackupReport, sections *ReportSectionsConfig) {
+	if sections == nil || (sections.LargestFiles <= 0 && sections.LargestGrowth <= 0) {
+		return
+	}
+
+	depth := sections.DirectoryDepth
+	if depth <= 0 {
+		depth = 3
+	}
+
+	// Compare each machine's newest set with the one before it
+	machines, byMachine := newestSetsByMachine(backupSets, 2)
+
+	for _, machine := range machines {
+		indexes := byMachine[machine]
+		newest := collectZipEntries(backupSets[indexes[0]])
+		insights := &SetInsights{}
+
+		if sections.LargestFiles > 0 {
+			insights.LargestFiles = topSizeItems(newest, nil, sections.LargestFiles, false)
+			insights.LargestDirectories = topSizeItems(directorySizes(newest, depth), nil, sections.LargestFiles, false)
+		}
+
+		if sections.LargestGrowth > 0 && len(indexes) > 1 {
+			previousSet := backupSets[indexes[1]]
+			previous := collectZipEntries(previousSet)
+			insights.ComparedTo = previousSet.Path
+
+			growth := topSizeItems(newest, previous, sections.LargestGrowth, true)
+			dirGrowth := topSizeItems(directorySizes(newest, depth), directorySizes(previous, depth), sections.LargestGrowth, true)
+			insights.LargestGrowth = mergeGrowth(growth, dirGrowth, sections.LargestGrowth)
+		}
+
+		reports[indexes[0]].Insights = insights
+	}
+}
+
+// directorySizes sums file sizes into their directories down to depth levels
+func directorySizes(files map[string]int64, depth int) map[string]int64 {
+	dirs := make(map[string]int64)
+	for file, size := range files {
+		parts := strings.Split(path.Dir(file), "/")
+		if parts[0] == "." {
+			continue
+		}
+		for i := 1; i <= len(parts) && i <= depth; i++ {
+			dirs[strings.Join(parts[:i], "/")+"/"] += size
+		}
+	}
+	return dirs
+}
+
+// topSizeItems returns the n largest items, or the n items that grew most
+// relative to previous when growth is set
+func topSizeItems(current, previous map[string]int64, n int, growth bool) []SizeItem {
+	var items []SizeItem
+	for p, size := range current {
+		item := SizeItem{Path: p, Size: size}
+		if growth {
+			item.PreviousSize = previous[p]
+			item.Growth = size - previous[p]
+			if item.Growth <= 0 {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i].Size, items[j].Size
+		if growth {
+			a, b = items[i].Growth, items[j].Growth
+		}
+		if a != b {
+			return a > b
+		}
+		return items[i].Path < items[j].Path
+	})
+
+	if len(items) > n {
+		items = items[:n]
+	}
+	return items
+}
+
+// mergeGrowth combines file and directory growth into one list of the n
+// largest increases
+func mergeGrowth(files, dirs []SizeItem, n int) []SizeItem {
+	items := append(append([]SizeItem{}, files...), dirs...)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Growth > items[j].Growth
+	})
+	if len(items) > n {
+		items = items[:n]
+	}
+	return items
+}
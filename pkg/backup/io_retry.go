@@ -0,0 +1,104 @@
+package winbackupchecker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// IORetryConfig sets how often a backup file whose read or stat fails with
+// an I/O error is tried again before it is reported unreadable. Network
+// shares drop connections and packets, which says nothing about the file.
+type IORetryConfig struct {
+	// Attempts is the number of tries in all; 1 disables retries
+	Attempts int `json:"attempts,omitempty"`
+
+	// Backoff is the wait before the first retry, doubled before each
+	// following one up to MaxBackoff
+	Backoff    string `json:"backoff,omitempty"`
+	MaxBackoff string `json:"max_backoff,omitempty"`
+}
+
+// Validate checks the attempts and backoff durations
+func (c *IORetryConfig) Validate() error {
+	if c.Attempts < 0 {
+		return fmt.Errorf("attempts cannot be negative")
+	}
+	if c.Backoff != "" {
+		if _, err := ParseDuration(c.Backoff); err != nil {
+			return fmt.Errorf("invalid backoff duration: %w", err)
+		}
+	}
+	if c.MaxBackoff != "" {
+		if _, err := ParseDuration(c.MaxBackoff); err != nil {
+			return fmt.Errorf("invalid max_backoff duration: %w", err)
+		}
+	}
+	return nil
+}
+
+// limits returns the attempts and backoffs, defaulting to 3 attempts
+// starting one second apart, and a nil config to the defaults
+func (c *IORetryConfig) limits() (attempts int, backoff, maxBackoff time.Duration) {
+	attempts, backoff, maxBackoff = 3, time.Second, 30*time.Second
+	if c == nil {
+		return attempts, backoff, maxBackoff
+	}
+	if c.Attempts > 0 {
+		attempts = c.Attempts
+	}
+	if d, _ := ParseDuration(c.Backoff); d > 0 {
+		backoff = d
+	}
+	if d, _ := ParseDuration(c.MaxBackoff); d > 0 {
+		maxBackoff = d
+	}
+	return attempts, backoff, max(backoff, maxBackoff)
+}
+
+// retryIO runs op until it succeeds, fails other than with a transient I/O
+// error, or was tried as often as o.IORetry allows, waiting longer before
+// each retry. Returns the number of tries.
+func (o ScanOptions) retryIO(ctx context.Context, op func() error) (int, error) {
+	attempts, backoff, maxBackoff := o.IORetry.limits()
+	for try := 1; ; try++ {
+		err := op()
+		if err == nil || try >= attempts || !isTransientIOError(err) {
+			return try, err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return try, err
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// isTransientIOError reports whether err is a failure to read or stat a
+// file that may pass when tried again, rather than what was read being
+// invalid or the file missing
+func isTransientIOError(err error) bool {
+	if err == nil || errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return false
+	}
+	var errno syscall.Errno
+	return errors.As(err, &errno) && transientErrnos[errno]
+}
+
+// unreadableIssue reports a file whose reads still failed with a transient
+// I/O error after attempts tries. Unlike corruption, this points to the
+// storage or network, and the file may well be intact.
+func unreadableIssue(severity ValidationSeverity, path string, attempts int, err error) ValidationIssue {
+	issue := newIssue(IssueFileUnreadable, severity,
+		msg("file could not be read after %d attempt(s): %v", attempts, err),
+		path,
+		"the storage or network failed rather than the file; check the disk or share connection and run the check again")
+	issue.Attempts = attempts
+	return issue
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package winbackupchecker
+
+import "syscall"
+
+// transientErrnos are the errors of device failures, interrupted calls and
+// dropped network file system connections
+var transientErrnos = map[syscall.Errno]bool{
+	syscall.EIO:          true,
+	syscall.EAGAIN:       true,
+	syscall.EINTR:        true,
+	syscall.ETIMEDOUT:    true,
+	syscall.ECONNRESET:   true,
+	syscall.ECONNABORTED: true,
+	syscall.ENETDOWN:     true,
+	syscall.ENETRESET:    true,
+	syscall.ENETUNREACH:  true,
+	syscall.EHOSTUNREACH: true,
+	syscall.ESTALE:       true,
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package winbackupchecker
+
+import "syscall"
+
+// transientErrnos are the Windows errors of dropped network connections,
+// device errors and files briefly locked by a backup
+var transientErrnos = map[syscall.Errno]bool{
+	23:   true, // ERROR_CRC
+	32:   true, // ERROR_SHARING_VIOLATION
+	33:   true, // ERROR_LOCK_VIOLATION
+	54:   true, // ERROR_NETWORK_BUSY
+	55:   true, // ERROR_DEV_NOT_EXIST
+	59:   true, // ERROR_UNEXP_NET_ERR
+	64:   true, // ERROR_NETNAME_DELETED
+	121:  true, // ERROR_SEM_TIMEOUT
+	1117: true, // ERROR_IO_DEVICE
+	1231: true, // ERROR_NETWORK_UNREACHABLE
+	1236: true, // ERROR_CONNECTION_ABORTED
+}
@@ -0,0 +1,155 @@
+package winbackupchecker
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// IOStats describes the backup data read while validating a set. Reads
+// taking most of a phase's time point to slow storage; reads taking little
+// of it to decompression and parsing, which more workers help with.
+type IOStats struct {
+	BytesRead int64 `json:"bytes_read"`
+	ReadOps   int64 `json:"read_ops"`
+
+	// ReadMs is the time spent waiting on storage, summed over the files
+	// read concurrently, so it can exceed the elapsed time. Time held back
+	// by the read throttle is not included.
+	ReadMs float64 `json:"read_ms"`
+
+	// MBPerSecond is BytesRead over the time the phases reading took
+	MBPerSecond float64 `json:"mb_per_second"`
+
+	// Phases breaks the totals down by the validation phases that read data
+	Phases map[string]PhaseIO `json:"phases,omitempty"`
+}
+
+// PhaseIO describes the data read in one validation phase
+type PhaseIO struct {
+	BytesRead   int64   `json:"bytes_read"`
+	ReadMs      float64 `json:"read_ms"`
+	ElapsedMs   float64 `json:"elapsed_ms"`
+	MBPerSecond float64 `json:"mb_per_second"`
+}
+
+// StorageShare returns the percentage of the elapsed time spent waiting on
+// storage, capped at 100
+func (p PhaseIO) StorageShare() float64 {
+	if p.ElapsedMs <= 0 {
+		return 0
+	}
+	return min(p.ReadMs/p.ElapsedMs*100, 100)
+}
+
+// ioCounter totals the reads of backupFiles opened with its context, safely
+// across file workers
+type ioCounter struct {
+	bytes     atomic.Int64
+	ops       atomic.Int64
+	readNanos atomic.Int64
+}
+
+// ioSample is the state of an ioCounter at one point
+type ioSample struct {
+	bytes int64
+	ops   int64
+	read  time.Duration
+}
+
+type ioCounterKey struct{}
+
+// withIOCounter returns a context under which backup file reads count
+// against c
+func withIOCounter(ctx context.Context, c *ioCounter) context.Context {
+	return context.WithValue(ctx, ioCounterKey{}, c)
+}
+
+// ioCounterFrom returns the counter of ctx, or nil
+func ioCounterFrom(ctx context.Context) *ioCounter {
+	c, _ := ctx.Value(ioCounterKey{}).(*ioCounter)
+	return c
+}
+
+// add records a read of n bytes that took d
+func (c *ioCounter) add(n int, d time.Duration) {
+	if c == nil {
+		return
+	}
+	c.bytes.Add(int64(n))
+	c.ops.Add(1)
+	c.readNanos.Add(int64(d))
+}
+
+func (c *ioCounter) sample() ioSample {
+	if c == nil {
+		return ioSample{}
+	}
+	return ioSample{bytes: c.bytes.Load(), ops: c.ops.Load(), read: time.Duration(c.readNanos.Load())}
+}
+
+// recordPhase adds the reads since before to stats as phase, which took
+// elapsed
+func (s *IOStats) recordPhase(phase string, before, after ioSample, elapsed time.Duration) {
+	if after.bytes == before.bytes {
+		return
+	}
+	s.add(phase, after.ops-before.ops, PhaseIO{
+		BytesRead: after.bytes - before.bytes,
+		ReadMs:    durationMs(after.read - before.read),
+		ElapsedMs: durationMs(elapsed),
+	})
+}
+
+// add adds the reads of a phase to the totals
+func (s *IOStats) add(phase string, ops int64, p PhaseIO) {
+	if s.Phases == nil {
+		s.Phases = make(map[string]PhaseIO)
+	}
+	sum := s.Phases[phase]
+	sum.BytesRead += p.BytesRead
+	sum.ReadMs += p.ReadMs
+	sum.ElapsedMs += p.ElapsedMs
+	sum.MBPerSecond = throughput(sum.BytesRead, sum.ElapsedMs)
+	s.Phases[phase] = sum
+
+	s.BytesRead += p.BytesRead
+	s.ReadOps += ops
+	s.ReadMs += p.ReadMs
+	var elapsedMs float64
+	for _, p := range s.Phases {
+		elapsedMs += p.ElapsedMs
+	}
+	s.MBPerSecond = throughput(s.BytesRead, elapsedMs)
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// throughput returns bytes over ms in megabytes per second
+func throughput(bytes int64, ms float64) float64 {
+	if ms <= 0 {
+		return 0
+	}
+	return float64(bytes) / (1 << 20) / (ms / 1000)
+}
+
+// TotalIO sums the IO statistics of every backup set of a run
+func TotalIO(results []ScanReport) IOStats {
+	var total IOStats
+	for _, scanReport := range results {
+		for _, br := range scanReport.Reports {
+			setIO := br.ValidationStats.IO
+			if setIO == nil {
+				continue
+			}
+			// Operations are not kept per phase, so they are added once
+			total.ReadOps += setIO.ReadOps
+			for phase, p := range setIO.Phases {
+				total.add(phase, 0, p)
+			}
+		}
+	}
+	return total
+}
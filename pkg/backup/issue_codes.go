@@ -0,0 +1,151 @@
+package winbackupchecker
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Issue codes identify the kind of each validation issue, independent of its
+// message, for severity_overrides
+const (
+	// Backup roots
+	IssueRootScanFailed         = "root_scan_failed"
+	IssueNoBackupRoots          = "no_backup_roots"
+	IssueMissingMediaID         = "missing_media_id"
+	IssueInvalidMediaID         = "invalid_media_id"
+	IssueExpectedMachineMissing = "expected_machine_missing"
+	IssueRecycleBin             = "recycle_bin"
+	IssueLiveData               = "live_data"
+	IssueNotFinished            = "not_finished"
+
+	// Structure and completeness
+	IssueMissingCatalogsFolder = "missing_catalogs_folder"
+	IssueNoCatalogFiles        = "no_catalog_files"
+	IssueNoBackupFiles         = "no_backup_files"
+	IssueFewFiles              = "few_files"
+	IssueSmallSet              = "small_set"
+	IssueMissingBackupFiles    = "missing_backup_files"
+	IssueLinkInSet             = "link_in_set"
+	IssueSetListingTruncated   = "set_listing_truncated"
+	IssueUnfinishedUpload      = "unfinished_upload"
+
+	// Content
+	IssueCorruptBackupFile     = "corrupt_backup_file"
+	IssueCorruptCatalog        = "corrupt_catalog"
+	IssueCatalogUnparsable     = "catalog_unparsable"
+	IssueFileUnreadable        = "file_unreadable"
+	IssueUnknownCatalogVersion = "unknown_catalog_version"
+	IssueContentDeferred       = "content_deferred"
+	IssueContentSkipped        = "content_skipped"
+	IssueSetTimeout            = "set_timeout"
+
+	// Age
+	IssueBackupTooRecent = "backup_too_recent"
+	IssueBackupTooOld    = "backup_too_old"
+
+	// Optional checks
+	IssueRequiredPathMissing     = "required_path_missing"
+	IssueForbiddenContent        = "forbidden_content"
+	IssueManifestUnreadable      = "manifest_unreadable"
+	IssueManifestRepaired        = "manifest_repaired"
+	IssueManifestMismatch        = "manifest_mismatch"
+	IssueParityFailed            = "parity_failed"
+	IssueParityGenerated         = "parity_generated"
+	IssueSampleRestoreFailed     = "sample_restore_failed"
+	IssueSampleRestoreVerified   = "sample_restore_verified"
+	IssueSetBusy                 = "set_busy"
+	IssueWrittenDuringValidation = "written_during_validation"
+	IssueEngineRunning           = "engine_running"
+	IssueUSNJournalUnreadable    = "usn_journal_unreadable"
+	IssueUSNJournalReset         = "usn_journal_reset"
+	IssueChangesOutsideWindow    = "changes_outside_window"
+	IssueFlapping                = "flapping"
+)
+
+// issueCodes holds every issue code, for validating severity_overrides
+var issueCodes = map[string]bool{
+	IssueRootScanFailed: true, IssueNoBackupRoots: true, IssueMissingMediaID: true,
+	IssueInvalidMediaID: true, IssueExpectedMachineMissing: true, IssueRecycleBin: true,
+	IssueLiveData: true, IssueNotFinished: true,
+	IssueMissingCatalogsFolder: true, IssueNoCatalogFiles: true, IssueNoBackupFiles: true,
+	IssueFewFiles: true, IssueSmallSet: true, IssueMissingBackupFiles: true, IssueLinkInSet: true,
+	IssueCorruptBackupFile: true, IssueCorruptCatalog: true, IssueCatalogUnparsable: true,
+	IssueUnknownCatalogVersion: true, IssueContentDeferred: true, IssueContentSkipped: true,
+	IssueSetTimeout: true, IssueFileUnreadable: true, IssueSetListingTruncated: true,
+	IssueUnfinishedUpload: true, IssueBackupTooRecent: true, IssueBackupTooOld: true,
+	IssueRequiredPathMissing: true, IssueForbiddenContent: true, IssueManifestUnreadable: true,
+	IssueManifestRepaired: true, IssueManifestMismatch: true, IssueParityFailed: true,
+	IssueParityGenerated: true, IssueSampleRestoreFailed: true, IssueSampleRestoreVerified: true,
+	IssueSetBusy: true, IssueWrittenDuringValidation: true, IssueEngineRunning: true,
+	IssueUSNJournalUnreadable: true, IssueUSNJournalReset: true, IssueChangesOutsideWindow: true,
+	IssueFlapping: true,
+}
+
+// newIssue creates a validation issue identified by code. The message is
+// kept with its arguments, so it can be shown in the configured language.
+func newIssue(code string, severity ValidationSeverity, message localText, path, suggestion string) ValidationIssue {
+	issue := NewValidationIssue(severity, message.String(), path, suggestion)
+	issue.Code = code
+	issue.text = message
+	return issue
+}
+
+// SeverityOverrides map issue codes to the severity their issues get instead
+// of the built-in one
+type SeverityOverrides map[string]string
+
+// Validate checks that every code is known and every severity valid
+func (o SeverityOverrides) Validate() error {
+	codes := make([]string, 0, len(o))
+	for code := range o {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		if !issueCodes[code] {
+			return fmt.Errorf("unknown issue code %q", code)
+		}
+		if _, err := ParseSeverity(o[code]); err != nil {
+			return fmt.Errorf("%s: %w", code, err)
+		}
+	}
+	return nil
+}
+
+// severity returns the severity of issue once overridden
+func (o SeverityOverrides) severity(issue ValidationIssue) ValidationSeverity {
+	name, ok := o[issue.Code]
+	if !ok {
+		return issue.Severity
+	}
+	// Validated when the config was loaded
+	severity, _ := ParseSeverity(name)
+	return severity
+}
+
+// ApplySeverityOverrides sets the overridden severity on matching issues,
+// keeping the built-in one as OverriddenFrom. Validity follows from the new
+// severities once ApplyFailOn runs. Returns the number of issues changed.
+func ApplySeverityOverrides(reports []ScanReport, overrides SeverityOverrides) int {
+	if len(overrides) == 0 {
+		return 0
+	}
+
+	changed := 0
+	for i := range reports {
+		for j := range reports[i].Reports {
+			issues := reports[i].Reports[j].Issues
+			for k := range issues {
+				severity := overrides.severity(issues[k])
+				if severity == issues[k].Severity {
+					continue
+				}
+				issues[k].OverriddenFrom = issues[k].Severity.String()
+				issues[k].Severity = severity
+				changed++
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,123 @@
+package winbackupchecker
+
+import (
+	"fmt"
+)
+
+// IssueGroupingConfig collapses the many issues a backup set can have with
+// the same code, such as one per corrupt ZIP file, into a single issue
+type IssueGroupingConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// MinIssues is how many issues with the same code and severity a set
+	// needs before they are grouped
+	MinIssues int `json:"min_issues,omitempty"`
+
+	// SampleSize is how many affected paths a group lists
+	SampleSize int `json:"sample_size,omitempty"`
+}
+
+// Validate checks if grouping configuration is valid
+func (c *IssueGroupingConfig) Validate() error {
+	minIssues, sampleSize := c.limits()
+	if minIssues < 2 {
+		return fmt.Errorf("min_issues must be at least 2")
+	}
+	if sampleSize < 1 {
+		return fmt.Errorf("sample_size must be at least 1")
+	}
+	return nil
+}
+
+func (c *IssueGroupingConfig) limits() (int, int) {
+	minIssues, sampleSize := c.MinIssues, c.SampleSize
+	if minIssues == 0 {
+		minIssues = 5
+	}
+	if sampleSize == 0 {
+		sampleSize = 10
+	}
+	return minIssues, sampleSize
+}
+
+// issueGroupKey identifies the issues grouped together: same code, same
+// severity and both suppressed or not, so grouping changes neither the
+// validity of a set nor what is alerted
+type issueGroupKey struct {
+	code       string
+	severity   ValidationSeverity
+	suppressed bool
+}
+
+// ApplyIssueGrouping replaces the issues of each set that share a code,
+// severity and suppression with a single issue once there are at least
+// MinIssues of them. The group keeps the first issue, with the count and a
+// sample of the affected paths; issues without a code are never grouped.
+// Returns the number of issues folded into groups.
+func ApplyIssueGrouping(reports []ScanReport, cfg *IssueGroupingConfig) int {
+	if cfg == nil || !cfg.Enabled {
+		return 0
+	}
+	minIssues, sampleSize := cfg.limits()
+
+	folded := 0
+	for i := range reports {
+		for j := range reports[i].Reports {
+			br := &reports[i].Reports[j]
+
+			counts := make(map[issueGroupKey]int)
+			for _, issue := range br.Issues {
+				if issue.Code != "" && issue.Count == 0 {
+					counts[groupKey(issue)]++
+				}
+			}
+
+			groups := make(map[issueGroupKey]int)
+			issues := make([]ValidationIssue, 0, len(br.Issues))
+			for _, issue := range br.Issues {
+				key := groupKey(issue)
+				if issue.Code == "" || issue.Count != 0 || counts[key] < minIssues {
+					issues = append(issues, issue)
+					continue
+				}
+
+				k, ok := groups[key]
+				if !ok {
+					// The first issue stands for the group, in its place
+					groups[key] = len(issues)
+					issue.Count = counts[key]
+					if issue.Path != "" {
+						issue.AffectedPaths = []string{issue.Path}
+					}
+					issue.Path = br.BackupDir
+					issue.Message, issue.text = groupMessage(issue, counts[key])
+					issues = append(issues, issue)
+					continue
+				}
+
+				group := &issues[k]
+				if issue.Path != "" && len(group.AffectedPaths) < sampleSize {
+					group.AffectedPaths = append(group.AffectedPaths, issue.Path)
+				}
+				folded++
+			}
+			br.Issues = issues
+		}
+	}
+	return folded
+}
+
+func groupKey(issue ValidationIssue) issueGroupKey {
+	return issueGroupKey{code: issue.Code, severity: issue.Severity, suppressed: issue.Suppressed}
+}
+
+// groupMessage returns the message of a group of count issues represented by
+// issue, in English and for localization
+func groupMessage(issue ValidationIssue, count int) (string, localText) {
+	var first any = issue.Message
+	if issue.text.format != "" {
+		first = issue.text
+	}
+	text := msg("%s (and %d more like it)", first, count-1)
+	return text.String(), text
+}
@@ -0,0 +1,252 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// LinkConfig sets which symbolic links, junctions and mount points are
+// followed while looking for backup sets and their files. By default none
+// are: Windows Backup writes no links, and one pointing out of a set would
+// validate files that are not part of the backup.
+type LinkConfig struct {
+	// Follow follows symbolic links and junctions
+	Follow bool `json:"follow,omitempty"`
+
+	// FollowMountPoints follows mount points onto other volumes
+	FollowMountPoints bool `json:"follow_mount_points,omitempty"`
+}
+
+// Kinds of links
+const (
+	LinkSymlink    = "symlink"
+	LinkJunction   = "junction"
+	LinkMountPoint = "mount point"
+)
+
+// SetLink is a link found inside a backup set
+type SetLink struct {
+	Path   string
+	Kind   string
+	Target string
+
+	// Followed is set when the files behind the link were included in the
+	// set, Broken when its target is missing and Cycle when it leads to a
+	// folder already walked, such as one holding the link
+	Followed bool
+	Broken   bool
+	Cycle    bool
+}
+
+// follows reports whether links of kind are followed
+func (c *LinkConfig) follows(kind string) bool {
+	if c == nil {
+		return false
+	}
+	if kind == LinkMountPoint {
+		return c.FollowMountPoints
+	}
+	return c.Follow
+}
+
+// isDir reports whether entry of the folder holding path is a folder to look
+// into: a plain folder, or a link to one that c follows
+func (c *LinkConfig) isDir(path string, entry fs.DirEntry) bool {
+	info, err := entry.Info()
+	if err != nil {
+		return false
+	}
+	if kind, _ := classifyLink(path, info, nil); kind != "" {
+		return c.follows(kind) && dirExists(path)
+	}
+	return info.IsDir()
+}
+
+// walkBatchSize is how many entries of a folder are read at once while
+// walking a set
+const walkBatchSize = 1024
+
+// setWalker walks the files of a backup set, following the links allowed by
+// links and recording every link it meets
+type setWalker struct {
+	ctx   context.Context
+	links *LinkConfig
+	fn    func(path string, info fs.FileInfo)
+
+	// maxFiles stops the walk after that many files when positive
+	maxFiles  int
+	files     int
+	truncated bool
+
+	// root is the set folder with links resolved, and visited holds the
+	// folders walked, so a link leading back to one, or to a folder holding
+	// the set, is not followed into a loop. Without links to follow there
+	// can be no loop, and visited stays empty.
+	root    string
+	visited []fs.FileInfo
+	found   []SetLink
+}
+
+// walkSetFiles calls fn for each file under setPath and returns the links
+// found. Folders are read a batch of entries at a time, and the files of a
+// folder are passed before those of its subfolders, so a set holding
+// millions of files is never listed in memory at once. The walk stops once
+// ctx ends or after maxFiles files when maxFiles is positive, and truncated
+// reports whether it did. Folders that cannot be read are skipped.
+func walkSetFiles(ctx context.Context, setPath string, links *LinkConfig, maxFiles int, fn func(path string, info fs.FileInfo)) (found []SetLink, truncated bool) {
+	root, err := statBackup(setPath)
+	if err != nil {
+		return nil, false
+	}
+	w := &setWalker{ctx: ctx, links: links, fn: fn, maxFiles: maxFiles, root: setPath}
+	if links.follows(LinkSymlink) || links.follows(LinkMountPoint) {
+		w.visited = []fs.FileInfo{root}
+	}
+	if resolved, err := filepath.EvalSymlinks(setPath); err == nil {
+		w.root = resolved
+	}
+	w.walk(setPath, root)
+	return w.found, w.truncated
+}
+
+// walkDir is a folder left to walk once the files around it are done
+type walkDir struct {
+	path string
+	info fs.FileInfo
+}
+
+func (w *setWalker) walk(dir string, dirInfo fs.FileInfo) {
+	f, err := openBackupDir(dir)
+	if err != nil {
+		return
+	}
+	var subdirs []walkDir
+	for !w.truncated {
+		if w.ctx.Err() != nil {
+			w.truncated = true
+			break
+		}
+		entries, err := f.ReadDir(walkBatchSize)
+		for _, entry := range entries {
+			if w.maxFiles > 0 && w.files >= w.maxFiles {
+				w.truncated = true
+				break
+			}
+			path := filepath.Join(dir, entry.Name())
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+
+			if kind, target := classifyLink(path, info, dirInfo); kind != "" {
+				if info = w.follow(path, kind, target); info == nil {
+					continue
+				}
+			} else if info.IsDir() {
+				if w.seen(info) {
+					continue
+				}
+				if w.visited != nil {
+					w.visited = append(w.visited, info)
+				}
+			}
+
+			if info.IsDir() {
+				subdirs = append(subdirs, walkDir{path: path, info: info})
+			} else {
+				w.files++
+				w.fn(path, info)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	f.Close()
+
+	// Subfolders in lexical order, so a truncated walk stops at the same
+	// place every run
+	sort.Slice(subdirs, func(i, j int) bool { return subdirs[i].path < subdirs[j].path })
+	for _, sub := range subdirs {
+		if w.truncated {
+			return
+		}
+		w.walk(sub.path, sub.info)
+	}
+}
+
+// follow records the link at path and returns what it points to when it is
+// followed, or nil
+func (w *setWalker) follow(path, kind, target string) fs.FileInfo {
+	link := SetLink{Path: path, Kind: kind, Target: target}
+	defer func() { w.found = append(w.found, link) }()
+
+	if !w.links.follows(kind) {
+		return nil
+	}
+	info, err := statBackup(path)
+	if err != nil {
+		link.Broken = true
+		return nil
+	}
+	if info.IsDir() {
+		// Files read by a built-in client have no identity to compare, so
+		// a loop could not be told
+		if _, _, remote := remoteFor(path); remote {
+			return nil
+		}
+		resolved, err := filepath.EvalSymlinks(path)
+		if w.seen(info) || err == nil && isSubPath(resolved, w.root) {
+			link.Cycle = true
+			return nil
+		}
+		w.visited = append(w.visited, info)
+	}
+	link.Followed = true
+	return info
+}
+
+// seen reports whether the folder of info was walked already
+func (w *setWalker) seen(info fs.FileInfo) bool {
+	for _, visited := range w.visited {
+		if os.SameFile(visited, info) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkIssue reports a link found inside a backup set
+func linkIssue(link SetLink) ValidationIssue {
+	what := link.Kind
+	if link.Target != "" {
+		what = fmt.Sprintf("%s to %s", link.Kind, link.Target)
+	}
+
+	switch {
+	case link.Followed:
+		return newIssue(IssueLinkInSet, SeverityInfo,
+			msg("followed %s", what),
+			link.Path,
+			"the files it points to were validated as part of the set")
+	case link.Broken:
+		return newIssue(IssueLinkInSet, SeverityInfo,
+			msg("did not follow %s: the target does not exist", what),
+			link.Path,
+			"restore the target, or remove the link")
+	case link.Cycle:
+		return newIssue(IssueLinkInSet, SeverityInfo,
+			msg("did not follow %s: it leads back into folders already checked", what),
+			link.Path,
+			"remove the link; the files it leads to are validated once already")
+	default:
+		return newIssue(IssueLinkInSet, SeverityInfo,
+			msg("did not follow %s", what),
+			link.Path,
+			"set follow or follow_mount_points under links to validate the files it points to, or remove the link if they are not part of the backup")
+	}
+}
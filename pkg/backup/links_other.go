@@ -0,0 +1,31 @@
+//go:build !windows
+
+package winbackupchecker
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// classifyLink returns the kind of link at path and its target, or "" for
+// plain files and folders. A folder on another device than parent, the
+// folder holding it, is a mount point.
+func classifyLink(path string, info, parent fs.FileInfo) (kind, target string) {
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, _ := os.Readlink(path)
+		return LinkSymlink, target
+	}
+	if info.IsDir() && parent != nil && deviceOf(info) != deviceOf(parent) {
+		return LinkMountPoint, ""
+	}
+	return "", ""
+}
+
+// deviceOf returns the device holding the file of info
+func deviceOf(info fs.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Dev)
+	}
+	return 0
+}
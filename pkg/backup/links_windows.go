@@ -0,0 +1,41 @@
+//go:build windows
+
+package winbackupchecker
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// classifyLink returns the kind of reparse point at path and its target, or
+// "" for plain files and folders. Junctions to a volume rather than a folder
+// are mount points. Other reparse points, such as cloud file placeholders
+// and deduplicated files, hold their data and count as plain files.
+func classifyLink(path string, info, parent fs.FileInfo) (kind, target string) {
+	if info.Mode()&(fs.ModeSymlink|fs.ModeIrregular) == 0 {
+		return "", ""
+	}
+	target, err := os.Readlink(path)
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		return LinkSymlink, target
+	case err != nil:
+		return "", ""
+	case isVolumePath(target):
+		return LinkMountPoint, target
+	default:
+		return LinkJunction, target
+	}
+}
+
+// isVolumePath reports whether target names a volume by its GUID, as the
+// targets of mount points do
+func isVolumePath(target string) bool {
+	for _, prefix := range []string{`\\?\Volume{`, `\??\Volume{`} {
+		if len(target) >= len(prefix) && strings.EqualFold(target[:len(prefix)], prefix) {
+			return true
+		}
+	}
+	return false
+}
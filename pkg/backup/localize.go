@@ -0,0 +1,204 @@
+package winbackupchecker
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultLanguage is the language of the messages in the source code and
+// in the JSON reports
+const DefaultLanguage = "en"
+
+// locales holds a message catalog per language, mapping English texts to
+// their translation
+//
+//go:embed locales/*.json
+var locales embed.FS
+
+// Languages returns the codes of the languages messages can be shown in
+func Languages() []string {
+	languages := []string{DefaultLanguage}
+	entries, _ := locales.ReadDir("locales")
+	for _, entry := range entries {
+		languages = append(languages, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Localizer translates the messages shown to people: issue messages and
+// suggestions, console output and emails. Reports and logs stay in English,
+// so they compare across runs whatever the language. A nil Localizer shows
+// English.
+type Localizer struct {
+	language string
+	catalog  map[string]string
+}
+
+// NewLocalizer returns a localizer for a language code such as "de". An
+// empty code selects English.
+func NewLocalizer(language string) (*Localizer, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" || language == DefaultLanguage {
+		return &Localizer{language: DefaultLanguage}, nil
+	}
+
+	data, err := locales.ReadFile(path.Join("locales", language+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported language %q (supported: %s)", language, strings.Join(Languages(), ", "))
+	}
+	catalog := make(map[string]string)
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse %s messages: %w", language, err)
+	}
+	return &Localizer{language: language, catalog: catalog}, nil
+}
+
+// Language returns the language code
+func (l *Localizer) Language() string {
+	if l == nil {
+		return DefaultLanguage
+	}
+	return l.language
+}
+
+// T translates text, or returns it unchanged when the catalog lacks it
+func (l *Localizer) T(text string) string {
+	if l == nil {
+		return text
+	}
+	if translated, ok := l.catalog[text]; ok {
+		return translated
+	}
+	return text
+}
+
+// Sprintf formats the translation of format
+func (l *Localizer) Sprintf(format string, args ...any) string {
+	return l.format(msg(format, args...))
+}
+
+// Printf prints the translation of format to stdout
+func (l *Localizer) Printf(format string, args ...any) {
+	fmt.Print(l.Sprintf(format, args...))
+}
+
+// Fprintf writes the translation of format to w
+func (l *Localizer) Fprintf(w io.Writer, format string, args ...any) {
+	fmt.Fprint(w, l.Sprintf(format, args...))
+}
+
+// format translates a message and the messages among its arguments
+func (l *Localizer) format(m localText) string {
+	if len(m.args) == 0 {
+		return l.T(m.format)
+	}
+	args := make([]any, len(m.args))
+	for i, arg := range m.args {
+		if text, ok := arg.(localText); ok {
+			arg = l.format(text)
+		}
+		args[i] = arg
+	}
+	return fmt.Sprintf(l.T(m.format), args...)
+}
+
+// Issue returns a copy of issue with its message and suggestion translated
+func (l *Localizer) Issue(issue ValidationIssue) ValidationIssue {
+	if l == nil || l.language == DefaultLanguage {
+		return issue
+	}
+	if issue.text.format != "" {
+		issue.Message = l.format(issue.text)
+	} else {
+		// Loaded from a report, where only fixed messages can be translated
+		issue.Message = l.T(issue.Message)
+	}
+	issue.Suggestion = l.T(issue.Suggestion)
+	return issue
+}
+
+// Reports returns a copy of reports with every issue translated
+func (l *Localizer) Reports(reports []ScanReport) []ScanReport {
+	if l == nil || l.language == DefaultLanguage {
+		return reports
+	}
+	localized := make([]ScanReport, len(reports))
+	for i, scanReport := range reports {
+		localized[i] = scanReport
+		localized[i].Reports = make([]BackupReport, len(scanReport.Reports))
+		for j, br := range scanReport.Reports {
+			localized[i].Reports[j] = br
+			localized[i].Reports[j].Issues = make([]ValidationIssue, len(br.Issues))
+			for k, issue := range br.Issues {
+				localized[i].Reports[j].Issues[k] = l.Issue(issue)
+			}
+		}
+	}
+	return localized
+}
+
+// Run returns a copy of run with every issue translated
+func (l *Localizer) Run(run RunReport) RunReport {
+	run.Results = l.Reports(run.Results)
+	if l == nil || l.language == DefaultLanguage {
+		return run
+	}
+	quarantined := make([]QuarantinedFile, len(run.Quarantined))
+	for i, file := range run.Quarantined {
+		quarantined[i] = file
+		quarantined[i].Issues = make([]ValidationIssue, len(file.Issues))
+		for k, issue := range file.Issues {
+			quarantined[i].Issues[k] = l.Issue(issue)
+		}
+	}
+	run.Quarantined = quarantined
+	return run
+}
+
+// localText is a message kept with its arguments until it is shown, so it
+// can be translated. Arguments that are localText are translated too.
+type localText struct {
+	format string
+	args   []any
+}
+
+// msg creates a translatable message from an English format
+func msg(format string, args ...any) localText {
+	return localText{format: format, args: args}
+}
+
+// String formats the message in English
+func (m localText) String() string {
+	if len(m.args) == 0 {
+		return m.format
+	}
+	return fmt.Sprintf(m.format, m.args...)
+}
+
+// replace replaces old with new in the arguments, as done to Message when
+// paths are rebased
+func (m localText) replace(old, new string) localText {
+	if len(m.args) == 0 {
+		return m
+	}
+	args := make([]any, len(m.args))
+	for i, arg := range m.args {
+		switch arg := arg.(type) {
+		case string:
+			args[i] = strings.ReplaceAll(arg, old, new)
+		case error:
+			args[i] = strings.ReplaceAll(arg.Error(), old, new)
+		case localText:
+			args[i] = arg.replace(old, new)
+		default:
+			args[i] = arg
+		}
+	}
+	return localText{format: m.format, args: args}
+}
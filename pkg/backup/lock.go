@@ -0,0 +1,81 @@
+package winbackupchecker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrLocked is returned when another check holds the run lock
+var ErrLocked = errors.New("another check is running")
+
+// RunLock is an exclusive lock on a file, held while a check runs so that
+// overlapping runs, such as a slow deep scan still running when the next
+// scheduled check starts, are detected. The operating system releases the
+// lock when the process exits, so a crashed check leaves no stale lock.
+type RunLock struct {
+	file *os.File
+}
+
+// TryRunLock takes the lock on path without waiting, creating the file if
+// needed. It returns an error wrapping ErrLocked, naming the holder, while
+// another process holds the lock.
+func TryRunLock(path string) (*RunLock, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	locked, err := lockFile(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	if !locked {
+		holder, _ := os.ReadFile(path)
+		file.Close()
+		if info := strings.TrimSpace(string(holder)); info != "" {
+			return nil, fmt.Errorf("%w (%s)", ErrLocked, info)
+		}
+		return nil, ErrLocked
+	}
+
+	// Record the holder for the runs that find the file locked
+	if err := file.Truncate(0); err == nil {
+		fmt.Fprintf(file, "pid %d, started %s\n", os.Getpid(), NowRFC3339())
+	}
+	return &RunLock{file: file}, nil
+}
+
+// AcquireRunLock takes the lock on path, trying again until wait has passed
+// or ctx is done
+func AcquireRunLock(ctx context.Context, path string, wait time.Duration) (*RunLock, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		lock, err := TryRunLock(path)
+		if !errors.Is(err, ErrLocked) || !time.Now().Before(deadline) {
+			return lock, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// Release unlocks the file. The file itself is left in place, since removing
+// it could let two later runs lock different files.
+func (l *RunLock) Release() error {
+	if err := unlockFile(l.file); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return l.file.Close()
+}
+
+// lockRetryInterval is how often a waiting run tries the lock again
+const lockRetryInterval = time.Second
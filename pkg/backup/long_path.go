@@ -0,0 +1,22 @@
+package winbackupchecker
+
+import "path/filepath"
+
+// Windows limits ordinary paths to MAX_PATH (260 characters), which the
+// files of deep user profiles in a backup easily exceed. The os package
+// adds the \\?\ extended-length prefix to long paths itself, so only paths
+// passed to Windows APIs directly go through extendedPath. Paths are kept
+// and reported without the prefix, so they compare and print the same
+// however they were configured.
+
+// NormalizePath returns path cleaned and without an extended-length prefix,
+// the form paths are kept and reported in
+func NormalizePath(path string) string {
+	if path == "" {
+		return path
+	}
+	if remoteScheme(path) != "" {
+		return cleanRemoteURL(path)
+	}
+	return filepath.Clean(stripExtendedPrefix(path))
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package winbackupchecker
+
+// extendedPath returns path unchanged, as only Windows limits path lengths
+func extendedPath(path string) string {
+	return path
+}
+
+// stripExtendedPrefix returns path unchanged, as only Windows has
+// extended-length paths
+func stripExtendedPrefix(path string) string {
+	return path
+}
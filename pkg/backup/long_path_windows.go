@@ -0,0 +1,49 @@
+//go:build windows
+
+package winbackupchecker
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// extendedPath returns the absolute extended-length form of path, for
+// Windows APIs that would otherwise fail on paths longer than MAX_PATH:
+// \\?\C:\... for drive paths and \\?\UNC\server\share\... for shares
+func extendedPath(path string) string {
+	if hasExtendedPrefix(path) {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
+
+// stripExtendedPrefix turns \\?\C:\... back into C:\... and
+// \\?\UNC\server\share\... into \\server\share\... Device paths such as
+// those of shadow copies have no other form and are returned unchanged.
+func stripExtendedPrefix(path string) string {
+	if !hasExtendedPrefix(path) {
+		return path
+	}
+	rest := path[4:]
+	switch {
+	case len(rest) >= 4 && strings.EqualFold(rest[:4], `UNC\`):
+		return `\\` + rest[4:]
+	case len(rest) >= 2 && rest[1] == ':':
+		return rest
+	default:
+		return path
+	}
+}
+
+// hasExtendedPrefix reports whether path starts with \\?\ or its NT
+// namespace equivalent \??\
+func hasExtendedPrefix(path string) bool {
+	return strings.HasPrefix(path, `\\?\`) || strings.HasPrefix(path, `\??\`)
+}
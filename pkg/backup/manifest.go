@@ -0,0 +1,578 @@
+package winbackupchecker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ManifestConfig controls verification of backup files against checksum
+// manifests written by replication tools (SHA256SUMS, .sfv, .par2)
+type ManifestConfig struct {
+	Enabled  bool     `json:"enabled"`
+	Formats  []string `json:"formats,omitempty"`
+	Repair   bool     `json:"repair"`
+	Par2Path string   `json:"par2_path,omitempty"`
+
+	// Algorithms limits verification to checksums of these algorithms, for
+	// example to skip the MD5 entries of a set that also has BLAKE3 ones;
+	// empty verifies all
+	Algorithms []string `json:"algorithms,omitempty"`
+
+	// HashWorkers is the number of goroutines hashing parts of one large
+	// file with BLAKE3; 0 uses every CPU
+	HashWorkers int `json:"hash_workers,omitempty"`
+}
+
+// Validate checks if manifest configuration is valid
+func (c *ManifestConfig) Validate() error {
+	for _, name := range c.Formats {
+		if findManifestFormat(name) == nil {
+			return fmt.Errorf("unknown manifest format %q", name)
+		}
+	}
+	for _, name := range c.Algorithms {
+		if _, ok := hashAlgorithms[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("unknown hash algorithm %q", name)
+		}
+	}
+	if c.HashWorkers < 0 {
+		return fmt.Errorf("hash_workers cannot be negative")
+	}
+	return nil
+}
+
+// enabled reports whether manifests of the named format should be verified
+func (c *ManifestConfig) enabled(format string) bool {
+	if len(c.Formats) == 0 {
+		return true
+	}
+	for _, name := range c.Formats {
+		if strings.EqualFold(name, format) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifies reports whether checksums of the named algorithm should be
+// verified
+func (c *ManifestConfig) verifies(algorithm string) bool {
+	if len(c.Algorithms) == 0 {
+		return true
+	}
+	for _, name := range c.Algorithms {
+		if strings.EqualFold(name, algorithm) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashWorkers returns the number of goroutines hashing one file
+func (c *ManifestConfig) hashWorkers() int {
+	if c.HashWorkers > 0 {
+		return c.HashWorkers
+	}
+	return runtime.NumCPU()
+}
+
+// hashAlgorithm is a checksum recorded by manifests
+type hashAlgorithm struct {
+	size int // digest size in bytes
+	new  func() hash.Hash
+}
+
+// hashAlgorithms lists the supported checksums by name
+var hashAlgorithms = map[string]hashAlgorithm{
+	"md5":    {size: md5.Size, new: md5.New},
+	"sha1":   {size: sha1.Size, new: sha1.New},
+	"sha256": {size: sha256.Size, new: sha256.New},
+	"blake3": {size: 32, new: newBlake3},
+	"xxh64":  {size: 8, new: newXXH64},
+	"crc32":  {size: crc32.Size, new: func() hash.Hash { return crc32.NewIEEE() }},
+}
+
+// manifestEntry is one file and its expected checksum from a manifest.
+// Parsers return paths relative to the manifest's base directory.
+type manifestEntry struct {
+	Path      string
+	Algorithm string
+	Sum       string
+}
+
+// manifestFormat recognizes and parses one kind of checksum manifest
+type manifestFormat struct {
+	name  string
+	match func(fileName string) bool
+	parse func(path string) ([]manifestEntry, error)
+}
+
+// manifestFormats lists the supported manifest formats; add an entry here to
+// support another tool's output
+var manifestFormats = []manifestFormat{
+	{name: "sums", match: isSumsManifest, parse: parseSumsManifest},
+	{name: "sfv", match: hasExt(".sfv"), parse: parseSFVManifest},
+	{name: "par2", match: isPar2Index, parse: parsePar2Manifest},
+}
+
+func findManifestFormat(name string) *manifestFormat {
+	for i := range manifestFormats {
+		if strings.EqualFold(manifestFormats[i].name, name) {
+			return &manifestFormats[i]
+		}
+	}
+	return nil
+}
+
+// manifestFormatFor returns the format of a manifest file, or nil if the file
+// is not a recognized manifest
+func manifestFormatFor(path string) *manifestFormat {
+	name := filepath.Base(path)
+	for i := range manifestFormats {
+		if manifestFormats[i].match(name) {
+			return &manifestFormats[i]
+		}
+	}
+	return nil
+}
+
+func hasExt(ext string) func(string) bool {
+	return func(name string) bool {
+		return strings.EqualFold(filepath.Ext(name), ext)
+	}
+}
+
+// isSumsManifest matches coreutils-style manifests such as SHA256SUMS,
+// MD5SUMS.txt and backup.sha256, and those of b3sum and xxhsum
+func isSumsManifest(name string) bool {
+	return sumsManifestAlgorithm(name) != ""
+}
+
+// sumsManifestAlgorithm returns the algorithm named by a sums manifest's
+// file name, or "" if the name is not one of a sums manifest
+func sumsManifestAlgorithm(name string) string {
+	switch strings.TrimSuffix(strings.ToUpper(name), ".TXT") {
+	case "SHA256SUMS":
+		return "sha256"
+	case "SHA1SUMS":
+		return "sha1"
+	case "MD5SUMS":
+		return "md5"
+	case "B3SUMS", "BLAKE3SUMS":
+		return "blake3"
+	case "XXH64SUMS", "XXHSUMS":
+		return "xxh64"
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".sha256":
+		return "sha256"
+	case ".sha1":
+		return "sha1"
+	case ".md5":
+		return "md5"
+	case ".b3", ".blake3":
+		return "blake3"
+	case ".xxh64", ".xxh":
+		return "xxh64"
+	}
+	return ""
+}
+
+// par2VolumePattern matches PAR2 recovery volumes, which repeat the index
+// file's descriptions and only need to be read by par2 itself
+var par2VolumePattern = regexp.MustCompile(`(?i)\.vol\d+[+-]\d+\.par2$`)
+
+func isPar2Index(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".par2") && !par2VolumePattern.MatchString(name)
+}
+
+// sumAlgorithms maps hex digest lengths to the algorithm producing them, for
+// manifests whose name does not tell
+var sumAlgorithms = map[int]string{16: "xxh64", 32: "md5", 40: "sha1", 64: "sha256"}
+
+// parseSumsManifest parses "<hex>  <name>" lines, with "*" marking binary mode.
+// SHA-256 and BLAKE3 digests have the same length, so the manifest's name
+// decides between them.
+func parseSumsManifest(path string) ([]manifestEntry, error) {
+	named := sumsManifestAlgorithm(filepath.Base(path))
+	var entries []manifestEntry
+	err := readManifestLines(path, func(line string) error {
+		sum, name, ok := strings.Cut(line, " ")
+		algorithm := sumAlgorithms[len(sum)]
+		if hashAlgorithms[named].size*2 == len(sum) {
+			algorithm = named
+		}
+		if !ok || algorithm == "" || !isHex(sum) {
+			return fmt.Errorf("malformed line %q", line)
+		}
+		name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+		entries = append(entries, newManifestEntry(name, algorithm, sum))
+		return nil
+	})
+	return entries, err
+}
+
+// parseSFVManifest parses "<name> <crc32>" lines; ";" starts a comment
+func parseSFVManifest(path string) ([]manifestEntry, error) {
+	var entries []manifestEntry
+	err := readManifestLines(path, func(line string) error {
+		if strings.HasPrefix(line, ";") {
+			return nil
+		}
+		i := strings.LastIndexAny(line, " \t")
+		if i < 0 || len(line)-i-1 != 8 || !isHex(line[i+1:]) {
+			return fmt.Errorf("malformed line %q", line)
+		}
+		name := strings.TrimSpace(line[:i])
+		entries = append(entries, newManifestEntry(name, "crc32", line[i+1:]))
+		return nil
+	})
+	return entries, err
+}
+
+func readManifestLines(path string, fn func(line string) error) error {
+	file, err := openBackupFile(context.Background(), path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+var (
+	par2Magic        = []byte("PAR2\x00PKT")
+	par2FileDescType = []byte("PAR 2.0\x00FileDesc")
+)
+
+// par2HeaderSize is the size of a PAR2 packet header: magic, length,
+// packet MD5, recovery set ID and type
+const par2HeaderSize = 64
+
+// parsePar2Manifest reads the file description packets of a PAR2 index file,
+// which record the MD5 of every protected file
+func parsePar2Manifest(path string) ([]manifestEntry, error) {
+	file, err := openBackupFile(context.Background(), path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	header := make([]byte, par2HeaderSize)
+	seen := make(map[string]bool)
+	var entries []manifestEntry
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("truncated PAR2 packet header")
+		}
+		if !bytes.Equal(header[:8], par2Magic) {
+			return nil, fmt.Errorf("not a PAR2 file")
+		}
+
+		length := binary.LittleEndian.Uint64(header[8:16])
+		if length < par2HeaderSize || length%4 != 0 {
+			return nil, fmt.Errorf("invalid PAR2 packet length %d", length)
+		}
+		bodyLen := int64(length - par2HeaderSize)
+
+		if !bytes.Equal(header[48:64], par2FileDescType) {
+			if _, err := r.Discard(int(bodyLen)); err != nil {
+				return nil, fmt.Errorf("truncated PAR2 packet")
+			}
+			continue
+		}
+
+		// File ID (16), file MD5 (16), first 16k MD5 (16), length (8), name
+		if bodyLen < 56 || bodyLen > 64*1024 {
+			return nil, fmt.Errorf("invalid PAR2 file description packet")
+		}
+		body := make([]byte, bodyLen)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, fmt.Errorf("truncated PAR2 packet")
+		}
+
+		name := string(bytes.TrimRight(body[56:], "\x00"))
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		entries = append(entries, newManifestEntry(name, "md5", hex.EncodeToString(body[16:32])))
+	}
+
+	return entries, nil
+}
+
+// newManifestEntry normalizes a manifest's relative file name to the local
+// path separator; callers resolve it against the manifest's base directory
+func newManifestEntry(name, algorithm, sum string) manifestEntry {
+	return manifestEntry{
+		Path:      filepath.FromSlash(strings.ReplaceAll(name, `\`, "/")),
+		Algorithm: algorithm,
+		Sum:       strings.ToLower(sum),
+	}
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// verifyManifestEntry checks one file against its expected checksum
+func verifyManifestEntry(ctx context.Context, entry manifestEntry, hashWorkers int) error {
+	sum, err := checksumFile(ctx, entry.Path, entry.Algorithm, hashWorkers)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("file is missing")
+		}
+		return err
+	}
+	if sum != entry.Sum {
+		return fmt.Errorf("%s mismatch: expected %s, got %s", entry.Algorithm, entry.Sum, sum)
+	}
+	return nil
+}
+
+// manifestFailure is a manifest entry that failed verification, or is yet
+// to be verified
+type manifestFailure struct {
+	entry    manifestEntry
+	manifest string
+	err      error
+	attempts int
+}
+
+// verifyManifests verifies every file listed in the set's manifests and, when
+// repair is enabled, tries to restore failed files from PAR2 recovery data.
+// Files are hashed opts.FileWorkers at a time, each holding a read slot.
+// It returns the issues found and the number of files that verified.
+func verifyManifests(ctx context.Context, setInfo BackupSetInfo, cfg *ManifestConfig, opts ScanOptions) ([]ValidationIssue, int) {
+	var issues []ValidationIssue
+	var checks []manifestFailure
+	par2ByFile := make(map[string]string)
+	checked := make(map[manifestEntry]bool)
+	escaping := make(map[string]bool)
+
+	for _, manifestPath := range setInfo.ManifestFiles {
+		format := manifestFormatFor(manifestPath)
+		if format == nil || !cfg.enabled(format.name) {
+			continue
+		}
+
+		entries, err := format.parse(manifestPath)
+		baseDir := manifestBaseDir(setInfo.Path, manifestPath)
+		if err != nil {
+			issues = append(issues, newIssue(IssueManifestUnreadable, SeverityWarning,
+				msg("cannot parse %s manifest: %v", format.name, err),
+				manifestPath,
+				"the manifest may be damaged; files it lists were not verified"))
+			continue
+		}
+
+		// Entries naming files outside the set, such as ../../Users/x, are
+		// neither read nor repaired
+		var outside []string
+		for _, entry := range entries {
+			listed := entry.Path
+			entry.Path = filepath.Join(baseDir, entry.Path)
+			if !isSubPath(setInfo.Path, entry.Path) {
+				outside = append(outside, listed)
+				continue
+			}
+			if format.name == "par2" {
+				par2ByFile[entry.Path] = manifestPath
+			}
+			if checked[entry] || !cfg.verifies(entry.Algorithm) {
+				continue
+			}
+			checked[entry] = true
+			checks = append(checks, manifestFailure{entry: entry, manifest: manifestPath})
+		}
+		if len(outside) > 0 {
+			escaping[manifestPath] = true
+			issues = append(issues, newIssue(IssueManifestUnreadable, SeverityWarning,
+				msg("%s manifest lists files outside the backup set, such as %s", format.name, outside[0]),
+				manifestPath,
+				"the manifest may have been tampered with; files outside the set were not read"))
+		}
+	}
+
+	done := verifyManifestEntries(ctx, checks, cfg.hashWorkers(), opts)
+	if ctx.Err() != nil {
+		// A hash interrupted by the timeout says nothing about the file
+		verified := 0
+		for i, check := range checks {
+			if done[i] && check.err == nil {
+				verified++
+			}
+		}
+		return issues, verified
+	}
+
+	// Files left unreadable by I/O errors are not damaged, so not repaired
+	var failures []manifestFailure
+	unreadable := make(map[string]bool)
+	verified := 0
+	for _, check := range checks {
+		if isTransientIOError(check.err) {
+			if !unreadable[check.entry.Path] {
+				unreadable[check.entry.Path] = true
+				issues = append(issues, unreadableIssue(SeverityError, check.entry.Path, check.attempts, check.err))
+			}
+			continue
+		}
+		if check.err != nil {
+			failures = append(failures, check)
+			continue
+		}
+		verified++
+	}
+
+	repairErrs := make(map[string]error)
+	if cfg.Repair {
+		for _, failure := range failures {
+			par2File, ok := par2ByFile[failure.entry.Path]
+			if !ok || escaping[par2File] {
+				continue
+			}
+			if _, done := repairErrs[par2File]; !done {
+				repairErrs[par2File] = repairWithPar2(ctx, cfg.Par2Path, par2File, manifestBaseDir(setInfo.Path, par2File))
+			}
+		}
+	}
+
+	// A damaged file is usually listed in several manifests; report it once
+	failedIn := make(map[string][]string)
+	for _, failure := range failures {
+		failedIn[failure.entry.Path] = append(failedIn[failure.entry.Path], filepath.Base(failure.manifest))
+	}
+
+	for _, failure := range failures {
+		manifests, pending := failedIn[failure.entry.Path]
+		if !pending {
+			continue
+		}
+		delete(failedIn, failure.entry.Path)
+
+		par2File, hasPar2 := par2ByFile[failure.entry.Path]
+		repairErr, attempted := repairErrs[par2File]
+
+		if attempted && repairErr == nil && verifyManifestEntry(ctx, failure.entry, cfg.hashWorkers()) == nil {
+			verified++
+			issues = append(issues, newIssue(IssueManifestRepaired, SeverityWarning,
+				msg("file failed manifest verification and was repaired from PAR2 data (%v)", failure.err),
+				failure.entry.Path,
+				"investigate the storage for the cause of the damage"))
+			continue
+		}
+
+		suggestion := "restore the file from another copy of the backup"
+		switch {
+		case attempted && repairErr != nil:
+			suggestion = fmt.Sprintf("PAR2 repair failed (%v); restore the file from another copy of the backup", repairErr)
+		case hasPar2 && !cfg.Repair:
+			suggestion = "PAR2 recovery data is available; enable manifests.repair to attempt a repair"
+		}
+
+		issues = append(issues, newIssue(IssueManifestMismatch, SeverityError,
+			msg("file failed verification against %s: %v", strings.Join(manifests, ", "), failure.err),
+			failure.entry.Path,
+			suggestion))
+	}
+
+	return issues, verified
+}
+
+// verifyManifestEntries verifies the entries of checks opts.FileWorkers at a
+// time, each holding a read slot, retrying files that fail with I/O errors.
+// It sets the error and attempts of each check, and returns whether each was
+// verified; entries not started before ctx ended are not.
+func verifyManifestEntries(ctx context.Context, checks []manifestFailure, hashWorkers int, opts ScanOptions) []bool {
+	done := make([]bool, len(checks))
+
+	workers := min(max(opts.FileWorkers, 1), len(checks))
+	work := make(chan int, len(checks))
+	for i := range checks {
+		work <- i
+	}
+	close(work)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range work {
+				if !opts.acquireRead(ctx) {
+					continue
+				}
+				check := &checks[idx]
+				check.attempts, check.err = opts.retryIO(ctx, func() error {
+					return verifyManifestEntry(ctx, check.entry, hashWorkers)
+				})
+				done[idx] = ctx.Err() == nil
+				opts.releaseRead()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return done
+}
+
+// manifestBaseDir returns the directory a manifest's file names are relative
+// to: the set itself for its sibling parity folder, otherwise the manifest's
+// own directory
+func manifestBaseDir(setPath, manifestPath string) string {
+	dir := filepath.Dir(manifestPath)
+	if dir == parityDir(setPath) {
+		return setPath
+	}
+	return dir
+}
+
+// repairWithPar2 runs par2cmdline's repair on a PAR2 index file whose file
+// names are relative to baseDir
+func repairWithPar2(ctx context.Context, par2Path, indexFile, baseDir string) error {
+	return runPar2(ctx, par2Path, "repair", "-q", "-B"+baseDir, indexFile)
+}
+
+// runPar2 runs par2cmdline, returning its last output line in errors
+func runPar2(ctx context.Context, par2Path string, args ...string) error {
+	if par2Path == "" {
+		par2Path = "par2"
+	}
+
+	_, err := runCommand(ctx, append([]string{par2Path}, args...))
+	return err
+}
@@ -0,0 +1,192 @@
+package winbackupchecker
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeManifest(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseSumsManifest(t *testing.T) {
+	dir := t.TempDir()
+	sha := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	tests := []struct {
+		name string
+		data string
+		want []manifestEntry
+	}{
+		{
+			name: "SHA256SUMS",
+			data: "# written by sha256sum\r\n" + sha + "  Backup files 1.zip\r\n\r\n" + sha + " *Catalogs\\GlobalCatalog.wbcat\n",
+			want: []manifestEntry{
+				{Path: "Backup files 1.zip", Algorithm: "sha256", Sum: sha},
+				{Path: filepath.Join("Catalogs", "GlobalCatalog.wbcat"), Algorithm: "sha256", Sum: sha},
+			},
+		},
+		{
+			// A BLAKE3 digest is as long as a SHA-256 one
+			name: "B3SUMS",
+			data: sha + "  a.zip\n",
+			want: []manifestEntry{{Path: "a.zip", Algorithm: "blake3", Sum: sha}},
+		},
+		{
+			name: "checksums.txt.md5",
+			data: "D41D8CD98F00B204E9800998ECF8427E  a.zip\nef46db3751d8e999  b.zip\n",
+			want: []manifestEntry{
+				{Path: "a.zip", Algorithm: "md5", Sum: "d41d8cd98f00b204e9800998ecf8427e"},
+				{Path: "b.zip", Algorithm: "xxh64", Sum: "ef46db3751d8e999"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		entries, err := parseSumsManifest(writeManifest(t, dir, tt.name, []byte(tt.data)))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !slices.Equal(entries, tt.want) {
+			t.Errorf("%s: entries = %+v, want %+v", tt.name, entries, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"abc  a.zip\n", sha + "\n", "zz" + sha[2:] + "  a.zip\n"} {
+		if _, err := parseSumsManifest(writeManifest(t, dir, "SHA256SUMS", []byte(bad))); err == nil {
+			t.Errorf("no error for %q", bad)
+		}
+	}
+}
+
+func TestParseSFVManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := writeManifest(t, dir, "set.sfv", []byte("; Generated by cksfv\r\nBackup files 1.zip 352441C2\r\nsub dir/b.zip\t00000000\n"))
+	entries, err := parseSFVManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []manifestEntry{
+		{Path: "Backup files 1.zip", Algorithm: "crc32", Sum: "352441c2"},
+		{Path: filepath.Join("sub dir", "b.zip"), Algorithm: "crc32", Sum: "00000000"},
+	}
+	if !slices.Equal(entries, want) {
+		t.Errorf("entries = %+v, want %+v", entries, want)
+	}
+
+	if _, err := parseSFVManifest(writeManifest(t, dir, "bad.sfv", []byte("a.zip 12345\n"))); err == nil {
+		t.Error("no error for a short CRC")
+	}
+}
+
+// par2Packet builds a PAR2 packet of type with body, padded to 4 bytes
+func par2Packet(kind string, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	header := make([]byte, par2HeaderSize)
+	copy(header, par2Magic)
+	binary.LittleEndian.PutUint64(header[8:], uint64(par2HeaderSize+len(body)))
+	copy(header[48:], kind)
+	return append(header, body...)
+}
+
+// par2FileDesc builds the file description packet of a file
+func par2FileDesc(name string, data []byte) []byte {
+	sum := md5.Sum(data)
+	body := make([]byte, 56)
+	copy(body[16:32], sum[:])
+	binary.LittleEndian.PutUint64(body[48:], uint64(len(data)))
+	return par2Packet(string(par2FileDescType), append(body, name...))
+}
+
+func TestParsePar2Manifest(t *testing.T) {
+	dir := t.TempDir()
+	var data []byte
+	data = append(data, par2Packet("PAR 2.0\x00Main\x00\x00\x00\x00", make([]byte, 12))...)
+	data = append(data, par2FileDesc("Backup files 1.zip", []byte("zip"))...)
+	data = append(data, par2FileDesc("Catalogs/GlobalCatalog.wbcat", nil)...)
+	data = append(data, par2FileDesc("Backup files 1.zip", []byte("zip"))...)
+	entries, err := parsePar2Manifest(writeManifest(t, dir, "set.par2", data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zip := md5.Sum([]byte("zip"))
+	empty := md5.Sum(nil)
+	want := []manifestEntry{
+		{Path: "Backup files 1.zip", Algorithm: "md5", Sum: hex.EncodeToString(zip[:])},
+		{Path: filepath.Join("Catalogs", "GlobalCatalog.wbcat"), Algorithm: "md5", Sum: hex.EncodeToString(empty[:])},
+	}
+	if !slices.Equal(entries, want) {
+		t.Errorf("entries = %+v, want %+v", entries, want)
+	}
+
+	for name, bad := range map[string][]byte{
+		"not PAR2":  bytes.Repeat([]byte{1}, par2HeaderSize),
+		"truncated": data[:len(data)-8],
+	} {
+		if _, err := parsePar2Manifest(writeManifest(t, dir, "bad.par2", bad)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestManifestFormatFor(t *testing.T) {
+	tests := map[string]string{
+		"SHA256SUMS":         "sums",
+		"md5sums.txt":        "sums",
+		"set.b3":             "sums",
+		"set.sfv":            "sfv",
+		"set.par2":           "par2",
+		"set.vol00+01.par2":  "",
+		"Backup files 1.zip": "",
+	}
+	for name, want := range tests {
+		got := ""
+		if format := manifestFormatFor(name); format != nil {
+			got = format.name
+		}
+		if got != want {
+			t.Errorf("manifestFormatFor(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestVerifyManifestsOutsideSet(t *testing.T) {
+	root := t.TempDir()
+	set := filepath.Join(root, "PC1", "Backup Set 2024-06-01 000000")
+	if err := os.MkdirAll(set, 0755); err != nil {
+		t.Fatal(err)
+	}
+	zip := []byte("backup")
+	writeManifest(t, set, "Backup files 1.zip", zip)
+	writeManifest(t, root, "secret.txt", []byte("secret"))
+
+	sum := func(data []byte) string {
+		s := sha256.Sum256(data)
+		return hex.EncodeToString(s[:])
+	}
+	manifest := writeManifest(t, set, "SHA256SUMS", []byte(
+		sum(zip)+"  Backup files 1.zip\n"+
+			sum([]byte("secret"))+"  ../../secret.txt\n"))
+
+	setInfo := BackupSetInfo{Path: set, ManifestFiles: []string{manifest}}
+	issues, verified := verifyManifests(context.Background(), setInfo, &ManifestConfig{Enabled: true}, ScanOptions{})
+	if verified != 1 {
+		t.Errorf("verified = %d, want 1", verified)
+	}
+	if len(issues) != 1 || issues[0].Code != IssueManifestUnreadable || issues[0].Path != manifest {
+		t.Errorf("issues = %+v, want one manifest_unreadable issue on the manifest", issues)
+	}
+}
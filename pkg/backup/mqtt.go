@@ -0,0 +1,288 @@
+package winbackupchecker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MQTT defaults
+const (
+	mqttDefaultClientID        = "win-backup-checker"
+	mqttDefaultTopicPrefix     = "win-backup-checker"
+	mqttDefaultDiscoveryPrefix = "homeassistant"
+)
+
+// MQTTConfig publishes backup state to an MQTT broker for dashboards such as
+// Home Assistant
+type MQTTConfig struct {
+	Enabled         bool   `json:"enabled"`
+	Broker          string `json:"broker"`
+	Username        string `json:"username,omitempty"`
+	Password        string `json:"password,omitempty" secret:"true"`
+	ClientID        string `json:"client_id,omitempty"`
+	TopicPrefix     string `json:"topic_prefix,omitempty"`
+	QoS             int    `json:"qos"`
+	HomeAssistant   bool   `json:"home_assistant"`
+	DiscoveryPrefix string `json:"discovery_prefix,omitempty"`
+}
+
+// Validate checks if MQTT configuration is valid
+func (c *MQTTConfig) Validate() error {
+	if c.Broker == "" {
+		return fmt.Errorf("broker is required")
+	}
+	if !strings.Contains(c.Broker, "://") {
+		return fmt.Errorf("broker must be a URL such as tcp://host:1883 or ssl://host:8883")
+	}
+	if c.QoS != 0 && c.QoS != 1 {
+		return fmt.Errorf("qos must be 0 or 1")
+	}
+	return nil
+}
+
+func (c *MQTTConfig) topicPrefix() string {
+	if c.TopicPrefix == "" {
+		return mqttDefaultTopicPrefix
+	}
+	return strings.TrimRight(c.TopicPrefix, "/")
+}
+
+// mqttSummary is published to <prefix>/summary
+type mqttSummary struct {
+	Status         string `json:"status"`
+	TotalBackups   int    `json:"total_backups"`
+	ValidBackups   int    `json:"valid_backups"`
+	InvalidBackups int    `json:"invalid_backups"`
+	FailedScans    int    `json:"failed_scans"`
+	CheckedAt      string `json:"checked_at"`
+}
+
+// mqttMachineState is published to <prefix>/machine/<machine>/state
+type mqttMachineState struct {
+	Machine      string `json:"machine"`
+	Status       string `json:"status"`
+	TotalSets    int    `json:"total_sets"`
+	ValidSets    int    `json:"valid_sets"`
+	InvalidSets  int    `json:"invalid_sets"`
+	Issues       int    `json:"issues"`
+	NewestBackup string `json:"newest_backup,omitempty"`
+	CheckedAt    string `json:"checked_at"`
+}
+
+// MQTTNotifier publishes the run summary and per-machine state as retained
+// messages after every run
+type MQTTNotifier struct {
+	cfg *MQTTConfig
+}
+
+func (n *MQTTNotifier) Name() string { return "mqtt" }
+
+// Notify publishes state on every run, so dashboards always show the latest
+// scan; the send_on_* triggers do not apply
+func (n *MQTTNotifier) Notify(ctx context.Context, report RunReport) error {
+	clientID := n.cfg.ClientID
+	if clientID == "" {
+		clientID = mqttDefaultClientID
+	}
+
+	client, err := dialMQTT(ctx, n.cfg.Broker, clientID, n.cfg.Username, n.cfg.Password)
+	if err != nil {
+		return fmt.Errorf("failed to publish to MQTT: %w", err)
+	}
+	defer client.close()
+
+	for _, msg := range n.messages(report) {
+		payload, err := json.Marshal(msg.payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal MQTT payload: %w", err)
+		}
+		if err := client.publish(msg.topic, payload, byte(n.cfg.QoS), true); err != nil {
+			return fmt.Errorf("failed to publish to MQTT: %w", err)
+		}
+	}
+	return nil
+}
+
+type mqttMessage struct {
+	topic   string
+	payload any
+}
+
+// messages returns the state messages for a run, preceded by Home Assistant
+// discovery configs when enabled
+func (n *MQTTNotifier) messages(report RunReport) []mqttMessage {
+	prefix := n.cfg.topicPrefix()
+	status := NewRunStatus(report.Summary, report.Results)
+
+	summaryTopic := prefix + "/summary"
+	summary := mqttSummary{
+		Status:         mqttStatus(status.HasErrors, status.HasWarnings),
+		TotalBackups:   report.Summary.TotalBackups,
+		ValidBackups:   report.Summary.ValidBackups,
+		InvalidBackups: report.Summary.InvalidBackups,
+		FailedScans:    report.Summary.FailedScans,
+		CheckedAt:      report.Timestamp,
+	}
+
+	var messages []mqttMessage
+	if n.cfg.HomeAssistant {
+		messages = append(messages, n.summaryDiscovery(summaryTopic)...)
+	}
+	messages = append(messages, mqttMessage{topic: summaryTopic, payload: summary})
+
+	for _, state := range mqttMachineStates(report) {
+		stateTopic := fmt.Sprintf("%s/machine/%s/state", prefix, mqttSlug(state.Machine))
+		if n.cfg.HomeAssistant {
+			messages = append(messages, n.machineDiscovery(state.Machine, stateTopic)...)
+		}
+		messages = append(messages, mqttMessage{topic: stateTopic, payload: state})
+	}
+	return messages
+}
+
+// mqttMachineStates summarizes the backup sets of each machine
+func mqttMachineStates(report RunReport) []mqttMachineState {
+	byName := make(map[string]*mqttMachineState)
+	hasWarnings := make(map[string]bool)
+
+	for _, scanReport := range report.Results {
+		for _, br := range scanReport.Reports {
+			name := MachineName(br.BackupDir)
+			state, ok := byName[name]
+			if !ok {
+				state = &mqttMachineState{Machine: name, CheckedAt: report.Timestamp}
+				byName[name] = state
+			}
+
+			state.TotalSets++
+			state.Issues += len(br.Issues)
+			if br.Valid {
+				state.ValidSets++
+			} else {
+				state.InvalidSets++
+			}
+			if worst, ok := worstSeverity(br.Issues); ok && worst == SeverityWarning {
+				hasWarnings[name] = true
+			}
+			if t := br.ValidationStats.NewestBackupTime; t != nil {
+				if stamp := t.Format(time.RFC3339); stamp > state.NewestBackup {
+					state.NewestBackup = stamp
+				}
+			}
+		}
+	}
+
+	states := make([]mqttMachineState, 0, len(byName))
+	for name, state := range byName {
+		state.Status = mqttStatus(state.InvalidSets > 0, hasWarnings[name])
+		states = append(states, *state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Machine < states[j].Machine })
+	return states
+}
+
+func mqttStatus(hasErrors, hasWarnings bool) string {
+	switch {
+	case hasErrors:
+		return "error"
+	case hasWarnings:
+		return "warning"
+	default:
+		return "ok"
+	}
+}
+
+// summaryDiscovery returns the Home Assistant config for the run status sensor
+func (n *MQTTNotifier) summaryDiscovery(summaryTopic string) []mqttMessage {
+	device := map[string]any{
+		"identifiers":  []string{"win_backup_checker"},
+		"name":         "Backup Checker",
+		"manufacturer": "win-backup-checker",
+		"sw_version":   Version,
+	}
+	return []mqttMessage{
+		n.discovery("sensor", "status", map[string]any{
+			"name":                  "Status",
+			"state_topic":           summaryTopic,
+			"value_template":        "{{ value_json.status }}",
+			"json_attributes_topic": summaryTopic,
+			"icon":                  "mdi:backup-restore",
+			"device":                device,
+		}),
+		n.discovery("sensor", "invalid_backups", map[string]any{
+			"name":           "Invalid backups",
+			"state_topic":    summaryTopic,
+			"value_template": "{{ value_json.invalid_backups }}",
+			"state_class":    "measurement",
+			"device":         device,
+		}),
+	}
+}
+
+// machineDiscovery returns the Home Assistant configs for one machine: a
+// problem sensor, the newest backup time and the invalid set count
+func (n *MQTTNotifier) machineDiscovery(machine, stateTopic string) []mqttMessage {
+	slug := mqttSlug(machine)
+	device := map[string]any{
+		"identifiers":  []string{"win_backup_checker_" + slug},
+		"name":         machine + " backups",
+		"manufacturer": "win-backup-checker",
+		"sw_version":   Version,
+		"via_device":   "win_backup_checker",
+	}
+	return []mqttMessage{
+		n.discovery("binary_sensor", slug+"_problem", map[string]any{
+			"name":                  "Backup problem",
+			"state_topic":           stateTopic,
+			"value_template":        "{{ 'OFF' if value_json.status == 'ok' else 'ON' }}",
+			"device_class":          "problem",
+			"json_attributes_topic": stateTopic,
+			"device":                device,
+		}),
+		n.discovery("sensor", slug+"_newest_backup", map[string]any{
+			"name":           "Newest backup",
+			"state_topic":    stateTopic,
+			"value_template": "{{ value_json.newest_backup if value_json.newest_backup else None }}",
+			"device_class":   "timestamp",
+			"device":         device,
+		}),
+		n.discovery("sensor", slug+"_invalid_sets", map[string]any{
+			"name":           "Invalid backup sets",
+			"state_topic":    stateTopic,
+			"value_template": "{{ value_json.invalid_sets }}",
+			"state_class":    "measurement",
+			"device":         device,
+		}),
+	}
+}
+
+// discovery returns a retained Home Assistant discovery config message
+func (n *MQTTNotifier) discovery(component, objectID string, config map[string]any) mqttMessage {
+	discoveryPrefix := n.cfg.DiscoveryPrefix
+	if discoveryPrefix == "" {
+		discoveryPrefix = mqttDefaultDiscoveryPrefix
+	}
+	config["unique_id"] = "win_backup_checker_" + objectID
+	return mqttMessage{
+		topic:   fmt.Sprintf("%s/%s/win_backup_checker/%s/config", strings.TrimRight(discoveryPrefix, "/"), component, objectID),
+		payload: config,
+	}
+}
+
+// mqttSlug makes a machine name safe for topics and Home Assistant IDs
+func mqttSlug(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,233 @@
+package winbackupchecker
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types
+const (
+	mqttConnect    = 0x10
+	mqttConnAck    = 0x20
+	mqttPublish    = 0x30
+	mqttPubAck     = 0x40
+	mqttDisconnect = 0xe0
+)
+
+// mqttKeepAlive is the keep-alive sent on connect; connections are short-lived
+const mqttKeepAlive = 60
+
+// mqttClient is a minimal MQTT 3.1.1 client that only publishes
+type mqttClient struct {
+	conn     net.Conn
+	r        *bufio.Reader
+	packetID uint16
+}
+
+// dialMQTT connects to a broker given as tcp://host:port, or ssl:// or
+// mqtts:// for TLS
+func dialMQTT(ctx context.Context, broker, clientID, username, password string) (*mqttClient, error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker URL: %w", err)
+	}
+
+	host := u.Host
+	useTLS := u.Scheme == "ssl" || u.Scheme == "mqtts" || u.Scheme == "tls"
+	if u.Port() == "" {
+		port := "1883"
+		if useTLS {
+			port = "8883"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: notificationTimeout}
+	var conn net.Conn
+	if useTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname()}}).DialContext(ctx, "tcp", host)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to broker: %w", err)
+	}
+
+	deadline := time.Now().Add(notificationTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	c := &mqttClient{conn: conn, r: bufio.NewReader(conn)}
+	if err := c.connect(clientID, username, password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *mqttClient) connect(clientID, username, password string) error {
+	var body []byte
+	body = appendMQTTString(body, "MQTT")
+	body = append(body, 4) // protocol level 3.1.1
+
+	flags := byte(0x02) // clean session
+	if username != "" {
+		flags |= 0x80
+	}
+	if password != "" {
+		flags |= 0x40
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, mqttKeepAlive)
+
+	body = appendMQTTString(body, clientID)
+	if username != "" {
+		body = appendMQTTString(body, username)
+	}
+	if password != "" {
+		body = appendMQTTString(body, password)
+	}
+
+	if err := c.write(mqttConnect, body); err != nil {
+		return err
+	}
+
+	packetType, payload, err := c.read()
+	if err != nil {
+		return err
+	}
+	if packetType != mqttConnAck || len(payload) != 2 {
+		return fmt.Errorf("unexpected reply to connect")
+	}
+	if code := payload[1]; code != 0 {
+		return fmt.Errorf("broker refused connection: %s", mqttConnectError(code))
+	}
+	return nil
+}
+
+// publish sends payload to topic, waiting for the broker's acknowledgement
+// when qos is 1
+func (c *mqttClient) publish(topic string, payload []byte, qos byte, retain bool) error {
+	header := byte(mqttPublish) | qos<<1
+	if retain {
+		header |= 0x01
+	}
+
+	body := appendMQTTString(nil, topic)
+	if qos > 0 {
+		c.packetID++
+		if c.packetID == 0 {
+			c.packetID = 1
+		}
+		body = binary.BigEndian.AppendUint16(body, c.packetID)
+	}
+	body = append(body, payload...)
+
+	if err := c.write(header, body); err != nil {
+		return err
+	}
+	if qos == 0 {
+		return nil
+	}
+
+	packetType, ack, err := c.read()
+	if err != nil {
+		return err
+	}
+	if packetType != mqttPubAck || len(ack) != 2 || binary.BigEndian.Uint16(ack) != c.packetID {
+		return fmt.Errorf("unexpected reply to publish on %s", topic)
+	}
+	return nil
+}
+
+// close disconnects cleanly from the broker
+func (c *mqttClient) close() error {
+	c.write(mqttDisconnect, nil)
+	return c.conn.Close()
+}
+
+func (c *mqttClient) write(header byte, body []byte) error {
+	packet := []byte{header}
+	packet = appendMQTTLength(packet, len(body))
+	packet = append(packet, body...)
+	if _, err := c.conn.Write(packet); err != nil {
+		return fmt.Errorf("failed to send to broker: %w", err)
+	}
+	return nil
+}
+
+// read returns the type and body of the next packet from the broker
+func (c *mqttClient) read() (byte, []byte, error) {
+	header, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read from broker: %w", err)
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to read from broker: %w", err)
+		}
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, fmt.Errorf("malformed packet from broker")
+		}
+		multiplier *= 128
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, fmt.Errorf("failed to read from broker: %w", err)
+	}
+	return header & 0xf0, body, nil
+}
+
+// appendMQTTLength appends the variable-length remaining length encoding
+func appendMQTTLength(b []byte, n int) []byte {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func mqttConnectError(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	default:
+		return fmt.Sprintf("return code %d", code)
+	}
+}
@@ -0,0 +1,381 @@
+package winbackupchecker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NotifyTriggers controls which run outcomes produce a notification. Each
+// channel has its own triggers, so channels can be tuned independently.
+type NotifyTriggers struct {
+	SendOnSuccess  bool   `json:"send_on_success"`
+	SendOnWarnings bool   `json:"send_on_warnings"`
+	SendOnErrors   bool   `json:"send_on_errors"`
+	MinSeverity    string `json:"min_severity,omitempty"`
+}
+
+// Validate checks if the triggers are valid
+func (t NotifyTriggers) Validate() error {
+	if t.MinSeverity != "" {
+		if _, err := ParseSeverity(t.MinSeverity); err != nil {
+			return fmt.Errorf("invalid min_severity: %w", err)
+		}
+	}
+	return nil
+}
+
+// ShouldSend reports whether a run with the given status triggers a send
+func (t NotifyTriggers) ShouldSend(status RunStatus) bool {
+	if !status.MeetsMinSeverity(t.MinSeverity) {
+		return false
+	}
+	switch {
+	case t.SendOnErrors && status.HasErrors:
+		return true
+	case t.SendOnWarnings && status.HasWarnings:
+		return true
+	case t.SendOnSuccess && !status.HasErrors && !status.HasWarnings:
+		return true
+	}
+	return false
+}
+
+// RunStatus is the overall outcome of a run used for notification gating
+type RunStatus struct {
+	HasErrors   bool
+	HasWarnings bool
+
+	// Worst is the most severe issue found, if HasIssues
+	Worst     ValidationSeverity
+	HasIssues bool
+}
+
+// NewRunStatus derives the run outcome from its summary and reports.
+// Unstable (flapping) sets count as warnings, not errors.
+func NewRunStatus(summary ScanSummary, reports []ScanReport) RunStatus {
+	var status RunStatus
+	flappingInvalid := 0
+
+	for _, scanReport := range reports {
+		for _, backupReport := range scanReport.Reports {
+			if backupReport.Flapping != nil && !backupReport.Valid {
+				flappingInvalid++
+			}
+			for _, issue := range backupReport.Issues {
+				if issue.Suppressed {
+					continue
+				}
+				severity := issue.Severity
+				if backupReport.Flapping != nil && severity > SeverityWarning {
+					severity = SeverityWarning
+				}
+				if severity == SeverityWarning {
+					status.HasWarnings = true
+				}
+				if !status.HasIssues || severity > status.Worst {
+					status.Worst = severity
+					status.HasIssues = true
+				}
+			}
+		}
+	}
+
+	status.HasErrors = summary.InvalidBackups > flappingInvalid || summary.FailedScans > 0
+	return status
+}
+
+// MeetsMinSeverity reports whether a run with problems found an issue of at
+// least the named severity. Clean runs and an empty name always pass.
+func (s RunStatus) MeetsMinSeverity(name string) bool {
+	if name == "" || (!s.HasErrors && !s.HasWarnings) {
+		return true
+	}
+	min, err := ParseSeverity(name)
+	if err != nil {
+		return true
+	}
+	return s.HasIssues && s.Worst >= min
+}
+
+// Label returns a short uppercase description of the status
+func (s RunStatus) Label() string {
+	switch {
+	case s.HasErrors:
+		return "ERRORS DETECTED"
+	case s.HasWarnings:
+		return "WARNINGS"
+	default:
+		return "SUCCESS"
+	}
+}
+
+// NotificationsConfig configures notification channels besides email
+type NotificationsConfig struct {
+	Slack        *SlackConfig        `json:"slack,omitempty"`
+	Discord      *DiscordConfig      `json:"discord,omitempty"`
+	Webhook      *WebhookConfig      `json:"webhook,omitempty"`
+	PagerDuty    *PagerDutyConfig    `json:"pagerduty,omitempty"`
+	Healthchecks *HealthchecksConfig `json:"healthchecks,omitempty"`
+	Telegram     *TelegramConfig     `json:"telegram,omitempty"`
+	Pushover     *PushoverConfig     `json:"pushover,omitempty"`
+	Twilio       *TwilioConfig       `json:"twilio,omitempty"`
+	Gotify       *GotifyConfig       `json:"gotify,omitempty"`
+	MQTT         *MQTTConfig         `json:"mqtt,omitempty"`
+}
+
+// Validate checks every enabled notification channel
+func (c *NotificationsConfig) Validate() error {
+	if c.Slack != nil && c.Slack.Enabled {
+		if err := c.Slack.Validate(); err != nil {
+			return fmt.Errorf("slack: %w", err)
+		}
+	}
+	if c.Discord != nil && c.Discord.Enabled {
+		if err := c.Discord.Validate(); err != nil {
+			return fmt.Errorf("discord: %w", err)
+		}
+	}
+	if c.Webhook != nil && c.Webhook.Enabled {
+		if err := c.Webhook.Validate(); err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+	}
+	if c.PagerDuty != nil && c.PagerDuty.Enabled {
+		if err := c.PagerDuty.Validate(); err != nil {
+			return fmt.Errorf("pagerduty: %w", err)
+		}
+	}
+	if c.Healthchecks != nil && c.Healthchecks.Enabled {
+		if err := c.Healthchecks.Validate(); err != nil {
+			return fmt.Errorf("healthchecks: %w", err)
+		}
+	}
+	if c.Telegram != nil && c.Telegram.Enabled {
+		if err := c.Telegram.Validate(); err != nil {
+			return fmt.Errorf("telegram: %w", err)
+		}
+	}
+	if c.Pushover != nil && c.Pushover.Enabled {
+		if err := c.Pushover.Validate(); err != nil {
+			return fmt.Errorf("pushover: %w", err)
+		}
+	}
+	if c.Twilio != nil && c.Twilio.Enabled {
+		if err := c.Twilio.Validate(); err != nil {
+			return fmt.Errorf("twilio: %w", err)
+		}
+	}
+	if c.Gotify != nil && c.Gotify.Enabled {
+		if err := c.Gotify.Validate(); err != nil {
+			return fmt.Errorf("gotify: %w", err)
+		}
+	}
+	if c.MQTT != nil && c.MQTT.Enabled {
+		if err := c.MQTT.Validate(); err != nil {
+			return fmt.Errorf("mqtt: %w", err)
+		}
+	}
+	return nil
+}
+
+// Notifier delivers a run report to one notification channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, report RunReport) error
+}
+
+// ReportsEveryRun reports whether a channel tracks the outcome of every run
+// rather than alerting on it, such as a dead man's switch or a Home Assistant
+// sensor. Such channels are notified even when alerts are limited to changes.
+func ReportsEveryRun(n Notifier) bool {
+	switch n.(type) {
+	case *HealthchecksNotifier, *MQTTNotifier:
+		return true
+	}
+	return false
+}
+
+// BuildNotifiers returns a notifier for every enabled channel in cfg
+func BuildNotifiers(cfg *NotificationsConfig) []Notifier {
+	if cfg == nil {
+		return nil
+	}
+
+	var notifiers []Notifier
+	if cfg.Slack != nil && cfg.Slack.Enabled {
+		notifiers = append(notifiers, &SlackNotifier{cfg: cfg.Slack})
+	}
+	if cfg.Discord != nil && cfg.Discord.Enabled {
+		notifiers = append(notifiers, &DiscordNotifier{cfg: cfg.Discord})
+	}
+	if cfg.Webhook != nil && cfg.Webhook.Enabled {
+		notifiers = append(notifiers, &WebhookNotifier{cfg: cfg.Webhook})
+	}
+	if cfg.PagerDuty != nil && cfg.PagerDuty.Enabled {
+		notifiers = append(notifiers, &PagerDutyNotifier{cfg: cfg.PagerDuty})
+	}
+	if cfg.Healthchecks != nil && cfg.Healthchecks.Enabled {
+		notifiers = append(notifiers, &HealthchecksNotifier{cfg: cfg.Healthchecks})
+	}
+	if cfg.Telegram != nil && cfg.Telegram.Enabled {
+		notifiers = append(notifiers, &TelegramNotifier{cfg: cfg.Telegram})
+	}
+	if cfg.Pushover != nil && cfg.Pushover.Enabled {
+		notifiers = append(notifiers, &PushoverNotifier{cfg: cfg.Pushover})
+	}
+	if cfg.Twilio != nil && cfg.Twilio.Enabled {
+		notifiers = append(notifiers, &TwilioNotifier{cfg: cfg.Twilio})
+	}
+	if cfg.Gotify != nil && cfg.Gotify.Enabled {
+		notifiers = append(notifiers, &GotifyNotifier{cfg: cfg.Gotify})
+	}
+	if cfg.MQTT != nil && cfg.MQTT.Enabled {
+		notifiers = append(notifiers, &MQTTNotifier{cfg: cfg.MQTT})
+	}
+	return notifiers
+}
+
+// notificationTimeout bounds a single notification HTTP request
+const notificationTimeout = 30 * time.Second
+
+// RetryError is returned when a notification still failed after retrying
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	if e.Attempts == 1 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (after %d attempts)", e.Err, e.Attempts)
+}
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// NotificationFailure records a notification that could not be delivered, so
+// lost alerts show up in the run report
+type NotificationFailure struct {
+	Channel  string `json:"channel"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error"`
+	FailedAt string `json:"failed_at"`
+}
+
+// NewNotificationFailure describes err, a failed send on channel
+func NewNotificationFailure(channel string, err error) NotificationFailure {
+	failure := NotificationFailure{
+		Channel:  channel,
+		Attempts: 1,
+		Error:    err.Error(),
+		FailedAt: time.Now().Format(time.RFC3339),
+	}
+	var retryErr *RetryError
+	if errors.As(err, &retryErr) {
+		failure.Attempts = retryErr.Attempts
+	}
+	return failure
+}
+
+// httpStatusError is returned when a server answers with a non-2xx status
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("server returned %s: %s", e.Status, e.Body)
+}
+
+// postJSON sends payload as a JSON POST and fails on non-2xx responses
+func postJSON(ctx context.Context, url string, payload any, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	return postBody(ctx, url, body, headers)
+}
+
+// postBody sends an already encoded JSON body, returning *httpStatusError on
+// non-2xx responses
+func postBody(ctx context.Context, url string, body []byte, headers map[string]string) error {
+	ctx, cancel := context.WithTimeout(ctx, notificationTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &httpStatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       strings.TrimSpace(string(msg)),
+		}
+	}
+
+	return nil
+}
+
+// worstSeverity returns the highest severity among the issues not
+// suppressed by the baseline
+func worstSeverity(issues []ValidationIssue) (ValidationSeverity, bool) {
+	worst, found := SeverityInfo, false
+	for _, issue := range issues {
+		if issue.Suppressed {
+			continue
+		}
+		if !found || issue.Severity > worst {
+			worst, found = issue.Severity, true
+		}
+	}
+	return worst, found
+}
+
+// severityColor returns the hex color used for a severity across channels,
+// matching the email template
+func severityColor(s ValidationSeverity) string {
+	switch s {
+	case SeverityCritical:
+		return "#dc3545"
+	case SeverityError:
+		return "#fd7e14"
+	case SeverityWarning:
+		return "#ffc107"
+	default:
+		return "#17a2b8"
+	}
+}
+
+// reportsWithIssues flattens the backup reports that have at least one issue
+func reportsWithIssues(report RunReport) []BackupReport {
+	var out []BackupReport
+	for _, scanReport := range report.Results {
+		for _, br := range scanReport.Reports {
+			if len(br.Issues) > 0 {
+				out = append(out, br)
+			}
+		}
+	}
+	return out
+}
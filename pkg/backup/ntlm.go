@@ -0,0 +1,397 @@
+package winbackupchecker
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rc4"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// NTLMv2 authentication (MS-NLMP) wrapped in SPNEGO (RFC 4178), as the
+// built-in SMB client signs in with. Neither the NTLM MIC nor the SPNEGO
+// mechListMIC is sent; both are optional, and servers accept NTLMv2
+// without them.
+
+// NTLM negotiate flags
+const (
+	ntlmNegotiateUnicode        = 0x00000001
+	ntlmRequestTarget           = 0x00000004
+	ntlmNegotiateSign           = 0x00000010
+	ntlmNegotiateNTLM           = 0x00000200
+	ntlmNegotiateAnonymous      = 0x00000800
+	ntlmNegotiateAlwaysSign     = 0x00008000
+	ntlmNegotiateExtendedSecure = 0x00080000
+	ntlmNegotiateTargetInfo     = 0x00800000
+	ntlmNegotiate128            = 0x20000000
+	ntlmNegotiateKeyExch        = 0x40000000
+	ntlmNegotiate56             = 0x80000000
+
+	ntlmClientFlags = ntlmNegotiateUnicode | ntlmRequestTarget | ntlmNegotiateSign |
+		ntlmNegotiateNTLM | ntlmNegotiateAlwaysSign | ntlmNegotiateExtendedSecure |
+		ntlmNegotiateTargetInfo | ntlmNegotiate128 | ntlmNegotiateKeyExch | ntlmNegotiate56
+)
+
+// ntlmSignature starts every NTLM message
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// ntlmAvTimestamp is the target info entry holding the server time
+const ntlmAvTimestamp = 7
+
+// ntlmClient holds the state of one NTLM sign-in
+type ntlmClient struct {
+	user, domain, password string
+
+	// sessionKey is the key the session is signed with, once authenticated;
+	// nil for anonymous sign-ins
+	sessionKey []byte
+}
+
+// newNTLMClient signs in as username, given as user, DOMAIN\user or
+// user@domain, or anonymously when username is empty
+func newNTLMClient(username, password string) *ntlmClient {
+	c := &ntlmClient{user: username, password: password}
+	if domain, user, ok := strings.Cut(username, `\`); ok {
+		c.domain, c.user = domain, user
+	}
+	return c
+}
+
+func (c *ntlmClient) anonymous() bool {
+	return c.user == "" && c.password == ""
+}
+
+// negotiate returns the NEGOTIATE_MESSAGE starting the sign-in
+func (c *ntlmClient) negotiate() []byte {
+	flags := uint32(ntlmClientFlags)
+	if c.anonymous() {
+		flags |= ntlmNegotiateAnonymous
+	}
+	msg := make([]byte, 32)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], flags)
+	// Empty domain and workstation fields point past the message
+	binary.LittleEndian.PutUint32(msg[20:], 32)
+	binary.LittleEndian.PutUint32(msg[28:], 32)
+	return msg
+}
+
+// authenticate answers the server's CHALLENGE_MESSAGE with the
+// AUTHENTICATE_MESSAGE, setting the session key
+func (c *ntlmClient) authenticate(challenge []byte) ([]byte, error) {
+	if len(challenge) < 48 || !bytes.Equal(challenge[:8], ntlmSignature) || binary.LittleEndian.Uint32(challenge[8:]) != 2 {
+		return nil, errors.New("invalid NTLM challenge")
+	}
+	flags := binary.LittleEndian.Uint32(challenge[20:]) & (ntlmClientFlags | ntlmNegotiateAnonymous)
+	serverChallenge := challenge[24:32]
+	targetInfo, err := ntlmField(challenge, 40)
+	if err != nil {
+		return nil, err
+	}
+
+	var lmResponse, ntResponse, encryptedKey []byte
+	if c.anonymous() {
+		lmResponse = []byte{0}
+		flags &^= ntlmNegotiateKeyExch | ntlmNegotiateSign
+	} else {
+		lmResponse, ntResponse, encryptedKey, err = c.respond(serverChallenge, targetInfo, flags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fields := [][]byte{lmResponse, ntResponse, utf16LE(c.domain), utf16LE(c.user), nil, encryptedKey}
+	const headerLen = 64
+	msg := make([]byte, headerLen)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+	offset := headerLen
+	for i, field := range fields {
+		pos := 12 + 8*i
+		binary.LittleEndian.PutUint16(msg[pos:], uint16(len(field)))
+		binary.LittleEndian.PutUint16(msg[pos+2:], uint16(len(field)))
+		binary.LittleEndian.PutUint32(msg[pos+4:], uint32(offset))
+		offset += len(field)
+	}
+	binary.LittleEndian.PutUint32(msg[60:], flags)
+	for _, field := range fields {
+		msg = append(msg, field...)
+	}
+	return msg, nil
+}
+
+// respond computes the NTLMv2 responses to serverChallenge and the session
+// key, returning the key encrypted for the server when keys are exchanged
+func (c *ntlmClient) respond(serverChallenge, targetInfo []byte, flags uint32) (lm, nt, encryptedKey []byte, err error) {
+	clientChallenge := make([]byte, 8)
+	if _, err := rand.Read(clientChallenge); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The server time avoids failures from clock skew, and makes the LM
+	// response redundant
+	timestamp, hasTimestamp := ntlmTargetTimestamp(targetInfo)
+	if !hasTimestamp {
+		timestamp = fileTime(time.Now())
+	}
+
+	responseKey := ntowfV2(c.user, c.domain, c.password)
+	temp := ntlmV2Blob(timestamp, clientChallenge, targetInfo)
+	proof := hmacMD5(responseKey, serverChallenge, temp)
+	nt = append(proof, temp...)
+	if hasTimestamp {
+		lm = make([]byte, 24)
+	} else {
+		lm = append(hmacMD5(responseKey, serverChallenge, clientChallenge), clientChallenge...)
+	}
+
+	keyExchangeKey := hmacMD5(responseKey, proof)
+	c.sessionKey = keyExchangeKey
+	if flags&ntlmNegotiateKeyExch != 0 {
+		c.sessionKey = make([]byte, 16)
+		if _, err := rand.Read(c.sessionKey); err != nil {
+			return nil, nil, nil, err
+		}
+		cipher, err := rc4.NewCipher(keyExchangeKey)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		encryptedKey = make([]byte, 16)
+		cipher.XORKeyStream(encryptedKey, c.sessionKey)
+	}
+	return lm, nt, encryptedKey, nil
+}
+
+// ntowfV2 returns the NTLMv2 response key of an account
+func ntowfV2(user, domain, password string) []byte {
+	ntHash := md4Sum(utf16LE(password))
+	return hmacMD5(ntHash[:], utf16LE(strings.ToUpper(user)+domain))
+}
+
+// ntlmV2Blob returns the client blob the NTLMv2 response proves, holding
+// the time, the client challenge and the server's target info
+func ntlmV2Blob(timestamp uint64, clientChallenge, targetInfo []byte) []byte {
+	temp := []byte{1, 1, 0, 0, 0, 0, 0, 0}
+	temp = binary.LittleEndian.AppendUint64(temp, timestamp)
+	temp = append(temp, clientChallenge...)
+	temp = append(temp, 0, 0, 0, 0)
+	temp = append(temp, targetInfo...)
+	return append(temp, 0, 0, 0, 0)
+}
+
+// ntlmField returns the payload of the length, max length and offset
+// field of msg at pos
+func ntlmField(msg []byte, pos int) ([]byte, error) {
+	length := int(binary.LittleEndian.Uint16(msg[pos:]))
+	offset := int(binary.LittleEndian.Uint32(msg[pos+4:]))
+	if length == 0 {
+		return nil, nil
+	}
+	if offset > len(msg) || length > len(msg)-offset {
+		return nil, errors.New("invalid NTLM challenge: field out of range")
+	}
+	return msg[offset : offset+length], nil
+}
+
+// ntlmTargetTimestamp returns the server time from the target info list
+func ntlmTargetTimestamp(targetInfo []byte) (uint64, bool) {
+	for len(targetInfo) >= 4 {
+		id := binary.LittleEndian.Uint16(targetInfo)
+		n := int(binary.LittleEndian.Uint16(targetInfo[2:]))
+		if id == 0 || 4+n > len(targetInfo) {
+			break
+		}
+		if id == ntlmAvTimestamp && n == 8 {
+			return binary.LittleEndian.Uint64(targetInfo[4:]), true
+		}
+		targetInfo = targetInfo[4+n:]
+	}
+	return 0, false
+}
+
+// fileTime returns t as a Windows FILETIME, in 100ns intervals since 1601
+func fileTime(t time.Time) uint64 {
+	return uint64(t.UnixNano()/100) + 116444736000000000
+}
+
+// fromFileTime returns the time of a Windows FILETIME
+func fromFileTime(ft uint64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, (int64(ft)-116444736000000000)*100)
+}
+
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(md5.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// utf16LE encodes s as little-endian UTF-16, as Windows protocols do
+func utf16LE(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(b[2*i:], u)
+	}
+	return b
+}
+
+// fromUTF16LE decodes little-endian UTF-16
+func fromUTF16LE(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// md4Sum returns the MD4 digest of data (RFC 1320), which NTLM hashes
+// passwords with
+func md4Sum(data []byte) [16]byte {
+	s := [4]uint32{0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476}
+
+	msg := append([]byte(nil), data...)
+	msg = append(msg, 0x80)
+	for len(msg)%64 != 56 {
+		msg = append(msg, 0)
+	}
+	msg = binary.LittleEndian.AppendUint64(msg, uint64(len(data))*8)
+
+	var x [16]uint32
+	for block := msg; len(block) > 0; block = block[64:] {
+		for i := range x {
+			x[i] = binary.LittleEndian.Uint32(block[4*i:])
+		}
+		a, b, c, d := s[0], s[1], s[2], s[3]
+
+		f := func(x, y, z uint32) uint32 { return x&y | ^x&z }
+		g := func(x, y, z uint32) uint32 { return x&y | x&z | y&z }
+		h := func(x, y, z uint32) uint32 { return x ^ y ^ z }
+
+		for _, i := range [4]int{0, 4, 8, 12} {
+			a = bits.RotateLeft32(a+f(b, c, d)+x[i], 3)
+			d = bits.RotateLeft32(d+f(a, b, c)+x[i+1], 7)
+			c = bits.RotateLeft32(c+f(d, a, b)+x[i+2], 11)
+			b = bits.RotateLeft32(b+f(c, d, a)+x[i+3], 19)
+		}
+		for _, i := range [4]int{0, 1, 2, 3} {
+			a = bits.RotateLeft32(a+g(b, c, d)+x[i]+0x5a827999, 3)
+			d = bits.RotateLeft32(d+g(a, b, c)+x[i+4]+0x5a827999, 5)
+			c = bits.RotateLeft32(c+g(d, a, b)+x[i+8]+0x5a827999, 9)
+			b = bits.RotateLeft32(b+g(c, d, a)+x[i+12]+0x5a827999, 13)
+		}
+		for _, i := range [4]int{0, 2, 1, 3} {
+			a = bits.RotateLeft32(a+h(b, c, d)+x[i]+0x6ed9eba1, 3)
+			d = bits.RotateLeft32(d+h(a, b, c)+x[i+8]+0x6ed9eba1, 9)
+			c = bits.RotateLeft32(c+h(d, a, b)+x[i+4]+0x6ed9eba1, 11)
+			b = bits.RotateLeft32(b+h(c, d, a)+x[i+12]+0x6ed9eba1, 15)
+		}
+
+		s[0] += a
+		s[1] += b
+		s[2] += c
+		s[3] += d
+	}
+
+	var sum [16]byte
+	for i, v := range s {
+		binary.LittleEndian.PutUint32(sum[4*i:], v)
+	}
+	return sum
+}
+
+// SPNEGO object identifiers, DER-encoded
+var (
+	spnegoOID = []byte{0x06, 0x06, 0x2b, 0x06, 0x01, 0x05, 0x05, 0x02}
+	ntlmOID   = []byte{0x06, 0x0a, 0x2b, 0x06, 0x01, 0x04, 0x01, 0x82, 0x37, 0x02, 0x02, 0x0a}
+)
+
+// spnegoInit wraps the first NTLM message in a NegTokenInit offering NTLM
+func spnegoInit(token []byte) []byte {
+	mechTypes := derTag(0xa0, derTag(0x30, ntlmOID))
+	mechToken := derTag(0xa2, derTag(0x04, token))
+	negTokenInit := derTag(0xa0, derTag(0x30, append(mechTypes, mechToken...)))
+	return derTag(0x60, append(append([]byte(nil), spnegoOID...), negTokenInit...))
+}
+
+// spnegoResponse wraps a further NTLM message in a NegTokenResp
+func spnegoResponse(token []byte) []byte {
+	return derTag(0xa1, derTag(0x30, derTag(0xa2, derTag(0x04, token))))
+}
+
+// spnegoToken returns the NTLM message in the server's NegTokenResp
+func spnegoToken(data []byte) ([]byte, error) {
+	resp, _, err := derNext(data, 0xa1)
+	if err != nil {
+		return nil, err
+	}
+	seq, _, err := derNext(resp, 0x30)
+	if err != nil {
+		return nil, err
+	}
+	for len(seq) > 0 {
+		tag := seq[0]
+		var content []byte
+		content, seq, err = derNext(seq, tag)
+		if err != nil {
+			return nil, err
+		}
+		if tag == 0xa2 {
+			token, _, err := derNext(content, 0x04)
+			return token, err
+		}
+	}
+	return nil, errors.New("SPNEGO response holds no token")
+}
+
+// derTag encodes content with tag and a DER length
+func derTag(tag byte, content []byte) []byte {
+	out := []byte{tag}
+	switch n := len(content); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	case n <= 0xffff:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	default:
+		out = append(out, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(out, content...)
+}
+
+// derNext reads the element with tag at the start of data, returning its
+// content and what follows it
+func derNext(data []byte, tag byte) (content, rest []byte, err error) {
+	if len(data) < 2 || data[0] != tag {
+		return nil, nil, fmt.Errorf("invalid SPNEGO token: expected tag %#x", tag)
+	}
+	n, pos := int(data[1]), 2
+	if n&0x80 != 0 {
+		size := n & 0x7f
+		if size == 0 || size > 3 || len(data) < 2+size {
+			return nil, nil, errors.New("invalid SPNEGO token: bad length")
+		}
+		n = 0
+		for _, b := range data[2 : 2+size] {
+			n = n<<8 | int(b)
+		}
+		pos += size
+	}
+	if n > len(data)-pos {
+		return nil, nil, errors.New("invalid SPNEGO token: truncated")
+	}
+	return data[pos : pos+n], data[pos+n:], nil
+}
@@ -0,0 +1,96 @@
+package winbackupchecker
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestMD4(t *testing.T) {
+	// RFC 1320, appendix A.5
+	tests := map[string]string{
+		"":                           "31d6cfe0d16ae931b73c59d7e0c089c0",
+		"a":                          "bde52cb31de33e46245e05fbdbd6fb24",
+		"abc":                        "a448017aaf21d8525fc10ae87aa6729d",
+		"message digest":             "d9130a8164549fe818874806e1c7014b",
+		"abcdefghijklmnopqrstuvwxyz": "d79e1c308aa5bbcdeea8ed63df412da9",
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789": "043f8582f241db351ce627e153e7f0e4",
+		strings.Repeat("1234567890", 8):                                  "e33b4ddc9c38f2199c3e7b164fcc0536",
+	}
+	for in, want := range tests {
+		sum := md4Sum([]byte(in))
+		if got := hex.EncodeToString(sum[:]); got != want {
+			t.Errorf("md4(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestNTLMv2(t *testing.T) {
+	// MS-NLMP 4.2.1 and 4.2.4
+	ntHash := md4Sum(utf16LE("Password"))
+	if got, want := hex.EncodeToString(ntHash[:]), "a4f49c406510bdcab6824ee7c30fd852"; got != want {
+		t.Errorf("NTOWFv1 = %s, want %s", got, want)
+	}
+
+	responseKey := ntowfV2("User", "Domain", "Password")
+	if got, want := hex.EncodeToString(responseKey), "0c868a403bfd7a93a3001ef22ef02e3f"; got != want {
+		t.Errorf("NTOWFv2 = %s, want %s", got, want)
+	}
+
+	serverChallenge := mustHex(t, "0123456789abcdef")
+	clientChallenge := mustHex(t, "aaaaaaaaaaaaaaaa")
+	targetInfo := mustHex(t, "02000c0044006f006d00610069006e00 01000c005300650072007600650072000000 0000")
+	blob := ntlmV2Blob(0, clientChallenge, targetInfo)
+	want := mustHex(t, "0101000000000000 0000000000000000 aaaaaaaaaaaaaaaa 00000000"+
+		" 02000c0044006f006d00610069006e00 01000c005300650072007600650072000000 0000 00000000")
+	if !bytes.Equal(blob, want) {
+		t.Errorf("blob = %x, want %x", blob, want)
+	}
+
+	proof := hmacMD5(responseKey, serverChallenge, blob)
+	if got, want := hex.EncodeToString(proof), "68cd0ab851e51c96aabc927bebef6a1c"; got != want {
+		t.Errorf("NTProofStr = %s, want %s", got, want)
+	}
+	if got, want := hex.EncodeToString(hmacMD5(responseKey, proof)), "8de40ccadbc14a82f15cb0ad0de95ca3"; got != want {
+		t.Errorf("session base key = %s, want %s", got, want)
+	}
+}
+
+func TestNTLMRespond(t *testing.T) {
+	c := newNTLMClient(`Domain\User`, "Password")
+	serverChallenge := mustHex(t, "0123456789abcdef")
+	// MsvAvTimestamp of 1 second after the file time epoch, then MsvAvEOL
+	targetInfo := mustHex(t, "07000800 8096980000000000 00000000")
+	lm, nt, encryptedKey, err := c.respond(serverChallenge, targetInfo, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(lm, make([]byte, 24)) {
+		t.Errorf("lm = %x, want zeros when the server sends a timestamp", lm)
+	}
+	if encryptedKey != nil {
+		t.Errorf("encrypted key = %x without key exchange", encryptedKey)
+	}
+
+	// The response is the proof of the blob that follows it, with the
+	// server's time and the random client challenge
+	clientChallenge := nt[32:40]
+	blob := ntlmV2Blob(10000000, clientChallenge, targetInfo)
+	if !bytes.Equal(nt[16:], blob) {
+		t.Errorf("blob = %x, want %x", nt[16:], blob)
+	}
+	responseKey := ntowfV2("User", "Domain", "Password")
+	if proof := hmacMD5(responseKey, serverChallenge, blob); !bytes.Equal(nt[:16], proof) {
+		t.Errorf("proof = %x, want %x", nt[:16], proof)
+	}
+}
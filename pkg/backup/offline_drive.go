@@ -0,0 +1,219 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OfflineDriveOptions bring the disk of a backup path on a drive letter
+// online for the scan and take it offline again afterwards, for USB drives
+// that are rotated offsite and kept offline or BitLocker-locked in between
+type OfflineDriveOptions struct {
+	// Disks are the disks that may hold the path, one for each drive in
+	// the rotation; the first one attached is used
+	Disks []OfflineDisk `json:"disks"`
+}
+
+// OfflineDisk is a disk of the rotation and the key unlocking its BitLocker
+// volume, if it is encrypted
+type OfflineDisk struct {
+	// Serial is the serial number Get-Disk shows for the disk
+	Serial string `json:"serial"`
+
+	RecoveryPassword string `json:"recovery_password,omitempty" secret:"true"`
+	Password         string `json:"password,omitempty" secret:"true"`
+
+	// RecoveryPasswordCredential and PasswordCredential name keys stored
+	// with credentials set, used when RecoveryPassword and Password are
+	// empty
+	RecoveryPasswordCredential string `json:"recovery_password_credential,omitempty"`
+	PasswordCredential         string `json:"password_credential,omitempty"`
+}
+
+// Validate checks that each disk has a serial number and one key at most
+func (o *OfflineDriveOptions) Validate() error {
+	if len(o.Disks) == 0 {
+		return fmt.Errorf("at least one disk is required")
+	}
+	for _, disk := range o.Disks {
+		if strings.TrimSpace(disk.Serial) == "" {
+			return fmt.Errorf("disk serial is required")
+		}
+		keys := 0
+		for _, key := range []string{disk.RecoveryPassword, disk.Password, disk.RecoveryPasswordCredential, disk.PasswordCredential} {
+			if key != "" {
+				keys++
+			}
+		}
+		if keys > 1 {
+			return fmt.Errorf("disk %s: use one of recovery_password, password, recovery_password_credential or password_credential", disk.Serial)
+		}
+	}
+	return nil
+}
+
+// offlineDriveTimeout bounds bringing a disk online and unlocking it, and
+// taking it offline again
+const offlineDriveTimeout = 2 * time.Minute
+
+// driveLetter returns the drive of a path such as E:\Backups, or "" when
+// path is not on a drive letter
+func driveLetter(path string) string {
+	if len(path) < 2 || path[1] != ':' {
+		return ""
+	}
+	if c := path[0] | 0x20; c < 'a' || c > 'z' {
+		return ""
+	}
+	return strings.ToUpper(path[:2])
+}
+
+// offlineDriveScript brings the first attached disk of $serials online,
+// gives its data partition the drive letter $letter and unlocks its
+// BitLocker volume with the key in the environment. It prints the disk and
+// partition number followed by the steps taken, for offlineDriveReleaseScript
+// to undo.
+const offlineDriveScript = `$ErrorActionPreference = 'Stop'
+trap { [Console]::Error.WriteLine($_.Exception.Message); exit 1 }
+$index = -1
+$disk = $null
+foreach ($d in Get-Disk) {
+	$i = [array]::IndexOf($serials, "$($d.SerialNumber)".Trim())
+	if ($i -ge 0) { $index = $i; $disk = $d; break }
+}
+if (-not $disk) { throw "none of the disks $($serials -join ', ') is attached" }
+$steps = @()
+if ($disk.IsOffline) {
+	Set-Disk -Number $disk.Number -IsOffline $false
+	$steps += 'online'
+}
+$partition = Get-Partition -DiskNumber $disk.Number | Where-Object { $_.DriveLetter -eq $letter[0] } | Select-Object -First 1
+if (-not $partition) {
+	$partition = Get-Partition -DiskNumber $disk.Number | Where-Object { $_.Type -in 'Basic', 'IFS' } | Sort-Object Size -Descending | Select-Object -First 1
+	if (-not $partition) { throw "disk $($disk.Number) has no data partition" }
+	if ($partition.DriveLetter -match '[A-Z]') {
+		Set-Partition -DiskNumber $disk.Number -PartitionNumber $partition.PartitionNumber -NewDriveLetter $letter[0]
+		$steps += "letter:$($partition.DriveLetter)"
+	} else {
+		Add-PartitionAccessPath -DiskNumber $disk.Number -PartitionNumber $partition.PartitionNumber -AccessPath "$letter\"
+		$steps += 'letter'
+	}
+}
+$bitlocker = Get-BitLockerVolume -MountPoint $letter -ErrorAction SilentlyContinue
+if ($bitlocker -and $bitlocker.LockStatus -eq 'Locked') {
+	$recovery = [Environment]::GetEnvironmentVariable("WBC_RECOVERY_PASSWORD_$index")
+	$password = [Environment]::GetEnvironmentVariable("WBC_PASSWORD_$index")
+	if ($recovery) {
+		Unlock-BitLocker -MountPoint $letter -RecoveryPassword $recovery | Out-Null
+	} elseif ($password) {
+		Unlock-BitLocker -MountPoint $letter -Password (ConvertTo-SecureString $password -AsPlainText -Force) | Out-Null
+	} else {
+		throw "$letter is BitLocker-locked and disk $($serials[$index]) has no key"
+	}
+	$steps += 'unlock'
+}
+$disk.Number
+$partition.PartitionNumber
+$steps`
+
+// offlineDriveReleaseScript undoes the steps of offlineDriveScript on disk
+// $disk, partition $partition. A partition that had another drive letter,
+// recorded as a letter:X step, gets that letter back.
+const offlineDriveReleaseScript = `$ErrorActionPreference = 'Stop'
+trap { [Console]::Error.WriteLine($_.Exception.Message); exit 1 }
+if ($steps -contains 'unlock') { Lock-BitLocker -MountPoint $letter -ForceDismount | Out-Null }
+if ($steps -contains 'letter') { Remove-PartitionAccessPath -DiskNumber $disk -PartitionNumber $partition -AccessPath "$letter\" }
+$original = $steps | Where-Object { $_ -like 'letter:?' } | Select-Object -First 1
+if ($original) { Set-Partition -DiskNumber $disk -PartitionNumber $partition -NewDriveLetter $original[-1] }
+if ($steps -contains 'online') { Set-Disk -Number $disk -IsOffline $true }`
+
+// connectOfflineDrive brings the disk of the path online and unlocks it,
+// returning a function that locks it and takes it offline again. Only the
+// steps that were needed are undone, so a drive someone attached and
+// unlocked by hand stays as it is.
+func (p BackupPath) connectOfflineDrive() (disconnect func() error, err error) {
+	if runtime.GOOS != "windows" {
+		return nil, fmt.Errorf("offline drives are only available on Windows")
+	}
+	letter := driveLetter(p.Path)
+
+	serials := make([]string, len(p.OfflineDrive.Disks))
+	env := os.Environ()
+	for i, disk := range p.OfflineDrive.Disks {
+		serials[i] = powerShellQuote(strings.TrimSpace(disk.Serial))
+		recovery, password := disk.RecoveryPassword, disk.Password
+		if recovery == "" && disk.RecoveryPasswordCredential != "" {
+			if recovery, err = GetCredential(disk.RecoveryPasswordCredential); err != nil {
+				return nil, err
+			}
+		}
+		if password == "" && disk.PasswordCredential != "" {
+			if password, err = GetCredential(disk.PasswordCredential); err != nil {
+				return nil, err
+			}
+		}
+		// Keys go through the environment, as command lines are visible to
+		// other processes
+		if recovery != "" {
+			env = append(env, fmt.Sprintf("WBC_RECOVERY_PASSWORD_%d=%s", i, recovery))
+		}
+		if password != "" {
+			env = append(env, fmt.Sprintf("WBC_PASSWORD_%d=%s", i, password))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), offlineDriveTimeout)
+	defer cancel()
+	script := fmt.Sprintf("$serials = @(%s)\n$letter = %s\n%s", strings.Join(serials, ", "), powerShellQuote(letter), offlineDriveScript)
+	output, err := runPowerShell(ctx, script, env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bring %s online: %w", letter, err)
+	}
+	fields := strings.Fields(output)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("unexpected output bringing %s online: %q", letter, output)
+	}
+	disk, errDisk := strconv.Atoi(fields[0])
+	partition, errPartition := strconv.Atoi(fields[1])
+	if errDisk != nil || errPartition != nil {
+		return nil, fmt.Errorf("unexpected output bringing %s online: %q", letter, output)
+	}
+	steps := fields[2:]
+	if len(steps) == 0 {
+		return func() error { return nil }, nil
+	}
+
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), offlineDriveTimeout)
+		defer cancel()
+		quoted := make([]string, len(steps))
+		for i, step := range steps {
+			quoted[i] = powerShellQuote(step)
+		}
+		script := fmt.Sprintf("$disk = %d\n$partition = %d\n$letter = %s\n$steps = @(%s)\n%s",
+			disk, partition, powerShellQuote(letter), strings.Join(quoted, ", "), offlineDriveReleaseScript)
+		if _, err := runPowerShell(ctx, script, nil); err != nil {
+			return fmt.Errorf("failed to take %s offline: %w", letter, err)
+		}
+		return nil
+	}, nil
+}
+
+// powerShellQuote quotes s as a PowerShell string literal
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// runPowerShell runs script with env, or the environment of the checker
+// when env is nil, as runCommand runs commands
+func runPowerShell(ctx context.Context, script string, env []string) (string, error) {
+	cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	cmd.Env = env
+	return commandOutput(cmd)
+}
@@ -0,0 +1,235 @@
+package winbackupchecker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pagerDutyEventsURL is the Events API v2 enqueue endpoint
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyConfig configures PagerDuty incidents for invalid backups
+type PagerDutyConfig struct {
+	Enabled    bool   `json:"enabled"`
+	RoutingKey string `json:"routing_key" secret:"true"`
+	EventsURL  string `json:"events_url,omitempty"`
+	StateFile  string `json:"state_file,omitempty"`
+}
+
+// Validate checks if PagerDuty configuration is valid
+func (c *PagerDutyConfig) Validate() error {
+	if c.RoutingKey == "" {
+		return fmt.Errorf("routing_key is required")
+	}
+	return nil
+}
+
+// PagerDutyNotifier triggers one incident per backup root and machine with
+// invalid backups, and resolves it once a later run finds that machine clean.
+// Open incidents are tracked in a state file between runs.
+type PagerDutyNotifier struct {
+	cfg *PagerDutyConfig
+}
+
+func (n *PagerDutyNotifier) Name() string { return "pagerduty" }
+
+// pagerDutyIncident records an incident the checker has triggered
+type pagerDutyIncident struct {
+	Root        string    `json:"root"`
+	Machine     string    `json:"machine"`
+	TriggeredAt time.Time `json:"triggered_at"`
+}
+
+// pagerDutyMachine collects the backup reports of one root and machine
+type pagerDutyMachine struct {
+	root    string
+	machine string
+	reports []BackupReport
+}
+
+// Notify triggers incidents for machines with invalid sets and resolves
+// previously triggered incidents for machines that are now valid
+func (n *PagerDutyNotifier) Notify(ctx context.Context, report RunReport) error {
+	statePath := n.cfg.StateFile
+	if statePath == "" {
+		statePath = "pagerduty-state.json"
+	}
+
+	open, err := loadPagerDutyState(statePath)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, m := range groupReportsByMachine(report) {
+		key := pagerDutyDedupKey(m.root, m.machine)
+		invalid := invalidReports(m.reports)
+
+		if len(invalid) > 0 {
+			if err := n.send(ctx, n.triggerEvent(key, m, invalid, report.Timestamp)); err != nil {
+				errs = append(errs, fmt.Sprintf("trigger %s: %v", m.machine, err))
+				continue
+			}
+			if _, ok := open[key]; !ok {
+				open[key] = pagerDutyIncident{Root: m.root, Machine: m.machine, TriggeredAt: time.Now()}
+			}
+			continue
+		}
+
+		if _, ok := open[key]; ok {
+			event := map[string]any{
+				"routing_key":  n.cfg.RoutingKey,
+				"event_action": "resolve",
+				"dedup_key":    key,
+			}
+			if err := n.send(ctx, event); err != nil {
+				errs = append(errs, fmt.Sprintf("resolve %s: %v", m.machine, err))
+				continue
+			}
+			delete(open, key)
+		}
+	}
+
+	if err := savePagerDutyState(statePath, open); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("pagerduty: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (n *PagerDutyNotifier) triggerEvent(key string, m pagerDutyMachine, invalid []BackupReport, timestamp string) map[string]any {
+	var issues []ValidationIssue
+	details := make(map[string][]string)
+	for _, br := range invalid {
+		set := filepath.Base(br.BackupDir)
+		for _, issue := range br.Issues {
+			issues = append(issues, issue)
+			details[set] = append(details[set], fmt.Sprintf("[%s] %s", strings.ToUpper(issue.Severity.String()), issue.Message))
+		}
+	}
+
+	severity, _ := worstSeverity(issues)
+
+	return map[string]any{
+		"routing_key":  n.cfg.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    key,
+		"payload": map[string]any{
+			"summary":        fmt.Sprintf("Backup validation failed for %s: %d invalid backup set(s)", m.machine, len(invalid)),
+			"source":         m.machine,
+			"severity":       pagerDutySeverity(severity),
+			"timestamp":      timestamp,
+			"component":      m.root,
+			"group":          m.machine,
+			"class":          "backup-validation",
+			"custom_details": details,
+		},
+	}
+}
+
+func (n *PagerDutyNotifier) send(ctx context.Context, event map[string]any) error {
+	url := n.cfg.EventsURL
+	if url == "" {
+		url = pagerDutyEventsURL
+	}
+	return postJSON(ctx, url, event, nil)
+}
+
+// pagerDutyDedupKey identifies the incident for one backup root and machine
+func pagerDutyDedupKey(root, machine string) string {
+	return "win-backup-checker:" + filepath.ToSlash(root) + ":" + machine
+}
+
+// pagerDutySeverity maps a validation severity to a PagerDuty severity
+func pagerDutySeverity(s ValidationSeverity) string {
+	switch s {
+	case SeverityCritical:
+		return "critical"
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// groupReportsByMachine splits each root's reports by machine folder, in a
+// stable order
+func groupReportsByMachine(report RunReport) []pagerDutyMachine {
+	var machines []pagerDutyMachine
+	for _, scanReport := range report.Results {
+		index := make(map[string]int)
+		for _, br := range scanReport.Reports {
+			name := MachineName(br.BackupDir)
+			i, ok := index[name]
+			if !ok {
+				i = len(machines)
+				index[name] = i
+				machines = append(machines, pagerDutyMachine{root: scanReport.Root, machine: name})
+			}
+			machines[i].reports = append(machines[i].reports, br)
+		}
+	}
+
+	sort.SliceStable(machines, func(i, j int) bool {
+		if machines[i].root != machines[j].root {
+			return machines[i].root < machines[j].root
+		}
+		return machines[i].machine < machines[j].machine
+	})
+	return machines
+}
+
+func invalidReports(reports []BackupReport) []BackupReport {
+	var out []BackupReport
+	for _, br := range reports {
+		if !br.Valid {
+			out = append(out, br)
+		}
+	}
+	return out
+}
+
+func loadPagerDutyState(path string) (map[string]pagerDutyIncident, error) {
+	open := make(map[string]pagerDutyIncident)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return open, nil
+		}
+		return nil, fmt.Errorf("failed to read pagerduty state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &open); err != nil {
+		return nil, fmt.Errorf("failed to parse pagerduty state: %w", err)
+	}
+	return open, nil
+}
+
+func savePagerDutyState(path string, open map[string]pagerDutyIncident) error {
+	data, err := json.MarshalIndent(open, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal pagerduty state: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write pagerduty state: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace pagerduty state: %w", err)
+	}
+	return nil
+}
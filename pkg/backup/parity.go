@@ -0,0 +1,116 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Parity file locations
+const (
+	ParityLocationSet     = "set"
+	ParityLocationSibling = "sibling"
+)
+
+// parityIndexName is the PAR2 index file written for each backup set
+const parityIndexName = "recovery.par2"
+
+// ParityConfig controls generating PAR2 recovery data for valid backup sets,
+// so damage found by later runs can be repaired
+type ParityConfig struct {
+	Enabled    bool   `json:"enabled"`
+	Redundancy int    `json:"redundancy,omitempty"`
+	Location   string `json:"location,omitempty"`
+	Par2Path   string `json:"par2_path,omitempty"`
+}
+
+// Validate checks if parity configuration is valid
+func (c *ParityConfig) Validate() error {
+	if c.Redundancy < 0 || c.Redundancy > 100 {
+		return fmt.Errorf("redundancy must be between 1 and 100 percent")
+	}
+	switch c.Location {
+	case "", ParityLocationSet, ParityLocationSibling:
+	default:
+		return fmt.Errorf("location must be %q or %q", ParityLocationSet, ParityLocationSibling)
+	}
+	return nil
+}
+
+// parityDir returns the sibling folder holding a set's recovery data. Its
+// extension keeps it from being discovered as a backup set.
+func parityDir(setPath string) string {
+	return setPath + ".parity"
+}
+
+// parityIndexPath returns where the PAR2 index file for a set is written
+func (c *ParityConfig) parityIndexPath(setPath string) string {
+	if c.Location == ParityLocationSibling {
+		return filepath.Join(parityDir(setPath), parityIndexName)
+	}
+	return filepath.Join(setPath, parityIndexName)
+}
+
+// generateParity creates PAR2 recovery data for every valid set that has
+// none yet. Sets that may still be in progress are left for a later run.
+func generateParity(ctx context.Context, backupSets []BackupSetInfo, reports []BackupReport, opts ScanOptions) {
+	cfg := opts.Parity
+
+	redundancy := cfg.Redundancy
+	if redundancy == 0 {
+		redundancy = 10
+	}
+
+	for i, setInfo := range backupSets {
+		if ctx.Err() != nil {
+			return
+		}
+
+		files := append(append([]string{}, setInfo.BackupFiles...), setInfo.CatalogFiles...)
+		if !reports[i].Valid || len(files) == 0 || time.Since(setInfo.ModTime) < time.Hour {
+			continue
+		}
+
+		indexPath := cfg.parityIndexPath(setInfo.Path)
+		if fileExists(indexPath) {
+			continue
+		}
+
+		opts.logf("Generating PAR2 recovery data for %s\n", filepath.Base(setInfo.Path))
+
+		if err := createParity(ctx, cfg.Par2Path, indexPath, setInfo.Path, redundancy, files); err != nil {
+			reports[i].addIssues(newIssue(IssueParityFailed, SeverityWarning,
+				msg("failed to generate PAR2 recovery data: %v", err),
+				indexPath,
+				"check that par2 is installed and the backup location is writable"))
+			continue
+		}
+
+		reports[i].addIssues(newIssue(IssueParityGenerated, SeverityInfo,
+			msg("generated PAR2 recovery data with %d%% redundancy", redundancy),
+			indexPath,
+			"enable manifests to verify the set against it on later runs"))
+	}
+}
+
+// createParity runs par2 create for files relative to baseDir, removing any
+// partial output if it fails
+func createParity(ctx context.Context, par2Path, indexPath, baseDir string, redundancy int, files []string) error {
+	dir := filepath.Dir(indexPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create parity dir: %w", err)
+	}
+
+	args := []string{"create", "-q", "-r" + strconv.Itoa(redundancy), "-B" + baseDir, indexPath}
+	if err := runPar2(ctx, par2Path, append(args, files...)...); err != nil {
+		matches, _ := filepath.Glob(filepath.Join(dir, "recovery*.par2"))
+		for _, match := range matches {
+			os.Remove(match)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package winbackupchecker
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// PathCheck is the result of probing a configured backup path without
+// validating it
+type PathCheck struct {
+	Path        string      `json:"path"`
+	Resolved    string      `json:"resolved"`
+	Reachable   bool        `json:"reachable"`
+	BackupRoots []string    `json:"backup_roots,omitempty"`
+	Storage     StorageType `json:"storage,omitempty"`
+	Elapsed     string      `json:"elapsed"`
+	Error       string      `json:"error,omitempty"`
+}
+
+// OK reports whether the path can be scanned
+func (c PathCheck) OK() bool {
+	return c.Reachable && len(c.BackupRoots) > 0
+}
+
+// CheckBackupPath resolves path and looks for the backup roots a scan would
+// validate. File system calls on an unreachable network share can block for
+// minutes, so the probe gives up after timeout.
+func CheckBackupPath(path string, timeout time.Duration) PathCheck {
+	check := PathCheck{Path: path, Resolved: path}
+	if abs, err := filepath.Abs(path); err == nil && !isRemotePath(path) {
+		check.Resolved = abs
+	}
+
+	start := time.Now()
+	done := make(chan PathCheck, 1)
+	go func() {
+		done <- probeBackupPath(check)
+	}()
+
+	select {
+	case check = <-done:
+	case <-time.After(timeout):
+		check.Error = fmt.Sprintf("no response within %v; the share may be offline", timeout)
+	}
+	check.Elapsed = time.Since(start).Round(time.Millisecond).String()
+	return check
+}
+
+func probeBackupPath(check PathCheck) PathCheck {
+	info, err := statBackup(check.Resolved)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if !info.IsDir() {
+		check.Error = "not a directory"
+		return check
+	}
+	check.Reachable = true
+	check.Storage = DetectStorageType(check.Resolved)
+
+	// Same layouts ScanFileBackupDir accepts: a backup root, or a folder of them
+	if fileExists(filepath.Join(check.Resolved, "MediaID.bin")) {
+		check.BackupRoots = []string{check.Resolved}
+		return check
+	}
+
+	entries, err := readBackupDir(check.Resolved)
+	if err != nil {
+		check.Error = fmt.Sprintf("failed to read directory: %v", err)
+		return check
+	}
+	for _, entry := range entries {
+		subPath := filepath.Join(check.Resolved, entry.Name())
+		if entry.IsDir() && fileExists(filepath.Join(subPath, "MediaID.bin")) {
+			check.BackupRoots = append(check.BackupRoots, subPath)
+		}
+	}
+	if len(check.BackupRoots) == 0 {
+		check.Error = "no backup roots found (missing MediaID.bin)"
+	}
+	return check
+}
@@ -0,0 +1,104 @@
+package winbackupchecker
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// prtgResult is one channel of a PRTG EXE/Script Advanced sensor
+type prtgResult struct {
+	Channel       string `xml:"channel" json:"channel"`
+	Value         string `xml:"value" json:"value"`
+	Unit          string `xml:"unit,omitempty" json:"unit,omitempty"`
+	CustomUnit    string `xml:"customunit,omitempty" json:"customunit,omitempty"`
+	Float         int    `xml:"float,omitempty" json:"float,omitempty"`
+	LimitMode     int    `xml:"limitmode,omitempty" json:"limitmode,omitempty"`
+	LimitMaxError string `xml:"limitmaxerror,omitempty" json:"limitmaxerror,omitempty"`
+}
+
+type prtgOutput struct {
+	XMLName xml.Name     `xml:"prtg" json:"-"`
+	Results []prtgResult `xml:"result" json:"result"`
+	Text    string       `xml:"text" json:"text"`
+	Error   int          `xml:"error,omitempty" json:"error,omitempty"`
+}
+
+// WritePRTG writes the run summary as PRTG custom sensor output, in XML or,
+// when asJSON is set, in PRTG's JSON format. The oldest backup age channel is
+// the age of the stalest machine's newest backup set.
+func WritePRTG(w io.Writer, report RunReport, asJSON bool) error {
+	out := prtgOutput{
+		Results: []prtgResult{
+			{Channel: "Valid Backups", Value: fmt.Sprint(report.Summary.ValidBackups), Unit: "Count"},
+			{Channel: "Invalid Backups", Value: fmt.Sprint(report.Summary.InvalidBackups), Unit: "Count", LimitMode: 1, LimitMaxError: "0"},
+			{Channel: "Failed Scans", Value: fmt.Sprint(report.Summary.FailedScans), Unit: "Count", LimitMode: 1, LimitMaxError: "0"},
+			{Channel: "Oldest Backup Age", Value: fmt.Sprintf("%.1f", oldestLatestBackupAge(report).Hours()/24), Unit: "Custom", CustomUnit: "days", Float: 1},
+			{Channel: "Total Size", Value: fmt.Sprint(totalBackupSize(report)), Unit: "BytesDisk"},
+		},
+		Text: fmt.Sprintf("%d/%d backups valid", report.Summary.ValidBackups, report.Summary.TotalBackups),
+	}
+
+	if report.Summary.FailedScans > 0 {
+		out.Text = fmt.Sprintf("%s, %d scans failed", out.Text, report.Summary.FailedScans)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]prtgOutput{"prtg": out})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// oldestLatestBackupAge returns the age of the least recently backed up
+// machine, based on each machine's newest backup set
+func oldestLatestBackupAge(report RunReport) time.Duration {
+	latest := make(map[string]time.Time)
+	for _, scanReport := range report.Results {
+		for _, br := range scanReport.Reports {
+			if br.ValidationStats.NewestBackupTime == nil {
+				continue
+			}
+			machine := MachineName(br.BackupDir)
+			if t := *br.ValidationStats.NewestBackupTime; t.After(latest[machine]) {
+				latest[machine] = t
+			}
+		}
+	}
+
+	now := report.Time()
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var oldest time.Duration
+	for _, t := range latest {
+		if age := now.Sub(t); age > oldest {
+			oldest = age
+		}
+	}
+	return oldest
+}
+
+func totalBackupSize(report RunReport) int64 {
+	var total int64
+	for _, scanReport := range report.Results {
+		for _, br := range scanReport.Reports {
+			total += br.ValidationStats.TotalSize
+		}
+	}
+	return total
+}
@@ -0,0 +1,150 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pushoverAPIURL is the Pushover message endpoint
+const pushoverAPIURL = "https://api.pushover.net/1/messages.json"
+
+// pushoverMessageLimit is Pushover's maximum message length
+const pushoverMessageLimit = 1024
+
+// Pushover priorities
+const (
+	pushoverPriorityEmergency = 2
+
+	pushoverMinRetry  = 30 * time.Second
+	pushoverMaxExpire = 3 * time.Hour
+)
+
+// PushoverConfig configures a Pushover notifier
+type PushoverConfig struct {
+	Enabled             bool   `json:"enabled"`
+	AppToken            string `json:"app_token" secret:"true"`
+	UserKey             string `json:"user_key" secret:"true"`
+	Device              string `json:"device,omitempty"`
+	Priority            int    `json:"priority"`
+	EmergencyOnCritical bool   `json:"emergency_on_critical"`
+	Retry               string `json:"retry,omitempty"`
+	Expire              string `json:"expire,omitempty"`
+	NotifyTriggers
+}
+
+// Validate checks if Pushover configuration is valid
+func (c *PushoverConfig) Validate() error {
+	if c.AppToken == "" {
+		return fmt.Errorf("app_token is required")
+	}
+	if c.UserKey == "" {
+		return fmt.Errorf("user_key is required")
+	}
+	if c.Priority < -2 || c.Priority > 1 {
+		return fmt.Errorf("priority must be between -2 and 1; use emergency_on_critical for emergency alerts")
+	}
+	retry, expire, err := c.emergencyTimings()
+	if err != nil {
+		return err
+	}
+	if retry < pushoverMinRetry {
+		return fmt.Errorf("retry must be at least %v", pushoverMinRetry)
+	}
+	if expire > pushoverMaxExpire {
+		return fmt.Errorf("expire must be at most %v", pushoverMaxExpire)
+	}
+	return c.NotifyTriggers.Validate()
+}
+
+// emergencyTimings returns how often an emergency alert is repeated until
+// acknowledged, and when it stops
+func (c *PushoverConfig) emergencyTimings() (time.Duration, time.Duration, error) {
+	retry, expire := time.Minute, time.Hour
+	var err error
+	if c.Retry != "" {
+		if retry, err = ParseDuration(c.Retry); err != nil {
+			return 0, 0, fmt.Errorf("invalid retry: %w", err)
+		}
+	}
+	if c.Expire != "" {
+		if expire, err = ParseDuration(c.Expire); err != nil {
+			return 0, 0, fmt.Errorf("invalid expire: %w", err)
+		}
+	}
+	return retry, expire, nil
+}
+
+// PushoverNotifier sends run summaries as Pushover notifications
+type PushoverNotifier struct {
+	cfg *PushoverConfig
+}
+
+func (n *PushoverNotifier) Name() string { return "pushover" }
+
+// Notify sends the summary, escalating to emergency priority (repeated until
+// acknowledged) when a critical issue was found and emergency_on_critical is set
+func (n *PushoverNotifier) Notify(ctx context.Context, report RunReport) error {
+	status := NewRunStatus(report.Summary, report.Results)
+	if !n.cfg.ShouldSend(status) {
+		return nil
+	}
+
+	var issues []ValidationIssue
+	for _, br := range reportsWithIssues(report) {
+		issues = append(issues, br.Issues...)
+	}
+	worst, _ := worstSeverity(issues)
+
+	payload := map[string]any{
+		"token":    n.cfg.AppToken,
+		"user":     n.cfg.UserKey,
+		"title":    fmt.Sprintf("Backup Validation: %s", status.Label()),
+		"message":  pushoverMessage(report),
+		"html":     1,
+		"priority": n.cfg.Priority,
+	}
+	if t := report.Time(); !t.IsZero() {
+		payload["timestamp"] = t.Unix()
+	}
+	if n.cfg.Device != "" {
+		payload["device"] = n.cfg.Device
+	}
+
+	if n.cfg.EmergencyOnCritical && worst == SeverityCritical {
+		retry, expire, _ := n.cfg.emergencyTimings()
+		payload["priority"] = pushoverPriorityEmergency
+		payload["retry"] = int(retry.Seconds())
+		payload["expire"] = int(expire.Seconds())
+	}
+
+	if err := postJSON(ctx, pushoverAPIURL, payload, nil); err != nil {
+		return fmt.Errorf("failed to send Pushover notification: %w", err)
+	}
+	return nil
+}
+
+// pushoverMessage summarizes the run, listing the backup sets with issues
+func pushoverMessage(report RunReport) string {
+	summary := report.Summary
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d/%d backups valid, %d invalid, %d failed scans",
+		summary.ValidBackups, summary.TotalBackups, summary.InvalidBackups, summary.FailedScans)
+
+	sets := reportsWithIssues(report)
+	for i, br := range sets {
+		worst, _ := worstSeverity(br.Issues)
+		line := fmt.Sprintf("\n<b>%s</b> %s/%s: %d issue(s)", strings.ToUpper(worst.String()),
+			html.EscapeString(MachineName(br.BackupDir)), html.EscapeString(filepath.Base(br.BackupDir)), len(br.Issues))
+		if b.Len()+len(line) > pushoverMessageLimit-32 {
+			fmt.Fprintf(&b, "\n…and %d more", len(sets)-i)
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
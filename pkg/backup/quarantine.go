@@ -0,0 +1,174 @@
+package winbackupchecker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Quarantine lists backup files known to be corrupt and awaiting
+// replacement. The issues found on a quarantined file are taken out of its
+// set and reported once per run under the quarantined files, so the file no
+// longer fails its set, the exit code or alerts every run.
+type Quarantine struct {
+	Files []QuarantineEntry `json:"files"`
+}
+
+// QuarantineEntry quarantines the file at Path, or every file under it when
+// it is a folder
+type QuarantineEntry struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason,omitempty"`
+	Added  string `json:"added,omitempty"`
+}
+
+// QuarantinedFile reports the issues found in one backup set on the files
+// of a quarantine entry
+type QuarantinedFile struct {
+	QuarantineEntry
+	BackupDir string            `json:"backup_dir"`
+	Issues    []ValidationIssue `json:"issues"`
+}
+
+// LoadQuarantine reads and validates a quarantine file. A missing file is an
+// empty quarantine, so the first entry can be added to it.
+func LoadQuarantine(path string) (*Quarantine, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Quarantine{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine file: %w", err)
+	}
+
+	var quarantine Quarantine
+	if err := json.Unmarshal(data, &quarantine); err != nil {
+		return nil, fmt.Errorf("failed to parse quarantine file: %w", err)
+	}
+	if err := quarantine.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid quarantine file: %w", err)
+	}
+	return &quarantine, nil
+}
+
+// Validate checks that every entry names a path
+func (q *Quarantine) Validate() error {
+	for i, entry := range q.Files {
+		if entry.Path == "" {
+			return fmt.Errorf("file entry %d: path is required", i+1)
+		}
+	}
+	return nil
+}
+
+// Save writes the quarantine to path, replacing the file in one step
+func (q *Quarantine) Save(path string) error {
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantine: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create quarantine dir: %w", err)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write quarantine file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace quarantine file: %w", err)
+	}
+	return nil
+}
+
+// Add quarantines path with reason. Returns false when path was already
+// quarantined, in which case only a new reason replaces the old one.
+func (q *Quarantine) Add(path, reason string, now time.Time) bool {
+	path = NormalizePath(path)
+	if i := q.find(path); i >= 0 {
+		if reason != "" {
+			q.Files[i].Reason = reason
+		}
+		return false
+	}
+	q.Files = append(q.Files, QuarantineEntry{Path: path, Reason: reason, Added: now.Format(time.RFC3339)})
+	return true
+}
+
+// Remove releases path from the quarantine. Returns false when it was not
+// quarantined.
+func (q *Quarantine) Remove(path string) bool {
+	i := q.find(NormalizePath(path))
+	if i < 0 {
+		return false
+	}
+	q.Files = append(q.Files[:i], q.Files[i+1:]...)
+	return true
+}
+
+// find returns the index of the entry for path, or -1. Paths are compared
+// without regard to case, as on Windows.
+func (q *Quarantine) find(path string) int {
+	for i, entry := range q.Files {
+		if strings.EqualFold(NormalizePath(entry.Path), path) {
+			return i
+		}
+	}
+	return -1
+}
+
+// match returns the entry quarantining the file of issue, if any. Issues
+// without a path belong to no file.
+func (q *Quarantine) match(issue ValidationIssue) *QuarantineEntry {
+	if issue.Path == "" {
+		return nil
+	}
+	for i, entry := range q.Files {
+		if isSubPath(entry.Path, issue.Path) {
+			return &q.Files[i]
+		}
+	}
+	return nil
+}
+
+// ApplyQuarantine moves the issues on quarantined files out of their sets
+// and returns them by entry and set, in the order of the reports. The
+// validity of the sets is left to ApplyFailOn.
+func ApplyQuarantine(reports []ScanReport, quarantine *Quarantine) []QuarantinedFile {
+	if quarantine == nil || len(quarantine.Files) == 0 {
+		return nil
+	}
+
+	var quarantined []QuarantinedFile
+	for i := range reports {
+		for j := range reports[i].Reports {
+			br := &reports[i].Reports[j]
+			found := make(map[*QuarantineEntry]int)
+			issues := br.Issues[:0]
+			for _, issue := range br.Issues {
+				entry := quarantine.match(issue)
+				if entry == nil {
+					issues = append(issues, issue)
+					continue
+				}
+				k, ok := found[entry]
+				if !ok {
+					k = len(quarantined)
+					found[entry] = k
+					quarantined = append(quarantined, QuarantinedFile{QuarantineEntry: *entry, BackupDir: br.BackupDir})
+				}
+				quarantined[k].Issues = append(quarantined[k].Issues, issue)
+			}
+			br.Issues = issues
+		}
+	}
+	return quarantined
+}
@@ -0,0 +1,221 @@
+package winbackupchecker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RcloneOptions set how the rclone command is run for an rclone:// backup
+// path, whose host names a remote of the rclone config
+type RcloneOptions struct {
+	// Config is the rclone config file, in place of rclone's default one
+	// and RCLONE_CONFIG
+	Config string `json:"config,omitempty"`
+
+	// Flags are passed to every rclone command, such as
+	// "--s3-requester-pays" or "--bwlimit=10M"
+	Flags []string `json:"flags,omitempty"`
+}
+
+// Validate checks that flags are flags
+func (o *RcloneOptions) Validate() error {
+	for _, flag := range o.Flags {
+		if !strings.HasPrefix(flag, "-") {
+			return fmt.Errorf("flags must start with -: %q", flag)
+		}
+	}
+	return nil
+}
+
+// rcloneTimeout bounds each rclone command, and rcloneReadAhead is what a
+// file reads at once, large as each read starts rclone
+const (
+	rcloneTimeout   = 2 * time.Minute
+	rcloneReadAhead = 4 << 20
+)
+
+// rclone exit codes for a missing folder and file
+const (
+	rcloneExitDirNotFound  = 3
+	rcloneExitFileNotFound = 4
+)
+
+// rcloneFS reads a remote of the rclone config by running rclone for each
+// listing and read, so every storage rclone supports can be checked
+type rcloneFS struct {
+	remote string
+	opts   RcloneOptions
+}
+
+// rcloneError is an rclone command failing with its exit code and the last
+// line it logged
+type rcloneError struct {
+	code    int
+	message string
+}
+
+func (e *rcloneError) Error() string {
+	if e.message != "" {
+		return e.message
+	}
+	return fmt.Sprintf("rclone failed with exit code %d", e.code)
+}
+
+// Is lets errors.Is match fs.ErrNotExist for missing files and folders
+func (e *rcloneError) Is(target error) bool {
+	return target == fs.ErrNotExist && (e.code == rcloneExitDirNotFound || e.code == rcloneExitFileNotFound)
+}
+
+// rcloneEntry is an entry of rclone lsjson
+type rcloneEntry struct {
+	Name    string    `json:"Name"`
+	Size    int64     `json:"Size"`
+	ModTime time.Time `json:"ModTime"`
+	IsDir   bool      `json:"IsDir"`
+}
+
+func (e *rcloneEntry) info() *remoteFileInfo {
+	info := &remoteFileInfo{name: e.Name, size: e.Size, modTime: e.ModTime, dir: e.IsDir}
+	if info.dir {
+		info.size = 0
+	}
+	return info
+}
+
+// dialRclone checks that the remote of key, rclone://remote, is in the
+// rclone config
+func dialRclone(key string, opts *RcloneOptions) (*rcloneFS, error) {
+	r := &rcloneFS{remote: strings.TrimPrefix(key, "rclone://")}
+	if opts != nil {
+		r.opts = *opts
+	}
+	out, err := r.run("listremotes")
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(strings.Fields(string(out)), r.remote+":") {
+		return nil, fmt.Errorf("remote %s is not in the rclone config", r.remote)
+	}
+	return r, nil
+}
+
+// run runs rclone with args and returns what it writes to stdout
+func (r *rcloneFS) run(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rcloneTimeout)
+	defer cancel()
+
+	var flags []string
+	if r.opts.Config != "" {
+		flags = append(flags, "--config", r.opts.Config)
+	}
+	flags = append(flags, r.opts.Flags...)
+	cmd := exec.CommandContext(ctx, "rclone", append(args, flags...)...)
+	stderr := &tailWriter{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("rclone not found; install it from rclone.org")
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("rclone %s timed out", args[0])
+		}
+		return nil, &rcloneError{code: exitErr.ExitCode(), message: trimRcloneLog(stderr.lastLine())}
+	}
+	return out, err
+}
+
+// trimRcloneLog drops the date and time rclone starts its log lines with,
+// as in "2024/06/01 12:00:00 ERROR : error listing: directory not found"
+func trimRcloneLog(line string) string {
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) == 3 && strings.Count(fields[0], "/") == 2 && strings.Count(fields[1], ":") == 2 {
+		return fields[2]
+	}
+	return line
+}
+
+// target returns the rclone path of name, remote:/name. Storage with
+// buckets or a drive root ignores the leading /, and on SFTP and local
+// remotes it makes the path absolute, as on sftp:// paths.
+func (r *rcloneFS) target(name string) string {
+	return r.remote + ":" + name
+}
+
+func (r *rcloneFS) Stat(name string) (fs.FileInfo, error) {
+	if strings.TrimPrefix(name, "/") == "" {
+		return &remoteFileInfo{name: "/", dir: true}, nil
+	}
+	out, err := r.run("lsjson", "--stat", "--no-mimetype", r.target(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	var entry rcloneEntry
+	if err := json.Unmarshal(out, &entry); err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fmt.Errorf("failed to parse rclone lsjson: %w", err)}
+	}
+	entry.Name = path.Base(name)
+	return entry.info(), nil
+}
+
+// OpenDir lists the folder at once, as rclone lsjson prints it whole
+func (r *rcloneFS) OpenDir(name string) (dirReader, error) {
+	out, err := r.run("lsjson", "--no-mimetype", r.target(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	var listing []rcloneEntry
+	if err := json.Unmarshal(out, &listing); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("failed to parse rclone lsjson: %w", err)}
+	}
+	listed := false
+	list := func() ([]fs.DirEntry, error) {
+		if listed {
+			return nil, io.EOF
+		}
+		listed = true
+		entries := make([]fs.DirEntry, len(listing))
+		for i := range listing {
+			entries[i] = listing[i].info()
+		}
+		return entries, nil
+	}
+	return &remoteDir{list: list, close: func() error { return nil }}, nil
+}
+
+func (r *rcloneFS) Open(name string) (readableFile, error) {
+	info, err := r.Stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.Unwrap(err)}
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	target := r.target(name)
+	return &bufferedFile{
+		info:      info,
+		readAhead: rcloneReadAhead,
+		fetch: func(off int64, n int) ([]byte, error) {
+			if off >= info.Size() {
+				return nil, io.EOF
+			}
+			return r.run("cat", "--offset", strconv.FormatInt(off, 10), "--count", strconv.Itoa(n), target)
+		},
+		close: func() error { return nil },
+	}, nil
+}
+
+func (r *rcloneFS) Close() error {
+	return nil
+}
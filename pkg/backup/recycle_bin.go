@@ -0,0 +1,241 @@
+package winbackupchecker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// recycleBinDir is the per-volume recycle bin folder on NTFS volumes
+const recycleBinDir = "$RECYCLE.BIN"
+
+// recycledItem is a file or folder found in the recycle bin
+type recycledItem struct {
+	OriginalPath string
+	DeletedAt    time.Time
+	Size         int64
+}
+
+// findRecycleBin returns the recycle bin of the volume holding root, looking
+// in root and each of its parents so network shares mounted below their
+// volume root are covered too
+func findRecycleBin(root string) (string, bool) {
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return "", false
+	}
+	for {
+		candidate := filepath.Join(dir, recycleBinDir)
+		if dirExists(candidate) {
+			return candidate, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// readRecycleBin parses the $I metadata files of every user's recycle bin.
+// Bins of other users are usually unreadable and skipped.
+func readRecycleBin(binPath string) []recycledItem {
+	var items []recycledItem
+
+	userDirs, err := os.ReadDir(binPath)
+	if err != nil {
+		return nil
+	}
+	for _, userDir := range userDirs {
+		if !userDir.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(binPath, userDir.Name()))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasPrefix(entry.Name(), "$I") {
+				continue
+			}
+			item, err := parseRecycleBinInfo(filepath.Join(binPath, userDir.Name(), entry.Name()))
+			if err != nil {
+				continue
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseRecycleBinInfo parses a $I file: version, original size and deletion
+// time, followed by the original path as UTF-16LE. Version 1 (Vista to 8.1)
+// stores the path in a fixed 260-character field; version 2 (Windows 10 and
+// later) prefixes it with its length.
+func parseRecycleBinInfo(path string) (recycledItem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return recycledItem{}, err
+	}
+	if len(data) < 24 {
+		return recycledItem{}, fmt.Errorf("truncated recycle bin entry")
+	}
+
+	version := binary.LittleEndian.Uint64(data[0:8])
+	item := recycledItem{
+		Size:      int64(binary.LittleEndian.Uint64(data[8:16])),
+		DeletedAt: filetimeToTime(binary.LittleEndian.Uint64(data[16:24])),
+	}
+
+	var raw []byte
+	switch version {
+	case 1:
+		raw = data[24:]
+	case 2:
+		if len(data) < 28 {
+			return recycledItem{}, fmt.Errorf("truncated recycle bin entry")
+		}
+		chars := int(binary.LittleEndian.Uint32(data[24:28]))
+		raw = data[28:]
+		if chars*2 < len(raw) {
+			raw = raw[:chars*2]
+		}
+	default:
+		return recycledItem{}, fmt.Errorf("unsupported recycle bin entry version %d", version)
+	}
+
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		unit := binary.LittleEndian.Uint16(raw[i:])
+		if unit == 0 {
+			break
+		}
+		units = append(units, unit)
+	}
+	item.OriginalPath = string(utf16.Decode(units))
+
+	return item, nil
+}
+
+// filetimeToTime converts a Windows FILETIME (100ns intervals since 1601)
+func filetimeToTime(ft uint64) time.Time {
+	const epochDiff = 116444736000000000 // 1601-01-01 to 1970-01-01 in 100ns
+	if ft < epochDiff {
+		return time.Time{}
+	}
+	ns := (ft - epochDiff) * 100
+	return time.Unix(0, int64(ns)).UTC()
+}
+
+// volumeRelative returns a Windows path relative to its volume root, lower
+// cased with forward slashes, so paths recorded by Windows can be compared
+// with paths seen through a mount or share
+func volumeRelative(path string) string {
+	path = strings.ToLower(strings.ReplaceAll(path, `\`, "/"))
+	if len(path) >= 2 && path[1] == ':' {
+		path = path[2:]
+	}
+	return strings.Trim(path, "/")
+}
+
+// checkRecycleBin raises a critical issue for backup files that were moved to
+// the recycle bin of the target volume, which usually means a deletion is in
+// progress. Items inside an existing set are reported on that set; deleted
+// sets are reported on the root.
+func checkRecycleBin(root string, backupSets []BackupSetInfo, reports []BackupReport) []BackupReport {
+	binPath, ok := findRecycleBin(root)
+	if !ok {
+		return reports
+	}
+	items := readRecycleBin(binPath)
+	if len(items) == 0 {
+		return reports
+	}
+
+	volumeRoot := filepath.Dir(binPath)
+	relPath := func(path string) string {
+		abs, _ := filepath.Abs(path)
+		rel, err := filepath.Rel(volumeRoot, abs)
+		if err != nil {
+			return ""
+		}
+		return volumeRelative(rel)
+	}
+
+	rootRel := relPath(root)
+	setRels := make([]string, len(backupSets))
+	for i, set := range backupSets {
+		setRels[i] = relPath(set.Path)
+	}
+
+	bySet := make(map[int][]recycledItem)
+	for _, item := range items {
+		orig := volumeRelative(item.OriginalPath)
+		if !isWithin(orig, rootRel) {
+			continue
+		}
+
+		owner := -1
+		for i, setRel := range setRels {
+			if isWithin(orig, setRel) {
+				owner = i
+				break
+			}
+		}
+		bySet[owner] = append(bySet[owner], item)
+	}
+
+	for owner, deleted := range bySet {
+		issue := recycleBinIssue(deleted, binPath)
+		if owner >= 0 {
+			reports[owner].addIssues(issue)
+			continue
+		}
+		reports = append(reports, BackupReport{
+			BackupDir: root,
+			Valid:     false,
+			Issues:    []ValidationIssue{issue},
+			CheckedAt: NowRFC3339(),
+		})
+	}
+
+	return reports
+}
+
+// isWithin reports whether the volume-relative path is dir or below it
+func isWithin(path, dir string) bool {
+	return dir == "" || path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// recycleBinMaxListed caps the deleted items named in one issue
+const recycleBinMaxListed = 5
+
+func recycleBinIssue(deleted []recycledItem, binPath string) ValidationIssue {
+	sort.Slice(deleted, func(i, j int) bool {
+		return deleted[i].DeletedAt.After(deleted[j].DeletedAt)
+	})
+
+	var total int64
+	names := make([]string, 0, recycleBinMaxListed)
+	for i, item := range deleted {
+		total += item.Size
+		if i < recycleBinMaxListed {
+			names = append(names, item.OriginalPath)
+		}
+	}
+	more := msg("")
+	if len(deleted) > recycleBinMaxListed {
+		more = msg(" and %d more", len(deleted)-recycleBinMaxListed)
+	}
+
+	return newIssue(IssueRecycleBin, SeverityCritical,
+		msg("%d backup item(s) (%s) moved to the recycle bin, most recently at %s: %s%s",
+			len(deleted), formatByteSize(total), deleted[0].DeletedAt.Format(time.RFC3339), strings.Join(names, ", "), more),
+		binPath,
+		"a deletion may be in progress; restore the items from the recycle bin and find out who deleted them")
+}
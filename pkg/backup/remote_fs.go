@@ -0,0 +1,452 @@
+package winbackupchecker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// remoteFS is a file system reached by a built-in client rather than
+// mounted by the system, such as a share read over SMB from Linux or a
+// server reached over SFTP or FTP. Names are the rest of a path after the
+// share or server, in the form the file system expects: relative with \
+// between folders on a share, absolute with / on a server or in a bucket.
+type remoteFS interface {
+	Stat(name string) (fs.FileInfo, error)
+	OpenDir(name string) (dirReader, error)
+	Open(name string) (readableFile, error)
+	Close() error
+}
+
+// dirReader lists a folder a batch of entries at a time, like os.File
+type dirReader interface {
+	ReadDir(n int) ([]fs.DirEntry, error)
+	Close() error
+}
+
+// unfinishedLister is a remote file system that keeps uploads started but
+// never finished apart from its files, such as large files on B2 missing
+// parts. Names are those of the file system.
+type unfinishedLister interface {
+	Unfinished(name string) ([]UnfinishedUpload, error)
+}
+
+// UnfinishedUpload is a file whose upload to a bucket never finished, so it
+// is missing there
+type UnfinishedUpload struct {
+	Path    string    `json:"path"`
+	Parts   int       `json:"parts"`
+	Size    int64     `json:"size"`
+	Started time.Time `json:"started"`
+}
+
+// readableFile is an open file of a backup set, like os.File
+type readableFile interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+	Stat() (fs.FileInfo, error)
+}
+
+// remoteScheme returns the lowercased scheme of a URL path such as
+// sftp://host/backups, or "" for other paths. Paths are kept cleaned, which
+// folds the slashes after the scheme into one, so any separator is taken.
+// Drive letters are a single letter and never a scheme.
+func remoteScheme(path string) string {
+	scheme, rest, ok := strings.Cut(path, ":")
+	if !ok || len(scheme) < 2 || !strings.HasPrefix(rest, "/") && !strings.HasPrefix(rest, `\`) {
+		return ""
+	}
+	for i, r := range scheme {
+		isLetter := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+		if !isLetter && (i == 0 || !strings.ContainsRune("0123456789+.-", r)) {
+			return ""
+		}
+	}
+	return strings.ToLower(scheme)
+}
+
+// isRemotePath reports whether path is on a share or server rather than a
+// local or mounted drive
+func isRemotePath(path string) bool {
+	return isUNCPath(path) || remoteScheme(path) != ""
+}
+
+// cleanRemoteURL cleans a URL path, keeping the // after its scheme that
+// filepath.Clean folds
+func cleanRemoteURL(url string) string {
+	key, name, ok := remoteLocation(url)
+	if !ok {
+		return url
+	}
+	if name == "/" {
+		return key
+	}
+	return key + name
+}
+
+// remoteLocation splits a path into the share or server it is on, which
+// keys its mount, and its name there: \\server\share and the rest, or
+// scheme://user@host and the absolute path on the server
+func remoteLocation(p string) (key, name string, ok bool) {
+	if root := shareRoot(p); root != "" {
+		return strings.ToLower(root), remoteName(p), true
+	}
+	scheme := remoteScheme(p)
+	if scheme == "" {
+		return "", "", false
+	}
+	rest := strings.ReplaceAll(strings.TrimLeft(p[len(scheme)+1:], `\/`), `\`, "/")
+	host, name, _ := strings.Cut(rest, "/")
+	if host == "" {
+		return "", "", false
+	}
+	return scheme + "://" + host, path.Clean("/" + name), true
+}
+
+// remoteMount is a remote file system and how many connections use it
+type remoteMount struct {
+	fsys remoteFS
+	refs int
+}
+
+// remoteMounts holds the remote file systems by the share or server they
+// read, so paths on it are read through its client
+var remoteMounts struct {
+	sync.RWMutex
+	mounts map[string]*remoteMount
+}
+
+// mountRemote reads the paths on the share or server of p through fsys
+// until unmountRemote is called as often. When it is mounted already, fsys
+// is closed and the mount shared instead.
+func mountRemote(p string, fsys remoteFS) {
+	key, _, _ := remoteLocation(p)
+	remoteMounts.Lock()
+	defer remoteMounts.Unlock()
+	if mount, ok := remoteMounts.mounts[key]; ok {
+		mount.refs++
+		fsys.Close()
+		return
+	}
+	if remoteMounts.mounts == nil {
+		remoteMounts.mounts = make(map[string]*remoteMount)
+	}
+	remoteMounts.mounts[key] = &remoteMount{fsys: fsys, refs: 1}
+}
+
+// acquireRemote shares the mount of the share or server of p, if any, as
+// mountRemote does
+func acquireRemote(p string) bool {
+	key, _, _ := remoteLocation(p)
+	remoteMounts.Lock()
+	defer remoteMounts.Unlock()
+	mount, ok := remoteMounts.mounts[key]
+	if ok {
+		mount.refs++
+	}
+	return ok
+}
+
+// unmountRemote releases a mount of the share or server of p, closing its
+// file system once the last is released
+func unmountRemote(p string) error {
+	key, _, _ := remoteLocation(p)
+	remoteMounts.Lock()
+	mount, ok := remoteMounts.mounts[key]
+	if !ok {
+		remoteMounts.Unlock()
+		return nil
+	}
+	mount.refs--
+	if mount.refs > 0 {
+		remoteMounts.Unlock()
+		return nil
+	}
+	delete(remoteMounts.mounts, key)
+	remoteMounts.Unlock()
+	return mount.fsys.Close()
+}
+
+// remoteFor returns the remote file system path is on and its name there,
+// or false for local paths
+func remoteFor(path string) (remoteFS, string, bool) {
+	remoteMounts.RLock()
+	defer remoteMounts.RUnlock()
+	if len(remoteMounts.mounts) == 0 {
+		return nil, "", false
+	}
+
+	key, name, ok := remoteLocation(path)
+	if !ok {
+		return nil, "", false
+	}
+	mount, ok := remoteMounts.mounts[key]
+	if !ok {
+		return nil, "", false
+	}
+	return mount.fsys, name, true
+}
+
+// remoteName returns the part of the UNC path after its \\server\share
+func remoteName(path string) string {
+	parts := strings.FieldsFunc(path[2:], func(r rune) bool { return r == '\\' || r == '/' })
+	return strings.Join(parts[2:], `\`)
+}
+
+// bucketKey returns the key of an object named name, an absolute path in
+// its bucket
+func bucketKey(name string) string {
+	return strings.TrimPrefix(name, "/")
+}
+
+// connectRemote signs in to the server of a URL path with the client of its
+// scheme, returning a function that disconnects again
+func (p BackupPath) connectRemote() (disconnect func() error, err error) {
+	key, _, ok := remoteLocation(p.Path)
+	if !ok {
+		return nil, fmt.Errorf("%s names no server", p.Path)
+	}
+	if !acquireRemote(p.Path) {
+		var fsys remoteFS
+		switch scheme := remoteScheme(p.Path); scheme {
+		case "sftp":
+			var client *sftpClient
+			if client, err = dialSFTP(key, p.SSH); err == nil {
+				fsys = &sftpFS{client: client}
+			}
+		case "s3":
+			fsys, err = dialS3(key, p.S3)
+		case "b2":
+			fsys, err = dialB2(key, p.B2)
+		case "rclone":
+			fsys, err = dialRclone(key, p.Rclone)
+		case "ftp", "ftps":
+			var client *ftpClient
+			if client, err = dialFTP(key, p.FTP); err == nil {
+				fsys = &ftpFS{client: client}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported scheme %s:// in %s", scheme, p.Path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", key, err)
+		}
+		mountRemote(p.Path, fsys)
+	}
+	return func() error {
+		if err := unmountRemote(p.Path); err != nil {
+			return fmt.Errorf("failed to disconnect from %s: %w", key, err)
+		}
+		return nil
+	}, nil
+}
+
+// remoteDir lists a remote folder a batch at a time as list returns them,
+// which returns io.EOF once all were
+type remoteDir struct {
+	list    func() ([]fs.DirEntry, error)
+	close   func() error
+	pending []fs.DirEntry
+	done    bool
+}
+
+// ReadDir returns the next n entries, or all that are left when n <= 0, in
+// the order the server lists them
+func (d *remoteDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	for n <= 0 || len(entries) < n {
+		if len(d.pending) == 0 {
+			if d.done {
+				break
+			}
+			batch, err := d.list()
+			if err == io.EOF {
+				d.done = true
+				continue
+			}
+			if err != nil {
+				return entries, err
+			}
+			d.pending = batch
+			continue
+		}
+		take := len(d.pending)
+		if n > 0 {
+			take = min(take, n-len(entries))
+		}
+		entries = append(entries, d.pending[:take]...)
+		d.pending = d.pending[take:]
+	}
+	if n > 0 && len(entries) == 0 {
+		return nil, io.EOF
+	}
+	return entries, nil
+}
+
+func (d *remoteDir) Close() error {
+	return d.close()
+}
+
+// bufferedFile is a remote file read through a buffer of readAhead bytes,
+// as zip files are read in small pieces that would each wait on the server
+// otherwise. fetch returns up to n bytes from off, or io.EOF past the end.
+type bufferedFile struct {
+	info      fs.FileInfo
+	readAhead int
+	fetch     func(off int64, n int) ([]byte, error)
+	close     func() error
+	offset    int64
+
+	// buf holds the bytes of the file from bufOffset, guarded by mu
+	mu        sync.Mutex
+	buf       []byte
+	bufOffset int64
+}
+
+func (f *bufferedFile) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt fills p from off, reading ahead past it
+func (f *bufferedFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	read := 0
+	for read < len(p) {
+		pos := off + int64(read)
+		if pos < f.bufOffset || pos >= f.bufOffset+int64(len(f.buf)) {
+			data, err := f.fetch(pos, max(len(p)-read, f.readAhead))
+			if err == nil && len(data) == 0 {
+				err = io.EOF
+			}
+			if err != nil {
+				return read, err
+			}
+			f.buf, f.bufOffset = data, pos
+		}
+		read += copy(p[read:], f.buf[pos-f.bufOffset:])
+	}
+	return read, nil
+}
+
+func (f *bufferedFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.Size()
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *bufferedFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *bufferedFile) Close() error {
+	return f.close()
+}
+
+// remoteFileInfo is the metadata of a file or folder as a listing of the
+// server gives it
+type remoteFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i *remoteFileInfo) Name() string       { return i.name }
+func (i *remoteFileInfo) Size() int64        { return i.size }
+func (i *remoteFileInfo) ModTime() time.Time { return i.modTime }
+func (i *remoteFileInfo) IsDir() bool        { return i.dir }
+func (i *remoteFileInfo) Sys() any           { return nil }
+
+func (i *remoteFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (i *remoteFileInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i *remoteFileInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// statBackup returns the metadata of a file or folder of the backups, like
+// os.Stat
+func statBackup(path string) (fs.FileInfo, error) {
+	if fsys, name, ok := remoteFor(path); ok {
+		return fsys.Stat(name)
+	}
+	return os.Stat(path)
+}
+
+// openBackupDir opens a folder of the backups for listing
+func openBackupDir(path string) (dirReader, error) {
+	if fsys, name, ok := remoteFor(path); ok {
+		return fsys.OpenDir(name)
+	}
+	return os.Open(path)
+}
+
+// readBackupDir lists a folder of the backups sorted by name, like
+// os.ReadDir
+func readBackupDir(path string) ([]fs.DirEntry, error) {
+	fsys, name, ok := remoteFor(path)
+	if !ok {
+		return os.ReadDir(path)
+	}
+	dir, err := fsys.OpenDir(name)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	entries, err := dir.ReadDir(-1)
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, err
+}
+
+// unfinishedUploads returns the uploads under the folder path that never
+// finished, on file systems that keep them
+func unfinishedUploads(path string) ([]UnfinishedUpload, error) {
+	fsys, name, ok := remoteFor(path)
+	if !ok {
+		return nil, nil
+	}
+	lister, ok := fsys.(unfinishedLister)
+	if !ok {
+		return nil, nil
+	}
+	uploads, err := lister.Unfinished(name)
+	for i := range uploads {
+		uploads[i].Path = filepath.Join(path, strings.TrimPrefix(uploads[i].Path, strings.TrimSuffix(name, "/")+"/"))
+	}
+	return uploads, err
+}
+
+// openBackupPath opens a file of the backups, like os.Open
+func openBackupPath(path string) (readableFile, error) {
+	if fsys, name, ok := remoteFor(path); ok {
+		return fsys.Open(name)
+	}
+	return os.Open(path)
+}
@@ -0,0 +1,82 @@
+package winbackupchecker
+
+import "path/filepath"
+
+// RunReport is the complete result of one checker run across all roots
+type RunReport struct {
+	Timestamp string       `json:"timestamp"`
+	Profile   string       `json:"profile,omitempty"`
+	Results   []ScanReport `json:"results"`
+	Summary   ScanSummary  `json:"summary"`
+
+	// PhaseTimings totals the time spent per validation phase in this run
+	PhaseTimings PhaseTimings `json:"phase_timings_ms,omitempty"`
+
+	// CacheStats reports hit rates for each cache used in this run
+	CacheStats map[string]CacheStats `json:"cache_stats,omitempty"`
+
+	// Quarantined lists the issues found on quarantined files, which are
+	// not part of the results
+	Quarantined []QuarantinedFile `json:"quarantined,omitempty"`
+
+	// NotificationFailures lists alerts that could not be delivered
+	NotificationFailures []NotificationFailure `json:"notification_failures,omitempty"`
+}
+
+// ScanSummary aggregates backup counts for a run
+type ScanSummary struct {
+	TotalBackups   int `json:"total_backups"`
+	ValidBackups   int `json:"valid_backups"`
+	InvalidBackups int `json:"invalid_backups"`
+	FailedScans    int `json:"failed_scans"`
+
+	// SuppressedIssues counts the issues accepted in the baseline file
+	SuppressedIssues int `json:"suppressed_issues,omitempty"`
+
+	// QuarantinedFiles counts the quarantine entries with issues in the run
+	QuarantinedFiles int `json:"quarantined_files,omitempty"`
+}
+
+// add adds the counts of other to the summary
+func (s *ScanSummary) add(other ScanSummary) {
+	s.TotalBackups += other.TotalBackups
+	s.ValidBackups += other.ValidBackups
+	s.InvalidBackups += other.InvalidBackups
+	s.FailedScans += other.FailedScans
+	s.SuppressedIssues += other.SuppressedIssues
+	s.QuarantinedFiles += other.QuarantinedFiles
+}
+
+// MachineName returns the machine folder a backup set belongs to
+func MachineName(backupDir string) string {
+	return filepath.Base(filepath.Dir(backupDir))
+}
+
+// addIssues appends issues to the report and re-evaluates its validity
+func (r *BackupReport) addIssues(issues ...ValidationIssue) {
+	r.Issues = append(r.Issues, issues...)
+	for _, issue := range issues {
+		if issue.Severity >= SeverityError {
+			r.Valid = false
+		}
+	}
+}
+
+// ApplyFailOn re-evaluates the validity of every report against threshold: a
+// report is invalid when it has an issue at least that severe that is not
+// suppressed by the baseline. The scanner itself fails reports on errors,
+// which ApplyFailOn with SeverityError keeps.
+func ApplyFailOn(reports []ScanReport, threshold ValidationSeverity) {
+	for i := range reports {
+		for j := range reports[i].Reports {
+			br := &reports[i].Reports[j]
+			br.Valid = true
+			for _, issue := range br.Issues {
+				if !issue.Suppressed && issue.Severity >= threshold {
+					br.Valid = false
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package winbackupchecker
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// setNameLayout is the date in the folder names Windows Backup gives its
+// sets, e.g. "Backup Set 2024-06-01 000000"
+const setNameLayout = "Backup Set 2006-01-02 150405"
+
+// sortReports puts the set reports of each root in a fixed order, by machine
+// and then set date, newest first, and the issues of each set by code and
+// path. Sets are validated concurrently, so without it two runs over the
+// same backups list them differently. Reports on a root itself, such as a
+// missing MediaID.bin, stay first in the order they were found.
+func sortReports(reports []ScanReport) {
+	for i := range reports {
+		root := reports[i].Root
+		sets := reports[i].Reports
+		sort.SliceStable(sets, func(a, b int) bool {
+			return setReportLess(root, sets[a].BackupDir, sets[b].BackupDir)
+		})
+		for j := range sets {
+			sortIssues(sets[j].Issues)
+		}
+	}
+}
+
+// setReportLess reports whether the report on dir a comes before the one on
+// dir b in a scan of root
+func setReportLess(root, a, b string) bool {
+	setA, setB := isSetReport(root, a), isSetReport(root, b)
+	if setA != setB {
+		return !setA
+	}
+	if !setA {
+		return false
+	}
+
+	if machineA, machineB := strings.ToLower(MachineName(a)), strings.ToLower(MachineName(b)); machineA != machineB {
+		return machineA < machineB
+	}
+	dateA, errA := time.Parse(setNameLayout, filepath.Base(a))
+	dateB, errB := time.Parse(setNameLayout, filepath.Base(b))
+	if errA == nil && errB == nil && !dateA.Equal(dateB) {
+		return dateA.After(dateB)
+	}
+	if (errA == nil) != (errB == nil) {
+		return errA == nil
+	}
+	return a < b
+}
+
+// isSetReport reports whether dir is a backup set under root, at least a
+// machine folder deep, rather than root or one of its backup roots
+func isSetReport(root, dir string) bool {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	return strings.ContainsRune(rel, filepath.Separator)
+}
+
+// sortIssues orders issues by code, then path and message. Issues without
+// a code come first.
+func sortIssues(issues []ValidationIssue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Message < b.Message
+	})
+}
@@ -0,0 +1,226 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// RunOptions adjust a Run beyond what its config sets
+type RunOptions struct {
+	// Baseline suppresses the known issues it lists; nil suppresses none
+	Baseline *Baseline
+
+	// Quarantine takes the issues on the files it lists out of their sets;
+	// nil quarantines none
+	Quarantine *Quarantine
+
+	// History holds earlier runs, oldest first, for escalation and flapping
+	// detection, as returned by LoadHistory
+	History []RunReport
+
+	// FailThreshold is the least severe issue that fails a backup set.
+	// SeverityInfo, the zero value, fails sets on errors like the scanner.
+	FailThreshold ValidationSeverity
+
+	// FailFast stops at the first critical issue even if the config's
+	// fail_fast is off
+	FailFast bool
+
+	// Spill, when set, receives the report of each backup path as soon as
+	// it is finished, and the results of the run keep only the issues and
+	// counts of its sets. Write the full report with WriteRunReport.
+	Spill *ReportSpill
+}
+
+// RunResult is the outcome of a Run
+type RunResult struct {
+	Report RunReport
+
+	// FatalErrors describes the backup paths that could not be scanned
+	FatalErrors []string
+
+	// Elapsed is the time spent scanning the backup paths
+	Elapsed time.Duration
+
+	// Overridden, Suppressed, Escalated, Flapping and Grouped count the
+	// issues given an overridden severity, the known issues suppressed by the
+	// baseline, the persistent issues escalated, the unstable backup sets
+	// found and the issues folded into groups
+	Overridden, Suppressed, Escalated, Flapping, Grouped int
+}
+
+// Run validates every backup path of cfg with opts, as returned by
+// cfg.ScanOptions, then applies the severity overrides, quarantine,
+// baseline, escalation, flapping detection, fail threshold and issue
+// grouping. This is one run of the checker command without its output: Run
+// neither logs the report nor sends alerts, and leaves saving opts' caches
+// to the caller.
+func Run(ctx context.Context, cfg *Config, opts ScanOptions, run RunOptions) RunResult {
+	if cfg.FailFast || run.FailFast {
+		var stop context.CancelFunc
+		ctx, stop = opts.EnableFailFast(ctx)
+		defer stop()
+	}
+
+	var result RunResult
+	allReports := []ScanReport{}
+	var (
+		summary     ScanSummary
+		quarantined []QuarantinedFile
+		timings     = PhaseTimings{}
+	)
+	finish := func(reports []ScanReport) {
+		found, s, t := result.postProcess(cfg, run, reports)
+		quarantined = append(quarantined, found...)
+		summary.add(s)
+		timings.Merge(t)
+	}
+
+	scanStart := time.Now()
+	for _, backupPath := range cfg.BackupPaths {
+		path := backupPath.Path
+		var report ScanReport
+		if opts.Interrupted() {
+			report = ScanReport{
+				Root:    path,
+				Reports: []BackupReport{unfinishedReport(path, ErrInterrupted)},
+			}
+		} else if scanned, err := scanBackupPath(ctx, backupPath, backupPath.ScanOptions(opts)); err != nil {
+			result.FatalErrors = append(result.FatalErrors, fmt.Sprintf("Scan failed for %s: %v", path, err))
+			report = failedScanReport(path, err)
+		} else {
+			report = *scanned
+		}
+
+		// A spilled root is finished on its own, so only a compact copy
+		// stays in memory
+		if run.Spill != nil {
+			finish([]ScanReport{report})
+			if run.Spill.Add(report) == nil {
+				compactReport(&report)
+			}
+		}
+		allReports = append(allReports, report)
+	}
+	result.Elapsed = time.Since(scanStart)
+
+	if run.Spill == nil {
+		finish(allReports)
+	}
+	summary.FailedScans = len(result.FatalErrors)
+	summary.QuarantinedFiles = len(quarantined)
+
+	result.Report = RunReport{
+		Timestamp:    time.Now().Format(time.RFC3339),
+		Profile:      cfg.Profile,
+		Results:      allReports,
+		Summary:      summary,
+		PhaseTimings: timings,
+		CacheStats:   opts.CacheStats(),
+		Quarantined:  quarantined,
+	}
+	return result
+}
+
+// postProcess sorts reports and applies the severity overrides, quarantine,
+// baseline, escalation, fail threshold, flapping detection and issue
+// grouping to them, counting the changes in result. Returns the quarantined
+// files found, and the summary and phase timings of reports before flapping
+// detection and grouping.
+func (result *RunResult) postProcess(cfg *Config, run RunOptions, reports []ScanReport) ([]QuarantinedFile, ScanSummary, PhaseTimings) {
+	sortReports(reports)
+	result.Overridden += ApplySeverityOverrides(reports, cfg.SeverityOverrides)
+	quarantined := ApplyQuarantine(reports, run.Quarantine)
+	result.Suppressed += ApplyBaseline(reports, run.Baseline)
+	if cfg.Escalation != nil && cfg.Escalation.Enabled {
+		result.Escalated += ApplyEscalation(run.History, reports, cfg.Escalation)
+	}
+
+	threshold := run.FailThreshold
+	if threshold == SeverityInfo {
+		threshold = SeverityError
+	}
+	ApplyFailOn(reports, threshold)
+
+	if cfg.Flapping != nil && cfg.Flapping.Enabled {
+		result.Flapping += DetectFlapping(run.History, &RunReport{Results: reports}, cfg.Flapping)
+		// Flapping issues are only known now, and an override may raise
+		// them past the threshold
+		ApplySeverityOverrides(reports, cfg.SeverityOverrides)
+		ApplyBaseline(reports, run.Baseline)
+		ApplyFailOn(reports, threshold)
+		sortReports(reports)
+	}
+	summary := Summarize(reports, nil)
+	timings := TotalPhaseTimings(reports)
+
+	// Escalation and flapping detection above look at every issue
+	result.Grouped += ApplyIssueGrouping(reports, cfg.IssueGrouping)
+
+	return quarantined, summary, timings
+}
+
+// scanBackupPath scans backupPath with opts, connected to its share for
+// the scan when it has credentials, or to its server for sftp:// paths
+func scanBackupPath(ctx context.Context, backupPath BackupPath, opts ScanOptions) (*ScanReport, error) {
+	disconnect, err := backupPath.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := disconnect(); err != nil {
+			opts.logf("Warning: %v\n", err)
+		}
+	}()
+	report, err := ScanFileBackupDir(ctx, backupPath.Path, opts)
+	if err == nil && remoteScheme(backupPath.Path) != "" {
+		// Paths joined onto a URL lose the // after its scheme
+		rebaseReports(report.Reports, filepath.Clean(backupPath.Path), backupPath.Path)
+	}
+	return report, err
+}
+
+// failedScanReport reports a backup path that could not be scanned as a
+// single invalid set
+func failedScanReport(path string, err error) ScanReport {
+	issue := NewValidationIssue(SeverityCritical, err.Error(), path, "check path accessibility and permissions")
+	issue.Code = IssueRootScanFailed
+	return ScanReport{
+		Root: path,
+		Reports: []BackupReport{
+			{
+				BackupDir: path,
+				Valid:     false,
+				Issues:    []ValidationIssue{issue},
+				CheckedAt: time.Now().Format(time.RFC3339),
+			},
+		},
+	}
+}
+
+// Summarize counts the valid and invalid backup sets of a run
+func Summarize(reports []ScanReport, fatalErrors []string) ScanSummary {
+	summary := ScanSummary{
+		FailedScans: len(fatalErrors),
+	}
+
+	for _, sr := range reports {
+		for _, br := range sr.Reports {
+			summary.TotalBackups++
+			if br.Valid {
+				summary.ValidBackups++
+			} else {
+				summary.InvalidBackups++
+			}
+			for _, issue := range br.Issues {
+				if issue.Suppressed {
+					summary.SuppressedIssues++
+				}
+			}
+		}
+	}
+
+	return summary
+}
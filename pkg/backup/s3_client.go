@@ -0,0 +1,384 @@
+package winbackupchecker
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Options set how the bucket of an s3:// backup path is reached. Keys
+// missing here are taken from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN and AWS_REGION environment variables; without any, the
+// bucket is read anonymously.
+type S3Options struct {
+	// Endpoint is the URL of an S3-compatible service such as MinIO or
+	// Wasabi, e.g. "https://s3.eu-central-1.wasabisys.com". Without one,
+	// AWS is used.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	Region          string `json:"region,omitempty"`
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty" secret:"true"`
+
+	// SecretAccessKeyCredential names a secret key stored with credentials
+	// set, used when SecretAccessKey is empty
+	SecretAccessKeyCredential string `json:"secret_access_key_credential,omitempty"`
+}
+
+// Validate checks that the endpoint is an http or https URL and the keys
+// are given together
+func (o *S3Options) Validate() error {
+	if o.Endpoint != "" {
+		u, err := url.Parse(o.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("endpoint must be an http or https URL")
+		}
+	}
+	if o.AccessKeyID != "" && o.SecretAccessKey == "" && o.SecretAccessKeyCredential == "" {
+		return fmt.Errorf("access_key_id requires secret_access_key or secret_access_key_credential")
+	}
+	return nil
+}
+
+// s3RequestTimeout bounds each request, and s3ReadAhead is what a file
+// reads at once
+const (
+	s3RequestTimeout = 2 * time.Minute
+	s3ReadAhead      = 1 << 20
+)
+
+// s3EmptyHash is the SHA-256 of an empty payload, which every request sends
+const s3EmptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3Client reads objects from one bucket with the S3 REST API, signing
+// requests with Signature Version 4
+type s3Client struct {
+	http      *http.Client
+	base      *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	token     string
+
+	// pathStyle names the bucket in the path rather than the host name, as
+	// most S3-compatible services expect
+	pathStyle bool
+}
+
+// s3Error is an S3 request failing with an HTTP status and error code
+type s3Error struct {
+	status  int
+	code    string
+	message string
+	region  string
+}
+
+func (e *s3Error) Error() string {
+	switch {
+	case e.region != "":
+		return fmt.Sprintf("the bucket is in region %s; set region to it", e.region)
+	case e.code != "" && e.message != "":
+		return fmt.Sprintf("%s: %s", e.code, e.message)
+	case e.code != "":
+		return e.code
+	}
+	return fmt.Sprintf("S3 request failed with HTTP status %d", e.status)
+}
+
+// Is lets errors.Is match the fs errors the status stands for
+func (e *s3Error) Is(target error) bool {
+	switch e.status {
+	case http.StatusNotFound:
+		return target == fs.ErrNotExist
+	case http.StatusForbidden:
+		return target == fs.ErrPermission
+	}
+	return false
+}
+
+// newS3Client returns a client for bucket with opts, signing in with the
+// keys of opts or the environment
+func newS3Client(bucket string, opts *S3Options) (*s3Client, error) {
+	if opts == nil {
+		opts = &S3Options{}
+	}
+	c := &s3Client{
+		http:      &http.Client{},
+		bucket:    bucket,
+		region:    opts.Region,
+		accessKey: opts.AccessKeyID,
+		secretKey: opts.SecretAccessKey,
+	}
+	if c.secretKey == "" && opts.SecretAccessKeyCredential != "" {
+		secret, err := GetCredential(opts.SecretAccessKeyCredential)
+		if err != nil {
+			return nil, err
+		}
+		c.secretKey = secret
+	}
+	if c.accessKey == "" {
+		c.accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		c.secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		c.token = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if c.region == "" {
+		c.region = firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+	}
+
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + c.region + ".amazonaws.com"
+		// Bucket names with dots do not match the certificate of their
+		// virtual host
+		c.pathStyle = strings.Contains(bucket, ".")
+	} else {
+		c.pathStyle = true
+	}
+	base, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	c.base = base
+	return c, nil
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// do sends a request on key, or the bucket when key is "", and returns the
+// response when its status is one of ok
+func (c *s3Client) do(ctx context.Context, method, key string, query url.Values, header http.Header, ok ...int) (*http.Response, error) {
+	u := *c.base
+	path := "/" + s3Escape(key, false)
+	if c.pathStyle {
+		path = "/" + s3Escape(c.bucket, false) + path
+	} else {
+		u.Host = c.bucket + "." + u.Host
+	}
+	u.Path, u.RawPath = "", ""
+	u.Opaque = "//" + u.Host + path
+	u.RawQuery = s3Query(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if c.accessKey != "" {
+		c.sign(req, path, time.Now().UTC())
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range ok {
+		if resp.StatusCode == status {
+			return resp, nil
+		}
+	}
+	defer resp.Body.Close()
+	return nil, readS3Error(resp)
+}
+
+// readS3Error returns the error of a failed response
+func readS3Error(resp *http.Response) error {
+	e := &s3Error{status: resp.StatusCode}
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10)); err == nil && xml.Unmarshal(data, &body) == nil {
+		e.code, e.message = body.Code, body.Message
+	}
+	if resp.StatusCode == http.StatusMovedPermanently || e.code == "AuthorizationHeaderMalformed" {
+		e.region = resp.Header.Get("X-Amz-Bucket-Region")
+	}
+	return e
+}
+
+// sign adds a Signature Version 4 authorization to req, whose escaped path
+// is path
+func (c *s3Client) sign(req *http.Request, path string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3EmptyHash)
+	if c.token != "" {
+		req.Header.Set("X-Amz-Security-Token", c.token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{req.Method, path, req.URL.RawQuery,
+		canonicalHeaders.String(), signedHeaders, s3EmptyHash}, "\n")
+	scope := now.Format("20060102") + "/" + c.region + "/s3/aws4_request"
+	digest := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(digest[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), now.Format("20060102"))
+	for _, part := range []string{c.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape percent-encodes s as Signature Version 4 expects: every byte
+// but letters, digits and -._~, and / too when slash is set
+func s3Escape(s string, slash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch >= 'A' && ch <= 'Z', ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9',
+			ch == '-', ch == '.', ch == '_', ch == '~', ch == '/' && !slash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// s3Query encodes query sorted by name, as signing expects
+func s3Query(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var parts []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			parts = append(parts, s3Escape(name, true)+"="+s3Escape(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Object is an object or common prefix of a listing
+type s3Object struct {
+	Key          string `xml:"Key"`
+	Size         int64  `xml:"Size"`
+	LastModified string `xml:"LastModified"`
+}
+
+// s3Listing is a page of ListObjectsV2
+type s3Listing struct {
+	Contents              []s3Object `xml:"Contents"`
+	CommonPrefixes        []s3Object `xml:"CommonPrefixes>Prefix"`
+	IsTruncated           bool       `xml:"IsTruncated"`
+	NextContinuationToken string     `xml:"NextContinuationToken"`
+}
+
+// UnmarshalXML reads CommonPrefixes, whose Prefix holds the key directly
+func (o *s3Object) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if start.Name.Local == "Prefix" {
+		return d.DecodeElement(&o.Key, &start)
+	}
+	type object s3Object
+	return d.DecodeElement((*object)(o), &start)
+}
+
+// list returns a page of the objects and folders directly under prefix,
+// continuing from token
+func (c *s3Client) list(prefix, token string, maxKeys int) (*s3Listing, error) {
+	query := url.Values{"list-type": {"2"}, "delimiter": {"/"}, "prefix": {prefix}}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	if maxKeys > 0 {
+		query.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+	resp, err := c.do(ctx, http.MethodGet, "", query, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var listing s3Listing
+	if err := xml.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		return nil, fmt.Errorf("failed to parse listing: %w", err)
+	}
+	return &listing, nil
+}
+
+// head returns the size and modification time of the object key
+func (c *s3Client) head(key string) (*remoteFileInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+	resp, err := c.do(ctx, http.MethodHead, key, nil, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &remoteFileInfo{name: key[strings.LastIndexByte(key, '/')+1:], size: resp.ContentLength, modTime: modTime}, nil
+}
+
+// read returns up to n bytes of the object key from off
+func (c *s3Client) read(key string, off int64, n int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+	header := http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", off, off+int64(n)-1)}}
+	resp, err := c.do(ctx, http.MethodGet, key, nil, header, http.StatusPartialContent, http.StatusOK)
+	var s3Err *s3Error
+	if errors.As(err, &s3Err) && s3Err.status == http.StatusRequestedRangeNotSatisfiable {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		// The server ignored the range and sends the whole object
+		if _, err := io.CopyN(io.Discard, resp.Body, off); err != nil {
+			return nil, io.EOF
+		}
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, int64(n)))
+}
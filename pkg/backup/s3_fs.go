@@ -0,0 +1,121 @@
+package winbackupchecker
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// s3FS reads a bucket through the S3 client. Keys are split into folders at
+// each /, as the consoles of S3 services show them; the host of an s3:// path
+// is the bucket.
+type s3FS struct {
+	client *s3Client
+}
+
+// dialS3 connects to the bucket of key, s3://bucket, checking it can be
+// listed
+func dialS3(key string, opts *S3Options) (*s3FS, error) {
+	client, err := newS3Client(strings.TrimPrefix(key, "s3://"), opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := client.list("", "", 1); err != nil {
+		return nil, err
+	}
+	return &s3FS{client: client}, nil
+}
+
+func (s *s3FS) Stat(name string) (fs.FileInfo, error) {
+	key := bucketKey(name)
+	if key == "" {
+		return &remoteFileInfo{name: "/", dir: true}, nil
+	}
+	info, err := s.client.head(key)
+	if err == nil {
+		return info, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	// Folders have no object of their own but are the prefix of some
+	listing, listErr := s.client.list(key+"/", "", 1)
+	if listErr != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: listErr}
+	}
+	if len(listing.Contents) == 0 && len(listing.CommonPrefixes) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return &remoteFileInfo{name: key[strings.LastIndexByte(key, '/')+1:], dir: true}, nil
+}
+
+func (s *s3FS) OpenDir(name string) (dirReader, error) {
+	info, err := s.Stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.Unwrap(err)}
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("not a directory")}
+	}
+	prefix := bucketKey(name)
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	token, done := "", false
+	list := func() ([]fs.DirEntry, error) {
+		if done {
+			return nil, io.EOF
+		}
+		listing, err := s.client.list(prefix, token, 0)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		token, done = listing.NextContinuationToken, !listing.IsTruncated
+		var entries []fs.DirEntry
+		for _, p := range listing.CommonPrefixes {
+			entries = append(entries, &remoteFileInfo{name: strings.TrimSuffix(strings.TrimPrefix(p.Key, prefix), "/"), dir: true})
+		}
+		for _, o := range listing.Contents {
+			// Consoles create empty objects ending in / as folders
+			if o.Key == prefix || strings.HasSuffix(o.Key, "/") {
+				continue
+			}
+			modTime, _ := time.Parse(time.RFC3339, o.LastModified)
+			entries = append(entries, &remoteFileInfo{name: strings.TrimPrefix(o.Key, prefix), size: o.Size, modTime: modTime})
+		}
+		return entries, nil
+	}
+	return &remoteDir{list: list, close: func() error { return nil }}, nil
+}
+
+func (s *s3FS) Open(name string) (readableFile, error) {
+	key := bucketKey(name)
+	info, err := s.client.head(key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			if dir, statErr := s.Stat(name); statErr == nil && dir.IsDir() {
+				err = errors.New("is a directory")
+			}
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &bufferedFile{
+		info:      info,
+		readAhead: s3ReadAhead,
+		fetch: func(off int64, n int) ([]byte, error) {
+			if off >= info.Size() {
+				return nil, io.EOF
+			}
+			return s.client.read(key, off, n)
+		},
+		close: func() error { return nil },
+	}, nil
+}
+
+func (s *s3FS) Close() error {
+	s.client.http.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,199 @@
+package winbackupchecker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// scanCacheVersion is bumped whenever the on-disk scan cache layout changes
+const scanCacheVersion = 1
+
+// scanCacheEntry identifies the version of a file that passed validation
+type scanCacheEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// ScanCache records the zip and catalog files that passed validation, by
+// size and modification time, so files left unchanged since are not read
+// again. Failed files are not recorded and are read on every run, so a
+// transient read error is not remembered.
+type ScanCache struct {
+	mu          sync.Mutex
+	path        string
+	dirty       bool
+	revalidate  bool
+	counters    cacheCounters
+	Version     int                                  `json:"version"`
+	Fingerprint string                               `json:"fingerprint"`
+	Sets        map[string]map[string]scanCacheEntry `json:"sets"`
+}
+
+// LoadScanCache loads the scan cache at path, returning an empty cache if the
+// file does not exist or was written by another checker version, so upgrades
+// revalidate every file
+func LoadScanCache(path string) (*ScanCache, error) {
+	cache := &ScanCache{
+		path:        path,
+		Version:     scanCacheVersion,
+		Fingerprint: CacheFingerprint(),
+		Sets:        make(map[string]map[string]scanCacheEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return nil, fmt.Errorf("failed to read scan cache file: %w", err)
+	}
+
+	var stored ScanCache
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse scan cache file: %w", err)
+	}
+
+	if stored.Version != scanCacheVersion || stored.Fingerprint != CacheFingerprint() || stored.Sets == nil {
+		// Saving the empty cache replaces the stale one even if nothing changes
+		cache.dirty = true
+		return cache, nil
+	}
+
+	cache.Sets = stored.Sets
+	return cache, nil
+}
+
+// Revalidate makes every lookup miss, so all files are read again and their
+// new results recorded
+func (c *ScanCache) Revalidate() {
+	c.revalidate = true
+}
+
+// lookup reports whether the file at path passed validation unchanged. The
+// returned info is the file's current state, to record after validating it.
+func (c *ScanCache) lookup(setPath, path string) (os.FileInfo, bool) {
+	if c == nil {
+		return nil, false
+	}
+	info, err := statBackup(path)
+	if err != nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	entry, ok := c.Sets[setPath][path]
+	c.mu.Unlock()
+
+	if ok && !c.revalidate && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) {
+		c.counters.hit(info.Size())
+		return info, true
+	}
+	c.counters.miss()
+	return info, false
+}
+
+// record remembers that the file at path passed validation in the state
+// described by info, as returned by lookup before the file was read
+func (c *ScanCache) record(setPath, path string, info os.FileInfo) {
+	if c == nil || info == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, ok := c.Sets[setPath]
+	if !ok {
+		files = make(map[string]scanCacheEntry)
+		c.Sets[setPath] = files
+	}
+	entry := scanCacheEntry{Size: info.Size(), ModTime: info.ModTime()}
+	if files[path] != entry {
+		files[path] = entry
+		c.dirty = true
+	}
+}
+
+// prune drops the sets under root that were not discovered, and the files
+// of discovered sets that no longer exist, so deleted backups do not pile up
+func (c *ScanCache) prune(root string, sets []BackupSetInfo) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	discovered := make(map[string]BackupSetInfo, len(sets))
+	for _, set := range sets {
+		discovered[set.Path] = set
+	}
+
+	for setPath, files := range c.Sets {
+		set, ok := discovered[setPath]
+		if !ok {
+			if isSubPath(root, setPath) {
+				delete(c.Sets, setPath)
+				c.dirty = true
+			}
+			continue
+		}
+
+		present := make(map[string]bool, len(set.BackupFiles)+len(set.CatalogFiles))
+		for _, path := range set.BackupFiles {
+			present[path] = true
+		}
+		for _, path := range set.CatalogFiles {
+			present[path] = true
+		}
+		for path := range files {
+			if !present[path] {
+				delete(files, path)
+				c.dirty = true
+			}
+		}
+	}
+}
+
+// Stats returns file lookups since the previous call; a hit means an
+// unchanged file was not read
+func (c *ScanCache) Stats() CacheStats {
+	return c.counters.snapshot()
+}
+
+// Save writes the cache back to disk if it changed
+func (c *ScanCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scan cache: %w", err)
+	}
+
+	if dir := filepath.Dir(c.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create scan cache dir: %w", err)
+		}
+	}
+
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write scan cache file: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace scan cache file: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
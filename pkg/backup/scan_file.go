@@ -0,0 +1,1416 @@
+package winbackupchecker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScanOptions controls how backup roots are scanned
+type ScanOptions struct {
+	MaxWorkers   int
+	CatalogCache *CatalogCache
+	Index        *CatalogIndex
+	Sections     *ReportSectionsConfig
+
+	// ScanCache skips zip and catalog files that passed validation before
+	// and are unchanged since
+	ScanCache *ScanCache
+
+	// RequiredPaths must match a file in each machine's newest set
+	RequiredPaths []string
+
+	// ForbiddenContent must not appear in each machine's newest set
+	ForbiddenContent []ForbiddenContentRule
+
+	// Manifests enables verification against checksum manifests in each set
+	Manifests *ManifestConfig
+
+	// Parity generates PAR2 recovery data for valid sets
+	Parity *ParityConfig
+
+	// ArchiveTier lists roots in cold storage, validated from metadata only
+	ArchiveTier *ArchiveTierConfig
+
+	// ActiveJobs detects backup jobs writing to a root during validation
+	ActiveJobs *ActiveJobConfig
+
+	// Snapshots validates roots against temporary read-only snapshots
+	Snapshots *SnapshotConfig
+
+	// USNJournal reports changes to backup data outside the backup windows
+	USNJournal *USNJournalConfig
+
+	// MinBackupAge and MaxBackupAge bound the age of each set; zero uses one
+	// hour and 90 days
+	MinBackupAge time.Duration
+	MaxBackupAge time.Duration
+
+	// SkipContent validates sets from file metadata without reading zip and
+	// catalog contents
+	SkipContent bool
+
+	// SampleRate is the fraction of zip files read per set; 0 reads all
+	SampleRate float64
+
+	// ExpectedMachines must each have at least one backup set under the root
+	ExpectedMachines []string
+
+	// PathTimeout limits the scan of a root and SetTimeout the validation of
+	// each set; zero leaves them to the overall timeout
+	PathTimeout time.Duration
+	SetTimeout  time.Duration
+
+	// BackupFilePatterns match the names of numbered backup files, without
+	// the extension, with the number as the first group. They are tried
+	// before the built-in English and localized patterns.
+	BackupFilePatterns []*regexp.Regexp
+
+	// Checks selects the validators run on each set; empty runs them all
+	Checks []string
+
+	// SeverityOverrides decide which issues are critical for fail-fast
+	SeverityOverrides SeverityOverrides
+
+	// Sets limits validation to these backup set paths; empty validates all
+	Sets []string
+
+	// NewestSets limits validation to the newest sets of each machine; 0
+	// validates all
+	NewestSets int
+
+	// FileWorkers is the number of zip and catalog files of a set read
+	// concurrently; 0 or 1 reads them one at a time
+	FileWorkers int
+
+	// MaxReads limits the files read at once across all sets; zero uses
+	// MaxWorkers
+	MaxReads int
+
+	// FastZip checks zip files from their central directory only
+	FastZip bool
+
+	// IORetry retries files whose reads fail with I/O errors; nil uses the
+	// defaults
+	IORetry *IORetryConfig
+
+	// Links sets which links are followed in backup roots and sets
+	Links *LinkConfig
+
+	// MaxSetFiles stops listing a set after that many files; 0 lists all
+	MaxSetFiles int
+
+	// Storage is the type of storage the root is on, detected when unknown.
+	// MaxWorkers and FileWorkers left at zero use its defaults.
+	Storage StorageType
+
+	// readSlots holds a token for each file being read, shared by the sets
+	// of a root
+	readSlots chan struct{}
+
+	// Interrupt, once closed, stops new sets and paths from being started.
+	// Sets being validated finish, and the rest are reported as not
+	// finished with ErrInterrupted.
+	Interrupt <-chan struct{}
+
+	// abort stops the run once a set has a critical issue; set by
+	// EnableFailFast
+	abort context.CancelCauseFunc
+
+	// archive is set while scanning a root covered by ArchiveTier
+	archive bool
+
+	// root is the backup root being scanned, and snapshotRoot the
+	// read-only snapshot it is validated through, if any
+	root         string
+	snapshotRoot string
+
+	// Progress receives human-readable progress output; nil means stdout
+	Progress io.Writer
+
+	// OnEvent, if set, receives progress events as sets are discovered,
+	// validated and found to have issues. It is called from the goroutines
+	// validating sets, so it must be safe for concurrent use, and the scan
+	// waits for it to return.
+	OnEvent func(Event)
+
+	// Localizer translates the progress output; nil means English
+	Localizer *Localizer
+}
+
+// ErrFailFast is the cause of a run stopped by fail-fast after a critical
+// issue
+var ErrFailFast = errors.New("stopped after a critical issue")
+
+// ErrInterrupted is the cause of sets not validated because the run was
+// interrupted through ScanOptions.Interrupt
+var ErrInterrupted = errors.New("interrupted")
+
+// EnableFailFast makes the scans using o stop once a set has a critical
+// issue. The scans must use the returned context, which is cancelled with
+// ErrFailFast as the cause; the sets not validated by then are reported as
+// not finished. Call the returned function when the run is done.
+func (o *ScanOptions) EnableFailFast(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	o.abort = cancel
+	return ctx, func() { cancel(nil) }
+}
+
+// Interrupted reports whether Interrupt has been closed
+func (o ScanOptions) Interrupted() bool {
+	select {
+	case <-o.Interrupt:
+		return true
+	default:
+		return false
+	}
+}
+
+// acquireRead waits for a free read slot. Returns false when ctx ended
+// first.
+func (o ScanOptions) acquireRead(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if o.readSlots == nil {
+		return true
+	}
+	select {
+	case o.readSlots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// releaseRead frees the read slot taken by acquireRead
+func (o ScanOptions) releaseRead() {
+	if o.readSlots != nil {
+		<-o.readSlots
+	}
+}
+
+// logf writes a progress line to the configured progress writer
+func (o ScanOptions) logf(format string, args ...any) {
+	w := o.Progress
+	if w == nil {
+		w = os.Stdout
+	}
+	o.Localizer.Fprintf(w, format, args...)
+}
+
+// BackupSetInfo contains metadata about a backup set
+type BackupSetInfo struct {
+	Path          string
+	Size          int64
+	FileCount     int
+	ModTime       time.Time
+	CatalogFiles  []string
+	BackupFiles   []string
+	ManifestFiles []string
+
+	// Links are the links found inside the set
+	Links []SetLink
+
+	// Unfinished are the uploads of files into the set that never
+	// finished, on storage that keeps them
+	Unfinished []UnfinishedUpload
+
+	// Truncated is set when listing the set stopped early, after
+	// ScanOptions.MaxSetFiles files or as the scan ran out of time, so its
+	// file lists and counts are partial
+	Truncated bool
+}
+
+func ScanFileBackupDir(ctx context.Context, root string, opts ScanOptions) (*ScanReport, error) {
+	storage := opts.Storage
+	if storage == StorageUnknown && (opts.MaxWorkers <= 0 || opts.FileWorkers <= 0) {
+		storage = DetectStorageType(root)
+	}
+	opts = opts.ForStorage(storage)
+	opts.logf("Scanning file backup root: %s (max workers: %d)\n", root, opts.MaxWorkers)
+	if storage != StorageUnknown {
+		opts.logf("Storage: %s, %d file(s) per backup set at once\n", storage, opts.FileWorkers)
+	}
+
+	report := &ScanReport{Root: root, Storage: storage, Reports: []BackupReport{}, PhaseTimings: PhaseTimings{}}
+	startTime := time.Now()
+
+	if opts.PathTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.PathTimeout)
+		defer cancel()
+	}
+
+	// Set workers and file workers share one limit on concurrent reads
+	maxReads := opts.MaxReads
+	if maxReads <= 0 {
+		maxReads = max(opts.MaxWorkers, 1)
+	}
+	opts.readSlots = make(chan struct{}, maxReads)
+
+	// Check if this path directly contains MediaID.bin (single backup root)
+	mediaIDPath := filepath.Join(root, "MediaID.bin")
+	if fileExists(mediaIDPath) {
+		report, err := scanSingleBackupRoot(ctx, root, opts)
+		if err != nil {
+			return nil, err
+		}
+		checkExpectedMachines(root, report, opts.ExpectedMachines)
+		return report, nil
+	}
+
+	// Otherwise, check if this is a parent directory containing multiple backup roots
+	entries, err := readBackupDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	foundBackups := false
+	for _, entry := range entries {
+		subPath := filepath.Join(root, entry.Name())
+		if !opts.Links.isDir(subPath, entry) {
+			continue
+		}
+		subMediaIDPath := filepath.Join(subPath, "MediaID.bin")
+
+		// Check if this subdirectory is a backup root
+		if fileExists(subMediaIDPath) {
+			foundBackups = true
+			opts.logf("Found backup root: %s\n", entry.Name())
+
+			subReport, err := scanSingleBackupRoot(ctx, subPath, opts)
+			if err != nil {
+				report.Reports = append(report.Reports, BackupReport{
+					BackupDir: subPath,
+					Valid:     false,
+					Issues: []ValidationIssue{
+						newIssue(IssueRootScanFailed, SeverityCritical,
+							msg("failed to scan backup root: %v", err),
+							subPath,
+							"check path accessibility and permissions"),
+					},
+					CheckedAt: NowRFC3339(),
+				})
+				continue
+			}
+
+			report.Reports = append(report.Reports, subReport.Reports...)
+			report.PhaseTimings.Merge(subReport.PhaseTimings)
+			for machine := range subReport.machines {
+				report.addMachine(machine)
+			}
+		}
+	}
+
+	if !foundBackups {
+		// No MediaID.bin found at this level or in subdirectories
+		issue := newIssue(IssueNoBackupRoots, SeverityCritical,
+			msg("no backup roots found (missing MediaID.bin)"),
+			root,
+			"ensure the path contains backup roots with MediaID.bin files")
+
+		report.Reports = append(report.Reports, BackupReport{
+			BackupDir: root,
+			Valid:     false,
+			Issues:    []ValidationIssue{issue},
+			CheckedAt: NowRFC3339(),
+		})
+	} else {
+		checkExpectedMachines(root, report, opts.ExpectedMachines)
+	}
+
+	opts.logf("Completed validation in %v\n", time.Since(startTime))
+	return report, nil
+}
+
+func scanSingleBackupRoot(ctx context.Context, root string, opts ScanOptions) (*ScanReport, error) {
+	report := &ScanReport{Root: root, Reports: []BackupReport{}, PhaseTimings: PhaseTimings{}}
+
+	opts.root = root
+	opts.archive = opts.ArchiveTier.covers(root)
+	if opts.archive {
+		opts.logf("%s is on an archive tier; content checks are deferred\n", filepath.Base(root))
+	}
+
+	// Root must have MediaID.bin
+	mediaIDPath := filepath.Join(root, "MediaID.bin")
+	if !fileExists(mediaIDPath) {
+		issue := newIssue(IssueMissingMediaID, SeverityCritical,
+			msg("missing MediaID.bin at root"),
+			root,
+			"ensure the backup root directory is correct and contains MediaID.bin")
+
+		report.Reports = append(report.Reports, BackupReport{
+			BackupDir: root,
+			Valid:     false,
+			Issues:    []ValidationIssue{issue},
+			CheckedAt: NowRFC3339(),
+		})
+		opts.emitLateIssues(report.Reports, nil)
+		return report, nil
+	}
+
+	// Validate MediaID.bin
+	if err := validateMediaID(ctx, mediaIDPath); err != nil {
+		issue := newIssue(IssueInvalidMediaID, SeverityError,
+			msg("invalid MediaID.bin: %v", err),
+			mediaIDPath,
+			"check if MediaID.bin is corrupted or from a different backup system")
+
+		report.Reports = append(report.Reports, BackupReport{
+			BackupDir: root,
+			Valid:     false,
+			Issues:    []ValidationIssue{issue},
+			CheckedAt: NowRFC3339(),
+		})
+	}
+
+	// Look for a backup job writing to the root before reading it
+	var job activeJob
+	if opts.ActiveJobs != nil && opts.ActiveJobs.Enabled {
+		phaseStart := time.Now()
+		job = awaitIdleBackupJob(ctx, root, opts)
+		switch {
+		case len(job.sets) > 0:
+			opts.logf("Backup job in progress on %s: %s\n", filepath.Base(root), activeSetNames(job))
+		case job.engine != "":
+			opts.logf("Backup engine %s is running\n", job.engine)
+		}
+		report.PhaseTimings.Since(PhaseActiveJob, phaseStart)
+	}
+
+	// Read from a snapshot where configured, so a backup job starting
+	// mid-scan cannot change files under validation
+	scanRoot := root
+	if target, ok := opts.Snapshots.target(root); ok {
+		phaseStart := time.Now()
+		snap, err := createSnapshot(ctx, target, root)
+		if err != nil {
+			report.Reports = append(report.Reports, BackupReport{
+				BackupDir: root,
+				Valid:     true,
+				Issues: []ValidationIssue{newIssue(IssueLiveData, SeverityWarning,
+					msg("validating live data: %v", err),
+					root,
+					"check the snapshot configuration; a backup job running during the scan may cause false findings")},
+				CheckedAt: NowRFC3339(),
+			})
+		} else {
+			defer func() {
+				if err := snap.release(); err != nil {
+					opts.logf("Warning: %v\n", err)
+				}
+			}()
+			opts.logf("Validating %s against snapshot %s\n", filepath.Base(root), snap.root)
+			scanRoot = snap.root
+		}
+		report.PhaseTimings.Since(PhaseSnapshot, phaseStart)
+	}
+
+	// Discover backup sets
+	phaseStart := time.Now()
+	snapshotSets, err := discoverBackupSets(ctx, scanRoot, opts.Links, opts.MaxSetFiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover backup sets: %w", err)
+	}
+	report.PhaseTimings.Since(PhaseDiscovery, phaseStart)
+
+	// Everything but validation works on the live paths
+	backupSets := snapshotSets
+	if scanRoot != root {
+		backupSets = rebaseSets(snapshotSets, scanRoot, root)
+		opts.snapshotRoot = scanRoot
+	}
+
+	opts.logf("Found %d backup sets to validate in %s\n", len(backupSets), filepath.Base(root))
+	for _, set := range backupSets {
+		report.addMachine(MachineName(set.Path))
+	}
+
+	opts.ScanCache.prune(root, backupSets)
+
+	// Keep the search index current with the catalogs just discovered
+	if opts.Index != nil && !opts.archive {
+		phaseStart = time.Now()
+		if _, err := opts.Index.Update(ctx, backupSets, opts.CatalogCache); err != nil {
+			opts.logf("Warning: failed to update catalog index: %v\n", err)
+		}
+		report.PhaseTimings.Since(PhaseIndex, phaseStart)
+	}
+
+	// Leave older sets to a full run, which need not be as frequent
+	if opts.NewestSets > 0 {
+		snapshotSets, backupSets = selectNewestSets(snapshotSets, backupSets, opts.NewestSets)
+		opts.logf("Validating the newest %d of each machine: %d sets\n", opts.NewestSets, len(backupSets))
+	}
+
+	// Validate only the requested sets, such as those a watch saw change
+	if len(opts.Sets) > 0 {
+		snapshotSets, backupSets = selectSets(snapshotSets, backupSets, opts.Sets)
+		opts.logf("Validating %d of them\n", len(backupSets))
+	}
+
+	for _, set := range backupSets {
+		opts.setEvent(EventSetDiscovered, set.Path)
+	}
+
+	// Validate backup sets with controlled concurrency
+	skipBusy := job.active() && opts.ActiveJobs.action() == ActiveJobSkip
+	var reports []BackupReport
+	if skipBusy {
+		reports = validateIdleSets(ctx, snapshotSets, backupSets, job, opts)
+	} else {
+		reports = validateBackupSets(ctx, snapshotSets, opts)
+	}
+
+	// The issues of each set sent as it finished, after those on the root
+	sent := make([]int, len(report.Reports), len(report.Reports)+len(reports))
+	for _, r := range reports {
+		sent = append(sent, len(r.Issues))
+	}
+	if scanRoot != root {
+		rebaseReports(reports, scanRoot, root)
+	}
+	if job.active() {
+		reports = annotateActiveJob(root, backupSets, reports, job, skipBusy)
+	}
+
+	phaseStart = time.Now()
+	reports = checkRecycleBin(root, backupSets, reports)
+	report.PhaseTimings.Since(PhaseRecycleBin, phaseStart)
+
+	if opts.USNJournal != nil && opts.USNJournal.Enabled {
+		phaseStart = time.Now()
+		reports = checkUSNJournal(root, backupSets, reports, opts)
+		report.PhaseTimings.Since(PhaseUSNJournal, phaseStart)
+	}
+
+	if opts.archive && opts.ArchiveTier.SampleEvery != "" {
+		phaseStart = time.Now()
+		sampleArchiveRestores(ctx, root, backupSets, reports, opts)
+		report.PhaseTimings.Since(PhaseSampleRestore, phaseStart)
+	}
+
+	// The remaining checks read backup contents, which archive tiers defer
+	if opts.archive {
+		report.Reports = append(report.Reports, reports...)
+		opts.emitLateIssues(report.Reports, sent)
+		return report, nil
+	}
+
+	if len(opts.RequiredPaths) > 0 {
+		phaseStart = time.Now()
+		checkRequiredPaths(backupSets, reports, opts)
+		report.PhaseTimings.Since(PhaseRequiredPaths, phaseStart)
+	}
+
+	if len(opts.ForbiddenContent) > 0 {
+		phaseStart = time.Now()
+		checkForbiddenContent(backupSets, reports, opts)
+		report.PhaseTimings.Since(PhaseForbiddenContent, phaseStart)
+	}
+
+	if opts.Parity != nil && opts.Parity.Enabled {
+		phaseStart = time.Now()
+		generateParity(ctx, backupSets, reports, opts)
+		report.PhaseTimings.Since(PhaseParity, phaseStart)
+	}
+
+	if opts.Sections != nil {
+		phaseStart = time.Now()
+		addInsights(backupSets, reports, opts.Sections)
+		report.PhaseTimings.Since(PhaseInsights, phaseStart)
+	}
+	report.Reports = append(report.Reports, reports...)
+	opts.emitLateIssues(report.Reports, sent)
+
+	return report, nil
+}
+
+// selectSets returns the sets whose live path is in paths, keeping the
+// snapshot and live lists aligned
+func selectSets(snapshotSets, backupSets []BackupSetInfo, paths []string) ([]BackupSetInfo, []BackupSetInfo) {
+	wanted := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		wanted[filepath.Clean(path)] = true
+	}
+
+	var selectedSnapshot, selected []BackupSetInfo
+	for i, set := range backupSets {
+		if wanted[filepath.Clean(set.Path)] {
+			selectedSnapshot = append(selectedSnapshot, snapshotSets[i])
+			selected = append(selected, set)
+		}
+	}
+	return selectedSnapshot, selected
+}
+
+// selectNewestSets returns the newest n sets of each machine, keeping the
+// snapshot and live lists aligned and in order
+func selectNewestSets(snapshotSets, backupSets []BackupSetInfo, n int) ([]BackupSetInfo, []BackupSetInfo) {
+	_, byMachine := newestSetsByMachine(backupSets, n)
+	wanted := make(map[int]bool, len(backupSets))
+	for _, indexes := range byMachine {
+		for _, i := range indexes {
+			wanted[i] = true
+		}
+	}
+
+	var selectedSnapshot, selected []BackupSetInfo
+	for i, set := range backupSets {
+		if wanted[i] {
+			selectedSnapshot = append(selectedSnapshot, snapshotSets[i])
+			selected = append(selected, set)
+		}
+	}
+	return selectedSnapshot, selected
+}
+
+// DiscoverBackupSets finds backup sets under root, which may be a single
+// backup root (containing MediaID.bin) or a directory of backup roots,
+// following the links allowed by links
+func DiscoverBackupSets(root string, links *LinkConfig) ([]BackupSetInfo, error) {
+	if fileExists(filepath.Join(root, "MediaID.bin")) {
+		return discoverBackupSets(context.Background(), root, links, 0)
+	}
+
+	entries, err := readBackupDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	var backupSets []BackupSetInfo
+	for _, entry := range entries {
+		subPath := filepath.Join(root, entry.Name())
+		if !links.isDir(subPath, entry) || !fileExists(filepath.Join(subPath, "MediaID.bin")) {
+			continue
+		}
+
+		sets, err := discoverBackupSets(context.Background(), subPath, links, 0)
+		if err != nil {
+			return nil, err
+		}
+		backupSets = append(backupSets, sets...)
+	}
+
+	return backupSets, nil
+}
+
+// discoverBackupSets finds the backup sets of the backup root at root,
+// listing at most maxFiles files of each when positive
+func discoverBackupSets(ctx context.Context, root string, links *LinkConfig, maxFiles int) ([]BackupSetInfo, error) {
+	var backupSets []BackupSetInfo
+
+	entries, err := readBackupDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup root: %w", err)
+	}
+
+	for _, entry := range entries {
+		machineDir := filepath.Join(root, entry.Name())
+		if !links.isDir(machineDir, entry) || entry.Name() == "." || entry.Name() == ".." {
+			continue
+		}
+
+		backupSetDirs, err := readBackupDir(machineDir)
+		if err != nil {
+			continue
+		}
+
+		for _, setDir := range backupSetDirs {
+			setPath := filepath.Join(machineDir, setDir.Name())
+			if !links.isDir(setPath, setDir) || filepath.Ext(setDir.Name()) != "" {
+				continue
+			}
+
+			backupSets = append(backupSets, *gatherBackupSetInfo(ctx, setPath, links, maxFiles))
+		}
+	}
+
+	// Sort by modification time (newest first)
+	sort.Slice(backupSets, func(i, j int) bool {
+		return backupSets[i].ModTime.After(backupSets[j].ModTime)
+	})
+
+	return backupSets, nil
+}
+
+// gatherBackupSetInfo lists the files of the set at setPath, skipping
+// folders that cannot be read and stopping after maxFiles files when
+// positive
+func gatherBackupSetInfo(ctx context.Context, setPath string, links *LinkConfig, maxFiles int) *BackupSetInfo {
+	info := &BackupSetInfo{
+		Path:         setPath,
+		CatalogFiles: []string{},
+		BackupFiles:  []string{},
+	}
+	info.Links, info.Truncated = walkSetFiles(ctx, setPath, links, maxFiles, func(path string, fileInfo os.FileInfo) {
+		ext := strings.ToLower(filepath.Ext(path))
+
+		// Track file counts and sizes
+		info.FileCount++
+		info.Size += fileInfo.Size()
+
+		// Update modification time to newest file. Manifests and recovery
+		// data are written after the backup and must not make it look newer.
+		isManifest := manifestFormatFor(path) != nil
+		if !isManifest && ext != ".par2" && fileInfo.ModTime().After(info.ModTime) {
+			info.ModTime = fileInfo.ModTime()
+		}
+
+		// Categorize files
+		switch ext {
+		case ".wbcat", ".cat":
+			if filepath.Base(filepath.Dir(path)) == "Catalogs" {
+				info.CatalogFiles = append(info.CatalogFiles, path)
+			}
+		case ".zip":
+			info.BackupFiles = append(info.BackupFiles, path)
+		default:
+			if isManifest {
+				info.ManifestFiles = append(info.ManifestFiles, path)
+			}
+		}
+
+	})
+
+	// Uploads that failed cannot be told from ones still running, so they
+	// are reported but do not stop the scan when they cannot be listed
+	info.Unfinished, _ = unfinishedUploads(setPath)
+
+	// Recovery data generated into the sibling parity folder
+	if entries, err := readBackupDir(parityDir(setPath)); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() && manifestFormatFor(entry.Name()) != nil {
+				info.ManifestFiles = append(info.ManifestFiles, filepath.Join(parityDir(setPath), entry.Name()))
+			}
+		}
+	}
+
+	sort.Strings(info.BackupFiles)
+	sort.Strings(info.CatalogFiles)
+	sort.Strings(info.ManifestFiles)
+
+	return info
+}
+
+func validateBackupSets(ctx context.Context, backupSets []BackupSetInfo, opts ScanOptions) []BackupReport {
+	reports := make([]BackupReport, len(backupSets))
+
+	group, groupCtx := newWorkGroup(ctx, opts.MaxWorkers)
+	for i := range backupSets {
+		group.Go(func() error {
+			if groupCtx.Err() != nil || opts.Interrupted() {
+				return nil
+			}
+			opts.setEvent(EventSetStarted, backupSets[i].Path)
+			reports[i] = validateSetWithTimeout(groupCtx, backupSets[i], opts)
+			opts.finishSet(reports[i])
+			if opts.abort != nil && hasCriticalIssue(reports[i], opts.SeverityOverrides) {
+				opts.abort(ErrFailFast)
+				return ErrFailFast
+			}
+			return nil
+		})
+	}
+	cause := group.Wait()
+	if cause == nil {
+		cause = context.Cause(ctx)
+	}
+	if cause == nil && opts.Interrupted() {
+		cause = ErrInterrupted
+	}
+
+	// Sets not reached before the scan ran out of time, failed fast or was
+	// interrupted
+	for i := range reports {
+		if reports[i].BackupDir == "" {
+			reports[i] = unfinishedReport(backupSets[i].Path, cause)
+			opts.finishSet(reports[i])
+		}
+	}
+
+	return reports
+}
+
+// hasCriticalIssue reports whether a set has a critical issue, counting
+// the severity_overrides
+func hasCriticalIssue(report BackupReport, overrides SeverityOverrides) bool {
+	for _, issue := range report.Issues {
+		if overrides.severity(issue) >= SeverityCritical {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSetWithTimeout validates a set within opts.SetTimeout, so a single
+// slow set fails with a timeout issue instead of using up the whole scan
+func validateSetWithTimeout(ctx context.Context, setInfo BackupSetInfo, opts ScanOptions) BackupReport {
+	setCtx := ctx
+	if opts.SetTimeout > 0 {
+		var cancel context.CancelFunc
+		setCtx, cancel = context.WithTimeout(ctx, opts.SetTimeout)
+		defer cancel()
+	}
+
+	report := validateFileBackupSet(setCtx, setInfo, opts)
+	switch {
+	case ctx.Err() != nil:
+		report.Issues = append(report.Issues, unfinishedIssue(setInfo.Path, context.Cause(ctx)))
+		report.Valid = false
+	case setCtx.Err() != nil:
+		report.Issues = append(report.Issues, newIssue(IssueSetTimeout, SeverityError,
+			msg("validation timed out after %s", FormatDuration(opts.SetTimeout)),
+			setInfo.Path,
+			"raise set_timeout for this path, or lower sample_rate so fewer files are read"))
+		report.Valid = false
+	}
+	return report
+}
+
+// unfinishedReport reports a set whose validation did not start before the
+// scan timed out, was cancelled or was interrupted
+func unfinishedReport(setPath string, err error) BackupReport {
+	return BackupReport{
+		BackupDir: setPath,
+		Valid:     false,
+		Issues:    []ValidationIssue{unfinishedIssue(setPath, err)},
+		CheckedAt: NowRFC3339(),
+	}
+}
+
+func unfinishedIssue(setPath string, err error) ValidationIssue {
+	if errors.Is(err, ErrInterrupted) {
+		return newIssue(IssueNotFinished, SeverityError,
+			msg("validation not finished: the check was interrupted"),
+			setPath,
+			"run the check again to validate the remaining sets")
+	}
+	if errors.Is(err, ErrFailFast) {
+		return newIssue(IssueNotFinished, SeverityError,
+			msg("validation not finished: stopped after a critical issue (fail-fast)"),
+			setPath,
+			"fix the critical issue, or run without fail-fast to validate every set")
+	}
+
+	message := msg("validation not finished: the scan was cancelled")
+	if errors.Is(err, context.DeadlineExceeded) {
+		message = msg("validation not finished: the scan timed out")
+	}
+	return newIssue(IssueNotFinished, SeverityError,
+		message,
+		setPath,
+		"raise path_timeout or --timeout, or set set_timeout so a slow set cannot hold up the others")
+}
+
+func validateFileBackupSet(ctx context.Context, setInfo BackupSetInfo, opts ScanOptions) BackupReport {
+	startTime := time.Now()
+	stats := ValidationStats{
+		TotalFiles:   setInfo.FileCount,
+		PhaseTimings: PhaseTimings{},
+	}
+
+	opts.logf("Validating backup set: %s\n", filepath.Base(setInfo.Path))
+
+	issues := runValidators(ctx, setInfo, opts, setValidators(opts, &stats), &stats)
+
+	// Calculate final stats
+	stats.ValidationTime = time.Since(startTime).String()
+	stats.TotalSize = setInfo.Size
+	stats.CatalogFiles = len(setInfo.CatalogFiles)
+	opts.logf("Finished validating backup set: %d\n", len(setInfo.CatalogFiles))
+
+	stats.BackupFiles = len(setInfo.BackupFiles)
+
+	if len(setInfo.CatalogFiles) > 0 || len(setInfo.BackupFiles) > 0 {
+		stats.OldestBackupTime = &setInfo.ModTime
+		stats.NewestBackupTime = &setInfo.ModTime
+	}
+
+	// Determine validity (no critical or error issues)
+	valid := true
+	for _, issue := range issues {
+		if issue.Severity >= SeverityError {
+			valid = false
+			break
+		}
+	}
+
+	return BackupReport{
+		BackupDir:       setInfo.Path,
+		Valid:           valid,
+		Issues:          issues,
+		CheckedAt:       NowRFC3339(),
+		ValidationStats: stats,
+		SkippedChecks:   opts.skippedChecks(),
+	}
+}
+
+func validateBackupStructure(setInfo BackupSetInfo) []ValidationIssue {
+	issues := []ValidationIssue{}
+
+	// The files past a partial listing may hold the catalogs and backup
+	// files looked for below, so only their folder is checked
+	if setInfo.Truncated {
+		issues = append(issues, newIssue(IssueSetListingTruncated, SeverityWarning,
+			msg("stopped listing the set after %d files; files past them were not checked", setInfo.FileCount),
+			setInfo.Path,
+			"raise max_set_files or path_timeout, or move files that are not part of the backup out of the set"))
+	}
+
+	// Check for catalog directory and files
+	catalogDir := filepath.Join(setInfo.Path, "Catalogs")
+	if !dirExists(catalogDir) {
+		issues = append(issues, newIssue(IssueMissingCatalogsFolder, SeverityError,
+			msg("missing Catalogs folder"),
+			catalogDir,
+			"backup set should contain a Catalogs folder with .wbcat files"))
+	} else if len(setInfo.CatalogFiles) == 0 && !setInfo.Truncated {
+		issues = append(issues, newIssue(IssueNoCatalogFiles, SeverityError,
+			msg("no catalog files found in Catalogs folder"),
+			catalogDir,
+			"ensure the backup completed successfully and catalog files exist"))
+	}
+
+	// Check for backup files
+	if len(setInfo.BackupFiles) == 0 && !setInfo.Truncated {
+		issues = append(issues, newIssue(IssueNoBackupFiles, SeverityError,
+			msg("no backup files (.zip) found"),
+			setInfo.Path,
+			"backup set should contain .zip files with the actual backup data"))
+	}
+
+	// Check for reasonable file count
+	if setInfo.FileCount < 2 && !setInfo.Truncated {
+		issues = append(issues, newIssue(IssueFewFiles, SeverityWarning,
+			msg("backup set contains only %d files", setInfo.FileCount),
+			setInfo.Path,
+			"typical backup sets should contain multiple files (catalogs + backup files)"))
+	}
+
+	// Check for reasonable size
+	if setInfo.Size < 1024 && !setInfo.Truncated { // 1KB
+		issues = append(issues, newIssue(IssueSmallSet, SeverityWarning,
+			msg("backup set is very small (%d bytes)", setInfo.Size),
+			setInfo.Path,
+			"backup might be incomplete or corrupted"))
+	}
+
+	for _, link := range setInfo.Links {
+		issues = append(issues, linkIssue(link))
+	}
+
+	for _, upload := range setInfo.Unfinished {
+		issues = append(issues, newIssue(IssueUnfinishedUpload, SeverityError,
+			msg("upload never finished: %d parts (%d bytes) uploaded since %s", upload.Parts, upload.Size, upload.Started.Format(time.RFC3339)),
+			upload.Path,
+			"sync the file again, and cancel the unfinished upload so its parts are no longer stored"))
+	}
+
+	return issues
+}
+
+func validateBackupCompleteness(setInfo BackupSetInfo, opts ScanOptions) []ValidationIssue {
+	issues := []ValidationIssue{}
+
+	opts.logf("DEBUG: Checking completeness for %s with %d backup files\n", filepath.Base(setInfo.Path), len(setInfo.BackupFiles))
+
+	// Check for sequential backup file numbering, which a partial listing
+	// cannot tell
+	if len(setInfo.BackupFiles) > 0 && !setInfo.Truncated {
+		missing := findMissingBackupFiles(setInfo.BackupFiles, opts.BackupFilePatterns)
+		if len(missing) > 0 {
+			missingStr := strings.Join(missing, ", ")
+			issues = append(issues, newIssue(IssueMissingBackupFiles, SeverityWarning,
+				msg("missing backup files in sequence: %s", missingStr),
+				setInfo.Path,
+				"some backup data may be incomplete or files were deleted"))
+		}
+	}
+
+	return issues
+}
+
+// defaultBackupFilePatterns match the backup file names Windows Backup uses
+// in English and common localized versions, with the file number as the
+// first group
+var defaultBackupFilePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^backup ?files ?\(?(\d+)\)?$`),
+	regexp.MustCompile(`(?i)^sicherungsdateien ?\(?(\d+)\)?$`),
+	regexp.MustCompile(`(?i)^fichiers de sauvegarde ?\(?(\d+)\)?$`),
+	regexp.MustCompile(`(?i)^archivos de copia de seguridad ?\(?(\d+)\)?$`),
+	regexp.MustCompile(`(?i)^file di backup ?\(?(\d+)\)?$`),
+	regexp.MustCompile(`(?i)^back-?upbestanden ?\(?(\d+)\)?$`),
+	regexp.MustCompile(`(?i)^arquivos de backup ?\(?(\d+)\)?$`),
+}
+
+// compileBackupFilePatterns compiles the backup_file_patterns config list.
+// Each pattern needs a group capturing the file number.
+func compileBackupFilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", pattern, err)
+		}
+		if re.NumSubexp() == 0 {
+			return nil, fmt.Errorf("%q: needs a group capturing the file number, such as (\\d+)", pattern)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// findMissingBackupFiles returns the names of the files missing from the
+// numbered sequence of backup files, named like the first file found. Each
+// file name without its extension is matched against patterns, then the
+// built-in patterns; files matching none are not part of the sequence.
+func findMissingBackupFiles(backupFiles []string, patterns []*regexp.Regexp) []string {
+	if len(backupFiles) == 0 {
+		return nil
+	}
+	patterns = append(append([]*regexp.Regexp{}, patterns...), defaultBackupFilePatterns...)
+
+	numbers := make(map[int]bool)
+	minNum, maxNum := -1, -1
+	var prefix, suffix string
+
+	for _, path := range backupFiles {
+		base := filepath.Base(path)
+		ext := filepath.Ext(base)
+		name := strings.TrimSuffix(base, ext)
+
+		for _, pattern := range patterns {
+			match := pattern.FindStringSubmatchIndex(name)
+			if match == nil || match[2] < 0 {
+				continue
+			}
+			num, err := strconv.Atoi(name[match[2]:match[3]])
+			if err != nil {
+				continue
+			}
+			if minNum == -1 {
+				prefix, suffix = name[:match[2]], name[match[3]:]+ext
+			}
+
+			numbers[num] = true
+			if minNum == -1 || num < minNum {
+				minNum = num
+			}
+			if num > maxNum {
+				maxNum = num
+			}
+			break
+		}
+	}
+
+	if minNum == -1 {
+		return nil
+	}
+
+	var missing []string
+	for i := minNum; i <= maxNum; i++ {
+		if !numbers[i] {
+			missing = append(missing, prefix+strconv.Itoa(i)+suffix)
+		}
+	}
+
+	return missing
+}
+
+func validateBackupContent(ctx context.Context, setInfo BackupSetInfo, opts ScanOptions) ([]ValidationIssue, ValidationStats) {
+	issues := []ValidationIssue{}
+	stats := ValidationStats{}
+	catalogs := []*Catalog{}
+
+	// Reading content from cold storage triggers retrievals; rely on the
+	// structural checks made from file metadata instead
+	if opts.archive {
+		suggestion := ""
+		if opts.ArchiveTier.SampleEvery == "" {
+			suggestion = "configure archive_tier sample restores to periodically deep-verify files"
+		}
+		stats.ContentDeferred = true
+		issues = append(issues, newIssue(IssueContentDeferred, SeverityInfo,
+			msg("content checks deferred (archive tier)"), setInfo.Path, suggestion))
+		return issues, stats
+	}
+
+	if opts.SkipContent {
+		stats.ContentDeferred = true
+		issues = append(issues, newIssue(IssueContentSkipped, SeverityInfo,
+			msg("content checks skipped (deep_validation disabled)"), setInfo.Path, ""))
+		return issues, stats
+	}
+
+	// Validate ZIP files, or a random sample of them, and catalog files
+	zipPaths := sampleFiles(setInfo.BackupFiles, opts.SampleRate)
+	results := readContentFiles(ctx, setInfo.Path, zipPaths, setInfo.CatalogFiles, opts)
+
+	for i, zipPath := range zipPaths {
+		result := results[i]
+		if !result.read {
+			return issues, stats
+		}
+		if result.attempts > 1 {
+			stats.RetriedFiles++
+		}
+		if isTransientIOError(result.err) {
+			issues = append(issues, unreadableIssue(SeverityError, zipPath, result.attempts, result.err))
+			continue
+		}
+
+		stats.ValidatedFiles++
+
+		if result.err != nil {
+			stats.CorruptFiles++
+			issues = append(issues, newIssue(IssueCorruptBackupFile, SeverityError,
+				msg("corrupted backup file: %v", result.err),
+				zipPath,
+				"backup file may need to be restored from another source"))
+		} else {
+			stats.ContentChecks++
+		}
+	}
+
+	for i, catPath := range setInfo.CatalogFiles {
+		result := results[len(zipPaths)+i]
+		if !result.read {
+			return issues, stats
+		}
+		if result.attempts > 1 {
+			stats.RetriedFiles++
+		}
+		if isTransientIOError(result.err) {
+			issues = append(issues, unreadableIssue(SeverityWarning, catPath, result.attempts, result.err))
+			continue
+		}
+
+		stats.ValidatedFiles++
+
+		if result.err != nil {
+			stats.CorruptFiles++
+			issues = append(issues, newIssue(IssueCorruptCatalog, SeverityWarning,
+				msg("catalog file issue: %v", result.err),
+				catPath,
+				"catalog may be corrupted but backup data might still be recoverable"))
+			continue
+		}
+
+		stats.ContentChecks++
+
+		catalog := result.catalog
+		if isTransientIOError(result.parseErr) {
+			issues = append(issues, unreadableIssue(SeverityWarning, catPath, result.attempts, result.parseErr))
+			continue
+		}
+		if result.parseErr != nil {
+			issues = append(issues, newIssue(IssueCatalogUnparsable, SeverityWarning,
+				msg("cannot parse catalog file: %v", result.parseErr),
+				catPath,
+				"catalog may be corrupted but backup data might still be recoverable"))
+			continue
+		}
+		if catalog.Format == CatalogFormatUnknown {
+			issues = append(issues, newIssue(IssueUnknownCatalogVersion, SeverityInfo,
+				msg("unrecognized catalog format (header %s); catalog contents were not checked", catalog.Header),
+				catPath,
+				"no UTF-16 or ANSI paths were found in the catalog; please report the header bytes"))
+			continue
+		}
+		stats.CatalogEntries += len(catalog.Entries)
+		catalogs = append(catalogs, catalog)
+	}
+
+	if len(catalogs) > 0 {
+		stats.ContentBreakdown = contentBreakdown(catalogs)
+	}
+
+	return issues, stats
+}
+
+// contentResult is the outcome of reading a zip or catalog file
+type contentResult struct {
+	read     bool
+	err      error
+	catalog  *Catalog
+	parseErr error
+
+	// attempts is how often the file was tried, more than once after I/O
+	// errors
+	attempts int
+}
+
+// readContentFiles reads the zip files and then the catalog files of a set,
+// opts.FileWorkers at a time, each holding a read slot. Files unchanged since
+// they passed validation are not read again. The results are in the order of
+// the files; files not started before ctx ended are not read.
+func readContentFiles(ctx context.Context, setPath string, zipPaths, catPaths []string, opts ScanOptions) []contentResult {
+	paths := append(append([]string{}, zipPaths...), catPaths...)
+	results := make([]contentResult, len(paths))
+
+	// Snapshot paths change with every snapshot, so they are never cached
+	cache := opts.ScanCache
+	if opts.snapshotRoot != "" {
+		cache = nil
+	}
+
+	read := func(i int) contentResult {
+		path := paths[i]
+		result := contentResult{read: true}
+		info, unchanged := cache.lookup(setPath, path)
+		if !unchanged {
+			validate, thorough := validateCatalogFile, true
+			if i < len(zipPaths) {
+				validate = validateZipFile
+				if opts.FastZip {
+					// Too weak a check to skip the file on later runs
+					validate, thorough = validateZipDirectory, false
+				}
+			}
+			result.attempts, result.err = opts.retryIO(ctx, func() error {
+				return validate(ctx, path)
+			})
+			if result.err == nil && thorough {
+				cache.record(setPath, path, info)
+			}
+		}
+
+		// Catalogs are parsed even when unchanged, for their entries
+		if i >= len(zipPaths) && result.err == nil {
+			attempts, err := opts.retryIO(ctx, func() error {
+				var err error
+				result.catalog, err = opts.CatalogCache.Get(ctx, path)
+				return err
+			})
+			result.attempts = max(result.attempts, attempts)
+			result.parseErr = err
+		}
+
+		// A read interrupted by the timeout says nothing about the file
+		if ctx.Err() != nil && (result.err != nil || result.parseErr != nil) {
+			return contentResult{}
+		}
+		return result
+	}
+
+	workers := min(max(opts.FileWorkers, 1), len(results))
+	work := make(chan int, len(results))
+	for i := range results {
+		work <- i
+	}
+	close(work)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range work {
+				if !opts.acquireRead(ctx) {
+					continue
+				}
+				results[idx] = read(idx)
+				opts.releaseRead()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func validateBackupAge(setInfo BackupSetInfo, opts ScanOptions) []ValidationIssue {
+	issues := []ValidationIssue{}
+
+	if setInfo.ModTime.IsZero() {
+		return issues
+	}
+
+	now := time.Now()
+	age := now.Sub(setInfo.ModTime)
+	minAge, maxAge := opts.AgeLimits()
+
+	// Check if backup is too new (might be in progress)
+	if age < minAge {
+		issues = append(issues, newIssue(IssueBackupTooRecent, SeverityInfo,
+			msg("backup is very recent (%v old)", age),
+			setInfo.Path,
+			"backup might still be in progress"))
+	}
+
+	// Check if backup is too old
+	if age > maxAge {
+		issues = append(issues, newIssue(IssueBackupTooOld, SeverityWarning,
+			msg("backup is quite old (%v)", age),
+			setInfo.Path,
+			"consider creating more recent backups"))
+	}
+
+	return issues
+}
+
+func validateZipFile(ctx context.Context, zipPath string) error {
+	r, zipFile, err := openZip(ctx, zipPath)
+	if err != nil {
+		return fmt.Errorf("cannot open zip: %w", err)
+	}
+	defer zipFile.Close()
+
+	if len(r.File) == 0 {
+		return fmt.Errorf("zip file is empty")
+	}
+
+	// Test reading first files to ensure not corrupted
+	testCount := minInt(len(r.File), 3)
+	for i := 0; i < testCount; i++ {
+		file := r.File[i]
+
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("cannot open file %s in zip: %w", file.Name, err)
+		}
+
+		// Try to read some data
+		buffer := make([]byte, minInt64(1024, int64(file.UncompressedSize64)))
+		_, err = io.ReadFull(rc, buffer)
+		rc.Close()
+
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("cannot read file %s in zip: %w", file.Name, err)
+		}
+	}
+
+	// Check for suspicious zip structure
+	if len(r.File) == 1 && r.File[0].UncompressedSize64 == 0 {
+		return fmt.Errorf("zip contains only empty file")
+	}
+
+	return nil
+}
+
+func validateCatalogFile(ctx context.Context, catPath string) error {
+	info, err := statBackup(catPath)
+	if err != nil {
+		return fmt.Errorf("cannot stat catalog file: %w", err)
+	}
+
+	if info.Size() == 0 {
+		return fmt.Errorf("catalog file is empty")
+	}
+
+	// Basic file readability check
+	file, err := openBackupFile(ctx, catPath)
+	if err != nil {
+		return fmt.Errorf("cannot open catalog file: %w", err)
+	}
+	defer file.Close()
+
+	// Read first bytes to ensure file is readable
+	buffer := make([]byte, minInt64(512, info.Size()))
+	_, err = file.Read(buffer)
+	if err != nil {
+		return fmt.Errorf("cannot read catalog file: %w", err)
+	}
+
+	return nil
+}
+
+func validateMediaID(ctx context.Context, mediaIDPath string) error {
+	info, err := statBackup(mediaIDPath)
+	if err != nil {
+		return fmt.Errorf("cannot stat MediaID.bin: %w", err)
+	}
+
+	if info.Size() == 0 {
+		return fmt.Errorf("MediaID.bin is empty")
+	}
+
+	// MediaID.bin should be a reasonable size
+	if info.Size() > 1024*1024 { // 1MB
+		return fmt.Errorf("MediaID.bin is unusually large (%d bytes)", info.Size())
+	}
+
+	// Test file readability
+	file, err := openBackupFile(ctx, mediaIDPath)
+	if err != nil {
+		return fmt.Errorf("cannot open MediaID.bin: %w", err)
+	}
+	defer file.Close()
+
+	buffer := make([]byte, minInt64(256, info.Size()))
+	_, err = file.Read(buffer)
+	if err != nil {
+		return fmt.Errorf("cannot read MediaID.bin: %w", err)
+	}
+
+	return nil
+}
+
+func countPassedChecks(issues []ValidationIssue, severities ...ValidationSeverity) int {
+	severitySet := make(map[ValidationSeverity]bool)
+	for _, s := range severities {
+		severitySet[s] = true
+	}
+
+	failedChecks := 0
+	for _, issue := range issues {
+		if severitySet[issue.Severity] {
+			failedChecks++
+		}
+	}
+
+	baselineChecks := 5 // catalog existence, backup files, file count, size, structure
+	return maxInt(0, baselineChecks-failedChecks)
+}
+
+// Helper functions
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// fileExists returns true if path exists and is a regular file
+func fileExists(path string) bool {
+	info, err := statBackup(path)
+	if err != nil {
+		return false
+	}
+	return !info.IsDir()
+}
+
+// dirExists returns true if path exists and is a directory
+func dirExists(path string) bool {
+	info, err := statBackup(path)
+	if err != nil {
+		return false
+	}
+	return info.IsDir()
+}
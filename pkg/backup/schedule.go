@@ -0,0 +1,28 @@
+package winbackupchecker
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultTaskName is the name the checker's scheduled task is registered as
+const DefaultTaskName = "Backup Checker"
+
+// ErrTaskSchedulerUnsupported is returned by the scheduled task functions on
+// systems without the Windows Task Scheduler
+var ErrTaskSchedulerUnsupported = errors.New("scheduled tasks are only supported on Windows; use a cron job or systemd timer instead")
+
+// ScheduledTask is a daily run of the checker in the Windows Task Scheduler
+type ScheduledTask struct {
+	Name       string
+	Command    string
+	Arguments  []string
+	WorkingDir string
+
+	// At is the time of day the task runs; the date is ignored
+	At time.Time
+
+	// System runs the task as SYSTEM whether or not anyone is logged on,
+	// instead of as the current user while logged on
+	System bool
+}
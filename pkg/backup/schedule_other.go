@@ -0,0 +1,13 @@
+//go:build !windows
+
+package winbackupchecker
+
+// InstallScheduledTask is only supported on Windows
+func InstallScheduledTask(task ScheduledTask) error {
+	return ErrTaskSchedulerUnsupported
+}
+
+// UninstallScheduledTask is only supported on Windows
+func UninstallScheduledTask(name string) error {
+	return ErrTaskSchedulerUnsupported
+}
@@ -0,0 +1,135 @@
+//go:build windows
+
+package winbackupchecker
+
+import (
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf16"
+)
+
+// systemSID is the well-known SID of the LocalSystem account
+const systemSID = "S-1-5-18"
+
+// taskDefinition is the subset of the Task Scheduler XML schema the checker
+// registers. schtasks options cannot set the working directory, so tasks are
+// imported from XML.
+type taskDefinition struct {
+	XMLName     xml.Name `xml:"Task"`
+	Version     string   `xml:"version,attr"`
+	Namespace   string   `xml:"xmlns,attr"`
+	Description string   `xml:"RegistrationInfo>Description"`
+	Trigger     struct {
+		StartBoundary string `xml:"StartBoundary"`
+		Enabled       bool   `xml:"Enabled"`
+		DaysInterval  int    `xml:"ScheduleByDay>DaysInterval"`
+	} `xml:"Triggers>CalendarTrigger"`
+	Principal struct {
+		ID        string `xml:"id,attr"`
+		UserID    string `xml:"UserId,omitempty"`
+		LogonType string `xml:"LogonType,omitempty"`
+		RunLevel  string `xml:"RunLevel"`
+	} `xml:"Principals>Principal"`
+	Settings struct {
+		MultipleInstancesPolicy    string `xml:"MultipleInstancesPolicy"`
+		DisallowStartIfOnBatteries bool   `xml:"DisallowStartIfOnBatteries"`
+		StopIfGoingOnBatteries     bool   `xml:"StopIfGoingOnBatteries"`
+		StartWhenAvailable         bool   `xml:"StartWhenAvailable"`
+		ExecutionTimeLimit         string `xml:"ExecutionTimeLimit"`
+		Enabled                    bool   `xml:"Enabled"`
+	} `xml:"Settings"`
+	Actions struct {
+		Context string `xml:"Context,attr"`
+		Exec    struct {
+			Command          string `xml:"Command"`
+			Arguments        string `xml:"Arguments,omitempty"`
+			WorkingDirectory string `xml:"WorkingDirectory,omitempty"`
+		} `xml:"Exec"`
+	} `xml:"Actions"`
+}
+
+// InstallScheduledTask registers task with the Task Scheduler, replacing a
+// task of the same name. A missed run, such as when the computer was off,
+// starts as soon as possible.
+func InstallScheduledTask(task ScheduledTask) error {
+	def := taskDefinition{
+		Version:     "1.2",
+		Namespace:   "http://schemas.microsoft.com/windows/2004/02/mit/task",
+		Description: "Validates Windows Backup sets and sends alerts about failed or missing backups.",
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), task.At.Hour(), task.At.Minute(), 0, 0, time.Local)
+	def.Trigger.StartBoundary = start.Format("2006-01-02T15:04:05")
+	def.Trigger.Enabled = true
+	def.Trigger.DaysInterval = 1
+
+	def.Principal.ID = "Author"
+	if task.System {
+		def.Principal.UserID = systemSID
+		def.Principal.RunLevel = "HighestAvailable"
+	} else {
+		def.Principal.LogonType = "InteractiveToken"
+		def.Principal.RunLevel = "LeastPrivilege"
+	}
+
+	def.Settings.MultipleInstancesPolicy = "IgnoreNew"
+	def.Settings.StartWhenAvailable = true
+	def.Settings.ExecutionTimeLimit = "PT4H"
+	def.Settings.Enabled = true
+
+	args := make([]string, len(task.Arguments))
+	for i, arg := range task.Arguments {
+		args[i] = syscall.EscapeArg(arg)
+	}
+	def.Actions.Context = "Author"
+	def.Actions.Exec.Command = task.Command
+	def.Actions.Exec.Arguments = strings.Join(args, " ")
+	def.Actions.Exec.WorkingDirectory = task.WorkingDir
+
+	data, err := xml.MarshalIndent(def, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to build task definition: %w", err)
+	}
+
+	// schtasks only reads task XML encoded as UTF-16
+	file, err := os.CreateTemp("", "backup-checker-task-*.xml")
+	if err != nil {
+		return fmt.Errorf("failed to write task definition: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	content := `<?xml version="1.0" encoding="UTF-16"?>` + "\r\n" + string(data)
+	encoded := append([]uint16{0xFEFF}, utf16.Encode([]rune(content))...)
+	err = binary.Write(file, binary.LittleEndian, encoded)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write task definition: %w", err)
+	}
+
+	return schtasks("/Create", "/TN", task.Name, "/XML", file.Name(), "/F")
+}
+
+// UninstallScheduledTask removes the task registered as name
+func UninstallScheduledTask(name string) error {
+	return schtasks("/Delete", "/TN", name, "/F")
+}
+
+func schtasks(args ...string) error {
+	output, err := exec.Command("schtasks", args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("schtasks failed: %s", msg)
+		}
+		return fmt.Errorf("schtasks failed: %w", err)
+	}
+	return nil
+}
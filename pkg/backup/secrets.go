@@ -0,0 +1,112 @@
+package winbackupchecker
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Secret reference schemes accepted in fields tagged secret:"true"
+const (
+	secretSchemeEnv  = "env:"
+	secretSchemeFile = "file:"
+)
+
+// resolveSecrets replaces secret references in fields tagged secret:"true":
+// "env:NAME" with the value of environment variable NAME, and "file:PATH"
+// with the contents of PATH without surrounding whitespace. Other values are
+// kept as written, so configs holding references can be committed safely.
+func resolveSecrets(v any) error {
+	return resolveSecretsIn(reflect.ValueOf(v).Elem(), "")
+}
+
+func resolveSecretsIn(s reflect.Value, path string) error {
+	t := s.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := s.Field(i)
+
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		name := path + key
+		if field.Anonymous {
+			name = strings.TrimSuffix(path, ".")
+		}
+
+		if field.Tag.Get("secret") == "true" {
+			if err := resolveSecretValue(value, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch {
+		case value.Kind() == reflect.Struct:
+			if err := resolveSecretsIn(value, name+"."); err != nil {
+				return err
+			}
+		case value.Kind() == reflect.Pointer && !value.IsNil() && value.Elem().Kind() == reflect.Struct:
+			if err := resolveSecretsIn(value.Elem(), name+"."); err != nil {
+				return err
+			}
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct:
+			for j := 0; j < value.Len(); j++ {
+				if err := resolveSecretsIn(value.Index(j), fmt.Sprintf("%s[%d].", name, j)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resolveSecretValue resolves a secret string, or each value of a map of
+// strings such as webhook headers
+func resolveSecretValue(value reflect.Value, name string) error {
+	switch value.Kind() {
+	case reflect.String:
+		resolved, err := resolveSecret(value.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		value.SetString(resolved)
+	case reflect.Map:
+		if value.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		iter := value.MapRange()
+		for iter.Next() {
+			resolved, err := resolveSecret(iter.Value().String())
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", name, iter.Key(), err)
+			}
+			value.SetMapIndex(iter.Key(), reflect.ValueOf(resolved))
+		}
+	}
+	return nil
+}
+
+func resolveSecret(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, secretSchemeEnv):
+		name := strings.TrimPrefix(s, secretSchemeEnv)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+
+	case strings.HasPrefix(s, secretSchemeFile):
+		data, err := os.ReadFile(strings.TrimPrefix(s, secretSchemeFile))
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+
+	default:
+		return s, nil
+	}
+}
@@ -0,0 +1,14 @@
+package winbackupchecker
+
+import "errors"
+
+// Windows service registration
+const (
+	ServiceName        = "WinBackupChecker"
+	serviceDisplayName = "Windows Backup Checker"
+	serviceDescription = "Periodically validates Windows Backup sets and sends alerts about failed or missing backups."
+)
+
+// ErrServiceUnsupported is returned by the service functions on systems
+// without the Windows service control manager
+var ErrServiceUnsupported = errors.New("services are only supported on Windows; use a cron job or systemd timer instead")
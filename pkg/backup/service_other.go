@@ -0,0 +1,20 @@
+//go:build !windows
+
+package winbackupchecker
+
+import "context"
+
+// InstallService is only supported on Windows
+func InstallService(exe string, args []string) error {
+	return ErrServiceUnsupported
+}
+
+// UninstallService is only supported on Windows
+func UninstallService() error {
+	return ErrServiceUnsupported
+}
+
+// RunService is only supported on Windows
+func RunService(run func(ctx context.Context) error) error {
+	return ErrServiceUnsupported
+}
@@ -0,0 +1,344 @@
+//go:build windows
+
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Service control manager constants from winsvc.h
+const (
+	scManagerAllAccess    = 0xF003F
+	serviceAllAccess      = 0xF01FF
+	serviceWin32OwnProc   = 0x10
+	serviceAutoStart      = 2
+	serviceErrorNormal    = 1
+	serviceNoChange       = 0xFFFFFFFF
+	serviceControlStop    = 1
+	serviceControlQuery   = 4
+	serviceControlOff     = 5
+	serviceAcceptStop     = 1
+	serviceAcceptShutdown = 4
+
+	serviceStopped     = 1
+	serviceStopPending = 3
+	serviceRunning     = 4
+
+	serviceConfigDescription    = 1
+	serviceConfigFailureActions = 2
+	serviceConfigFailureFlag    = 4
+	scActionRestart             = 1
+
+	errorCallNotImplemented    syscall.Errno = 120
+	errorServiceAlreadyRunning syscall.Errno = 1056
+	errorServiceDoesNotExist   syscall.Errno = 1060
+	errorServiceNotActive      syscall.Errno = 1062
+	errorServiceNoController   syscall.Errno = 1063
+	errorServiceSpecific       syscall.Errno = 1066
+	errorServiceExists         syscall.Errno = 1073
+)
+
+var (
+	procOpenSCManagerW                = advapi32.NewProc("OpenSCManagerW")
+	procCreateServiceW                = advapi32.NewProc("CreateServiceW")
+	procOpenServiceW                  = advapi32.NewProc("OpenServiceW")
+	procChangeServiceConfigW          = advapi32.NewProc("ChangeServiceConfigW")
+	procChangeServiceConfig2W         = advapi32.NewProc("ChangeServiceConfig2W")
+	procStartServiceW                 = advapi32.NewProc("StartServiceW")
+	procControlService                = advapi32.NewProc("ControlService")
+	procQueryServiceStatus            = advapi32.NewProc("QueryServiceStatus")
+	procDeleteService                 = advapi32.NewProc("DeleteService")
+	procCloseServiceHandle            = advapi32.NewProc("CloseServiceHandle")
+	procStartServiceCtrlDispatcherW   = advapi32.NewProc("StartServiceCtrlDispatcherW")
+	procRegisterServiceCtrlHandlerExW = advapi32.NewProc("RegisterServiceCtrlHandlerExW")
+	procSetServiceStatus              = advapi32.NewProc("SetServiceStatus")
+)
+
+// serviceStatus mirrors SERVICE_STATUS
+type serviceStatus struct {
+	ServiceType             uint32
+	CurrentState            uint32
+	ControlsAccepted        uint32
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+	CheckPoint              uint32
+	WaitHint                uint32
+}
+
+// serviceTableEntry mirrors SERVICE_TABLE_ENTRYW
+type serviceTableEntry struct {
+	ServiceName *uint16
+	ServiceProc uintptr
+}
+
+// serviceFailureActions mirrors SERVICE_FAILURE_ACTIONSW
+type serviceFailureActions struct {
+	ResetPeriod  uint32
+	RebootMsg    *uint16
+	Command      *uint16
+	ActionsCount uint32
+	Actions      *scAction
+}
+
+// scAction mirrors SC_ACTION
+type scAction struct {
+	Type  uint32
+	Delay uint32
+}
+
+// serviceRecovery restarts a failed service after 1, 1 and 5 minutes,
+// counting failures over a day
+var serviceRecovery = []scAction{
+	{Type: scActionRestart, Delay: 60 * 1000},
+	{Type: scActionRestart, Delay: 60 * 1000},
+	{Type: scActionRestart, Delay: 5 * 60 * 1000},
+}
+
+// InstallService registers exe, started with args, as a service that starts
+// with Windows and is restarted by the service control manager when it
+// fails. An installed service is reconfigured and restarted. The service
+// runs as LocalSystem.
+func InstallService(exe string, args []string) error {
+	scm, err := openSCManager()
+	if err != nil {
+		return err
+	}
+	defer procCloseServiceHandle.Call(scm)
+
+	commandLine := syscall.EscapeArg(exe)
+	for _, arg := range args {
+		commandLine += " " + syscall.EscapeArg(arg)
+	}
+
+	name, _ := syscall.UTF16PtrFromString(ServiceName)
+	displayName, _ := syscall.UTF16PtrFromString(serviceDisplayName)
+	binaryPath, err := syscall.UTF16PtrFromString(commandLine)
+	if err != nil {
+		return err
+	}
+
+	service, _, err := procCreateServiceW.Call(scm, uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(displayName)),
+		serviceAllAccess, serviceWin32OwnProc, serviceAutoStart, serviceErrorNormal,
+		uintptr(unsafe.Pointer(binaryPath)), 0, 0, 0, 0, 0)
+	if service == 0 {
+		if err != errorServiceExists {
+			return fmt.Errorf("failed to create service: %w", err)
+		}
+		if service, err = openService(scm); err != nil {
+			return err
+		}
+		ret, _, err := procChangeServiceConfigW.Call(service, serviceNoChange, serviceAutoStart, serviceNoChange,
+			uintptr(unsafe.Pointer(binaryPath)), 0, 0, 0, 0, 0, uintptr(unsafe.Pointer(displayName)))
+		if ret == 0 {
+			procCloseServiceHandle.Call(service)
+			return fmt.Errorf("failed to update service: %w", err)
+		}
+		if err := stopService(service); err != nil {
+			procCloseServiceHandle.Call(service)
+			return err
+		}
+	}
+	defer procCloseServiceHandle.Call(service)
+
+	if err := configureService(service); err != nil {
+		return err
+	}
+
+	if ret, _, err := procStartServiceW.Call(service, 0, 0); ret == 0 && err != errorServiceAlreadyRunning {
+		return fmt.Errorf("service installed but failed to start: %w", err)
+	}
+	return nil
+}
+
+// configureService sets the description and recovery options
+func configureService(service uintptr) error {
+	description, _ := syscall.UTF16PtrFromString(serviceDescription)
+	if ret, _, err := procChangeServiceConfig2W.Call(service, serviceConfigDescription,
+		uintptr(unsafe.Pointer(&description))); ret == 0 {
+		return fmt.Errorf("failed to set service description: %w", err)
+	}
+
+	actions := serviceFailureActions{
+		ResetPeriod:  uint32((24 * time.Hour).Seconds()),
+		ActionsCount: uint32(len(serviceRecovery)),
+		Actions:      &serviceRecovery[0],
+	}
+	if ret, _, err := procChangeServiceConfig2W.Call(service, serviceConfigFailureActions,
+		uintptr(unsafe.Pointer(&actions))); ret == 0 {
+		return fmt.Errorf("failed to set service recovery options: %w", err)
+	}
+
+	// Also recover when the service stops with an error rather than crashing
+	onError := int32(1)
+	if ret, _, err := procChangeServiceConfig2W.Call(service, serviceConfigFailureFlag,
+		uintptr(unsafe.Pointer(&onError))); ret == 0 {
+		return fmt.Errorf("failed to set service recovery options: %w", err)
+	}
+	return nil
+}
+
+// UninstallService stops and removes the service
+func UninstallService() error {
+	scm, err := openSCManager()
+	if err != nil {
+		return err
+	}
+	defer procCloseServiceHandle.Call(scm)
+
+	service, err := openService(scm)
+	if err != nil {
+		return err
+	}
+	defer procCloseServiceHandle.Call(service)
+
+	if err := stopService(service); err != nil {
+		return err
+	}
+	if ret, _, err := procDeleteService.Call(service); ret == 0 {
+		return fmt.Errorf("failed to delete service: %w", err)
+	}
+	return nil
+}
+
+func openSCManager() (uintptr, error) {
+	scm, _, err := procOpenSCManagerW.Call(0, 0, scManagerAllAccess)
+	if scm == 0 {
+		return 0, fmt.Errorf("failed to open service control manager (run from an elevated prompt): %w", err)
+	}
+	return scm, nil
+}
+
+func openService(scm uintptr) (uintptr, error) {
+	name, _ := syscall.UTF16PtrFromString(ServiceName)
+	service, _, err := procOpenServiceW.Call(scm, uintptr(unsafe.Pointer(name)), serviceAllAccess)
+	if service == 0 {
+		if err == errorServiceDoesNotExist {
+			return 0, fmt.Errorf("service %s is not installed", ServiceName)
+		}
+		return 0, fmt.Errorf("failed to open service: %w", err)
+	}
+	return service, nil
+}
+
+// stopService stops a running service and waits up to a minute for it to
+// finish the scan in progress
+func stopService(service uintptr) error {
+	var status serviceStatus
+	if ret, _, err := procControlService.Call(service, serviceControlStop, uintptr(unsafe.Pointer(&status))); ret == 0 {
+		if err == errorServiceNotActive {
+			return nil
+		}
+		return fmt.Errorf("failed to stop service: %w", err)
+	}
+
+	deadline := time.Now().Add(time.Minute)
+	for status.CurrentState != serviceStopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service did not stop within a minute")
+		}
+		time.Sleep(500 * time.Millisecond)
+		if ret, _, err := procQueryServiceStatus.Call(service, uintptr(unsafe.Pointer(&status))); ret == 0 {
+			return fmt.Errorf("failed to query service status: %w", err)
+		}
+	}
+	return nil
+}
+
+// runningService is the state of the service run by RunService. The service
+// control manager calls back on threads of its own, so it is shared through
+// a package variable rather than closures.
+var runningService struct {
+	mu     sync.Mutex
+	run    func(ctx context.Context) error
+	cancel context.CancelFunc
+	handle uintptr
+	err    error
+}
+
+// RunService runs run under the service control manager, cancelling its
+// context when the service is stopped or Windows shuts down. A non-nil error
+// from run stops the service as failed, which triggers the recovery options.
+func RunService(run func(ctx context.Context) error) error {
+	runningService.run = run
+
+	name, _ := syscall.UTF16PtrFromString(ServiceName)
+	table := []serviceTableEntry{
+		{ServiceName: name, ServiceProc: syscall.NewCallback(serviceMain)},
+		{},
+	}
+	if ret, _, err := procStartServiceCtrlDispatcherW.Call(uintptr(unsafe.Pointer(&table[0]))); ret == 0 {
+		if err == errorServiceNoController {
+			return fmt.Errorf("service run is started by the service control manager; use service install instead")
+		}
+		return fmt.Errorf("failed to start service dispatcher: %w", err)
+	}
+	return runningService.err
+}
+
+func serviceMain(argc, argv uintptr) uintptr {
+	name, _ := syscall.UTF16PtrFromString(ServiceName)
+	handle, _, _ := procRegisterServiceCtrlHandlerExW.Call(uintptr(unsafe.Pointer(name)),
+		syscall.NewCallback(serviceControl), 0)
+	if handle == 0 {
+		return 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runningService.mu.Lock()
+	runningService.handle = handle
+	runningService.cancel = cancel
+	runningService.mu.Unlock()
+
+	setServiceStatus(serviceRunning, 0)
+	err := runningService.run(ctx)
+	cancel()
+
+	runningService.err = err
+	if err != nil {
+		setServiceStatus(serviceStopped, errorServiceSpecific)
+	} else {
+		setServiceStatus(serviceStopped, 0)
+	}
+	return 0
+}
+
+func serviceControl(control, eventType, eventData, userContext uintptr) uintptr {
+	switch control {
+	case serviceControlStop, serviceControlOff:
+		setServiceStatus(serviceStopPending, 0)
+		runningService.mu.Lock()
+		runningService.cancel()
+		runningService.mu.Unlock()
+		return 0
+	case serviceControlQuery:
+		return 0
+	default:
+		return uintptr(errorCallNotImplemented)
+	}
+}
+
+func setServiceStatus(state uint32, exitCode syscall.Errno) {
+	runningService.mu.Lock()
+	defer runningService.mu.Unlock()
+
+	status := serviceStatus{
+		ServiceType:   serviceWin32OwnProc,
+		CurrentState:  state,
+		Win32ExitCode: uint32(exitCode),
+	}
+	switch state {
+	case serviceRunning:
+		status.ControlsAccepted = serviceAcceptStop | serviceAcceptShutdown
+	case serviceStopPending:
+		status.WaitHint = uint32(time.Minute.Milliseconds())
+	}
+	if exitCode == errorServiceSpecific {
+		status.ServiceSpecificExitCode = 1
+	}
+	procSetServiceStatus.Call(runningService.handle, uintptr(unsafe.Pointer(&status)))
+}
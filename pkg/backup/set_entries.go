@@ -0,0 +1,167 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// collectZipEntries returns the uncompressed size of every file stored in a
+// backup set's zips, keyed by its normalized path. Only central directories
+// are read, so this is cheap even for large sets. Unreadable zips are
+// skipped; content validation reports them.
+func collectZipEntries(setInfo BackupSetInfo) map[string]int64 {
+	entries := make(map[string]int64)
+
+	for _, zipPath := range setInfo.BackupFiles {
+		r, zipFile, err := openZip(context.Background(), zipPath)
+		if err != nil {
+			continue
+		}
+		for _, file := range r.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			entries[normalizeEntryPath(file.Name)] += int64(file.UncompressedSize64)
+		}
+		zipFile.Close()
+	}
+
+	return entries
+}
+
+// normalizeEntryPath converts catalog and zip paths to a common form using
+// forward slashes without drive colons, so "C:\Users" and "C/Users" match
+func normalizeEntryPath(p string) string {
+	p = strings.ReplaceAll(p, `\`, "/")
+	if len(p) >= 2 && p[1] == ':' {
+		p = p[:1] + p[2:]
+	}
+	return strings.TrimPrefix(p, "/")
+}
+
+// collectSetEntries returns every file known to be in a backup set, from both
+// its zips and its catalogs. Sizes come from the zips; files only listed in a
+// catalog have size 0.
+func collectSetEntries(setInfo BackupSetInfo, cache *CatalogCache) map[string]int64 {
+	entries := collectZipEntries(setInfo)
+
+	// Zips may store paths without the drive letter the catalog records
+	driveless := make(map[string]bool, len(entries))
+	for entry := range entries {
+		driveless[stripDriveSegment(entry)] = true
+	}
+
+	for _, catPath := range setInfo.CatalogFiles {
+		catalog, err := cache.Get(context.Background(), catPath)
+		if err != nil {
+			continue
+		}
+		for _, entry := range catalog.Entries {
+			if strings.HasSuffix(entry, `\`) {
+				continue
+			}
+			key := normalizeEntryPath(entry)
+			if _, ok := entries[key]; ok || driveless[stripDriveSegment(key)] {
+				continue
+			}
+			entries[key] = 0
+		}
+	}
+
+	return entries
+}
+
+// newestSetsByMachine returns, per machine in discovery order, the indexes of
+// up to n of its newest backup sets. backupSets must be sorted newest first.
+func newestSetsByMachine(backupSets []BackupSetInfo, n int) ([]string, map[string][]int) {
+	byMachine := make(map[string][]int)
+	var machines []string
+	for i, set := range backupSets {
+		machine := MachineName(set.Path)
+		if _, ok := byMachine[machine]; !ok {
+			machines = append(machines, machine)
+		}
+		if len(byMachine[machine]) < n {
+			byMachine[machine] = append(byMachine[machine], i)
+		}
+	}
+	return machines, byMachine
+}
+
+// matchEntryPattern reports whether entry lies at or below a path matching
+// pattern. Each pattern segment is a glob matched case-insensitively against
+// one path segment. Patterns without a drive letter match on any drive.
+func matchEntryPattern(pattern, entry string) bool {
+	_, ok := matchEntryPrefix(pattern, entry)
+	return ok
+}
+
+// matchEntryPrefix is like matchEntryPattern but also returns the part of
+// entry that matched. Single-segment patterns such as "node_modules" or
+// "*.pst" match a file or folder name at any depth.
+func matchEntryPrefix(pattern, entry string) (string, bool) {
+	patternParts := strings.Split(strings.ToLower(normalizeEntryPath(pattern)), "/")
+	entryParts := strings.Split(entry, "/")
+	lowerParts := strings.Split(strings.ToLower(entry), "/")
+
+	if len(patternParts) == 1 && !hasDriveSegment(pattern) {
+		for i, part := range lowerParts {
+			if ok, _ := path.Match(patternParts[0], part); ok {
+				return strings.Join(entryParts[:i+1], "/"), true
+			}
+		}
+		return "", false
+	}
+
+	if matchSegments(patternParts, lowerParts) {
+		return strings.Join(entryParts[:len(patternParts)], "/"), true
+	}
+
+	// Retry without the entry's drive segment for drive-less patterns
+	if !hasDriveSegment(pattern) && len(lowerParts) > 1 && len(lowerParts[0]) == 1 {
+		if matchSegments(patternParts, lowerParts[1:]) {
+			return strings.Join(entryParts[:len(patternParts)+1], "/"), true
+		}
+	}
+	return "", false
+}
+
+func matchSegments(pattern, parts []string) bool {
+	if len(pattern) > len(parts) {
+		return false
+	}
+	for i, p := range pattern {
+		if ok, _ := path.Match(p, parts[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// validateEntryPattern checks that every segment of a path pattern is a
+// valid glob
+func validateEntryPattern(pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("pattern is empty")
+	}
+	for _, segment := range strings.Split(normalizeEntryPath(pattern), "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripDriveSegment removes a leading single-letter drive segment
+func stripDriveSegment(entry string) string {
+	if len(entry) > 2 && entry[1] == '/' {
+		return entry[2:]
+	}
+	return entry
+}
+
+func hasDriveSegment(p string) bool {
+	return len(p) >= 2 && p[1] == ':'
+}
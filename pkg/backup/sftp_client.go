@@ -0,0 +1,565 @@
+package winbackupchecker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSHOptions set how ssh reaches the server of an sftp:// backup path. The
+// server is otherwise reached as ssh would from a shell: with the keys of
+// the ssh agent and ~/.ssh, and host keys checked against
+// ~/.ssh/known_hosts.
+type SSHOptions struct {
+	// IdentityFile is the private key to sign in with
+	IdentityFile string `json:"identity_file,omitempty"`
+
+	// KnownHostsFile holds the host keys the server is checked against
+	KnownHostsFile string `json:"known_hosts_file,omitempty"`
+}
+
+// SFTP version 3 packet types
+const (
+	sftpInit     = 1
+	sftpVersion  = 2
+	sftpOpen     = 3
+	sftpClose    = 4
+	sftpRead     = 5
+	sftpFstat    = 8
+	sftpOpendir  = 11
+	sftpReaddir  = 12
+	sftpStat     = 17
+	sftpStatus   = 101
+	sftpHandle   = 102
+	sftpData     = 103
+	sftpName     = 104
+	sftpAttrs    = 105
+	sftpOpenRead = 0x00000001
+)
+
+// SFTP status codes the client tells apart
+const (
+	sftpStatusEOF              = 1
+	sftpStatusNoSuchFile       = 2
+	sftpStatusPermissionDenied = 3
+)
+
+// SFTP file attribute flags
+const (
+	sftpAttrSize        = 0x00000001
+	sftpAttrUIDGID      = 0x00000002
+	sftpAttrPermissions = 0x00000004
+	sftpAttrACModTime   = 0x00000008
+	sftpAttrExtended    = 0x80000000
+)
+
+// sftpChunkSize is the most asked for in one read, which every server
+// allows, and sftpReadAhead what a file reads at once, in as many chunks
+// requested together
+const (
+	sftpChunkSize = 32 << 10
+	sftpReadAhead = 512 << 10
+)
+
+// sftpDialTimeout bounds starting ssh and signing in
+const sftpDialTimeout = time.Minute
+
+// sftpClient speaks SFTP version 3 with the sftp subsystem of a server,
+// through the ssh command so its keys, agent, known hosts and config apply
+// as they do in a shell. It sends the requests of one call at a time.
+type sftpClient struct {
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	in     io.WriteCloser
+	out    *bufio.Reader
+	stderr *tailWriter
+	exited chan struct{}
+	nextID uint32
+}
+
+// sftpError is an SFTP request failing with a status
+type sftpError struct {
+	code    uint32
+	message string
+}
+
+func (e *sftpError) Error() string {
+	if e.message != "" {
+		return e.message
+	}
+	return fmt.Sprintf("SFTP request failed with status %d", e.code)
+}
+
+// Is lets errors.Is match the fs errors the status stands for
+func (e *sftpError) Is(target error) bool {
+	switch e.code {
+	case sftpStatusNoSuchFile:
+		return target == fs.ErrNotExist
+	case sftpStatusPermissionDenied:
+		return target == fs.ErrPermission
+	}
+	return false
+}
+
+// dialSFTP starts the sftp subsystem on server, given as
+// sftp://[user@]host[:port], with opts
+func dialSFTP(server string, opts *SSHOptions) (*sftpClient, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=30",
+		"-o", "ServerAliveInterval=15", "-o", "ServerAliveCountMax=4"}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	if user := u.User.Username(); user != "" {
+		args = append(args, "-l", user)
+	}
+	if opts != nil && opts.IdentityFile != "" {
+		args = append(args, "-i", opts.IdentityFile, "-o", "IdentitiesOnly=yes")
+	}
+	if opts != nil && opts.KnownHostsFile != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+opts.KnownHostsFile)
+	}
+	args = append(args, "-s", u.Hostname(), "sftp")
+
+	c := &sftpClient{cmd: exec.Command("ssh", args...), stderr: &tailWriter{}, exited: make(chan struct{})}
+	c.cmd.Stderr = c.stderr
+	if c.in, err = c.cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	out, err := c.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	c.out = bufio.NewReaderSize(out, 64<<10)
+	if err := c.cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("ssh not found; install the OpenSSH client")
+		}
+		return nil, err
+	}
+	go func() {
+		c.cmd.Wait()
+		close(c.exited)
+	}()
+
+	// ssh gives up on unreachable servers by itself, but not on ones that
+	// accept the connection and never answer
+	timer := time.AfterFunc(sftpDialTimeout, func() { c.cmd.Process.Kill() })
+	err = c.handshake()
+	timer.Stop()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *sftpClient) handshake() error {
+	if err := c.send(sftpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+		return c.failed(err)
+	}
+	typ, _, err := c.receive()
+	if err != nil {
+		return err
+	}
+	if typ != sftpVersion {
+		return fmt.Errorf("unexpected SFTP packet %d instead of the version", typ)
+	}
+	return nil
+}
+
+// Close ends the session, stopping ssh if it does not exit by itself
+func (c *sftpClient) Close() error {
+	c.in.Close()
+	select {
+	case <-c.exited:
+	case <-time.After(5 * time.Second):
+		c.cmd.Process.Kill()
+		<-c.exited
+	}
+	return nil
+}
+
+// send writes one packet
+func (c *sftpClient) send(typ byte, payload []byte) error {
+	packet := make([]byte, 5, 5+len(payload))
+	binary.BigEndian.PutUint32(packet, uint32(1+len(payload)))
+	packet[4] = typ
+	_, err := c.in.Write(append(packet, payload...))
+	return err
+}
+
+// receive reads one packet
+func (c *sftpClient) receive() (typ byte, payload []byte, err error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.out, header[:]); err != nil {
+		return 0, nil, c.failed(err)
+	}
+	n := binary.BigEndian.Uint32(header[:])
+	if n < 1 || n > 1<<24 {
+		return 0, nil, fmt.Errorf("invalid SFTP packet length %d", n)
+	}
+	payload = make([]byte, n-1)
+	if _, err := io.ReadFull(c.out, payload); err != nil {
+		return 0, nil, c.failed(err)
+	}
+	return header[4], payload, nil
+}
+
+// failed explains a broken connection with what ssh last wrote, such as a
+// refused key or an unknown host key
+func (c *sftpClient) failed(err error) error {
+	// What ssh wrote is only complete once it exited
+	select {
+	case <-c.exited:
+	case <-time.After(2 * time.Second):
+	}
+	if msg := c.stderr.lastLine(); msg != "" {
+		return fmt.Errorf("ssh: %s", msg)
+	}
+	if err == io.EOF {
+		return errors.New("ssh exited")
+	}
+	return err
+}
+
+// request sends a request with a new id followed by fields, and returns the
+// response to it
+func (c *sftpClient) request(typ byte, fields []byte) (byte, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	if err := c.send(typ, append(binary.BigEndian.AppendUint32(nil, c.nextID), fields...)); err != nil {
+		return 0, nil, c.failed(err)
+	}
+	for {
+		respType, resp, err := c.receive()
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(resp) < 4 {
+			return 0, nil, errors.New("SFTP response too short")
+		}
+		if binary.BigEndian.Uint32(resp) == c.nextID {
+			return respType, resp[4:], nil
+		}
+	}
+}
+
+// handleRequest sends a request answered with a handle
+func (c *sftpClient) handleRequest(typ byte, fields []byte) (string, error) {
+	respType, resp, err := c.request(typ, fields)
+	if err != nil {
+		return "", err
+	}
+	if respType != sftpHandle {
+		return "", sftpStatusError(respType, resp)
+	}
+	handle, _, err := sftpString(resp)
+	return string(handle), err
+}
+
+// attrsRequest sends a request answered with file attributes
+func (c *sftpClient) attrsRequest(typ byte, fields []byte) (*sftpFileInfo, error) {
+	respType, resp, err := c.request(typ, fields)
+	if err != nil {
+		return nil, err
+	}
+	if respType != sftpAttrs {
+		return nil, sftpStatusError(respType, resp)
+	}
+	info, _, err := parseSFTPAttrs(resp)
+	return info, err
+}
+
+func (c *sftpClient) stat(name string) (*sftpFileInfo, error) {
+	return c.attrsRequest(sftpStat, sftpAppendString(nil, name))
+}
+
+func (c *sftpClient) open(name string) (string, error) {
+	fields := sftpAppendString(nil, name)
+	fields = binary.BigEndian.AppendUint32(fields, sftpOpenRead)
+	fields = binary.BigEndian.AppendUint32(fields, 0)
+	return c.handleRequest(sftpOpen, fields)
+}
+
+func (c *sftpClient) fstat(handle string) (*sftpFileInfo, error) {
+	return c.attrsRequest(sftpFstat, sftpAppendString(nil, handle))
+}
+
+func (c *sftpClient) opendir(name string) (string, error) {
+	return c.handleRequest(sftpOpendir, sftpAppendString(nil, name))
+}
+
+func (c *sftpClient) closeHandle(handle string) error {
+	respType, resp, err := c.request(sftpClose, sftpAppendString(nil, handle))
+	if err != nil {
+		return err
+	}
+	return sftpStatusError(respType, resp)
+}
+
+// readdir returns the next entries of the open folder handle, or io.EOF
+// once all were returned
+func (c *sftpClient) readdir(handle string) ([]*sftpFileInfo, error) {
+	respType, resp, err := c.request(sftpReaddir, sftpAppendString(nil, handle))
+	if err != nil {
+		return nil, err
+	}
+	if respType != sftpName {
+		return nil, sftpStatusError(respType, resp)
+	}
+	if len(resp) < 4 {
+		return nil, errors.New("SFTP name response too short")
+	}
+	count := binary.BigEndian.Uint32(resp)
+	rest := resp[4:]
+	var entries []*sftpFileInfo
+	for i := uint32(0); i < count; i++ {
+		name, r, err := sftpString(rest)
+		if err != nil {
+			return nil, err
+		}
+		// The long name, as ls -l would print it
+		_, r, err = sftpString(r)
+		if err != nil {
+			return nil, err
+		}
+		info, r, err := parseSFTPAttrs(r)
+		if err != nil {
+			return nil, err
+		}
+		rest = r
+		if info.name = string(name); info.name != "." && info.name != ".." {
+			entries = append(entries, info)
+		}
+	}
+	return entries, nil
+}
+
+// read reads up to n bytes from off, asking for them in chunks sent
+// together so a slow link is not waited on for each. It returns io.EOF at
+// the end of the file.
+func (c *sftpClient) read(handle string, off int64, n int) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chunks := (n + sftpChunkSize - 1) / sftpChunkSize
+	first := c.nextID + 1
+	for i := 0; i < chunks; i++ {
+		c.nextID++
+		fields := binary.BigEndian.AppendUint32(nil, c.nextID)
+		fields = sftpAppendString(fields, handle)
+		fields = binary.BigEndian.AppendUint64(fields, uint64(off)+uint64(i*sftpChunkSize))
+		fields = binary.BigEndian.AppendUint32(fields, uint32(min(sftpChunkSize, n-i*sftpChunkSize)))
+		if err := c.send(sftpRead, fields); err != nil {
+			return nil, c.failed(err)
+		}
+	}
+
+	// Servers may answer out of order, and every answer is read before
+	// returning so none is left for the next request
+	data := make([][]byte, chunks)
+	var failure error
+	eof := chunks
+	for received := 0; received < chunks; {
+		respType, resp, err := c.receive()
+		if err != nil {
+			return nil, err
+		}
+		if len(resp) < 4 {
+			return nil, errors.New("SFTP response too short")
+		}
+		i := int(binary.BigEndian.Uint32(resp) - first)
+		if i < 0 || i >= chunks {
+			continue
+		}
+		received++
+		switch respType {
+		case sftpData:
+			chunk, _, err := sftpString(resp[4:])
+			if err != nil && failure == nil {
+				failure = err
+			}
+			data[i] = chunk
+		default:
+			err := sftpStatusError(respType, resp[4:])
+			var status *sftpError
+			if errors.As(err, &status) && status.code == sftpStatusEOF {
+				eof = min(eof, i)
+			} else if failure == nil {
+				failure = err
+			}
+		}
+	}
+	if failure != nil {
+		return nil, failure
+	}
+
+	// Only the bytes up to the first short chunk follow on from off
+	var buf []byte
+	for i := 0; i < eof; i++ {
+		buf = append(buf, data[i]...)
+		if len(data[i]) < min(sftpChunkSize, n-i*sftpChunkSize) {
+			break
+		}
+	}
+	if len(buf) == 0 {
+		return nil, io.EOF
+	}
+	return buf, nil
+}
+
+// sftpStatusError returns the error of a status response, nil when it is a
+// success, or an error for a response of another type
+func sftpStatusError(respType byte, resp []byte) error {
+	if respType != sftpStatus {
+		return fmt.Errorf("unexpected SFTP response %d", respType)
+	}
+	if len(resp) < 4 {
+		return errors.New("SFTP status too short")
+	}
+	code := binary.BigEndian.Uint32(resp)
+	if code == 0 {
+		return nil
+	}
+	message, _, _ := sftpString(resp[4:])
+	return &sftpError{code: code, message: string(message)}
+}
+
+// sftpFileInfo is the metadata of a file on an SFTP server
+type sftpFileInfo struct {
+	name    string
+	size    int64
+	mode    uint32
+	modTime time.Time
+}
+
+func (i *sftpFileInfo) Name() string       { return i.name }
+func (i *sftpFileInfo) Size() int64        { return i.size }
+func (i *sftpFileInfo) ModTime() time.Time { return i.modTime }
+func (i *sftpFileInfo) IsDir() bool        { return i.Mode().IsDir() }
+func (i *sftpFileInfo) Sys() any           { return nil }
+
+// Mode returns the file type and permissions from the POSIX mode bits
+func (i *sftpFileInfo) Mode() fs.FileMode {
+	mode := fs.FileMode(i.mode & 0777)
+	switch i.mode & 0170000 {
+	case 0040000:
+		mode |= fs.ModeDir
+	case 0120000:
+		mode |= fs.ModeSymlink
+	case 0100000, 0:
+	default:
+		mode |= fs.ModeIrregular
+	}
+	return mode
+}
+
+func (i *sftpFileInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i *sftpFileInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// parseSFTPAttrs reads the file attributes at the start of b
+func parseSFTPAttrs(b []byte) (*sftpFileInfo, []byte, error) {
+	short := errors.New("SFTP attributes too short")
+	if len(b) < 4 {
+		return nil, nil, short
+	}
+	flags := binary.BigEndian.Uint32(b)
+	b = b[4:]
+	info := &sftpFileInfo{}
+	if flags&sftpAttrSize != 0 {
+		if len(b) < 8 {
+			return nil, nil, short
+		}
+		info.size = int64(binary.BigEndian.Uint64(b))
+		b = b[8:]
+	}
+	if flags&sftpAttrUIDGID != 0 {
+		if len(b) < 8 {
+			return nil, nil, short
+		}
+		b = b[8:]
+	}
+	if flags&sftpAttrPermissions != 0 {
+		if len(b) < 4 {
+			return nil, nil, short
+		}
+		info.mode = binary.BigEndian.Uint32(b)
+		b = b[4:]
+	}
+	if flags&sftpAttrACModTime != 0 {
+		if len(b) < 8 {
+			return nil, nil, short
+		}
+		info.modTime = time.Unix(int64(binary.BigEndian.Uint32(b[4:])), 0)
+		b = b[8:]
+	}
+	if flags&sftpAttrExtended != 0 {
+		if len(b) < 4 {
+			return nil, nil, short
+		}
+		count := binary.BigEndian.Uint32(b)
+		b = b[4:]
+		for i := uint32(0); i < 2*count; i++ {
+			var err error
+			if _, b, err = sftpString(b); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return info, b, nil
+}
+
+// sftpString reads the length-prefixed string at the start of b
+func sftpString(b []byte) (s, rest []byte, err error) {
+	if len(b) < 4 {
+		return nil, nil, errors.New("SFTP string too short")
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint64(n) > uint64(len(b)-4) {
+		return nil, nil, errors.New("SFTP string out of range")
+	}
+	return b[4 : 4+n], b[4+n:], nil
+}
+
+func sftpAppendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// tailWriter keeps the end of what a command writes to stderr, to explain
+// why it failed
+type tailWriter struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	if len(w.buf) > 4096 {
+		w.buf = w.buf[len(w.buf)-4096:]
+	}
+	return len(p), nil
+}
+
+// lastLine returns the last line written that is not blank
+func (w *tailWriter) lastLine() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	lines := strings.Split(strings.TrimSpace(string(w.buf)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
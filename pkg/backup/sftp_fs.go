@@ -0,0 +1,75 @@
+package winbackupchecker
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+)
+
+// sftpFS reads a server through the SFTP client, for validating backups
+// replicated to it without mounting it
+type sftpFS struct {
+	client *sftpClient
+}
+
+func (s *sftpFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := s.client.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	info.name = path.Base(name)
+	return info, nil
+}
+
+func (s *sftpFS) OpenDir(name string) (dirReader, error) {
+	handle, err := s.client.opendir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	list := func() ([]fs.DirEntry, error) {
+		infos, err := s.client.readdir(handle)
+		if isSFTPEOF(err) {
+			return nil, io.EOF
+		}
+		entries := make([]fs.DirEntry, len(infos))
+		for i, info := range infos {
+			entries[i] = info
+		}
+		return entries, err
+	}
+	return &remoteDir{list: list, close: func() error { return s.client.closeHandle(handle) }}, nil
+}
+
+func (s *sftpFS) Open(name string) (readableFile, error) {
+	handle, err := s.client.open(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	info, err := s.client.fstat(handle)
+	if err != nil {
+		s.client.closeHandle(handle)
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	if info.IsDir() {
+		s.client.closeHandle(handle)
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	info.name = path.Base(name)
+	return &bufferedFile{
+		info:      info,
+		readAhead: sftpReadAhead,
+		fetch:     func(off int64, n int) ([]byte, error) { return s.client.read(handle, off, n) },
+		close:     func() error { return s.client.closeHandle(handle) },
+	}, nil
+}
+
+func (s *sftpFS) Close() error {
+	return s.client.Close()
+}
+
+// isSFTPEOF reports whether err is the status ending a listing or file
+func isSFTPEOF(err error) bool {
+	var status *sftpError
+	return errors.As(err, &status) && status.code == sftpStatusEOF
+}
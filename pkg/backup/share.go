@@ -0,0 +1,83 @@
+package winbackupchecker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ShareCredentials are the account a backup path on a network share is
+// connected with, for checks running under an account that has no mapped
+// drive or stored credentials for the share, such as LocalSystem
+type ShareCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty" secret:"true"`
+
+	// PasswordCredential names a password stored with credentials set,
+	// used when Password is empty
+	PasswordCredential string `json:"password_credential,omitempty"`
+}
+
+// Validate checks that a user is given
+func (c *ShareCredentials) Validate() error {
+	if strings.TrimSpace(c.Username) == "" {
+		return fmt.Errorf("username is required")
+	}
+	return nil
+}
+
+// shareRoot returns the \\server\share part of a UNC path, or "" when path
+// is not one
+func shareRoot(path string) string {
+	if !isUNCPath(path) {
+		return ""
+	}
+	parts := strings.FieldsFunc(path[2:], func(r rune) bool { return r == '\\' || r == '/' })
+	if len(parts) < 2 {
+		return ""
+	}
+	return `\\` + parts[0] + `\` + parts[1]
+}
+
+// Connect connects to the share of the path with its credentials, or the
+// server or bucket of a URL path such as sftp:// or s3://, or brings the
+// offline drive of the path online, returning a function that disconnects
+// again.
+// On Windows, shares without credentials are left to the connections of the
+// account running the check; elsewhere the built-in client connects to them
+// anonymously.
+func (p BackupPath) Connect() (disconnect func() error, err error) {
+	if remoteScheme(p.Path) != "" {
+		return p.connectRemote()
+	}
+	if p.OfflineDrive != nil {
+		return p.connectOfflineDrive()
+	}
+	share := shareRoot(p.Path)
+	if p.Credentials == nil && (!builtinShareClient || share == "") {
+		return func() error { return nil }, nil
+	}
+
+	var username, password string
+	if p.Credentials != nil {
+		username, password = p.Credentials.Username, p.Credentials.Password
+		if password == "" && p.Credentials.PasswordCredential != "" {
+			password, err = GetCredential(p.Credentials.PasswordCredential)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := connectShare(share, username, password); err != nil {
+		if username == "" {
+			return nil, fmt.Errorf("failed to connect to %s anonymously: %w", share, err)
+		}
+		return nil, fmt.Errorf("failed to connect to %s as %s: %w", share, username, err)
+	}
+	return func() error {
+		if err := disconnectShare(share); err != nil {
+			return fmt.Errorf("failed to disconnect from %s: %w", share, err)
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package winbackupchecker
+
+import "context"
+
+// builtinShareClient is set where shares are read through the built-in SMB
+// client rather than connected by the system
+const builtinShareClient = true
+
+// connectShare signs in to share with the built-in SMB client, anonymously
+// without a username, and reads the paths on it through the client until
+// disconnectShare
+func connectShare(share, username, password string) error {
+	if acquireRemote(share) {
+		return nil
+	}
+	client, err := dialSMB(context.Background(), share, username, password)
+	if err != nil {
+		return err
+	}
+	mountRemote(share, &smbFS{client: client})
+	return nil
+}
+
+// disconnectShare closes the connection of connectShare once no other path
+// uses it
+func disconnectShare(share string) error {
+	return unmountRemote(share)
+}
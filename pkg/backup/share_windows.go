@@ -0,0 +1,81 @@
+//go:build windows
+
+package winbackupchecker
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	mpr                        = syscall.NewLazyDLL("mpr.dll")
+	procWNetAddConnection2W    = mpr.NewProc("WNetAddConnection2W")
+	procWNetCancelConnection2W = mpr.NewProc("WNetCancelConnection2W")
+)
+
+// builtinShareClient is set where shares are read through the built-in SMB
+// client rather than connected by the system
+const builtinShareClient = false
+
+const (
+	resourceTypeDisk = 1
+
+	// errorSessionCredentialConflict is returned when the user is already
+	// connected to the server under another account
+	errorSessionCredentialConflict = syscall.Errno(1219)
+)
+
+// netResource is NETRESOURCEW
+type netResource struct {
+	Scope       uint32
+	Type        uint32
+	DisplayType uint32
+	Usage       uint32
+	LocalName   *uint16
+	RemoteName  *uint16
+	Comment     *uint16
+	Provider    *uint16
+}
+
+// connectShare connects to share without a drive letter, as net use
+// \\server\share does, for the current logon session only
+func connectShare(share, username, password string) error {
+	remote, err := syscall.UTF16PtrFromString(share)
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(username)
+	if err != nil {
+		return err
+	}
+	pass, err := syscall.UTF16PtrFromString(password)
+	if err != nil {
+		return err
+	}
+
+	resource := netResource{Type: resourceTypeDisk, RemoteName: remote}
+	r, _, _ := procWNetAddConnection2W.Call(uintptr(unsafe.Pointer(&resource)),
+		uintptr(unsafe.Pointer(pass)), uintptr(unsafe.Pointer(user)), 0)
+	if r != 0 {
+		if syscall.Errno(r) == errorSessionCredentialConflict {
+			return fmt.Errorf("%w; the account running the check is already connected to the server as another user, disconnect it with net use /delete first", syscall.Errno(r))
+		}
+		return syscall.Errno(r)
+	}
+	return nil
+}
+
+// disconnectShare drops the connection made by connectShare, leaving it
+// open while files on the share are still in use
+func disconnectShare(share string) error {
+	remote, err := syscall.UTF16PtrFromString(share)
+	if err != nil {
+		return err
+	}
+	r, _, _ := procWNetCancelConnection2W.Call(uintptr(unsafe.Pointer(remote)), 0, 0)
+	if r != 0 {
+		return syscall.Errno(r)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SlackConfig configures a Slack incoming-webhook notifier
+type SlackConfig struct {
+	Enabled    bool   `json:"enabled"`
+	WebhookURL string `json:"webhook_url" secret:"true"`
+	Channel    string `json:"channel,omitempty"`
+	Username   string `json:"username,omitempty"`
+	NotifyTriggers
+}
+
+// Validate checks if Slack configuration is valid
+func (c *SlackConfig) Validate() error {
+	if c.WebhookURL == "" {
+		return fmt.Errorf("webhook_url is required")
+	}
+	return c.NotifyTriggers.Validate()
+}
+
+// slackMaxSets caps the backup sets detailed in one Slack message
+const slackMaxSets = 10
+
+// slackMaxIssues caps the issues listed per backup set
+const slackMaxIssues = 5
+
+// SlackNotifier posts run summaries to a Slack incoming webhook
+type SlackNotifier struct {
+	cfg *SlackConfig
+}
+
+func (n *SlackNotifier) Name() string { return "slack" }
+
+// Notify posts the run summary using Block Kit, with one severity-colored
+// attachment per backup set that has issues
+func (n *SlackNotifier) Notify(ctx context.Context, report RunReport) error {
+	status := NewRunStatus(report.Summary, report.Results)
+	if !n.cfg.ShouldSend(status) {
+		return nil
+	}
+
+	summary := report.Summary
+	title := fmt.Sprintf("Backup Validation: %s", status.Label())
+	text := fmt.Sprintf("%s - %d/%d backups valid", title, summary.ValidBackups, summary.TotalBackups)
+
+	payload := map[string]any{
+		"text": text,
+		"blocks": []any{
+			map[string]any{
+				"type": "header",
+				"text": map[string]any{"type": "plain_text", "text": title},
+			},
+			map[string]any{
+				"type": "section",
+				"fields": []any{
+					slackField("Total Backups", summary.TotalBackups),
+					slackField("Valid Backups", summary.ValidBackups),
+					slackField("Invalid Backups", summary.InvalidBackups),
+					slackField("Failed Scans", summary.FailedScans),
+				},
+			},
+		},
+	}
+	if n.cfg.Channel != "" {
+		payload["channel"] = n.cfg.Channel
+	}
+	if n.cfg.Username != "" {
+		payload["username"] = n.cfg.Username
+	}
+
+	var attachments []any
+	sets := reportsWithIssues(report)
+	for i, br := range sets {
+		if i == slackMaxSets {
+			attachments = append(attachments, map[string]any{
+				"color": "#6c757d",
+				"text":  fmt.Sprintf("…and %d more backup sets with issues", len(sets)-slackMaxSets),
+			})
+			break
+		}
+
+		worst, _ := worstSeverity(br.Issues)
+		var lines []string
+		for j, issue := range br.Issues {
+			if j == slackMaxIssues {
+				lines = append(lines, fmt.Sprintf("…and %d more", len(br.Issues)-slackMaxIssues))
+				break
+			}
+			lines = append(lines, fmt.Sprintf("• *%s*: %s", strings.ToUpper(issue.Severity.String()), issue.Message))
+		}
+
+		validity := "Valid"
+		if !br.Valid {
+			validity = "Invalid"
+		}
+
+		attachments = append(attachments, map[string]any{
+			"color": severityColor(worst),
+			"blocks": []any{
+				map[string]any{
+					"type": "section",
+					"text": map[string]any{
+						"type": "mrkdwn",
+						"text": fmt.Sprintf("*%s* (%s)\n`%s`\n%s",
+							filepath.Base(br.BackupDir), validity, br.BackupDir, strings.Join(lines, "\n")),
+					},
+				},
+			},
+		})
+	}
+	if len(attachments) > 0 {
+		payload["attachments"] = attachments
+	}
+
+	if err := postJSON(ctx, n.cfg.WebhookURL, payload, nil); err != nil {
+		return fmt.Errorf("failed to post to Slack: %w", err)
+	}
+	return nil
+}
+
+func slackField(label string, value int) map[string]any {
+	return map[string]any{
+		"type": "mrkdwn",
+		"text": fmt.Sprintf("*%s:*\n%d", label, value),
+	}
+}
@@ -0,0 +1,656 @@
+package winbackupchecker
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMB2 commands
+const (
+	smbNegotiate      = 0x0000
+	smbSessionSetup   = 0x0001
+	smbLogoff         = 0x0002
+	smbTreeConnect    = 0x0003
+	smbTreeDisconnect = 0x0004
+	smbCreate         = 0x0005
+	smbClose          = 0x0006
+	smbRead           = 0x0008
+	smbQueryDirectory = 0x000e
+)
+
+// SMB2 dialects offered: 2.0.2 to 3.0.2. SMB 3.1.1 adds pre-authentication
+// integrity, which servers offering it also negotiate down from.
+var smbDialects = []uint16{0x0202, 0x0210, 0x0300, 0x0302}
+
+// NT status codes the client tells apart
+const (
+	statusSuccess                 = 0x00000000
+	statusPending                 = 0x00000103
+	statusNoMoreFiles             = 0x80000006
+	statusMoreProcessingRequired  = 0xc0000016
+	statusNoSuchFile              = 0xc000000f
+	statusEndOfFile               = 0xc0000011
+	statusAccessDenied            = 0xc0000022
+	statusObjectNameNotFound      = 0xc0000034
+	statusObjectPathNotFound      = 0xc000003a
+	statusLogonFailure            = 0xc000006d
+	statusAccountRestriction      = 0xc000006e
+	statusPasswordExpired         = 0xc0000071
+	statusAccountDisabled         = 0xc0000072
+	statusBadNetworkName          = 0xc00000cc
+	statusAccountLockedOut        = 0xc0000234
+	statusAccessDeniedNetworkName = 0xc00000ca
+)
+
+// SMB2 header fields and flags
+const (
+	smbHeaderLen       = 64
+	smbFlagAsync       = 0x00000002
+	smbFlagSigned      = 0x00000008
+	smbSigningEnabled  = 0x0001
+	smbSigningRequired = 0x0002
+	smbCapLargeMTU     = 0x00000004
+	smbSessionGuest    = 0x0001
+	smbSessionNull     = 0x0002
+	smbSessionEncrypt  = 0x0004
+	smbShareEncrypt    = 0x00008000
+)
+
+// SMB2 create and file information values
+const (
+	smbFileReadData        = 0x00000001
+	smbFileReadAttributes  = 0x00000080
+	smbSynchronize         = 0x00100000
+	smbShareAll            = 0x00000007
+	smbFileOpen            = 0x00000001
+	smbDirectoryFile       = 0x00000001
+	smbNonDirectoryFile    = 0x00000040
+	smbAttributeDirectory  = 0x00000010
+	smbImpersonation       = 0x00000002
+	smbFileDirectoryInfo   = 0x01
+	smbCreditUnit          = 64 << 10
+	smbMaxRead             = 1 << 20
+	smbCreditsRequested    = 64
+	smbDirectoryBufferSize = 64 << 10
+)
+
+// smbDialTimeout bounds connecting and signing in, and smbRequestTimeout
+// each request after
+const (
+	smbDialTimeout    = 30 * time.Second
+	smbRequestTimeout = 2 * time.Minute
+)
+
+// smbClient is a minimal SMB2 client reading files from one share, for
+// validating backups on a share from a host that has not mounted it. It
+// signs in with NTLMv2, signs requests when the server requires it, and
+// sends one request at a time. Shares requiring encryption are not
+// supported.
+type smbClient struct {
+	mu   sync.Mutex
+	conn net.Conn
+
+	dialect      uint16
+	maxRead      uint32
+	largeMTU     bool
+	credits      uint16
+	messageID    uint64
+	sessionID    uint64
+	treeID       uint32
+	sign         bool
+	signingKey   []byte
+	signWithCMAC bool
+}
+
+// smbError is an SMB2 request failing with an NT status
+type smbError struct {
+	command uint16
+	status  uint32
+}
+
+func (e *smbError) Error() string {
+	switch e.status {
+	case statusLogonFailure:
+		return "wrong user name or password"
+	case statusAccountDisabled, statusAccountRestriction, statusAccountLockedOut, statusPasswordExpired:
+		return fmt.Sprintf("the account cannot sign in (status %#08x)", e.status)
+	case statusBadNetworkName:
+		return "the share does not exist"
+	case statusAccessDenied, statusAccessDeniedNetworkName:
+		return "access denied"
+	case statusObjectNameNotFound, statusObjectPathNotFound, statusNoSuchFile:
+		return "not found"
+	}
+	return fmt.Sprintf("SMB command %d failed with status %#08x", e.command, e.status)
+}
+
+// Is lets errors.Is match the fs errors the status stands for
+func (e *smbError) Is(target error) bool {
+	switch e.status {
+	case statusObjectNameNotFound, statusObjectPathNotFound, statusNoSuchFile:
+		return target == fs.ErrNotExist
+	case statusAccessDenied, statusAccessDeniedNetworkName:
+		return target == fs.ErrPermission
+	}
+	return false
+}
+
+// dialSMB connects to the share \\server\share as username, anonymously
+// when username and password are empty
+func dialSMB(ctx context.Context, share, username, password string) (*smbClient, error) {
+	server, _, _ := strings.Cut(strings.TrimLeft(share, `\/`), `\`)
+	ctx, cancel := context.WithTimeout(ctx, smbDialTimeout)
+	defer cancel()
+
+	host := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		host = net.JoinHostPort(server, "445")
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c := &smbClient{conn: conn, credits: 1}
+	if err := c.negotiate(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to negotiate: %w", err)
+	}
+	if err := c.sessionSetup(username, password); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to sign in: %w", err)
+	}
+	if err := c.treeConnect(share); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("failed to open share: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// Close disconnects from the share and server
+func (c *smbClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetDeadline(time.Now().Add(smbDialTimeout))
+	if c.treeID != 0 {
+		c.roundTrip(smbTreeDisconnect, smbStruct(4), 0)
+		c.treeID = 0
+	}
+	if c.sessionID != 0 {
+		c.roundTrip(smbLogoff, smbStruct(4), 0)
+		c.sessionID = 0
+	}
+	return c.conn.Close()
+}
+
+func (c *smbClient) negotiate() error {
+	body := make([]byte, 36, 36+2*len(smbDialects))
+	binary.LittleEndian.PutUint16(body, 36)
+	binary.LittleEndian.PutUint16(body[2:], uint16(len(smbDialects)))
+	binary.LittleEndian.PutUint16(body[4:], smbSigningEnabled)
+	if _, err := rand.Read(body[12:28]); err != nil {
+		return err
+	}
+	for _, dialect := range smbDialects {
+		body = binary.LittleEndian.AppendUint16(body, dialect)
+	}
+
+	_, resp, err := c.roundTrip(smbNegotiate, body, 0)
+	if err != nil {
+		return err
+	}
+	if len(resp) < smbHeaderLen+64 {
+		return errors.New("negotiate response too short")
+	}
+	r := resp[smbHeaderLen:]
+	c.dialect = binary.LittleEndian.Uint16(r[4:])
+	c.sign = binary.LittleEndian.Uint16(r[2:])&smbSigningRequired != 0
+	c.largeMTU = c.dialect != 0x0202 && binary.LittleEndian.Uint32(r[24:])&smbCapLargeMTU != 0
+	c.maxRead = min(binary.LittleEndian.Uint32(r[32:]), smbMaxRead)
+	if !c.largeMTU {
+		c.maxRead = min(c.maxRead, smbCreditUnit)
+	}
+	if !isOffered(c.dialect) {
+		return fmt.Errorf("server chose unsupported dialect %#04x", c.dialect)
+	}
+	return nil
+}
+
+func isOffered(dialect uint16) bool {
+	for _, d := range smbDialects {
+		if d == dialect {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *smbClient) sessionSetup(username, password string) error {
+	auth := newNTLMClient(username, password)
+	token := spnegoInit(auth.negotiate())
+
+	for round := 0; ; round++ {
+		body := make([]byte, 24, 24+len(token))
+		binary.LittleEndian.PutUint16(body, 25)
+		body[3] = smbSigningEnabled
+		binary.LittleEndian.PutUint16(body[12:], smbHeaderLen+24)
+		binary.LittleEndian.PutUint16(body[14:], uint16(len(token)))
+		body = append(body, token...)
+
+		header, resp, err := c.roundTrip(smbSessionSetup, body, statusMoreProcessingRequired)
+		if err != nil {
+			return err
+		}
+		c.sessionID = binary.LittleEndian.Uint64(header[40:])
+		status := binary.LittleEndian.Uint32(header[8:])
+		if status == statusSuccess {
+			flags := binary.LittleEndian.Uint16(resp[smbHeaderLen+2:])
+			if flags&smbSessionEncrypt != 0 {
+				return errors.New("the server requires SMB encryption, which is not supported")
+			}
+			if flags&(smbSessionGuest|smbSessionNull) != 0 || auth.sessionKey == nil {
+				// Guest and anonymous sessions have no key to sign with
+				if c.sign {
+					return errors.New("the server requires signing, which guest and anonymous sessions cannot do")
+				}
+				return nil
+			}
+			c.setSigningKey(auth.sessionKey)
+			return nil
+		}
+		if round > 0 {
+			return errors.New("unexpected extra authentication round")
+		}
+
+		secOffset := int(binary.LittleEndian.Uint16(resp[smbHeaderLen+4:]))
+		secLen := int(binary.LittleEndian.Uint16(resp[smbHeaderLen+6:]))
+		if secOffset+secLen > len(resp) {
+			return errors.New("session setup response out of range")
+		}
+		challenge, err := spnegoToken(resp[secOffset : secOffset+secLen])
+		if err != nil {
+			return err
+		}
+		answer, err := auth.authenticate(challenge)
+		if err != nil {
+			return err
+		}
+		token = spnegoResponse(answer)
+	}
+}
+
+// setSigningKey derives the key requests are signed with from the session
+// key: the key itself up to SMB 2.1, or an AES-CMAC key from SMB 3.0
+func (c *smbClient) setSigningKey(sessionKey []byte) {
+	if c.dialect < 0x0300 {
+		c.signingKey = sessionKey
+		return
+	}
+	c.signingKey = smbKDF(sessionKey, []byte("SMB2AESCMAC\x00"), []byte("SmbSign\x00"))
+	c.signWithCMAC = true
+}
+
+func (c *smbClient) treeConnect(share string) error {
+	path := utf16LE(share)
+	body := make([]byte, 8, 8+len(path))
+	binary.LittleEndian.PutUint16(body, 9)
+	binary.LittleEndian.PutUint16(body[4:], smbHeaderLen+8)
+	binary.LittleEndian.PutUint16(body[6:], uint16(len(path)))
+	body = append(body, path...)
+
+	header, resp, err := c.roundTrip(smbTreeConnect, body, 0)
+	if err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint32(resp[smbHeaderLen+4:])&smbShareEncrypt != 0 {
+		return errors.New("the share requires SMB encryption, which is not supported")
+	}
+	c.treeID = binary.LittleEndian.Uint32(header[36:])
+	return nil
+}
+
+// smbFileID identifies an open file on the server
+type smbFileID [16]byte
+
+// smbFileInfo is the metadata of a file returned when opening it or
+// listing its folder
+type smbFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	attrs   uint32
+}
+
+func (i *smbFileInfo) Name() string       { return i.name }
+func (i *smbFileInfo) Size() int64        { return i.size }
+func (i *smbFileInfo) ModTime() time.Time { return i.modTime }
+func (i *smbFileInfo) IsDir() bool        { return i.attrs&smbAttributeDirectory != 0 }
+func (i *smbFileInfo) Sys() any           { return nil }
+
+func (i *smbFileInfo) Mode() fs.FileMode {
+	if i.IsDir() {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (i *smbFileInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i *smbFileInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// create opens name, a path in the share with backslashes, for access.
+// options selects a file or folder, or either when 0.
+func (c *smbClient) create(name string, access, options uint32) (smbFileID, *smbFileInfo, error) {
+	var id smbFileID
+	path := utf16LE(name)
+	body := make([]byte, 56, 56+max(len(path), 1))
+	binary.LittleEndian.PutUint16(body, 57)
+	binary.LittleEndian.PutUint32(body[4:], smbImpersonation)
+	binary.LittleEndian.PutUint32(body[24:], access)
+	binary.LittleEndian.PutUint32(body[32:], smbShareAll)
+	binary.LittleEndian.PutUint32(body[36:], smbFileOpen)
+	binary.LittleEndian.PutUint32(body[40:], options)
+	binary.LittleEndian.PutUint16(body[44:], smbHeaderLen+56)
+	binary.LittleEndian.PutUint16(body[46:], uint16(len(path)))
+	body = append(body, path...)
+	if len(path) == 0 {
+		body = append(body, 0)
+	}
+
+	_, resp, err := c.call(smbCreate, body, 0)
+	if err != nil {
+		return id, nil, err
+	}
+	r := resp[smbHeaderLen:]
+	if len(r) < 88 {
+		return id, nil, errors.New("create response too short")
+	}
+	copy(id[:], r[64:80])
+	base := name[strings.LastIndexByte(name, '\\')+1:]
+	info := &smbFileInfo{
+		name:    base,
+		modTime: fromFileTime(binary.LittleEndian.Uint64(r[24:])),
+		size:    int64(binary.LittleEndian.Uint64(r[48:])),
+		attrs:   binary.LittleEndian.Uint32(r[56:]),
+	}
+	return id, info, nil
+}
+
+func (c *smbClient) closeFile(id smbFileID) error {
+	body := smbStruct(24)
+	copy(body[8:], id[:])
+	_, _, err := c.call(smbClose, body, 0)
+	return err
+}
+
+// read reads into p from off, returning io.EOF past the end. It reads no
+// more than the server allows at once, or the credits granted cover.
+func (c *smbClient) read(id smbFileID, p []byte, off int64) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	length := min(uint32(len(p)), c.maxRead)
+	if c.largeMTU {
+		length = min(length, max(uint32(c.credits), 1)*smbCreditUnit)
+	}
+	body := smbStruct(49)
+	body[2] = 0x50 // data right after the response header
+	binary.LittleEndian.PutUint32(body[4:], length)
+	binary.LittleEndian.PutUint64(body[8:], uint64(off))
+	copy(body[16:], id[:])
+
+	c.conn.SetDeadline(time.Now().Add(smbRequestTimeout))
+	_, resp, err := c.roundTrip(smbRead, body, 0)
+	var smbErr *smbError
+	if errors.As(err, &smbErr) && smbErr.status == statusEndOfFile {
+		return 0, io.EOF
+	}
+	if err != nil {
+		return 0, err
+	}
+	r := resp[smbHeaderLen:]
+	dataOffset := int(r[2])
+	dataLen := int(binary.LittleEndian.Uint32(r[4:]))
+	if dataOffset+dataLen > len(resp) || dataLen > len(p) {
+		return 0, errors.New("read response out of range")
+	}
+	return copy(p, resp[dataOffset:dataOffset+dataLen]), nil
+}
+
+// queryDirectory returns the next entries of the open folder id, or
+// io.EOF once all were returned
+func (c *smbClient) queryDirectory(id smbFileID) ([]*smbFileInfo, error) {
+	pattern := utf16LE("*")
+	body := make([]byte, 32, 32+len(pattern))
+	binary.LittleEndian.PutUint16(body, 33)
+	body[2] = smbFileDirectoryInfo
+	copy(body[8:], id[:])
+	binary.LittleEndian.PutUint16(body[24:], smbHeaderLen+32)
+	binary.LittleEndian.PutUint16(body[26:], uint16(len(pattern)))
+	binary.LittleEndian.PutUint32(body[28:], min(smbDirectoryBufferSize, c.maxRead))
+	body = append(body, pattern...)
+
+	_, resp, err := c.call(smbQueryDirectory, body, 0)
+	var smbErr *smbError
+	if errors.As(err, &smbErr) && smbErr.status == statusNoMoreFiles {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := resp[smbHeaderLen:]
+	offset := int(binary.LittleEndian.Uint16(r[2:]))
+	length := int(binary.LittleEndian.Uint32(r[4:]))
+	if offset+length > len(resp) {
+		return nil, errors.New("directory listing out of range")
+	}
+
+	var entries []*smbFileInfo
+	buf := resp[offset : offset+length]
+	for len(buf) >= 64 {
+		next := int(binary.LittleEndian.Uint32(buf))
+		nameLen := int(binary.LittleEndian.Uint32(buf[60:]))
+		if 64+nameLen > len(buf) {
+			return nil, errors.New("directory entry out of range")
+		}
+		name := fromUTF16LE(buf[64 : 64+nameLen])
+		if name != "." && name != ".." {
+			entries = append(entries, &smbFileInfo{
+				name:    name,
+				modTime: fromFileTime(binary.LittleEndian.Uint64(buf[24:])),
+				size:    int64(binary.LittleEndian.Uint64(buf[40:])),
+				attrs:   binary.LittleEndian.Uint32(buf[56:]),
+			})
+		}
+		if next == 0 || next > len(buf) {
+			break
+		}
+		buf = buf[next:]
+	}
+	return entries, nil
+}
+
+// smbStruct returns a zeroed request body of structure size n. Odd sizes
+// count the first byte of the buffer that follows.
+func smbStruct(n int) []byte {
+	body := make([]byte, n)
+	binary.LittleEndian.PutUint16(body, uint16(n))
+	return body
+}
+
+// call sends a request on the share and returns the response, holding the
+// connection for the round trip
+func (c *smbClient) call(command uint16, body []byte, okStatus uint32) (header, resp []byte, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetDeadline(time.Now().Add(smbRequestTimeout))
+	return c.roundTrip(command, body, okStatus)
+}
+
+// roundTrip sends one request and reads its final response, failing unless
+// the status is success or okStatus
+func (c *smbClient) roundTrip(command uint16, body []byte, okStatus uint32) (header, resp []byte, err error) {
+	charge := uint16(1)
+	if command == smbRead || command == smbQueryDirectory {
+		size := binary.LittleEndian.Uint32(body[4:])
+		if command == smbQueryDirectory {
+			size = binary.LittleEndian.Uint32(body[28:])
+		}
+		charge = uint16((max(size, 1)-1)/smbCreditUnit + 1)
+	}
+	if !c.largeMTU {
+		charge = 1
+	}
+
+	msg := make([]byte, smbHeaderLen, smbHeaderLen+len(body))
+	copy(msg, "\xfeSMB")
+	binary.LittleEndian.PutUint16(msg[4:], smbHeaderLen)
+	if c.dialect != 0x0202 && c.dialect != 0 {
+		binary.LittleEndian.PutUint16(msg[6:], charge)
+	}
+	binary.LittleEndian.PutUint16(msg[12:], command)
+	binary.LittleEndian.PutUint16(msg[14:], smbCreditsRequested)
+	binary.LittleEndian.PutUint64(msg[24:], c.messageID)
+	binary.LittleEndian.PutUint32(msg[36:], c.treeID)
+	binary.LittleEndian.PutUint64(msg[40:], c.sessionID)
+	msg = append(msg, body...)
+
+	if c.signingKey != nil && c.sign && command != smbSessionSetup {
+		binary.LittleEndian.PutUint32(msg[16:], smbFlagSigned)
+		copy(msg[48:64], c.signature(msg))
+	}
+
+	id := c.messageID
+	c.messageID += uint64(charge)
+	c.credits -= min(c.credits, charge)
+
+	frame := make([]byte, 4, 4+len(msg))
+	binary.BigEndian.PutUint32(frame, uint32(len(msg)))
+	if _, err := c.conn.Write(append(frame, msg...)); err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		resp, err := c.readMessage()
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(resp) < smbHeaderLen || string(resp[:4]) != "\xfeSMB" {
+			return nil, nil, errors.New("invalid SMB2 response")
+		}
+		if binary.LittleEndian.Uint64(resp[24:]) != id {
+			continue
+		}
+		c.credits += binary.LittleEndian.Uint16(resp[14:])
+		status := binary.LittleEndian.Uint32(resp[8:])
+		flags := binary.LittleEndian.Uint32(resp[16:])
+		if status == statusPending && flags&smbFlagAsync != 0 {
+			// An interim response; the final one follows
+			continue
+		}
+		if status != statusSuccess && status != okStatus {
+			return nil, nil, &smbError{command: command, status: status}
+		}
+		return resp[:smbHeaderLen], resp, nil
+	}
+}
+
+// readMessage reads one message framed for direct TCP transport
+func (c *smbClient) readMessage() ([]byte, error) {
+	var frame [4]byte
+	if _, err := io.ReadFull(c.conn, frame[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(frame[:]) & 0xffffff
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(c.conn, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// signature returns the signature of msg, whose signature field is zero
+func (c *smbClient) signature(msg []byte) []byte {
+	if c.signWithCMAC {
+		return aesCMAC(c.signingKey, msg)
+	}
+	mac := hmac.New(sha256.New, c.signingKey)
+	mac.Write(msg)
+	return mac.Sum(nil)[:16]
+}
+
+// smbKDF derives a 128-bit key from key with the SP800-108 counter mode
+// KDF using HMAC-SHA256, as SMB 3 does
+func smbKDF(key, label, context []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte{0, 0, 0, 1})
+	mac.Write(label)
+	mac.Write([]byte{0})
+	mac.Write(context)
+	mac.Write([]byte{0, 0, 0, 128})
+	return mac.Sum(nil)[:16]
+}
+
+// aesCMAC returns the AES-CMAC of msg (RFC 4493)
+func aesCMAC(key, msg []byte) []byte {
+	block, _ := aes.NewCipher(key)
+	var zero, k1, k2 [aes.BlockSize]byte
+	block.Encrypt(k1[:], zero[:])
+	cmacDouble(&k1)
+	k2 = k1
+	cmacDouble(&k2)
+
+	n := max((len(msg)+aes.BlockSize-1)/aes.BlockSize, 1)
+	last := make([]byte, aes.BlockSize)
+	rest := msg[(n-1)*aes.BlockSize:]
+	if len(rest) == aes.BlockSize {
+		xorBytes(last, rest, k1[:])
+	} else {
+		copy(last, rest)
+		last[len(rest)] = 0x80
+		xorBytes(last, last, k2[:])
+	}
+
+	x := make([]byte, aes.BlockSize)
+	mode := cipher.NewCBCEncrypter(block, x)
+	if n > 1 {
+		prefix := make([]byte, (n-1)*aes.BlockSize)
+		mode.CryptBlocks(prefix, msg[:(n-1)*aes.BlockSize])
+		copy(x, prefix[len(prefix)-aes.BlockSize:])
+	}
+	xorBytes(x, x, last)
+	block.Encrypt(x, x)
+	return x
+}
+
+// cmacDouble doubles k in GF(2^128), deriving a CMAC subkey
+func cmacDouble(k *[aes.BlockSize]byte) {
+	carry := k[0] >> 7
+	for i := 0; i < aes.BlockSize-1; i++ {
+		k[i] = k[i]<<1 | k[i+1]>>7
+	}
+	k[aes.BlockSize-1] = k[aes.BlockSize-1]<<1 ^ carry*0x87
+}
+
+// subtle sets dst to a XOR b
+func xorBytes(dst, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
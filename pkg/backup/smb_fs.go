@@ -0,0 +1,105 @@
+package winbackupchecker
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// smbFS reads a share through the built-in SMB client, for validating it
+// from a host that has not mounted it
+type smbFS struct {
+	client *smbClient
+}
+
+func (s *smbFS) Stat(name string) (fs.FileInfo, error) {
+	id, info, err := s.client.create(name, smbFileReadAttributes|smbSynchronize, 0)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	s.client.closeFile(id)
+	return info, nil
+}
+
+func (s *smbFS) OpenDir(name string) (dirReader, error) {
+	id, _, err := s.client.create(name, smbFileReadData|smbFileReadAttributes|smbSynchronize, smbDirectoryFile)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	list := func() ([]fs.DirEntry, error) {
+		infos, err := s.client.queryDirectory(id)
+		entries := make([]fs.DirEntry, len(infos))
+		for i, info := range infos {
+			entries[i] = info
+		}
+		return entries, err
+	}
+	return &remoteDir{list: list, close: func() error { return s.client.closeFile(id) }}, nil
+}
+
+func (s *smbFS) Open(name string) (readableFile, error) {
+	id, info, err := s.client.create(name, smbFileReadData|smbFileReadAttributes|smbSynchronize, smbNonDirectoryFile)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &smbFile{client: s.client, id: id, info: info}, nil
+}
+
+func (s *smbFS) Close() error {
+	return s.client.Close()
+}
+
+// smbFile is a file open for reading on the share
+type smbFile struct {
+	client *smbClient
+	id     smbFileID
+	info   *smbFileInfo
+	offset int64
+}
+
+func (f *smbFile) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n, err := f.client.read(f.id, p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+// ReadAt fills p from off, in as many requests as the server needs
+func (f *smbFile) ReadAt(p []byte, off int64) (int, error) {
+	read := 0
+	for read < len(p) {
+		n, err := f.client.read(f.id, p[read:], off+int64(read))
+		read += n
+		if err != nil {
+			return read, err
+		}
+		if n == 0 {
+			return read, io.ErrUnexpectedEOF
+		}
+	}
+	return read, nil
+}
+
+func (f *smbFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.size
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *smbFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *smbFile) Close() error {
+	return f.client.closeFile(f.id)
+}
@@ -0,0 +1,193 @@
+package winbackupchecker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SMTP connection security modes
+const (
+	// TLSModeSTARTTLS upgrades a plain connection, usually on port 587, and
+	// fails if the server does not offer STARTTLS
+	TLSModeSTARTTLS = "starttls"
+
+	// TLSModeImplicit connects with TLS from the start, usually on port 465
+	TLSModeImplicit = "tls"
+
+	// TLSModeNone sends without encryption, for relays on a trusted network
+	TLSModeNone = "none"
+)
+
+// smtpTimeout bounds a whole SMTP conversation
+const smtpTimeout = 2 * time.Minute
+
+// SMTP retry defaults; the delay doubles after each failed attempt
+const (
+	smtpDefaultRetries      = 3
+	smtpDefaultRetryBackoff = 10 * time.Second
+)
+
+// tlsMode returns the configured mode, defaulting to implicit TLS on port
+// 465 and STARTTLS elsewhere
+func (e *EmailConfig) tlsMode() string {
+	if e.TLSMode != "" {
+		return e.TLSMode
+	}
+	if e.SMTPPort == 465 {
+		return TLSModeImplicit
+	}
+	return TLSModeSTARTTLS
+}
+
+// tlsConfig builds the TLS settings used to verify the SMTP server
+func (e *EmailConfig) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         e.SMTPHost,
+		InsecureSkipVerify: e.TLSSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if e.TLSServerName != "" {
+		cfg.ServerName = e.TLSServerName
+	}
+
+	if e.TLSCAFile != "" {
+		pem, err := os.ReadFile(e.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls_ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls_ca_file contains no PEM certificates")
+		}
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}
+
+// retryPolicy returns how many times a failed send is retried and the delay
+// before the first retry
+func (e *EmailConfig) retryPolicy() (int, time.Duration) {
+	retries, backoff := smtpDefaultRetries, smtpDefaultRetryBackoff
+	if e.Retries != nil {
+		retries = *e.Retries
+	}
+	if e.RetryBackoff != "" {
+		backoff, _ = ParseDuration(e.RetryBackoff)
+	}
+	return retries, backoff
+}
+
+// deliverSMTPWithRetry calls deliverSMTP until it succeeds, retrying
+// transient failures with exponential backoff. The returned error records
+// the number of attempts.
+func deliverSMTPWithRetry(cfg *EmailConfig, message []byte) error {
+	retries, backoff := cfg.retryPolicy()
+
+	for attempt := 1; ; attempt++ {
+		err := deliverSMTP(cfg, message)
+		if err == nil {
+			return nil
+		}
+		if attempt > retries || !transientSMTPError(err) {
+			return &RetryError{Attempts: attempt, Err: err}
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// transientSMTPError reports whether a failed send may succeed if retried.
+// Permanent (5xx) replies and certificate errors are not retried.
+func transientSMTPError(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code < 500
+	}
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return false
+	}
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	return !errors.As(err, &authorityErr) && !errors.As(err, &hostnameErr)
+}
+
+// deliverSMTP sends message to the configured server using its TLS mode.
+// PlainAuth refuses to send credentials over an unencrypted connection
+// except to localhost.
+func deliverSMTP(cfg *EmailConfig, message []byte) error {
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort))
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	var conn net.Conn
+	if cfg.tlsMode() == TLSModeImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if cfg.tlsMode() == TLSModeSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("server does not offer STARTTLS; use tls_mode tls on port 465, or none for an unencrypted relay")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	if cfg.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("server does not offer authentication")
+		}
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.SMTPHost)); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(cfg.From); err != nil {
+		return fmt.Errorf("sender rejected: %w", err)
+	}
+	for _, to := range cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("message rejected: %w", err)
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,278 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Snapshot methods
+const (
+	SnapshotMethodVSS     = "vss"
+	SnapshotMethodCommand = "command"
+)
+
+// snapshotReleaseTimeout bounds snapshot cleanup, which also runs after the
+// scan context was cancelled
+const snapshotReleaseTimeout = 2 * time.Minute
+
+// SnapshotConfig validates backup roots against a temporary read-only
+// snapshot, so a backup job starting mid-scan cannot cause false findings
+type SnapshotConfig struct {
+	Enabled bool             `json:"enabled"`
+	Targets []SnapshotTarget `json:"targets"`
+}
+
+// SnapshotTarget describes how to snapshot the storage holding a backup path.
+// Commands and snapshot_path may use {name}, replaced by a name unique to
+// each snapshot.
+type SnapshotTarget struct {
+	Path          string   `json:"path"`
+	Method        string   `json:"method"`
+	CreateCommand []string `json:"create_command,omitempty"`
+	DeleteCommand []string `json:"delete_command,omitempty"`
+	SnapshotPath  string   `json:"snapshot_path,omitempty"`
+}
+
+// Validate checks if snapshot configuration is valid
+func (c *SnapshotConfig) Validate() error {
+	if len(c.Targets) == 0 {
+		return fmt.Errorf("at least one target is required")
+	}
+	for _, target := range c.Targets {
+		if target.Path == "" {
+			return fmt.Errorf("target path is required")
+		}
+		switch target.Method {
+		case SnapshotMethodVSS:
+			if filepath.VolumeName(target.Path) == "" {
+				return fmt.Errorf("%s: vss snapshots need a path on a local drive", target.Path)
+			}
+		case SnapshotMethodCommand:
+			if len(target.CreateCommand) == 0 {
+				return fmt.Errorf("%s: create_command is required", target.Path)
+			}
+			if target.SnapshotPath == "" {
+				return fmt.Errorf("%s: snapshot_path is required", target.Path)
+			}
+		default:
+			return fmt.Errorf("%s: unknown method %q (use vss or command)", target.Path, target.Method)
+		}
+	}
+	return nil
+}
+
+// target returns the snapshot target covering root
+func (c *SnapshotConfig) target(root string) (SnapshotTarget, bool) {
+	if c == nil || !c.Enabled {
+		return SnapshotTarget{}, false
+	}
+	for _, target := range c.Targets {
+		if isSubPath(target.Path, root) {
+			return target, true
+		}
+	}
+	return SnapshotTarget{}, false
+}
+
+// isSubPath reports whether path is dir or inside it
+func isSubPath(dir, path string) bool {
+	rel, err := filepath.Rel(NormalizePath(dir), NormalizePath(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// snapshot is a read-only snapshot of a backup root
+type snapshot struct {
+	// root is where the backup root is visible inside the snapshot
+	root    string
+	release func() error
+}
+
+// createSnapshot snapshots the storage holding root
+func createSnapshot(ctx context.Context, target SnapshotTarget, root string) (*snapshot, error) {
+	name := "win-backup-checker-" + time.Now().Format("20060102-150405")
+
+	var snap *snapshot
+	var err error
+	switch target.Method {
+	case SnapshotMethodVSS:
+		snap, err = createVSSSnapshot(ctx, name, root)
+	default:
+		snap, err = createCommandSnapshot(ctx, target, name, root)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !dirExists(snap.root) {
+		snap.release()
+		return nil, fmt.Errorf("backup root not found in snapshot at %s", snap.root)
+	}
+	return snap, nil
+}
+
+// createCommandSnapshot runs a user supplied command, typically a btrfs or
+// ZFS snapshot on a NAS over SSH, and reads the snapshot from snapshot_path
+func createCommandSnapshot(ctx context.Context, target SnapshotTarget, name, root string) (*snapshot, error) {
+	expand := func(s string) string { return strings.ReplaceAll(s, "{name}", name) }
+	expandAll := func(args []string) []string {
+		out := make([]string, len(args))
+		for i, arg := range args {
+			out[i] = expand(arg)
+		}
+		return out
+	}
+
+	if _, err := runCommand(ctx, expandAll(target.CreateCommand)); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	rel, err := filepath.Rel(NormalizePath(target.Path), NormalizePath(root))
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate %s in snapshot: %w", root, err)
+	}
+
+	return &snapshot{
+		root: filepath.Join(expand(target.SnapshotPath), rel),
+		release: func() error {
+			if len(target.DeleteCommand) == 0 {
+				return nil
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), snapshotReleaseTimeout)
+			defer cancel()
+			if _, err := runCommand(ctx, expandAll(target.DeleteCommand)); err != nil {
+				return fmt.Errorf("failed to delete snapshot: %w", err)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// createVSSSnapshot creates a Volume Shadow Copy of the volume holding root
+// and links it into the temp directory, since shadow copy device paths are
+// not usable as regular paths
+func createVSSSnapshot(ctx context.Context, name, root string) (*snapshot, error) {
+	if runtime.GOOS != "windows" {
+		return nil, fmt.Errorf("vss snapshots are only available on Windows")
+	}
+
+	volume := filepath.VolumeName(root) + `\`
+	script := fmt.Sprintf(`$r = (Get-WmiObject -List Win32_ShadowCopy).Create('%s', 'ClientAccessible')
+if ($r.ReturnValue -ne 0) { throw "Win32_ShadowCopy.Create returned $($r.ReturnValue)" }
+$s = Get-WmiObject Win32_ShadowCopy -Filter "ID='$($r.ShadowID)'"
+$s.ID
+$s.DeviceObject`, volume)
+
+	output, err := runCommand(ctx, []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", script})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shadow copy of %s: %w", volume, err)
+	}
+	lines := strings.Fields(output)
+	if len(lines) != 2 {
+		return nil, fmt.Errorf("unexpected shadow copy output: %q", output)
+	}
+	id, device := lines[0], lines[1]
+
+	deleteShadow := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), snapshotReleaseTimeout)
+		defer cancel()
+		script := fmt.Sprintf(`Get-WmiObject Win32_ShadowCopy -Filter "ID='%s'" | ForEach-Object { $_.Delete() }`, id)
+		if _, err := runCommand(ctx, []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", script}); err != nil {
+			return fmt.Errorf("failed to delete shadow copy %s: %w", id, err)
+		}
+		return nil
+	}
+
+	link := filepath.Join(os.TempDir(), name)
+	if err := os.Symlink(device+`\`, link); err != nil {
+		deleteShadow()
+		return nil, fmt.Errorf("failed to link shadow copy: %w", err)
+	}
+
+	rel, _ := filepath.Rel(volume, root)
+	return &snapshot{
+		root: filepath.Join(link, rel),
+		release: func() error {
+			os.Remove(link)
+			return deleteShadow()
+		},
+	}, nil
+}
+
+// runCommand runs argv and returns its output, including the last line of
+// output in errors
+func runCommand(ctx context.Context, argv []string) (string, error) {
+	return commandOutput(exec.CommandContext(ctx, argv[0], argv[1:]...))
+}
+
+// commandOutput runs cmd as runCommand does
+func commandOutput(cmd *exec.Cmd) (string, error) {
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(output))
+		if i := strings.LastIndex(msg, "\n"); i >= 0 {
+			msg = msg[i+1:]
+		}
+		if msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+// rebaseSets returns copies of sets with paths under from moved under to
+func rebaseSets(sets []BackupSetInfo, from, to string) []BackupSetInfo {
+	rebase := func(path string) string { return rebasePath(path, from, to) }
+	rebaseAll := func(paths []string) []string {
+		out := make([]string, len(paths))
+		for i, path := range paths {
+			out[i] = rebase(path)
+		}
+		return out
+	}
+
+	out := make([]BackupSetInfo, len(sets))
+	for i, set := range sets {
+		set.Path = rebase(set.Path)
+		set.CatalogFiles = rebaseAll(set.CatalogFiles)
+		set.BackupFiles = rebaseAll(set.BackupFiles)
+		set.ManifestFiles = rebaseAll(set.ManifestFiles)
+		set.Links = append([]SetLink(nil), set.Links...)
+		for j := range set.Links {
+			set.Links[j].Path = rebase(set.Links[j].Path)
+		}
+		out[i] = set
+	}
+	return out
+}
+
+// rebaseReports moves paths under from in reports, including those named in
+// issue messages, under to
+func rebaseReports(reports []BackupReport, from, to string) {
+	for i := range reports {
+		reports[i].BackupDir = rebasePath(reports[i].BackupDir, from, to)
+		for j := range reports[i].Issues {
+			issue := &reports[i].Issues[j]
+			issue.Path = rebasePath(issue.Path, from, to)
+			issue.Message = strings.ReplaceAll(issue.Message, from, to)
+			issue.text = issue.text.replace(from, to)
+			issue.Suggestion = strings.ReplaceAll(issue.Suggestion, from, to)
+		}
+	}
+}
+
+func rebasePath(path, from, to string) string {
+	if path == from {
+		return to
+	}
+	if strings.HasPrefix(path, from+string(filepath.Separator)) {
+		return to + path[len(from):]
+	}
+	return path
+}
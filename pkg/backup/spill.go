@@ -0,0 +1,200 @@
+package winbackupchecker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ReportSpill keeps the finished reports of a run's backup paths in a
+// temporary file, one JSON line each, so roots with thousands of backup sets
+// do not hold every detail in memory until the report is written
+type ReportSpill struct {
+	file *os.File
+	w    *bufio.Writer
+
+	// roots counts the reports written; once a write failed, err is set and
+	// later reports stay in memory
+	roots int
+	err   error
+}
+
+// NewReportSpill creates a spill file in dir, or the temporary directory
+// when dir is empty
+func NewReportSpill(dir string) (*ReportSpill, error) {
+	file, err := os.CreateTemp(dir, "checker-report-*.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report spill file: %w", err)
+	}
+	return &ReportSpill{file: file, w: bufio.NewWriter(file)}, nil
+}
+
+// Add writes report to the spill file
+func (s *ReportSpill) Add(report ScanReport) error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := json.NewEncoder(s.w).Encode(report); err != nil {
+		s.err = fmt.Errorf("failed to spill report of %s: %w", report.Root, err)
+		return s.err
+	}
+	s.roots++
+	return nil
+}
+
+// Err returns the error that stopped reports from being spilled, if any
+func (s *ReportSpill) Err() error {
+	return s.err
+}
+
+// Close removes the spill file
+func (s *ReportSpill) Close() error {
+	s.file.Close()
+	if err := os.Remove(s.file.Name()); err != nil {
+		return fmt.Errorf("failed to remove report spill file: %w", err)
+	}
+	return nil
+}
+
+// reader returns a function reading the spilled reports back in order
+func (s *ReportSpill) reader() (func() (ScanReport, error), error) {
+	if err := s.w.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write report spill file: %w", err)
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read report spill file: %w", err)
+	}
+	decoder := json.NewDecoder(bufio.NewReader(s.file))
+	return func() (ScanReport, error) {
+		var report ScanReport
+		if err := decoder.Decode(&report); err != nil {
+			return report, fmt.Errorf("failed to read report spill file: %w", err)
+		}
+		return report, nil
+	}, nil
+}
+
+// compactReport drops the details of a spilled report that only the written
+// report shows: phase timings, content breakdown, I/O stats, insights and
+// skipped checks. Issues and counts stay for alerts and the exit code.
+func compactReport(report *ScanReport) {
+	report.PhaseTimings = nil
+	for i := range report.Reports {
+		br := &report.Reports[i]
+		br.ValidationStats.PhaseTimings = nil
+		br.ValidationStats.ContentBreakdown = nil
+		br.ValidationStats.IO = nil
+		br.Insights = nil
+		br.SkippedChecks = nil
+	}
+}
+
+// WriteRunReport writes run as indented JSON, exactly as json.MarshalIndent
+// with two spaces would, one backup set at a time. Spilled results are read
+// back from spill, which may be nil, with the issues kept in run. Issues are
+// translated by tr when it is set.
+func WriteRunReport(w io.Writer, run RunReport, spill *ReportSpill, tr *Localizer) error {
+	next := func(i int) (ScanReport, error) { return run.Results[i], nil }
+	if spill != nil && spill.roots > 0 {
+		read, err := spill.reader()
+		if err != nil {
+			return err
+		}
+		next = func(i int) (ScanReport, error) {
+			if i >= spill.roots {
+				return run.Results[i], nil
+			}
+			report, err := read()
+			if err != nil {
+				return report, err
+			}
+			// The issues in memory can still be translated
+			for j := range report.Reports {
+				report.Reports[j].Issues = run.Results[i].Reports[j].Issues
+			}
+			return report, nil
+		}
+	}
+
+	head := tr.Run(RunReport{
+		Timestamp:            run.Timestamp,
+		Profile:              run.Profile,
+		Results:              emptyList(run.Results),
+		Summary:              run.Summary,
+		PhaseTimings:         run.PhaseTimings,
+		CacheStats:           run.CacheStats,
+		Quarantined:          run.Quarantined,
+		NotificationFailures: run.NotificationFailures,
+	})
+	return writeJSONList(w, head, "", "results", len(run.Results), func(i int, prefix string) error {
+		report, err := next(i)
+		if err != nil {
+			return err
+		}
+		report = tr.Reports([]ScanReport{report})[0]
+		sets := report.Reports
+		report.Reports = emptyList(sets)
+		return writeJSONList(w, report, prefix, "reports", len(sets), func(j int, prefix string) error {
+			data, err := json.MarshalIndent(sets[j], prefix, "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal report of %s: %w", sets[j].BackupDir, err)
+			}
+			_, err = w.Write(data)
+			return err
+		})
+	})
+}
+
+// emptyList returns an empty list that is nil when list is, so both
+// marshal the same
+func emptyList[T any](list []T) []T {
+	if list == nil {
+		return nil
+	}
+	return []T{}
+}
+
+// writeJSONList writes v indented with prefix, with its empty list field
+// filled by the n items written by item, each indented one level deeper
+func writeJSONList(w io.Writer, v any, prefix, field string, n int, item func(i int, prefix string) error) error {
+	data, err := json.MarshalIndent(v, prefix, "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run report: %w", err)
+	}
+	if n == 0 {
+		_, err = w.Write(data)
+		return err
+	}
+	// Strings cannot contain the field name followed by an unescaped quote
+	empty := []byte(fmt.Sprintf("%q: []", field))
+	at := bytes.Index(data, empty)
+	if at < 0 {
+		return fmt.Errorf("failed to marshal run report: no %s list", field)
+	}
+	at += len(empty) - 1
+
+	if _, err := w.Write(data[:at]); err != nil {
+		return err
+	}
+	itemPrefix := prefix + "    "
+	for i := 0; i < n; i++ {
+		sep := ",\n"
+		if i == 0 {
+			sep = "\n"
+		}
+		if _, err := io.WriteString(w, sep+itemPrefix); err != nil {
+			return err
+		}
+		if err := item(i, itemPrefix); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "\n"+prefix+"  "); err != nil {
+		return err
+	}
+	_, err = w.Write(data[at:])
+	return err
+}
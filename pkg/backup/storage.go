@@ -0,0 +1,83 @@
+package winbackupchecker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StorageType is the kind of storage a backup path is on. It sets how many
+// backup sets and files are read at once when neither the command line nor
+// the config does: parallel reads make a spinning disk seek back and forth,
+// but keep an SSD busy.
+type StorageType string
+
+const (
+	StorageUnknown StorageType = ""
+	StorageHDD     StorageType = "hdd"
+	StorageSSD     StorageType = "ssd"
+	StorageNetwork StorageType = "network"
+)
+
+// ParseStorageType parses a storage setting; empty and "auto" detect the
+// storage type
+func ParseStorageType(s string) (StorageType, error) {
+	switch t := StorageType(strings.ToLower(strings.TrimSpace(s))); t {
+	case "", "auto":
+		return StorageUnknown, nil
+	case StorageHDD, StorageSSD, StorageNetwork:
+		return t, nil
+	default:
+		return StorageUnknown, fmt.Errorf("unknown storage type %q: must be auto, hdd, ssd or network", s)
+	}
+}
+
+// String returns the type, or "unknown"
+func (t StorageType) String() string {
+	if t == StorageUnknown {
+		return "unknown"
+	}
+	return string(t)
+}
+
+// Workers returns the default number of backup sets validated at once on t,
+// and of files read at once within each set
+func (t StorageType) Workers() (sets, files int) {
+	switch t {
+	case StorageHDD:
+		return 1, 1
+	case StorageSSD:
+		return 8, 2
+	default:
+		return 4, 1
+	}
+}
+
+// DetectStorageType returns the type of storage path is on, or
+// StorageUnknown when it cannot be told. USB bridges often report SSDs as
+// spinning disks, which only makes them read one file at a time.
+func DetectStorageType(path string) StorageType {
+	path = NormalizePath(path)
+	if isRemotePath(path) {
+		return StorageNetwork
+	}
+	return detectStorageType(path)
+}
+
+// isUNCPath reports whether path names a network share directly
+func isUNCPath(path string) bool {
+	return strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, "//")
+}
+
+// ForStorage returns o with the set and file workers left at zero set to
+// the defaults of storage
+func (o ScanOptions) ForStorage(storage StorageType) ScanOptions {
+	o.Storage = storage
+	sets, files := storage.Workers()
+	if o.MaxWorkers <= 0 {
+		o.MaxWorkers = sets
+	}
+	if o.FileWorkers <= 0 {
+		o.FileWorkers = files
+	}
+	return o
+}
@@ -0,0 +1,33 @@
+//go:build darwin
+
+package winbackupchecker
+
+import "syscall"
+
+// networkFilesystems are the file system names of network mounts
+var networkFilesystems = map[string]bool{
+	"smbfs":  true,
+	"nfs":    true,
+	"afpfs":  true,
+	"webdav": true,
+}
+
+// detectStorageType tells network mounts apart; local disks are unknown, as
+// macOS only reports whether they rotate through IOKit
+func detectStorageType(path string) StorageType {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return StorageUnknown
+	}
+	name := make([]byte, 0, len(fs.Fstypename))
+	for _, c := range fs.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	if networkFilesystems[string(name)] {
+		return StorageNetwork
+	}
+	return StorageUnknown
+}
@@ -0,0 +1,55 @@
+//go:build linux
+
+package winbackupchecker
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// networkFilesystems are the statfs magic numbers of NFS, SMB and FUSE
+// mounts such as sshfs and rclone
+var networkFilesystems = map[uint32]bool{
+	0x6969:     true, // nfs
+	0x517B:     true, // smbfs
+	0xFF534D42: true, // cifs
+	0xFE534D42: true, // smb2
+	0x65735546: true, // fuse
+}
+
+// detectStorageType looks at the file system holding path: network file
+// systems are network storage, and block devices are spinning disks when
+// the kernel marks their queue rotational
+func detectStorageType(path string) StorageType {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return StorageUnknown
+	}
+	if networkFilesystems[uint32(fs.Type)] {
+		return StorageNetwork
+	}
+
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return StorageUnknown
+	}
+	dev := uint64(st.Dev)
+	major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor := dev&0xff | (dev>>12)&^0xff
+
+	// A partition has no queue of its own; its disk is the parent
+	device := fmt.Sprintf("/sys/dev/block/%d:%d", major, minor)
+	data, err := os.ReadFile(device + "/queue/rotational")
+	if err != nil {
+		data, err = os.ReadFile(device + "/../queue/rotational")
+	}
+	if err != nil {
+		return StorageUnknown
+	}
+	if strings.TrimSpace(string(data)) == "1" {
+		return StorageHDD
+	}
+	return StorageSSD
+}
@@ -0,0 +1,8 @@
+//go:build !windows && !linux && !darwin
+
+package winbackupchecker
+
+// detectStorageType cannot tell storage types apart on this platform
+func detectStorageType(path string) StorageType {
+	return StorageUnknown
+}
@@ -0,0 +1,82 @@
+//go:build windows
+
+package winbackupchecker
+
+import (
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	driveRemote = 4
+
+	ioctlStorageQueryProperty        = 0x2D1400
+	storageDeviceSeekPenaltyProperty = 7
+)
+
+// storagePropertyQuery is a STORAGE_PROPERTY_QUERY for a standard query
+type storagePropertyQuery struct {
+	PropertyID           uint32
+	QueryType            uint32
+	AdditionalParameters [1]byte
+}
+
+// deviceSeekPenaltyDescriptor is a DEVICE_SEEK_PENALTY_DESCRIPTOR
+type deviceSeekPenaltyDescriptor struct {
+	Version           uint32
+	Size              uint32
+	IncursSeekPenalty byte
+}
+
+// detectStorageType looks at the drive holding path: network drives are
+// network storage, and local drives are spinning disks when the device
+// reports a seek penalty
+func detectStorageType(path string) StorageType {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return StorageUnknown
+	}
+	volume := filepath.VolumeName(abs)
+	if len(volume) != 2 || volume[1] != ':' {
+		return StorageUnknown
+	}
+
+	root, err := syscall.UTF16PtrFromString(volume + `\`)
+	if err != nil {
+		return StorageUnknown
+	}
+	driveType, _, _ := procGetDriveTypeW.Call(uintptr(unsafe.Pointer(root)))
+	switch driveType {
+	case driveRemote:
+		return StorageNetwork
+	case driveFixed, driveRemovable:
+	default:
+		return StorageUnknown
+	}
+
+	device, err := syscall.UTF16PtrFromString(`\\.\` + volume)
+	if err != nil {
+		return StorageUnknown
+	}
+	// Querying properties needs no access rights, so no elevation
+	handle, err := syscall.CreateFile(device, 0,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE, nil, syscall.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return StorageUnknown
+	}
+	defer syscall.CloseHandle(handle)
+
+	query := storagePropertyQuery{PropertyID: storageDeviceSeekPenaltyProperty}
+	var penalty deviceSeekPenaltyDescriptor
+	var returned uint32
+	if err := syscall.DeviceIoControl(handle, ioctlStorageQueryProperty,
+		(*byte)(unsafe.Pointer(&query)), uint32(unsafe.Sizeof(query)),
+		(*byte)(unsafe.Pointer(&penalty)), uint32(unsafe.Sizeof(penalty)), &returned, nil); err != nil {
+		return StorageUnknown
+	}
+	if penalty.IncursSeekPenalty != 0 {
+		return StorageHDD
+	}
+	return StorageSSD
+}
@@ -0,0 +1,176 @@
+package winbackupchecker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// telegramAPIURL is the Telegram Bot API base URL
+const telegramAPIURL = "https://api.telegram.org"
+
+// telegramMessageLimit is Telegram's maximum message length
+const telegramMessageLimit = 4096
+
+// TelegramConfig configures a Telegram bot notifier
+type TelegramConfig struct {
+	Enabled      bool     `json:"enabled"`
+	BotToken     string   `json:"bot_token" secret:"true"`
+	ChatIDs      []string `json:"chat_ids"`
+	AttachReport bool     `json:"attach_report"`
+	APIURL       string   `json:"api_url,omitempty"`
+	NotifyTriggers
+}
+
+// Validate checks if Telegram configuration is valid
+func (c *TelegramConfig) Validate() error {
+	if c.BotToken == "" {
+		return fmt.Errorf("bot_token is required")
+	}
+	if len(c.ChatIDs) == 0 {
+		return fmt.Errorf("at least one chat_ids entry is required")
+	}
+	return c.NotifyTriggers.Validate()
+}
+
+// TelegramNotifier sends run summaries through a Telegram bot
+type TelegramNotifier struct {
+	cfg *TelegramConfig
+}
+
+func (n *TelegramNotifier) Name() string { return "telegram" }
+
+// Notify sends the summary to every chat, attaching the JSON report as a
+// document when the run has errors and attach_report is set
+func (n *TelegramNotifier) Notify(ctx context.Context, report RunReport) error {
+	status := NewRunStatus(report.Summary, report.Results)
+	if !n.cfg.ShouldSend(status) {
+		return nil
+	}
+
+	text := telegramMessage(report, status)
+
+	var attachment []byte
+	if n.cfg.AttachReport && status.HasErrors {
+		var err error
+		if attachment, err = json.MarshalIndent(report, "", "  "); err != nil {
+			return fmt.Errorf("failed to marshal report: %w", err)
+		}
+	}
+
+	var errs []string
+	for _, chatID := range n.cfg.ChatIDs {
+		payload := map[string]any{
+			"chat_id":    chatID,
+			"text":       text,
+			"parse_mode": "HTML",
+		}
+		if err := postJSON(ctx, n.methodURL("sendMessage"), payload, nil); err != nil {
+			errs = append(errs, fmt.Sprintf("chat %s: %v", chatID, n.redact(err)))
+			continue
+		}
+
+		if attachment != nil {
+			if err := n.sendDocument(ctx, chatID, "backup-report.json", attachment); err != nil {
+				errs = append(errs, fmt.Sprintf("chat %s: failed to attach report: %v", chatID, n.redact(err)))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to send Telegram message: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// methodURL returns the Bot API URL for method
+func (n *TelegramNotifier) methodURL(method string) string {
+	base := n.cfg.APIURL
+	if base == "" {
+		base = telegramAPIURL
+	}
+	return fmt.Sprintf("%s/bot%s/%s", strings.TrimRight(base, "/"), n.cfg.BotToken, method)
+}
+
+// redact removes the bot token from errors, since request errors include the
+// URL and the token grants full control of the bot
+func (n *TelegramNotifier) redact(err error) string {
+	return strings.ReplaceAll(err.Error(), n.cfg.BotToken, "<bot_token>")
+}
+
+// sendDocument uploads data as a file to a chat
+func (n *TelegramNotifier) sendDocument(ctx context.Context, chatID, fileName string, data []byte) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("chat_id", chatID); err != nil {
+		return err
+	}
+	part, err := w.CreateFormFile("document", fileName)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notificationTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.methodURL("sendDocument"), &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(msg))}
+	}
+	return nil
+}
+
+// telegramMessage formats the run summary as Telegram HTML, listing issues
+// per backup set until the message length limit is reached
+func telegramMessage(report RunReport, status RunStatus) string {
+	summary := report.Summary
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<b>Backup Validation: %s</b>\n", html.EscapeString(status.Label()))
+	fmt.Fprintf(&b, "Total: %d | Valid: %d | Invalid: %d | Failed scans: %d\n",
+		summary.TotalBackups, summary.ValidBackups, summary.InvalidBackups, summary.FailedScans)
+
+	sets := reportsWithIssues(report)
+	for i, br := range sets {
+		var section strings.Builder
+		fmt.Fprintf(&section, "\n<b>%s</b> (%s)\n",
+			html.EscapeString(filepath.Base(br.BackupDir)), html.EscapeString(MachineName(br.BackupDir)))
+		for _, issue := range br.Issues {
+			fmt.Fprintf(&section, "• [%s] %s\n",
+				strings.ToUpper(issue.Severity.String()), html.EscapeString(issue.Message))
+		}
+
+		if b.Len()+section.Len() > telegramMessageLimit-64 {
+			fmt.Fprintf(&b, "\n…and %d more backup sets with issues", len(sets)-i)
+			break
+		}
+		b.WriteString(section.String())
+	}
+
+	return b.String()
+}
@@ -0,0 +1,232 @@
+package winbackupchecker
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ThrottleConfig limits how fast backup data is read, so deep validation
+// leaves bandwidth to other backups and users of a NAS or USB drive
+type ThrottleConfig struct {
+	// BytesPerSecond is a size such as "20MB"; empty or "0" is unlimited
+	BytesPerSecond string `json:"bytes_per_second,omitempty"`
+
+	// IOPS limits read operations per second; 0 is unlimited
+	IOPS int `json:"iops,omitempty"`
+}
+
+// Validate checks the rate and the operation limit
+func (c *ThrottleConfig) Validate() error {
+	if _, err := ParseByteSize(c.BytesPerSecond); err != nil {
+		return fmt.Errorf("invalid bytes_per_second: %w", err)
+	}
+	if c.IOPS < 0 {
+		return fmt.Errorf("iops cannot be negative")
+	}
+	return nil
+}
+
+// Throttle limits reads to a number of bytes and of read operations per
+// second, allowing bursts of up to one second's worth. A nil Throttle is
+// unlimited.
+type Throttle struct {
+	mu    sync.Mutex
+	bytes tokenBucket
+	ops   tokenBucket
+}
+
+// NewThrottle returns a throttle for the limits, or nil when both are zero
+func NewThrottle(bytesPerSecond int64, iops int) *Throttle {
+	if bytesPerSecond <= 0 && iops <= 0 {
+		return nil
+	}
+	return &Throttle{
+		bytes: tokenBucket{rate: float64(bytesPerSecond)},
+		ops:   tokenBucket{rate: float64(iops)},
+	}
+}
+
+// NewThrottleFromConfig returns the throttle configured by cfg, or nil
+func NewThrottleFromConfig(cfg *ThrottleConfig) (*Throttle, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	rate, err := ParseByteSize(cfg.BytesPerSecond)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bytes_per_second: %w", err)
+	}
+	return NewThrottle(rate, cfg.IOPS), nil
+}
+
+// String describes the limits, e.g. "20.0 MB/s, 200 IOPS"
+func (t *Throttle) String() string {
+	if t == nil {
+		return "unlimited"
+	}
+	switch {
+	case t.bytes.rate > 0 && t.ops.rate > 0:
+		return fmt.Sprintf("%s/s, %.0f IOPS", formatByteSize(int64(t.bytes.rate)), t.ops.rate)
+	case t.bytes.rate > 0:
+		return formatByteSize(int64(t.bytes.rate)) + "/s"
+	default:
+		return fmt.Sprintf("%.0f IOPS", t.ops.rate)
+	}
+}
+
+// wait blocks until ops read operations and n bytes fit within the limits,
+// or ctx ends
+func (t *Throttle) wait(ctx context.Context, ops, n int) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	now := time.Now()
+	delay := max(t.ops.take(float64(ops), now), t.bytes.take(float64(n), now))
+	t.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// tokenBucket refills at rate tokens per second, holding at most one
+// second's worth. A zero rate never runs out.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// take removes n tokens and returns how long until the balance is no longer
+// negative
+func (b *tokenBucket) take(n float64, now time.Time) time.Duration {
+	if b.rate <= 0 || n == 0 {
+		return 0
+	}
+	if b.last.IsZero() {
+		b.tokens = b.rate
+	} else {
+		b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, b.rate)
+	}
+	b.last = now
+
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// readThrottle limits the reads of backup data by the whole process, since
+// the storage it protects is shared by every scan
+var readThrottle atomic.Pointer[Throttle]
+
+// SetReadThrottle applies t to all following reads of backup files; nil
+// removes the limit
+func SetReadThrottle(t *Throttle) {
+	readThrottle.Store(t)
+}
+
+// backupFile is a file of a backup set whose reads count against the read
+// throttle and fail once ctx ends, so a timeout interrupts even a single
+// huge file. It wraps rather than embeds the file, so io.Copy cannot bypass
+// Read through the file's own WriteTo.
+type backupFile struct {
+	ctx      context.Context
+	file     readableFile
+	throttle *Throttle
+	counter  *ioCounter
+}
+
+// openBackupFile opens a zip, catalog or other file of a backup set for
+// reading until ctx ends, through the read throttle. Reads count in the IO
+// statistics of the set validated with ctx.
+func openBackupFile(ctx context.Context, path string) (*backupFile, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	file, err := openBackupPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &backupFile{ctx: ctx, file: file, throttle: readThrottle.Load(), counter: ioCounterFrom(ctx)}, nil
+}
+
+func (f *backupFile) Read(p []byte) (int, error) {
+	if err := f.before(); err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	n, err := f.file.Read(p)
+	f.counter.add(n, time.Since(start))
+	if waitErr := f.throttle.wait(f.ctx, 0, n); waitErr != nil && err == nil {
+		err = waitErr
+	}
+	return n, err
+}
+
+func (f *backupFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.before(); err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	n, err := f.file.ReadAt(p, off)
+	f.counter.add(n, time.Since(start))
+	if waitErr := f.throttle.wait(f.ctx, 0, n); waitErr != nil && err == nil {
+		err = waitErr
+	}
+	return n, err
+}
+
+// before checks ctx and takes a read operation from the throttle
+func (f *backupFile) before() error {
+	if err := f.ctx.Err(); err != nil {
+		return err
+	}
+	return f.throttle.wait(f.ctx, 1, 0)
+}
+
+func (f *backupFile) Seek(offset int64, whence int) (int64, error) {
+	return f.file.Seek(offset, whence)
+}
+
+func (f *backupFile) Stat() (os.FileInfo, error) {
+	return f.file.Stat()
+}
+
+func (f *backupFile) Close() error {
+	return f.file.Close()
+}
+
+// openZip opens a zip file like zip.OpenReader, reading it as a backupFile
+func openZip(ctx context.Context, path string) (*zip.Reader, io.Closer, error) {
+	file, err := openBackupFile(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	r, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return r, file, nil
+}
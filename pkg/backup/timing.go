@@ -0,0 +1,129 @@
+package winbackupchecker
+
+import (
+	"sort"
+	"time"
+)
+
+// Validation phases recorded in PhaseTimings
+const (
+	PhaseActiveJob        = "active_job"
+	PhaseSnapshot         = "snapshot"
+	PhaseDiscovery        = "discovery"
+	PhaseIndex            = "index"
+	PhaseStructure        = "structure"
+	PhaseCompleteness     = "completeness"
+	PhaseContent          = "content"
+	PhaseManifests        = "manifests"
+	PhaseAge              = "age"
+	PhaseRequiredPaths    = "required_paths"
+	PhaseForbiddenContent = "forbidden_content"
+	PhaseInsights         = "insights"
+	PhaseParity           = "parity"
+	PhaseSampleRestore    = "sample_restore"
+	PhaseRecycleBin       = "recycle_bin"
+	PhaseUSNJournal       = "usn_journal"
+)
+
+// PhaseTimings records the milliseconds spent in each validation phase
+type PhaseTimings map[string]float64
+
+// Add records d against phase
+func (p PhaseTimings) Add(phase string, d time.Duration) {
+	p[phase] += float64(d.Microseconds()) / 1000
+}
+
+// Since records the time elapsed since start against phase
+func (p PhaseTimings) Since(phase string, start time.Time) {
+	p.Add(phase, time.Since(start))
+}
+
+// Merge adds every phase of other into p
+func (p PhaseTimings) Merge(other PhaseTimings) {
+	for phase, ms := range other {
+		p[phase] += ms
+	}
+}
+
+// Total returns the milliseconds recorded across all phases
+func (p PhaseTimings) Total() float64 {
+	var total float64
+	for _, ms := range p {
+		total += ms
+	}
+	return total
+}
+
+// Phases returns the recorded phase names, slowest first
+func (p PhaseTimings) Phases() []string {
+	phases := make([]string, 0, len(p))
+	for phase := range p {
+		phases = append(phases, phase)
+	}
+	sort.Slice(phases, func(i, j int) bool {
+		if p[phases[i]] != p[phases[j]] {
+			return p[phases[i]] > p[phases[j]]
+		}
+		return phases[i] < phases[j]
+	})
+	return phases
+}
+
+// TotalPhaseTimings sums root-level and per-set phase timings for a run
+func TotalPhaseTimings(results []ScanReport) PhaseTimings {
+	total := PhaseTimings{}
+	for _, scanReport := range results {
+		total.Merge(scanReport.PhaseTimings)
+		for _, br := range scanReport.Reports {
+			total.Merge(br.ValidationStats.PhaseTimings)
+		}
+	}
+	return total
+}
+
+// PhaseBenchmark summarizes how long one validation phase took across the
+// backup sets of a run
+type PhaseBenchmark struct {
+	Phase      string  `json:"phase"`
+	Sets       int     `json:"sets"`
+	TotalMs    float64 `json:"total_ms"`
+	AverageMs  float64 `json:"average_ms"`
+	MaxMs      float64 `json:"max_ms"`
+	SlowestSet string  `json:"slowest_set"`
+}
+
+// BenchmarkPhases summarizes the per-set phase timings of a run, slowest
+// total first
+func BenchmarkPhases(results []ScanReport) []PhaseBenchmark {
+	byPhase := make(map[string]*PhaseBenchmark)
+	for _, scanReport := range results {
+		for _, br := range scanReport.Reports {
+			for phase, ms := range br.ValidationStats.PhaseTimings {
+				bench, ok := byPhase[phase]
+				if !ok {
+					bench = &PhaseBenchmark{Phase: phase}
+					byPhase[phase] = bench
+				}
+				bench.Sets++
+				bench.TotalMs += ms
+				if ms > bench.MaxMs || bench.SlowestSet == "" {
+					bench.MaxMs = ms
+					bench.SlowestSet = br.BackupDir
+				}
+			}
+		}
+	}
+
+	benchmarks := make([]PhaseBenchmark, 0, len(byPhase))
+	for _, bench := range byPhase {
+		bench.AverageMs = bench.TotalMs / float64(bench.Sets)
+		benchmarks = append(benchmarks, *bench)
+	}
+	sort.Slice(benchmarks, func(i, j int) bool {
+		if benchmarks[i].TotalMs != benchmarks[j].TotalMs {
+			return benchmarks[i].TotalMs > benchmarks[j].TotalMs
+		}
+		return benchmarks[i].Phase < benchmarks[j].Phase
+	})
+	return benchmarks
+}
@@ -0,0 +1,153 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// twilioAPIURL is the Twilio REST API base URL
+const twilioAPIURL = "https://api.twilio.com"
+
+// twilioMaxBody keeps messages within a single 160-character SMS segment
+const twilioMaxBody = 160
+
+// TwilioConfig configures SMS alerts sent through Twilio
+type TwilioConfig struct {
+	Enabled     bool     `json:"enabled"`
+	AccountSID  string   `json:"account_sid"`
+	AuthToken   string   `json:"auth_token" secret:"true"`
+	From        string   `json:"from"`
+	To          []string `json:"to"`
+	MinSeverity string   `json:"min_severity,omitempty"`
+	APIURL      string   `json:"api_url,omitempty"`
+}
+
+// Validate checks if Twilio configuration is valid
+func (c *TwilioConfig) Validate() error {
+	if c.AccountSID == "" {
+		return fmt.Errorf("account_sid is required")
+	}
+	if c.AuthToken == "" {
+		return fmt.Errorf("auth_token is required")
+	}
+	if c.From == "" {
+		return fmt.Errorf("from is required")
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("at least one to number is required")
+	}
+	return c.triggers().Validate()
+}
+
+// triggers are the fixed triggers of SMS alerts: errors only, of at least
+// min_severity
+func (c *TwilioConfig) triggers() NotifyTriggers {
+	return NotifyTriggers{SendOnErrors: true, MinSeverity: c.MinSeverity}
+}
+
+// TwilioNotifier texts a one-line summary to each number when a run has errors
+type TwilioNotifier struct {
+	cfg *TwilioConfig
+}
+
+func (n *TwilioNotifier) Name() string { return "twilio" }
+
+// Notify sends the SMS only for runs with errors of at least min_severity;
+// SMS is for problems that need attention, not routine reports
+func (n *TwilioNotifier) Notify(ctx context.Context, report RunReport) error {
+	if !n.cfg.triggers().ShouldSend(NewRunStatus(report.Summary, report.Results)) {
+		return nil
+	}
+
+	base := n.cfg.APIURL
+	if base == "" {
+		base = twilioAPIURL
+	}
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json",
+		strings.TrimRight(base, "/"), url.PathEscape(n.cfg.AccountSID))
+	body := smsSummary(report)
+
+	var errs []string
+	for _, to := range n.cfg.To {
+		form := url.Values{}
+		form.Set("From", n.cfg.From)
+		form.Set("To", to)
+		form.Set("Body", body)
+
+		if err := n.post(ctx, endpoint, form); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", to, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to send SMS: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (n *TwilioNotifier) post(ctx context.Context, endpoint string, form url.Values) error {
+	ctx, cancel := context.WithTimeout(ctx, notificationTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(n.cfg.AccountSID, n.cfg.AuthToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(msg))}
+	}
+	return nil
+}
+
+// smsSummary returns a terse one-line summary naming the affected machines
+func smsSummary(report RunReport) string {
+	summary := report.Summary
+	text := fmt.Sprintf("Backup check FAILED: %d of %d invalid", summary.InvalidBackups, summary.TotalBackups)
+	if summary.FailedScans > 0 {
+		text += fmt.Sprintf(", %d scan(s) failed", summary.FailedScans)
+	}
+
+	seen := make(map[string]bool)
+	var machines []string
+	for _, scanReport := range report.Results {
+		for _, br := range scanReport.Reports {
+			name := MachineName(br.BackupDir)
+			if !br.Valid && !seen[name] {
+				seen[name] = true
+				machines = append(machines, name)
+			}
+		}
+	}
+	sort.Strings(machines)
+
+	// Leave room to note how many machines did not fit
+	limit := twilioMaxBody - len(" +99 more")
+	for i, machine := range machines {
+		sep := ", "
+		if i == 0 {
+			sep = " - "
+		}
+		if len(text)+len(sep)+len(machine) > limit {
+			text += fmt.Sprintf(" +%d more", len(machines)-i)
+			break
+		}
+		text += sep + machine
+	}
+
+	return text
+}
@@ -0,0 +1,290 @@
+package winbackupchecker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// USNJournalConfig enables tamper detection through the NTFS change journal.
+// Changes to backup data made outside the backup windows are reported.
+type USNJournalConfig struct {
+	Enabled       bool     `json:"enabled"`
+	BackupWindows []string `json:"backup_windows"`
+	StateFile     string   `json:"state_file,omitempty"`
+}
+
+// Validate checks if USN journal configuration is valid
+func (c *USNJournalConfig) Validate() error {
+	if len(c.BackupWindows) == 0 {
+		return fmt.Errorf("backup_windows is required")
+	}
+	for _, window := range c.BackupWindows {
+		if _, err := parseBackupWindow(window); err != nil {
+			return fmt.Errorf("invalid backup window %q: %w", window, err)
+		}
+	}
+	return nil
+}
+
+// backupWindow is a daily time range, optionally limited to some weekdays.
+// A window ending before it starts runs past midnight.
+type backupWindow struct {
+	days  map[time.Weekday]bool
+	start time.Duration
+	end   time.Duration
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseBackupWindow parses "22:00-04:00" or "Sat,Sun 01:00-05:00"
+func parseBackupWindow(s string) (backupWindow, error) {
+	window := backupWindow{}
+
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+	case 2:
+		window.days = make(map[time.Weekday]bool)
+		for _, name := range strings.Split(fields[0], ",") {
+			day, ok := weekdayNames[strings.ToLower(name)]
+			if !ok {
+				return window, fmt.Errorf("unknown weekday %q", name)
+			}
+			window.days[day] = true
+		}
+		fields = fields[1:]
+	default:
+		return window, fmt.Errorf("expected [days] HH:MM-HH:MM")
+	}
+
+	start, end, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return window, fmt.Errorf("expected HH:MM-HH:MM")
+	}
+	var err error
+	if window.start, err = parseClock(start); err != nil {
+		return window, err
+	}
+	if window.end, err = parseClock(end); err != nil {
+		return window, err
+	}
+	return window, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	h, herr := strconv.Atoi(hh)
+	m, merr := strconv.Atoi(mm)
+	if !ok || herr != nil || merr != nil || h < 0 || h > 24 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// contains reports whether t (in local time) falls inside the window
+func (w backupWindow) contains(t time.Time) bool {
+	t = t.Local()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+
+	if w.start <= w.end {
+		return w.onDay(t.Weekday()) && offset >= w.start && offset < w.end
+	}
+
+	// Past midnight: the window belongs to the day it started on
+	if offset >= w.start {
+		return w.onDay(t.Weekday())
+	}
+	return offset < w.end && w.onDay(midnight.AddDate(0, 0, -1).Weekday())
+}
+
+func (w backupWindow) onDay(day time.Weekday) bool {
+	return w.days == nil || w.days[day]
+}
+
+// usnChange is a journaled change to a file below a backup root
+type usnChange struct {
+	Path    string
+	Reasons []string
+	Time    time.Time
+}
+
+// usnJournalState is the journal position reached by the previous run
+type usnJournalState struct {
+	JournalID uint64 `json:"journal_id"`
+	NextUSN   int64  `json:"next_usn"`
+}
+
+// usnReadResult is what reading the journal since the saved state found
+type usnReadResult struct {
+	State   usnJournalState
+	Changes []usnChange
+
+	// Reset is set when the journal was recreated or wrapped since the
+	// previous run, so some changes may be missing
+	Reset bool
+}
+
+// usnMaxListed caps the changes named in one issue
+const usnMaxListed = 5
+
+// checkUSNJournal reports changes to backup data recorded in the change
+// journal since the previous run that fall outside every backup window
+func checkUSNJournal(root string, backupSets []BackupSetInfo, reports []BackupReport, opts ScanOptions) []BackupReport {
+	cfg := opts.USNJournal
+
+	statePath := cfg.StateFile
+	if statePath == "" {
+		statePath = "usn-state.json"
+	}
+
+	states, err := loadUSNState(statePath)
+	if err != nil {
+		opts.logf("Warning: %v\n", err)
+		return reports
+	}
+
+	absRoot, _ := filepath.Abs(root)
+	previous, seen := states[absRoot]
+
+	result, err := readUSNChanges(absRoot, previous, seen)
+	if err != nil {
+		return append(reports, BackupReport{
+			BackupDir: root,
+			Valid:     true,
+			Issues: []ValidationIssue{newIssue(IssueUSNJournalUnreadable, SeverityWarning,
+				msg("cannot read USN change journal: %v", err),
+				root,
+				"USN monitoring needs a local NTFS volume and administrator rights")},
+			CheckedAt: NowRFC3339(),
+		})
+	}
+
+	states[absRoot] = result.State
+	if err := saveUSNState(statePath, states); err != nil {
+		opts.logf("Warning: %v\n", err)
+	}
+
+	var windows []backupWindow
+	for _, s := range cfg.BackupWindows {
+		window, _ := parseBackupWindow(s)
+		windows = append(windows, window)
+	}
+
+	bySet := make(map[int][]usnChange)
+	for _, change := range result.Changes {
+		if inAnyWindow(windows, change.Time) || isCheckerOutput(change.Path) {
+			continue
+		}
+		owner := -1
+		for i, set := range backupSets {
+			if change.Path == set.Path || strings.HasPrefix(change.Path, set.Path+string(filepath.Separator)) {
+				owner = i
+				break
+			}
+		}
+		bySet[owner] = append(bySet[owner], change)
+	}
+
+	var rootIssues []ValidationIssue
+	if result.Reset {
+		rootIssues = append(rootIssues, newIssue(IssueUSNJournalReset, SeverityWarning,
+			msg("USN change journal was reset or wrapped since the previous run; some changes to backup data cannot be verified"),
+			root,
+			"increase the journal size with fsutil usn createjournal, or run the checker more often"))
+	}
+
+	for owner, changes := range bySet {
+		issue := usnChangesIssue(changes, root)
+		if owner >= 0 {
+			reports[owner].addIssues(issue)
+			continue
+		}
+		rootIssues = append(rootIssues, issue)
+	}
+
+	if len(rootIssues) > 0 {
+		report := BackupReport{BackupDir: root, Valid: true, CheckedAt: NowRFC3339()}
+		report.addIssues(rootIssues...)
+		reports = append(reports, report)
+	}
+
+	return reports
+}
+
+func inAnyWindow(windows []backupWindow, t time.Time) bool {
+	for _, window := range windows {
+		if window.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCheckerOutput reports whether path is written by the checker itself
+func isCheckerOutput(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".par2")
+}
+
+func usnChangesIssue(changes []usnChange, root string) ValidationIssue {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Time.After(changes[j].Time)
+	})
+
+	listed := make([]string, 0, usnMaxListed)
+	more := msg("")
+	for i, change := range changes {
+		if i == usnMaxListed {
+			more = msg(" and %d more", len(changes)-usnMaxListed)
+			break
+		}
+		rel, err := filepath.Rel(root, change.Path)
+		if err != nil {
+			rel = change.Path
+		}
+		listed = append(listed, fmt.Sprintf("%s %s at %s",
+			rel, strings.Join(change.Reasons, "+"), change.Time.Local().Format(time.RFC3339)))
+	}
+
+	return newIssue(IssueChangesOutsideWindow, SeverityError,
+		msg("%d change(s) to backup data outside the backup windows: %s%s", len(changes), strings.Join(listed, "; "), more),
+		root,
+		"nothing but the backup engine should modify backup data; check who or what changed these files")
+}
+
+func loadUSNState(path string) (map[string]usnJournalState, error) {
+	states := make(map[string]usnJournalState)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return states, nil
+		}
+		return nil, fmt.Errorf("failed to read USN state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to parse USN state: %w", err)
+	}
+	return states, nil
+}
+
+func saveUSNState(path string, states map[string]usnJournalState) error {
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal USN state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write USN state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package winbackupchecker
+
+import "fmt"
+
+// readUSNChanges is only supported on Windows, where the NTFS change
+// journal can be queried
+func readUSNChanges(root string, previous usnJournalState, seen bool) (usnReadResult, error) {
+	return usnReadResult{}, fmt.Errorf("the USN change journal is only available on Windows")
+}
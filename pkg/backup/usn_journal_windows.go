@@ -0,0 +1,229 @@
+//go:build windows
+
+package winbackupchecker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// Change journal control codes and record reasons from winioctl.h
+const (
+	fsctlQueryUSNJournal = 0x000900f4
+	fsctlReadUSNJournal  = 0x000900bb
+
+	usnReasonDataOverwrite    = 0x00000001
+	usnReasonDataExtend       = 0x00000002
+	usnReasonDataTruncation   = 0x00000004
+	usnReasonNamedDataChanged = 0x00000070
+	usnReasonFileCreate       = 0x00000100
+	usnReasonFileDelete       = 0x00000200
+	usnReasonRenameOldName    = 0x00001000
+	usnReasonRenameNewName    = 0x00002000
+	usnReasonSecurityChange   = 0x00000800
+)
+
+// usnReasonNames lists the reasons that count as a change to backup data
+var usnReasonNames = []struct {
+	mask uint32
+	name string
+}{
+	{usnReasonFileCreate, "create"},
+	{usnReasonFileDelete, "delete"},
+	{usnReasonDataOverwrite | usnReasonDataExtend | usnReasonDataTruncation | usnReasonNamedDataChanged, "write"},
+	{usnReasonRenameOldName | usnReasonRenameNewName, "rename"},
+	{usnReasonSecurityChange, "permissions"},
+}
+
+// usnJournalData mirrors USN_JOURNAL_DATA_V0
+type usnJournalData struct {
+	JournalID       uint64
+	FirstUSN        int64
+	NextUSN         int64
+	LowestValidUSN  int64
+	MaxUSN          int64
+	MaximumSize     uint64
+	AllocationDelta uint64
+}
+
+// readUSNJournalData mirrors READ_USN_JOURNAL_DATA_V0
+type readUSNJournalData struct {
+	StartUSN          int64
+	ReasonMask        uint32
+	ReturnOnlyOnClose uint32
+	Timeout           uint64
+	BytesToWaitFor    uint64
+	JournalID         uint64
+}
+
+// readUSNChanges reads the change journal of the volume holding root from
+// the previous position, keeping changes to files below root. On the first
+// run it only records the current position.
+func readUSNChanges(root string, previous usnJournalState, seen bool) (usnReadResult, error) {
+	volume := filepath.VolumeName(root)
+	if len(volume) != 2 || volume[1] != ':' {
+		return usnReadResult{}, fmt.Errorf("%s is not on a local drive", root)
+	}
+
+	path, err := syscall.UTF16PtrFromString(`\\.\` + volume)
+	if err != nil {
+		return usnReadResult{}, err
+	}
+	handle, err := syscall.CreateFile(path, syscall.GENERIC_READ,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE, nil, syscall.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return usnReadResult{}, fmt.Errorf("failed to open volume %s: %w", volume, err)
+	}
+	defer syscall.CloseHandle(handle)
+
+	var journal usnJournalData
+	var returned uint32
+	if err := syscall.DeviceIoControl(handle, fsctlQueryUSNJournal, nil, 0,
+		(*byte)(unsafe.Pointer(&journal)), uint32(unsafe.Sizeof(journal)), &returned, nil); err != nil {
+		return usnReadResult{}, fmt.Errorf("failed to query change journal on %s: %w", volume, err)
+	}
+
+	result := usnReadResult{State: usnJournalState{JournalID: journal.JournalID, NextUSN: journal.NextUSN}}
+	if !seen {
+		return result, nil
+	}
+
+	start := previous.NextUSN
+	if previous.JournalID != journal.JournalID || start < journal.FirstUSN {
+		// Records since the previous run are gone; read what is left
+		result.Reset = true
+		start = journal.FirstUSN
+	}
+
+	dirs, err := directoryIDs(root)
+	if err != nil {
+		return usnReadResult{}, err
+	}
+
+	var mask uint32
+	for _, reason := range usnReasonNames {
+		mask |= reason.mask
+	}
+
+	request := readUSNJournalData{StartUSN: start, ReasonMask: mask, JournalID: journal.JournalID}
+	buf := make([]byte, 64*1024)
+
+	for request.StartUSN < journal.NextUSN {
+		if err := syscall.DeviceIoControl(handle, fsctlReadUSNJournal,
+			(*byte)(unsafe.Pointer(&request)), uint32(unsafe.Sizeof(request)),
+			&buf[0], uint32(len(buf)), &returned, nil); err != nil {
+			return usnReadResult{}, fmt.Errorf("failed to read change journal on %s: %w", volume, err)
+		}
+		if returned < 8 {
+			break
+		}
+
+		next := int64(binary.LittleEndian.Uint64(buf[0:8]))
+		result.Changes = append(result.Changes, parseUSNRecords(buf[8:returned], dirs, journal.NextUSN)...)
+		if next <= request.StartUSN {
+			break
+		}
+		request.StartUSN = next
+	}
+
+	return result, nil
+}
+
+// parseUSNRecords decodes USN_RECORD_V2 entries, keeping those whose parent
+// directory is below the backup root and that precede stop
+func parseUSNRecords(data []byte, dirs map[uint64]string, stop int64) []usnChange {
+	var changes []usnChange
+
+	for len(data) >= 60 {
+		length := int(binary.LittleEndian.Uint32(data[0:4]))
+		if length < 60 || length > len(data) {
+			break
+		}
+		record := data[:length]
+		data = data[length:]
+
+		if binary.LittleEndian.Uint16(record[4:6]) != 2 {
+			continue
+		}
+		parent := binary.LittleEndian.Uint64(record[16:24])
+		usn := int64(binary.LittleEndian.Uint64(record[24:32]))
+		timestamp := binary.LittleEndian.Uint64(record[32:40])
+		reason := binary.LittleEndian.Uint32(record[40:44])
+		nameLen := int(binary.LittleEndian.Uint16(record[56:58]))
+		nameOff := int(binary.LittleEndian.Uint16(record[58:60]))
+
+		dir, ok := dirs[parent]
+		if !ok || usn >= stop || nameOff+nameLen > length {
+			continue
+		}
+
+		units := make([]uint16, nameLen/2)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(record[nameOff+2*i:])
+		}
+
+		var reasons []string
+		for _, r := range usnReasonNames {
+			if reason&r.mask != 0 {
+				reasons = append(reasons, r.name)
+			}
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+
+		changes = append(changes, usnChange{
+			Path:    filepath.Join(dir, syscall.UTF16ToString(units)),
+			Reasons: reasons,
+			Time:    filetimeToTime(timestamp),
+		})
+	}
+
+	return changes
+}
+
+// directoryIDs maps the NTFS file reference number of root and every
+// directory below it to its path
+func directoryIDs(root string) (map[uint64]string, error) {
+	dirs := make(map[uint64]string)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		id, err := fileID(path)
+		if err != nil {
+			return nil
+		}
+		dirs[id] = path
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index directories under %s: %w", root, err)
+	}
+	return dirs, nil
+}
+
+func fileID(path string) (uint64, error) {
+	p, err := syscall.UTF16PtrFromString(extendedPath(path))
+	if err != nil {
+		return 0, err
+	}
+	handle, err := syscall.CreateFile(p, 0,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.CloseHandle(handle)
+
+	var info syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(handle, &info); err != nil {
+		return 0, err
+	}
+	return uint64(info.FileIndexHigh)<<32 | uint64(info.FileIndexLow), nil
+}
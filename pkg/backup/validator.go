@@ -0,0 +1,163 @@
+package winbackupchecker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Validator checks one aspect of a backup set. The structure, completeness,
+// content and age checks are validators, and RegisterValidator adds custom
+// ones that run on every set after them.
+type Validator interface {
+	// Name identifies the validator in the checks config list, --checks,
+	// skipped_checks and the phase timings
+	Name() string
+
+	// Applies reports whether the validator has anything to check in the set
+	Applies(setInfo BackupSetInfo) bool
+
+	// Validate checks the set, stopping early once ctx ends
+	Validate(ctx context.Context, setInfo BackupSetInfo) []ValidationIssue
+}
+
+var (
+	registeredMu         sync.RWMutex
+	registeredValidators []Validator
+)
+
+// RegisterValidator adds v to the validators run on every backup set, after
+// the built-in ones. Call it before loading the config, e.g. from an init
+// function, so the checks list can select v by name. It panics if v is nil
+// or its name is already taken.
+func RegisterValidator(v Validator) {
+	if v == nil {
+		panic("winbackupchecker: RegisterValidator with a nil validator")
+	}
+	name := v.Name()
+	if name == "" || isCheck(name) || name == PhaseManifests {
+		panic(fmt.Sprintf("winbackupchecker: validator name %q is empty or already registered", name))
+	}
+
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	registeredValidators = append(registeredValidators, v)
+}
+
+// RegisteredValidators returns the validators added with RegisterValidator,
+// in the order they run
+func RegisteredValidators() []Validator {
+	registeredMu.RLock()
+	defer registeredMu.RUnlock()
+	return append([]Validator(nil), registeredValidators...)
+}
+
+// setValidators returns the validators run on a set, built-in ones first.
+// The built-in validators fill in stats as they run.
+func setValidators(opts ScanOptions, stats *ValidationStats) []Validator {
+	validators := []Validator{
+		validatorFunc{
+			name: CheckStructure,
+			validate: func(ctx context.Context, setInfo BackupSetInfo) []ValidationIssue {
+				issues := validateBackupStructure(setInfo)
+				stats.StructuralChecks = countPassedChecks(issues, SeverityCritical, SeverityError)
+				return issues
+			},
+		},
+		validatorFunc{
+			// Warnings only
+			name: CheckCompleteness,
+			validate: func(ctx context.Context, setInfo BackupSetInfo) []ValidationIssue {
+				return validateBackupCompleteness(setInfo, opts)
+			},
+		},
+		validatorFunc{
+			name: CheckContent,
+			validate: func(ctx context.Context, setInfo BackupSetInfo) []ValidationIssue {
+				issues, contentStats := validateBackupContent(ctx, setInfo, opts)
+				stats.ContentChecks = contentStats.ContentChecks
+				stats.ValidatedFiles = contentStats.ValidatedFiles
+				stats.CorruptFiles = contentStats.CorruptFiles
+				stats.CatalogEntries = contentStats.CatalogEntries
+				stats.ContentBreakdown = contentStats.ContentBreakdown
+				stats.ContentDeferred = contentStats.ContentDeferred
+				return issues
+			},
+		},
+		validatorFunc{
+			// Checksum manifests shipped by replication tools, which read
+			// the contents like the content check
+			name: PhaseManifests,
+			applies: func(setInfo BackupSetInfo) bool {
+				return opts.Manifests != nil && opts.Manifests.Enabled && len(setInfo.ManifestFiles) > 0 && !opts.archive && opts.runs(CheckContent)
+			},
+			validate: func(ctx context.Context, setInfo BackupSetInfo) []ValidationIssue {
+				manifestCfg := opts.Manifests
+				if opts.snapshotRoot != "" && manifestCfg.Repair {
+					// Snapshots are read-only; repairs wait for a live run
+					cfg := *manifestCfg
+					cfg.Repair = false
+					manifestCfg = &cfg
+				}
+				issues, verified := verifyManifests(ctx, setInfo, manifestCfg, opts)
+				stats.ManifestVerified = verified
+				return issues
+			},
+		},
+		validatorFunc{
+			// Time-based validation
+			name: CheckAge,
+			validate: func(ctx context.Context, setInfo BackupSetInfo) []ValidationIssue {
+				return validateBackupAge(setInfo, opts)
+			},
+		},
+	}
+	return append(validators, RegisteredValidators()...)
+}
+
+// runValidators runs the validators that apply to the set and are selected
+// by opts, in order, recording the time of each as its phase along with the
+// data it read
+func runValidators(ctx context.Context, setInfo BackupSetInfo, opts ScanOptions, validators []Validator, stats *ValidationStats) []ValidationIssue {
+	counter := &ioCounter{}
+	ctx = withIOCounter(ctx, counter)
+	var ioStats IOStats
+
+	issues := []ValidationIssue{}
+	for _, v := range validators {
+		name := v.Name()
+		if isCheck(name) && !opts.runs(name) || !v.Applies(setInfo) {
+			continue
+		}
+		phaseStart := time.Now()
+		before := counter.sample()
+		issues = append(issues, v.Validate(ctx, setInfo)...)
+		stats.PhaseTimings.Since(name, phaseStart)
+		ioStats.recordPhase(name, before, counter.sample(), time.Since(phaseStart))
+	}
+	if ioStats.BytesRead > 0 {
+		stats.IO = &ioStats
+	}
+	return issues
+}
+
+// validatorFunc is a Validator made of functions; a nil applies applies to
+// every set
+type validatorFunc struct {
+	name     string
+	applies  func(setInfo BackupSetInfo) bool
+	validate func(ctx context.Context, setInfo BackupSetInfo) []ValidationIssue
+}
+
+func (v validatorFunc) Name() string {
+	return v.name
+}
+
+func (v validatorFunc) Applies(setInfo BackupSetInfo) bool {
+	return v.applies == nil || v.applies(setInfo)
+}
+
+func (v validatorFunc) Validate(ctx context.Context, setInfo BackupSetInfo) []ValidationIssue {
+	return v.validate(ctx, setInfo)
+}
@@ -0,0 +1,89 @@
+package winbackupchecker
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Build information, overridden at build time with
+// -ldflags "-X github.com/RyanHarang/win-backup-checker/pkg/backup.Version=v1.2.3"
+// and likewise for Commit and BuildDate
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// BuildInfo describes the running build
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+
+	// CommitTime is the time of Commit, known when built from a checkout
+	CommitTime string `json:"commit_time,omitempty"`
+	GoVersion  string `json:"go_version"`
+	Platform   string `json:"platform"`
+}
+
+// CurrentBuild returns the build information. A commit not set with -ldflags
+// is taken from the version control information go build embeds, when
+// available.
+func CurrentBuild() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok || Commit != "" {
+		return info
+	}
+	modified := false
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Commit = setting.Value
+		case "vcs.time":
+			info.CommitTime = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if modified && info.Commit != "" {
+		info.Commit += "-dirty"
+	}
+	return info
+}
+
+// String formats the build for --version output
+func (b BuildInfo) String() string {
+	s := "win-backup-checker " + b.Version
+	var details []string
+	if b.Commit != "" {
+		details = append(details, "commit "+b.Commit)
+	}
+	if b.BuildDate != "" {
+		details = append(details, "built "+b.BuildDate)
+	} else if b.CommitTime != "" {
+		details = append(details, "committed "+b.CommitTime)
+	}
+	details = append(details, b.GoVersion, b.Platform)
+	return s + " (" + strings.Join(details, ", ") + ")"
+}
+
+// checkDefinitionsVersion must be bumped whenever parsing or validation logic
+// changes in a way that could alter results for previously checked files
+const checkDefinitionsVersion = 2
+
+// CacheFingerprint identifies the checker build and check definitions that
+// produced a cached result. Cached entries with a different fingerprint are
+// discarded so an upgrade re-validates everything.
+func CacheFingerprint() string {
+	return fmt.Sprintf("%s/checks-%d", Version, checkDefinitionsVersion)
+}
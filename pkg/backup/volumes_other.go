@@ -0,0 +1,22 @@
+//go:build !windows
+
+package winbackupchecker
+
+import "path/filepath"
+
+// mountPatterns match where removable and extra drives are usually mounted
+var mountPatterns = []string{"/Volumes/*", "/media/*", "/media/*/*", "/run/media/*/*", "/mnt/*"}
+
+// mountedVolumes returns the directories drives are commonly mounted at
+func mountedVolumes() []string {
+	var volumes []string
+	for _, pattern := range mountPatterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			if dirExists(match) {
+				volumes = append(volumes, match)
+			}
+		}
+	}
+	return volumes
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package winbackupchecker
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// Drive types from GetDriveTypeW
+const (
+	driveRemovable = 2
+	driveFixed     = 3
+)
+
+var procGetDriveTypeW = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDriveTypeW")
+
+// mountedVolumes returns the roots of local fixed and removable drives
+func mountedVolumes() []string {
+	var volumes []string
+	for letter := 'A'; letter <= 'Z'; letter++ {
+		root := string(letter) + `:\`
+		p, err := syscall.UTF16PtrFromString(root)
+		if err != nil {
+			continue
+		}
+		driveType, _, _ := procGetDriveTypeW.Call(uintptr(unsafe.Pointer(p)))
+		if driveType == driveFixed || driveType == driveRemovable {
+			volumes = append(volumes, root)
+		}
+	}
+	return volumes
+}
@@ -0,0 +1,115 @@
+package winbackupchecker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SetWatcher follows the backup sets under a list of roots between polls and
+// reports each new or changed set once it has stopped changing, so a set is
+// validated after its backup job finished rather than while it is written
+type SetWatcher struct {
+	settle time.Duration
+	sets   map[string]*watchedSet
+
+	// primed holds the roots polled successfully at least once; their sets
+	// found on the first poll are left to the regular checks
+	primed map[string]bool
+}
+
+// watchedSet is the state of a set at the last poll
+type watchedSet struct {
+	size      int64
+	files     int
+	modTime   time.Time
+	changedAt time.Time
+	pending   bool
+}
+
+// NewSetWatcher returns a watcher reporting sets unchanged for settle
+func NewSetWatcher(settle time.Duration) *SetWatcher {
+	return &SetWatcher{
+		settle: settle,
+		sets:   make(map[string]*watchedSet),
+		primed: make(map[string]bool),
+	}
+}
+
+// Poll lists the sets under roots, following the links allowed by links, and
+// returns the paths of those that appeared or changed since an earlier poll
+// and have since been unchanged for the settle period. A root that cannot be
+// read is reported in the error and keeps its sets' state until the next
+// poll.
+func (w *SetWatcher) Poll(roots []string, links *LinkConfig, now time.Time) ([]string, error) {
+	var ready []string
+	var errs []error
+	seen := make(map[string]bool)
+
+	for _, root := range roots {
+		sets, err := DiscoverBackupSets(root, links)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to watch %s: %w", root, err))
+			w.keep(root, seen)
+			continue
+		}
+		first := !w.primed[root]
+		w.primed[root] = true
+
+		for _, set := range sets {
+			seen[set.Path] = true
+			state, ok := w.sets[set.Path]
+			if !ok {
+				w.sets[set.Path] = &watchedSet{
+					size:      set.Size,
+					files:     set.FileCount,
+					modTime:   set.ModTime,
+					changedAt: now,
+					pending:   !first,
+				}
+				continue
+			}
+			if state.size != set.Size || state.files != set.FileCount || !state.modTime.Equal(set.ModTime) {
+				state.size, state.files, state.modTime = set.Size, set.FileCount, set.ModTime
+				state.changedAt = now
+				state.pending = true
+				continue
+			}
+			if state.pending && now.Sub(state.changedAt) >= w.settle {
+				state.pending = false
+				ready = append(ready, set.Path)
+			}
+		}
+	}
+
+	// Forget sets that were deleted or whose root is no longer watched
+	for path := range w.sets {
+		if !seen[path] {
+			delete(w.sets, path)
+		}
+	}
+
+	sort.Strings(ready)
+	return ready, errors.Join(errs...)
+}
+
+// Retry reports sets again on the next poll, such as when their check could
+// not run
+func (w *SetWatcher) Retry(paths []string) {
+	for _, path := range paths {
+		if state, ok := w.sets[path]; ok {
+			state.pending = true
+		}
+	}
+}
+
+// keep marks the known sets under root as seen, so an unreachable root does
+// not lose their state
+func (w *SetWatcher) keep(root string, seen map[string]bool) {
+	for path := range w.sets {
+		if isSubPath(root, path) {
+			seen[path] = true
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package winbackupchecker
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WebhookConfig configures a generic webhook that receives the full run report
+type WebhookConfig struct {
+	Enabled      bool              `json:"enabled"`
+	URL          string            `json:"url" secret:"true"`
+	Secret       string            `json:"secret,omitempty" secret:"true"`
+	Headers      map[string]string `json:"headers,omitempty" secret:"true"`
+	MaxRetries   *int              `json:"max_retries,omitempty"`
+	RetryBackoff string            `json:"retry_backoff,omitempty"`
+	NotifyTriggers
+}
+
+// Validate checks if webhook configuration is valid
+func (c *WebhookConfig) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if c.MaxRetries != nil && *c.MaxRetries < 0 {
+		return fmt.Errorf("max_retries cannot be negative")
+	}
+	if c.RetryBackoff != "" {
+		if _, err := ParseDuration(c.RetryBackoff); err != nil {
+			return fmt.Errorf("invalid retry_backoff: %w", err)
+		}
+	}
+	return c.NotifyTriggers.Validate()
+}
+
+// WebhookSignatureHeader carries the hex HMAC-SHA256 of the request body,
+// keyed with the configured secret, as "sha256=<hex>"
+const WebhookSignatureHeader = "X-Backup-Checker-Signature"
+
+// WebhookTimestampHeader carries the Unix time the request was signed at;
+// the signature covers "<timestamp>.<body>" so captured requests cannot be
+// replayed later with a fresh timestamp
+const WebhookTimestampHeader = "X-Backup-Checker-Timestamp"
+
+// WebhookNotifier POSTs the full RunReport JSON to an arbitrary URL
+type WebhookNotifier struct {
+	cfg *WebhookConfig
+}
+
+func (n *WebhookNotifier) Name() string { return "webhook" }
+
+// Notify posts the report, retrying transient failures with exponential backoff
+func (n *WebhookNotifier) Notify(ctx context.Context, report RunReport) error {
+	status := NewRunStatus(report.Summary, report.Results)
+	if !n.cfg.ShouldSend(status) {
+		return nil
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	maxRetries := 3
+	if n.cfg.MaxRetries != nil {
+		maxRetries = *n.cfg.MaxRetries
+	}
+	backoff := 5 * time.Second
+	if n.cfg.RetryBackoff != "" {
+		backoff, _ = ParseDuration(n.cfg.RetryBackoff)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = postBody(ctx, n.cfg.URL, body, n.headers(body))
+		if err == nil {
+			return nil
+		}
+		if attempt >= maxRetries || !retryableWebhookError(err) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to post webhook: %w", ctx.Err())
+		case <-time.After(backoff << attempt):
+		}
+	}
+
+	return fmt.Errorf("failed to post webhook: %w", err)
+}
+
+// headers returns the configured headers plus the signature, if a secret is set
+func (n *WebhookNotifier) headers(body []byte) map[string]string {
+	headers := make(map[string]string, len(n.cfg.Headers)+2)
+	for k, v := range n.cfg.Headers {
+		headers[k] = v
+	}
+
+	if n.cfg.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers[WebhookTimestampHeader] = timestamp
+		headers[WebhookSignatureHeader] = "sha256=" + signWebhook(n.cfg.Secret, timestamp, body)
+	}
+
+	return headers
+}
+
+// signWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>"
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryableWebhookError reports whether a failed delivery is worth retrying.
+// Client errors other than 408 and 429 will not succeed on a retry.
+func retryableWebhookError(err error) bool {
+	var statusErr *httpStatusError
+	if !errors.As(err, &statusErr) {
+		return true
+	}
+	switch statusErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return statusErr.StatusCode >= 500
+}
@@ -0,0 +1,50 @@
+package winbackupchecker
+
+import (
+	"context"
+	"sync"
+)
+
+// workGroup runs tasks on at most limit goroutines at a time, like
+// golang.org/x/sync/errgroup with SetLimit, which it stands in for so the
+// module keeps to the standard library. The first task to fail cancels the
+// group's context with its error as the cause.
+type workGroup struct {
+	cancel context.CancelCauseFunc
+	slots  chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+// newWorkGroup returns a group running up to limit tasks at once, and the
+// context its tasks should use
+func newWorkGroup(ctx context.Context, limit int) (*workGroup, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &workGroup{cancel: cancel, slots: make(chan struct{}, max(limit, 1))}, ctx
+}
+
+// Go runs task on a new goroutine, first waiting for a free slot
+func (g *workGroup) Go(task func() error) {
+	g.slots <- struct{}{}
+	g.wg.Add(1)
+	go func() {
+		defer func() {
+			<-g.slots
+			g.wg.Done()
+		}()
+		if err := task(); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+	}()
+}
+
+// Wait waits for every task and returns the first error
+func (g *workGroup) Wait() error {
+	g.wg.Wait()
+	g.cancel(g.err)
+	return g.err
+}
@@ -0,0 +1,120 @@
+package winbackupchecker
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// XXH64 with a seed of 0, the default of xxhsum, for xxHash manifests. The
+// digest is written big-endian, as xxhsum prints it.
+
+const (
+	xxhPrime1 uint64 = 0x9E3779B185EBCA87
+	xxhPrime2 uint64 = 0xC2B2AE3D27D4EB4F
+	xxhPrime3 uint64 = 0x165667B19E3779F9
+	xxhPrime4 uint64 = 0x85EBCA77C2B2AE63
+	xxhPrime5 uint64 = 0x27D4EB2F165667C5
+)
+
+// xxh64Hasher is a hash.Hash computing XXH64 digests
+type xxh64Hasher struct {
+	total uint64
+	v     [4]uint64
+	buf   [32]byte
+	n     int
+}
+
+func newXXH64() hash.Hash {
+	h := &xxh64Hasher{}
+	h.Reset()
+	return h
+}
+
+func (h *xxh64Hasher) Size() int      { return 8 }
+func (h *xxh64Hasher) BlockSize() int { return 32 }
+
+func (h *xxh64Hasher) Reset() {
+	// The seeded accumulators wrap around, which constants cannot
+	prime1 := xxhPrime1
+	h.total = 0
+	h.v = [4]uint64{prime1 + xxhPrime2, xxhPrime2, 0, -prime1}
+	h.n = 0
+}
+
+func xxhRound(acc, input uint64) uint64 {
+	acc += input * xxhPrime2
+	return bits.RotateLeft64(acc, 31) * xxhPrime1
+}
+
+func xxhMergeRound(acc, v uint64) uint64 {
+	acc ^= xxhRound(0, v)
+	return acc*xxhPrime1 + xxhPrime4
+}
+
+func (h *xxh64Hasher) stripe(b []byte) {
+	h.v[0] = xxhRound(h.v[0], binary.LittleEndian.Uint64(b))
+	h.v[1] = xxhRound(h.v[1], binary.LittleEndian.Uint64(b[8:]))
+	h.v[2] = xxhRound(h.v[2], binary.LittleEndian.Uint64(b[16:]))
+	h.v[3] = xxhRound(h.v[3], binary.LittleEndian.Uint64(b[24:]))
+}
+
+func (h *xxh64Hasher) Write(p []byte) (int, error) {
+	n := len(p)
+	h.total += uint64(n)
+
+	if h.n > 0 {
+		c := copy(h.buf[h.n:], p)
+		h.n += c
+		p = p[c:]
+		if h.n < len(h.buf) {
+			return n, nil
+		}
+		h.stripe(h.buf[:])
+		h.n = 0
+	}
+	for len(p) >= 32 {
+		h.stripe(p)
+		p = p[32:]
+	}
+	h.n = copy(h.buf[:], p)
+	return n, nil
+}
+
+func (h *xxh64Hasher) Sum(b []byte) []byte {
+	var h64 uint64
+	if h.total >= 32 {
+		v := h.v
+		h64 = bits.RotateLeft64(v[0], 1) + bits.RotateLeft64(v[1], 7) +
+			bits.RotateLeft64(v[2], 12) + bits.RotateLeft64(v[3], 18)
+		for _, acc := range v {
+			h64 = xxhMergeRound(h64, acc)
+		}
+	} else {
+		h64 = xxhPrime5
+	}
+	h64 += h.total
+
+	rest := h.buf[:h.n]
+	for ; len(rest) >= 8; rest = rest[8:] {
+		h64 ^= xxhRound(0, binary.LittleEndian.Uint64(rest))
+		h64 = bits.RotateLeft64(h64, 27)*xxhPrime1 + xxhPrime4
+	}
+	if len(rest) >= 4 {
+		h64 ^= uint64(binary.LittleEndian.Uint32(rest)) * xxhPrime1
+		h64 = bits.RotateLeft64(h64, 23)*xxhPrime2 + xxhPrime3
+		rest = rest[4:]
+	}
+	for _, c := range rest {
+		h64 ^= uint64(c) * xxhPrime5
+		h64 = bits.RotateLeft64(h64, 11) * xxhPrime1
+	}
+
+	h64 ^= h64 >> 33
+	h64 *= xxhPrime2
+	h64 ^= h64 >> 29
+	h64 *= xxhPrime3
+	h64 ^= h64 >> 32
+
+	return binary.BigEndian.AppendUint64(b, h64)
+}
@@ -0,0 +1,242 @@
+package winbackupchecker
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Content modes, selecting how zip files are checked
+const (
+	// ContentModeStandard opens each zip file and reads the start of its
+	// first entries
+	ContentModeStandard = "standard"
+
+	// ContentModeFast checks the central directory of each zip file only,
+	// without reading any entry data, for frequent quick scans
+	ContentModeFast = "fast"
+)
+
+// ValidateContentMode checks that mode is empty, standard or fast
+func ValidateContentMode(mode string) error {
+	switch mode {
+	case "", ContentModeStandard, ContentModeFast:
+		return nil
+	default:
+		return fmt.Errorf("unknown content mode %q (use standard or fast)", mode)
+	}
+}
+
+// Zip record signatures and fixed sizes
+const (
+	zipLocalHeaderLen      = 30
+	zipCentralHeaderSig    = 0x02014b50
+	zipCentralHeaderLen    = 46
+	zipEndSig              = 0x06054b50
+	zipEndLen              = 22
+	zip64EndSig            = 0x06064b50
+	zip64EndLen            = 56
+	zip64LocatorSig        = 0x07064b50
+	zip64LocatorLen        = 20
+	zip64ExtraID           = 0x0001
+	zip64Saturated         = 0xffffffff
+	zipMaxCommentLen       = 0xffff
+	zipMethodStore         = 0
+	zipFlagEncrypted       = 0x1
+	zipDirectoryReadLimit  = 64 << 20
+	zipDirectoryEntryLimit = 1 << 22
+)
+
+// zipDirectory is the central directory of a zip file
+type zipDirectory struct {
+	offset  int64
+	entries []zipDirectoryEntry
+}
+
+// zipDirectoryEntry is a central directory file header
+type zipDirectoryEntry struct {
+	name             string
+	flags            uint16
+	method           uint16
+	compressedSize   uint64
+	uncompressedSize uint64
+	offset           uint64
+}
+
+// validateZipDirectory checks a zip file from its end of central directory
+// record and central directory alone, reading no entry data: the directory
+// must list entries, and each must fit in the file before the directory
+// without overlapping the next
+func validateZipDirectory(ctx context.Context, zipPath string) error {
+	file, err := openBackupFile(ctx, zipPath)
+	if err != nil {
+		return fmt.Errorf("cannot open zip: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("cannot open zip: %w", err)
+	}
+
+	dir, err := readZipDirectory(file, info.Size())
+	if err != nil {
+		return fmt.Errorf("cannot open zip: %w", err)
+	}
+	if len(dir.entries) == 0 {
+		return fmt.Errorf("zip file is empty")
+	}
+	if len(dir.entries) == 1 && dir.entries[0].uncompressedSize == 0 {
+		return fmt.Errorf("zip contains only empty file")
+	}
+
+	entries := append([]zipDirectoryEntry(nil), dir.entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].offset < entries[j].offset })
+	for i, entry := range entries {
+		if entry.method == zipMethodStore && entry.flags&zipFlagEncrypted == 0 && entry.compressedSize != entry.uncompressedSize {
+			return fmt.Errorf("stored file %s in zip has a compressed size of %d but a size of %d", entry.name, entry.compressedSize, entry.uncompressedSize)
+		}
+
+		// The local header is at least as long as its fixed part and name
+		end := entry.offset + zipLocalHeaderLen + uint64(len(entry.name)) + entry.compressedSize
+		if end < entry.offset || end > uint64(dir.offset) {
+			return fmt.Errorf("file %s in zip extends past the central directory", entry.name)
+		}
+		if i+1 < len(entries) && end > entries[i+1].offset {
+			return fmt.Errorf("file %s in zip overlaps %s", entry.name, entries[i+1].name)
+		}
+	}
+	return nil
+}
+
+// readZipDirectory locates and parses the central directory of the zip file
+// of size in r, including ZIP64 records
+func readZipDirectory(r io.ReaderAt, size int64) (*zipDirectory, error) {
+	// The end record is followed by a comment of up to 64 KiB
+	tailLen := min(size, zipEndLen+zipMaxCommentLen)
+	tail := make([]byte, tailLen)
+	if _, err := r.ReadAt(tail, size-tailLen); err != nil {
+		return nil, err
+	}
+	endPos := -1
+	for i := len(tail) - zipEndLen; i >= 0; i-- {
+		if binary.LittleEndian.Uint32(tail[i:]) == zipEndSig &&
+			i+zipEndLen+int(binary.LittleEndian.Uint16(tail[i+20:])) <= len(tail) {
+			endPos = i
+			break
+		}
+	}
+	if endPos < 0 {
+		return nil, errors.New("not a valid zip file: end of central directory not found")
+	}
+	end := tail[endPos:]
+	endOffset := size - tailLen + int64(endPos)
+
+	count := uint64(binary.LittleEndian.Uint16(end[10:]))
+	dirSize := uint64(binary.LittleEndian.Uint32(end[12:]))
+	dirOffset := uint64(binary.LittleEndian.Uint32(end[16:]))
+	limit := uint64(endOffset)
+
+	// ZIP64 files keep the real values in a record found through a locator
+	// right before the end record
+	if count == 0xffff || dirSize == zip64Saturated || dirOffset == zip64Saturated {
+		if endOffset < zip64LocatorLen {
+			return nil, errors.New("not a valid zip file: ZIP64 locator missing")
+		}
+		locator := make([]byte, zip64LocatorLen)
+		if _, err := r.ReadAt(locator, endOffset-zip64LocatorLen); err != nil {
+			return nil, err
+		}
+		if binary.LittleEndian.Uint32(locator) != zip64LocatorSig {
+			return nil, errors.New("not a valid zip file: ZIP64 locator missing")
+		}
+		recordOffset := binary.LittleEndian.Uint64(locator[8:])
+		if recordOffset > uint64(endOffset-zip64LocatorLen) {
+			return nil, errors.New("not a valid zip file: ZIP64 end record out of range")
+		}
+		record := make([]byte, zip64EndLen)
+		if _, err := r.ReadAt(record, int64(recordOffset)); err != nil {
+			return nil, err
+		}
+		if binary.LittleEndian.Uint32(record) != zip64EndSig {
+			return nil, errors.New("not a valid zip file: ZIP64 end record missing")
+		}
+		count = binary.LittleEndian.Uint64(record[32:])
+		dirSize = binary.LittleEndian.Uint64(record[40:])
+		dirOffset = binary.LittleEndian.Uint64(record[48:])
+		limit = recordOffset
+	}
+
+	if dirOffset > limit || dirSize > limit-dirOffset {
+		return nil, fmt.Errorf("central directory at %d (%d bytes) extends past its end record at %d", dirOffset, dirSize, limit)
+	}
+	if dirSize > zipDirectoryReadLimit || count > zipDirectoryEntryLimit || count*zipCentralHeaderLen > dirSize {
+		return nil, fmt.Errorf("central directory size %d does not fit its %d entries", dirSize, count)
+	}
+
+	data := make([]byte, dirSize)
+	if _, err := r.ReadAt(data, int64(dirOffset)); err != nil {
+		return nil, err
+	}
+
+	dir := &zipDirectory{offset: int64(dirOffset), entries: make([]zipDirectoryEntry, 0, count)}
+	for pos := 0; uint64(len(dir.entries)) < count; {
+		if len(data)-pos < zipCentralHeaderLen || binary.LittleEndian.Uint32(data[pos:]) != zipCentralHeaderSig {
+			return nil, fmt.Errorf("central directory entry %d is damaged", len(dir.entries)+1)
+		}
+		h := data[pos:]
+		nameLen := int(binary.LittleEndian.Uint16(h[28:]))
+		extraLen := int(binary.LittleEndian.Uint16(h[30:]))
+		commentLen := int(binary.LittleEndian.Uint16(h[32:]))
+		next := pos + zipCentralHeaderLen + nameLen + extraLen + commentLen
+		if next > len(data) {
+			return nil, fmt.Errorf("central directory entry %d extends past the directory", len(dir.entries)+1)
+		}
+
+		entry := zipDirectoryEntry{
+			name:             string(h[zipCentralHeaderLen : zipCentralHeaderLen+nameLen]),
+			flags:            binary.LittleEndian.Uint16(h[8:]),
+			method:           binary.LittleEndian.Uint16(h[10:]),
+			compressedSize:   uint64(binary.LittleEndian.Uint32(h[20:])),
+			uncompressedSize: uint64(binary.LittleEndian.Uint32(h[24:])),
+			offset:           uint64(binary.LittleEndian.Uint32(h[42:])),
+		}
+		extra := h[zipCentralHeaderLen+nameLen : zipCentralHeaderLen+nameLen+extraLen]
+		if err := entry.applyZip64Extra(extra); err != nil {
+			return nil, fmt.Errorf("central directory entry %s: %w", entry.name, err)
+		}
+		dir.entries = append(dir.entries, entry)
+		pos = next
+	}
+	return dir, nil
+}
+
+// applyZip64Extra replaces the sizes and offset saturated in the header
+// with those of the ZIP64 extra field, which lists only those, in order
+func (e *zipDirectoryEntry) applyZip64Extra(extra []byte) error {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		n := int(binary.LittleEndian.Uint16(extra[2:]))
+		if 4+n > len(extra) {
+			return errors.New("damaged extra field")
+		}
+		field := extra[4 : 4+n]
+		extra = extra[4+n:]
+		if id != zip64ExtraID {
+			continue
+		}
+		for _, value := range []*uint64{&e.uncompressedSize, &e.compressedSize, &e.offset} {
+			if *value != zip64Saturated {
+				continue
+			}
+			if len(field) < 8 {
+				return errors.New("ZIP64 extra field too short")
+			}
+			*value = binary.LittleEndian.Uint64(field)
+			field = field[8:]
+		}
+	}
+	return nil
+}